@@ -0,0 +1,71 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/apidiff"
+	"golang.org/x/tools/go/packages"
+)
+
+// apiExceptions are the incompatible changes to the baseline API which were
+// accepted. The structs gained slice and map fields, which only breaks
+// callers comparing clients or configs with ==. The string fields with a
+// fixed set of values became typed, untyped constants still compile but
+// string variables need a conversion.
+var apiExceptions = map[string]bool{
+	"Client: old is comparable, new is not": true,
+	"Config: old is comparable, new is not": true,
+
+	"Device.NodeType: changed from string to NodeType":             true,
+	"DeviceInfo.ProtocolType: changed from string to ProtocolType": true,
+	"DeviceInfo.Status: changed from string to DeviceStatus":       true,
+	"GetDevicesStruct.NodeType: changed from string to NodeType":   true,
+	"GetDevicesStruct.Sort: changed from string to SortOrder":      true,
+	"GetDevicesStruct.Status: changed from string to DeviceStatus": true,
+}
+
+// TestAPICompatibility fails when the exported API breaks code written
+// against the baseline API in testdata/apibase
+func TestAPICompatibility(t *testing.T) {
+	pkgs, err := packages.Load(&packages.Config{
+		Mode: packages.NeedName | packages.NeedTypes | packages.NeedTypesInfo |
+			packages.NeedSyntax | packages.NeedDeps | packages.NeedImports,
+	}, "./testdata/apibase", ".")
+	if !assert.Nil(t, err) || !assert.Equal(t, 2, len(pkgs)) {
+		return
+	}
+	var base, cur *packages.Package
+	for _, p := range pkgs {
+		if !assert.Empty(t, p.Errors, p.PkgPath) {
+			return
+		}
+		if p.PkgPath == "github.com/dualinventive/go-oceanconnect" {
+			cur = p
+		} else {
+			base = p
+		}
+	}
+	if !assert.NotNil(t, base) || !assert.NotNil(t, cur) {
+		return
+	}
+
+	for _, c := range apidiff.Changes(base.Types, cur.Types).Changes {
+		if !c.Compatible && !apiExceptions[c.Message] {
+			t.Errorf("incompatible API change: %s", c.Message)
+		}
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	assert.Nil(t, CheckVersion(Version))
+	assert.Nil(t, CheckVersion("v1.0.0"))
+	assert.NotNil(t, CheckVersion("1.1.0"))
+	assert.NotNil(t, CheckVersion("2.0.0"))
+	assert.NotNil(t, CheckVersion("0.9.0"))
+	assert.Equal(t, ErrInvalidVersion, CheckVersion("1.0"))
+}
@@ -0,0 +1,153 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// BatchTaskType is the type of a batch task
+type BatchTaskType string
+
+const (
+	// BatchTaskDeviceCommand sends a command to a list of devices
+	BatchTaskDeviceCommand BatchTaskType = "DeviceCmd"
+	// BatchTaskGroupCommand sends a command to a device group
+	BatchTaskGroupCommand BatchTaskType = "GroupCmd"
+	// BatchTaskDeviceRegistration registers a list of devices
+	BatchTaskDeviceRegistration BatchTaskType = "DeviceReg"
+	// BatchTaskSoftwareUpgrade upgrades the software of a list of devices
+	BatchTaskSoftwareUpgrade BatchTaskType = "SoftwareUpgrade"
+	// BatchTaskFirmwareUpgrade upgrades the firmware of a list of devices
+	BatchTaskFirmwareUpgrade BatchTaskType = "FirmwareUpgrade"
+)
+
+// BatchTaskStatus is the status of a batch task
+type BatchTaskStatus string
+
+const (
+	// BatchTaskPending is used for tasks which are not started yet
+	BatchTaskPending BatchTaskStatus = "Pending"
+	// BatchTaskRunning is used for tasks which are being executed
+	BatchTaskRunning BatchTaskStatus = "Running"
+	// BatchTaskComplete is used for tasks which are finished
+	BatchTaskComplete BatchTaskStatus = "Complete"
+	// BatchTaskTimeout is used for tasks which didn't finish in time
+	BatchTaskTimeout BatchTaskStatus = "Timeout"
+)
+
+// Done reports whether the task reached a final status
+func (s BatchTaskStatus) Done() bool {
+	return s == BatchTaskComplete || s == BatchTaskTimeout
+}
+
+// BatchTask struct with the details of a batch task
+type BatchTask struct {
+	TaskID      string          `json:"taskId"`
+	TaskName    string          `json:"taskName"`
+	AppID       string          `json:"appId"`
+	Operator    string          `json:"operator"`
+	TaskFrom    string          `json:"taskFrom"`
+	TaskType    BatchTaskType   `json:"taskType"`
+	Status      BatchTaskStatus `json:"status"`
+	StartTime   OcTime          `json:"startTime"`
+	Timeout     int             `json:"timeout"`
+	Progress    int             `json:"progress"`
+	TotalCnt    int             `json:"totalCnt"`
+	SuccessCnt  int             `json:"successCnt"`
+	FailCnt     int             `json:"failCnt"`
+	TimeoutCnt  int             `json:"timeoutCnt"`
+	ExpiredCnt  int             `json:"expiredCnt"`
+	CompleteCnt int             `json:"completeCnt"`
+	SuccessRate int             `json:"successRate"`
+	Param       json.RawMessage `json:"param"`
+}
+
+// BatchTaskFilter selects the batch tasks returned by ListBatchTasks, empty
+// fields are not filtered on
+type BatchTaskFilter struct {
+	TaskType BatchTaskType
+	Status   BatchTaskStatus
+	PageNo   int
+	PageSize int
+}
+
+// BatchTaskPage is a page of batch tasks
+type BatchTaskPage struct {
+	TotalCount int         `json:"totalCount"`
+	PageNo     int         `json:"pageNo"`
+	PageSize   int         `json:"pageSize"`
+	Tasks      []BatchTask `json:"tasks"`
+}
+
+// ListBatchTasks returns the batch tasks of all types of the application
+func (c *Client) ListBatchTasks(f BatchTaskFilter) (*BatchTaskPage, error) {
+	return c.ListBatchTasksCtx(context.Background(), f)
+}
+
+// ListBatchTasksCtx is like ListBatchTasks but with a context
+func (c *Client) ListBatchTasksCtx(ctx context.Context, f BatchTaskFilter) (*BatchTaskPage, error) {
+	e := c.appEndpoint("/iocm/app/batchtask/v1.1.0/tasks").
+		Set("taskType", string(f.TaskType)).
+		Set("status", string(f.Status)).
+		SetInt("pageNo", f.PageNo)
+	if f.PageSize != 0 {
+		e.SetInt("pageSize", f.PageSize)
+	}
+
+	resp, err := c.requestCtx(ctx, http.MethodGet, e.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.newAPIError(resp)
+	}
+	p := &BatchTaskPage{}
+	if err := c.decode(resp, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetBatchTask returns the details of a batch task
+func (c *Client) GetBatchTask(taskID string) (*BatchTask, error) {
+	return c.GetBatchTaskCtx(context.Background(), taskID)
+}
+
+// GetBatchTaskCtx is like GetBatchTask but with a context
+func (c *Client) GetBatchTaskCtx(ctx context.Context, taskID string) (*BatchTask, error) {
+	resp, err := c.requestCtx(ctx, http.MethodGet, c.appEndpoint("/iocm/app/batchtask/v1.1.0/tasks", taskID).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.newAPIError(resp)
+	}
+	t := &BatchTask{}
+	if err := c.decode(resp, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// DeleteBatchTask deletes a batch task
+func (c *Client) DeleteBatchTask(taskID string) error {
+	return c.DeleteBatchTaskCtx(context.Background(), taskID)
+}
+
+// DeleteBatchTaskCtx is like DeleteBatchTask but with a context
+func (c *Client) DeleteBatchTaskCtx(ctx context.Context, taskID string) error {
+	resp, err := c.requestCtx(ctx, http.MethodDelete, c.appEndpoint("/iocm/app/batchtask/v1.1.0/tasks", taskID).String(), nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return c.newAPIError(resp)
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,62 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchTasks(t *testing.T) {
+	var requests []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		switch {
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/iocm/app/batchtask/v1.1.0/tasks":
+			fmt.Fprintln(w, `{"totalCount":1,"pageNo":0,"pageSize":10,"tasks":[{"taskId":"task1","taskType":"FirmwareUpgrade","status":"Running","progress":40}]}`)
+		case r.URL.Path == "/iocm/app/batchtask/v1.1.0/tasks/task1":
+			fmt.Fprintln(w, `{"taskId":"task1","taskType":"FirmwareUpgrade","status":"Complete","startTime":"20170912T101530Z","totalCnt":2,"successCnt":2}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL}}
+	p, err := c.ListBatchTasks(BatchTaskFilter{TaskType: BatchTaskFirmwareUpgrade, Status: BatchTaskRunning, PageSize: 10})
+	if assert.Nil(t, err) && assert.Equal(t, 1, len(p.Tasks)) {
+		assert.Equal(t, BatchTaskRunning, p.Tasks[0].Status)
+		assert.False(t, p.Tasks[0].Status.Done())
+		assert.Equal(t, 40, p.Tasks[0].Progress)
+	}
+
+	task, err := c.GetBatchTask("task1")
+	if assert.Nil(t, err) {
+		assert.Equal(t, BatchTaskFirmwareUpgrade, task.TaskType)
+		assert.True(t, task.Status.Done())
+		assert.Equal(t, 2, task.SuccessCnt)
+	}
+	assert.Nil(t, c.DeleteBatchTask("task1"))
+
+	_, err = c.GetBatchTask("task2")
+	assert.True(t, IsNotFound(err))
+
+	assert.Equal(t, []string{
+		"GET /iocm/app/batchtask/v1.1.0/tasks?pageNo=0&pageSize=10&status=Running&taskType=FirmwareUpgrade",
+		"GET /iocm/app/batchtask/v1.1.0/tasks/task1",
+		"DELETE /iocm/app/batchtask/v1.1.0/tasks/task1",
+		"GET /iocm/app/batchtask/v1.1.0/tasks/task2",
+	}, requests)
+}
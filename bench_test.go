@@ -0,0 +1,130 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const benchDevice = `{"deviceId":"%s","gatewayId":"%s","nodeType":"ENDPOINT","creationTime":"20170912T101530Z",` +
+	`"lastModifiedTime":"20170912T101530Z","deviceInfo":{"nodeId":"867725030000000","name":"bench","manufacturerId":"foo",` +
+	`"deviceType":"Sensor","model":"m1","protocolType":"CoAP","status":"ONLINE"},` +
+	`"services":[{"serviceId":"Temperature","serviceType":"Temperature","data":{"temperature":215},"eventTime":"20170912T101530Z"}]}`
+
+const benchNotification = `{"notifyType":"deviceDataChanged","requestId":null,"deviceId":"dev1","gatewayId":"dev1",` +
+	`"service":{"serviceId":"Temperature","serviceType":"Temperature","data":{"temperature":215},"eventTime":"20170912T101530Z"}}`
+
+func benchDevicePage(n int) string {
+	devs := make([]string, n)
+	for i := range devs {
+		id := fmt.Sprintf("dev%d", i)
+		devs[i] = fmt.Sprintf(benchDevice, id, id)
+	}
+	return fmt.Sprintf(`{"totalCount":%d,"pageNo":0,"pageSize":%d,"devices":[%s]}`, n, n, strings.Join(devs, ","))
+}
+
+// newBenchClient returns a client for a mock platform serving a single device
+// and a page of 100 devices
+func newBenchClient() (*Client, func()) {
+	page := benchDevicePage(100)
+	dev := fmt.Sprintf(benchDevice, "dev1", "dev1")
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/iocm/app/sec/v1.1.0/login":
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+		case r.URL.Path == "/iocm/app/dm/v1.1.0/devices":
+			fmt.Fprintln(w, page)
+		default:
+			fmt.Fprintln(w, dev)
+		}
+	}))
+	c := &Client{
+		c:   &http.Client{},
+		cfg: Config{URL: s.URL},
+	}
+	return c, s.Close
+}
+
+func BenchmarkGetDevice(b *testing.B) {
+	c, done := newBenchClient()
+	defer done()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.GetDevice("dev1"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetDeviceParallel(b *testing.B) {
+	c, done := newBenchClient()
+	defer done()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := c.GetDevice("dev1"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetDevicesPage(b *testing.B) {
+	c, done := newBenchClient()
+	defer done()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.GetDevices(GetDevicesStruct{PageSize: 100}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkServerIngest(b *testing.B) {
+	s := &Server{}
+	s.RegisterCallback(NotificationDeviceDataChanged, func(interface{}) error { return nil })
+	body := []byte(benchNotification)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(body)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		s.handler(httptest.NewRecorder(), r)
+	}
+}
+
+func BenchmarkDeviceMemory(b *testing.B) {
+	const n = 1000
+	devs := make([]Device, n)
+	raw := []byte(fmt.Sprintf(benchDevice, "dev1", "dev1"))
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		for j := range devs {
+			devs[j] = Device{}
+			if err := (StdCodec{}).Unmarshal(raw, &devs[j]); err != nil {
+				b.Fatal(err)
+			}
+		}
+		runtime.ReadMemStats(&after)
+		b.ReportMetric(float64(after.TotalAlloc-before.TotalAlloc)/n, "B/device")
+	}
+}
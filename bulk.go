@@ -0,0 +1,285 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrBulkAborted is set on the items which weren't run because the error
+// rate of a bulk operation exceeded BulkOptions.MaxErrorRate
+var ErrBulkAborted = errors.New("bulk operation aborted, error rate exceeded")
+
+// BulkOptions controls how a BulkExecutor runs the operations
+type BulkOptions struct {
+	Concurrency int           // Concurrency is the number of operations run in parallel (default 4)
+	Rate        float64       // Rate limits the operations started per second, 0 is unlimited
+	Retries     int           // Retries is the number of retries after a failed operation (default 2), negative disables them
+	RetryDelay  time.Duration // RetryDelay is the time between retries (default 1s)
+
+	// MaxErrorRate aborts the operation when the fraction of failed items
+	// exceeds it, once MinSamples items are done. 0 never aborts.
+	MaxErrorRate float64
+	// MinSamples is the number of done items before the error rate is
+	// checked (default 10)
+	MinSamples int
+
+	// Progress is called after every finished item
+	Progress func(BulkProgress)
+	// Checkpoint records the finished items, items already recorded are
+	// skipped so an interrupted operation can be resumed
+	Checkpoint CheckpointStore
+}
+
+// BulkProgress is the progress of a bulk operation
+type BulkProgress struct {
+	Total  int
+	Done   int
+	Failed int
+}
+
+// BulkResult is the result of a single item of a bulk operation
+type BulkResult struct {
+	Key      string
+	Attempts int
+	Skipped  bool // Skipped is set for items done in an earlier run
+	Err      error
+}
+
+// BulkReport contains the results of a bulk operation, in input order
+type BulkReport struct {
+	Results []BulkResult
+}
+
+// Failed returns the results of the items which failed or weren't run
+func (r *BulkReport) Failed() []BulkResult {
+	var failed []BulkResult
+	for _, res := range r.Results {
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// CheckpointStore persists which items of a bulk operation are done
+type CheckpointStore interface {
+	// Load returns the keys of the items done so far
+	Load() (map[string]bool, error)
+	// Save records the item as done
+	Save(key string) error
+}
+
+// FileCheckpoint is a CheckpointStore which appends the done keys to a file,
+// one per line
+type FileCheckpoint struct {
+	Path string
+
+	lock sync.Mutex
+}
+
+// Load implements the CheckpointStore interface, a missing file is empty
+func (f *FileCheckpoint) Load() (map[string]bool, error) {
+	done := make(map[string]bool)
+	fd, err := os.Open(f.Path)
+	if os.IsNotExist(err) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	sc := bufio.NewScanner(fd)
+	for sc.Scan() {
+		if sc.Text() != "" {
+			done[sc.Text()] = true
+		}
+	}
+	return done, sc.Err()
+}
+
+// Save implements the CheckpointStore interface
+func (f *FileCheckpoint) Save(key string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	fd, err := os.OpenFile(f.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := fd.WriteString(key + "\n"); err != nil {
+		fd.Close()
+		return err
+	}
+	return fd.Close()
+}
+
+// BulkExecutor runs an operation for many items, it is the engine behind the
+// bulk features of the client
+type BulkExecutor struct {
+	opts  BulkOptions
+	clock Clock
+}
+
+// NewBulkExecutor creates a BulkExecutor which uses the clock of the client
+func (c *Client) NewBulkExecutor(opts ...BulkOptions) *BulkExecutor {
+	o := BulkOptions{
+		Concurrency: 4,
+		Retries:     2,
+		RetryDelay:  time.Second,
+		MinSamples:  10,
+	}
+	if len(opts) > 0 {
+		p := opts[0]
+		if p.Concurrency > 0 {
+			o.Concurrency = p.Concurrency
+		}
+		if p.Retries > 0 {
+			o.Retries = p.Retries
+		} else if p.Retries < 0 {
+			o.Retries = 0
+		}
+		if p.RetryDelay > 0 {
+			o.RetryDelay = p.RetryDelay
+		}
+		if p.MinSamples > 0 {
+			o.MinSamples = p.MinSamples
+		}
+		o.Rate = p.Rate
+		o.MaxErrorRate = p.MaxErrorRate
+		o.Progress = p.Progress
+		o.Checkpoint = p.Checkpoint
+	}
+	return &BulkExecutor{opts: o, clock: c.clock()}
+}
+
+// Run calls fn for every key. It returns ErrBulkAborted when the error rate
+// was exceeded and the context error when the context is done, the report
+// holds the results so far in both cases.
+func (e *BulkExecutor) Run(ctx context.Context, keys []string, fn func(ctx context.Context, key string) error) (*BulkReport, error) {
+	report := &BulkReport{Results: make([]BulkResult, len(keys))}
+	done := map[string]bool{}
+	if e.opts.Checkpoint != nil {
+		var err error
+		if done, err = e.opts.Checkpoint.Load(); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		lock     sync.Mutex
+		progress = BulkProgress{Total: len(keys)}
+		aborted  bool
+	)
+	finish := func(res *BulkResult) {
+		// the checkpoint is saved first, an item which can't be recorded
+		// counts as failed
+		if res.Err == nil && !res.Skipped && e.opts.Checkpoint != nil {
+			if err := e.opts.Checkpoint.Save(res.Key); err != nil {
+				res.Err = err
+			}
+		}
+		lock.Lock()
+		progress.Done++
+		if res.Err != nil {
+			progress.Failed++
+		}
+		if e.opts.MaxErrorRate > 0 && progress.Done >= e.opts.MinSamples &&
+			float64(progress.Failed)/float64(progress.Done) > e.opts.MaxErrorRate {
+			aborted = true
+		}
+		p := progress
+		lock.Unlock()
+
+		if e.opts.Progress != nil {
+			e.opts.Progress(p)
+		}
+	}
+	isAborted := func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return aborted
+	}
+
+	var wg sync.WaitGroup
+	work := make(chan *BulkResult)
+	for i := 0; i < e.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for res := range work {
+				for res.Attempts <= e.opts.Retries {
+					if res.Attempts > 0 {
+						select {
+						case <-ctx.Done():
+						case <-e.clock.After(e.opts.RetryDelay):
+						}
+						if ctx.Err() != nil {
+							// keep the error of the last attempt
+							break
+						}
+					}
+					res.Attempts++
+					res.Err = fn(ctx, res.Key)
+					if res.Err == nil || ctx.Err() != nil {
+						break
+					}
+				}
+				finish(res)
+			}
+		}()
+	}
+
+	var interval time.Duration
+	if e.opts.Rate > 0 {
+		interval = time.Duration(float64(time.Second) / e.opts.Rate)
+	}
+	var err error
+	started := 0
+	for i, key := range keys {
+		res := &report.Results[i]
+		res.Key = key
+		if err != nil {
+			res.Err = err
+			continue
+		}
+		if done[key] {
+			res.Skipped = true
+			finish(res)
+			continue
+		}
+		if isAborted() {
+			err = ErrBulkAborted
+			res.Err = err
+			continue
+		}
+		if interval > 0 && started > 0 {
+			select {
+			case <-ctx.Done():
+			case <-e.clock.After(interval):
+			}
+		}
+		if ctx.Err() != nil {
+			err = ctx.Err()
+			res.Err = err
+			continue
+		}
+		started++
+		work <- res
+	}
+	close(work)
+	wg.Wait()
+
+	if err == nil && isAborted() {
+		err = ErrBulkAborted
+	}
+	return report, err
+}
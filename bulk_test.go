@@ -0,0 +1,117 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkExecutor(t *testing.T) {
+	c := &Client{cfg: Config{Clock: &fakeClock{now: time.Now()}}}
+	cp := &FileCheckpoint{Path: filepath.Join(t.TempDir(), "checkpoint")}
+	assert.Nil(t, cp.Save("dev1"))
+
+	var lock sync.Mutex
+	calls := map[string]int{}
+	e := c.NewBulkExecutor(BulkOptions{Concurrency: 2, Retries: 1, Checkpoint: cp})
+	report, err := e.Run(context.Background(), []string{"dev1", "dev2", "dev3"}, func(_ context.Context, key string) error {
+		lock.Lock()
+		defer lock.Unlock()
+		calls[key]++
+		if key == "dev3" {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.True(t, report.Results[0].Skipped, "expected checkpointed item to be skipped")
+	assert.Equal(t, 0, calls["dev1"])
+	assert.Equal(t, 1, calls["dev2"])
+	assert.Equal(t, 2, calls["dev3"], "expected a retry")
+	assert.Equal(t, 1, len(report.Failed()))
+
+	done, err := cp.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]bool{"dev1": true, "dev2": true}, done)
+}
+
+func TestBulkExecutorDefaultRetries(t *testing.T) {
+	c := &Client{cfg: Config{Clock: &fakeClock{now: time.Now()}}}
+	fail := func(context.Context, string) error { return errors.New("failed") }
+
+	// options without Retries use the default instead of disabling them
+	report, err := c.NewBulkExecutor(BulkOptions{Concurrency: 1}).Run(context.Background(), []string{"dev1"}, fail)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, report.Results[0].Attempts)
+
+	report, err = c.NewBulkExecutor(BulkOptions{Concurrency: 1, Retries: -1}).Run(context.Background(), []string{"dev1"}, fail)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, report.Results[0].Attempts)
+}
+
+func TestBulkExecutorAbort(t *testing.T) {
+	c := &Client{}
+	keys := make([]string, 20)
+	for i := range keys {
+		keys[i] = string(rune('a' + i))
+	}
+	e := c.NewBulkExecutor(BulkOptions{Concurrency: 1, Retries: -1, MaxErrorRate: 0.5, MinSamples: 2})
+	report, err := e.Run(context.Background(), keys, func(context.Context, string) error {
+		return errors.New("failed")
+	})
+	assert.Equal(t, ErrBulkAborted, err)
+	assert.Equal(t, ErrBulkAborted, report.Results[len(keys)-1].Err)
+	assert.Equal(t, 0, report.Results[len(keys)-1].Attempts)
+}
+
+// failingCheckpoint is a CheckpointStore which can't save
+type failingCheckpoint struct{}
+
+func (failingCheckpoint) Load() (map[string]bool, error) { return map[string]bool{}, nil }
+func (failingCheckpoint) Save(string) error              { return errors.New("disk full") }
+
+func TestBulkExecutorCheckpointError(t *testing.T) {
+	c := &Client{}
+	var progress []BulkProgress
+	e := c.NewBulkExecutor(BulkOptions{Concurrency: 1, Checkpoint: failingCheckpoint{}, Progress: func(p BulkProgress) {
+		progress = append(progress, p)
+	}})
+	report, err := e.Run(context.Background(), []string{"dev1"}, func(context.Context, string) error {
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.EqualError(t, report.Results[0].Err, "disk full")
+	assert.Equal(t, []BulkProgress{{Total: 1, Done: 1, Failed: 1}}, progress)
+}
+
+func TestBulkExecutorCancelRetry(t *testing.T) {
+	c := &Client{}
+	ctx, cancel := context.WithCancel(context.Background())
+	e := c.NewBulkExecutor(BulkOptions{Concurrency: 1, Retries: 1, RetryDelay: time.Hour})
+	done := make(chan struct{})
+	var report *BulkReport
+	go func() {
+		defer close(done)
+		report, _ = e.Run(ctx, []string{"dev1"}, func(context.Context, string) error {
+			return errors.New("failed")
+		})
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the retry delay to end with the context")
+	}
+	assert.Equal(t, 1, report.Results[0].Attempts)
+	assert.EqualError(t, report.Results[0].Err, "failed")
+}
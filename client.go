@@ -6,139 +6,494 @@ package oceanconnect
 
 import (
 	"bytes"
+	"context"
+	"crypto"
 	"crypto/tls"
-	"encoding/json"
+	"crypto/x509"
+	"encoding/pem"
 	"errors"
 	"io"
+	"io/ioutil"
 	"net/http"
-	"net/http/httputil"
-	"strconv"
 	"sync"
+	"text/template"
 	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
 // Config struct for client configuration
 type Config struct {
 	CertFile    string `yaml:"cert_file"` // CertFile is the path to the PEM client certificate
 	CertKeyFile string `yaml:"key_file"`  // CertKeyFile is the path to the PEM client certificate public key
+	CertPEM     []byte `yaml:"cert_pem"`  // CertPEM is the PEM client certificate, used instead of CertFile
+	KeyPEM      []byte `yaml:"key_pem"`   // KeyPEM is the PEM client certificate key, used instead of CertKeyFile
 	URL         string `yaml:"url"`       // URL where the Oceanconnect API is present
 	AppID       string `yaml:"app_id"`    // AppID is the application Identifier
 	Secret      string `yaml:"secret"`
 
+	// CAFile is the path to a PEM bundle with the CA certificates the server
+	// certificate is verified with, instead of the system CA certificates
+	CAFile string `yaml:"ca_file"`
+	// ServerName is verified in the server certificate instead of the host
+	// of the URL
+	ServerName string `yaml:"server_name"`
+	// InsecureSkipVerify disables the verification of the server
+	// certificate, only for test environments
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+
+	// CertSigner is the private key of the certificate in CertPEM, for keys
+	// which never leave a HSM or secret manager. KeyPEM is ignored when set.
+	CertSigner crypto.Signer `yaml:"-"`
+
+	// AcceptLanguage is sent as Accept-Language header to request localized
+	// error descriptions from the platform (e.g. "zh-CN" or "en-US")
+	AcceptLanguage string `yaml:"accept_language"`
+	// ErrorBodyLimit is the maximum number of bytes of an error response body
+	// kept in the APIError (default 512), a negative value discards the body
+	ErrorBodyLimit int `yaml:"error_body_limit"`
+
 	ManufacturerName string `yaml:"manufacturer_name"`
 	ManufacturerID   string `yaml:"manufacturer_id"`
 	EndUserID        string `yaml:"end_user_id"`
 	Location         string `yaml:"location"`
 	DeviceType       string `yaml:"device_type"`
 	Model            string `yaml:"model"`
+	// ProtocolType of the devices, defaults to CoAP
+	ProtocolType ProtocolType `yaml:"protocol_type"`
+	// Devices holds device info overrides per device ID
+	Devices map[string]DeviceInfoOptions `yaml:"devices"`
+	// ProductID binds registered devices to the product (profile) at
+	// registration, instead of by the device type, manufacturer and model
+	ProductID string `yaml:"product_id"`
+
+	// RegistrationWebhook is an URL which is notified of every registered device
+	RegistrationWebhook string `yaml:"registration_webhook"`
+	// RegistrationWebhookOptions configures the notification of the
+	// RegistrationWebhook, by default without the PSK
+	RegistrationWebhookOptions WebhookOptions `yaml:"registration_webhook_options"`
+	// CommandCallbackURL is where the platform posts command status updates
+	CommandCallbackURL string `yaml:"command_callback_url"`
+	// NameTemplate is a text/template used to name devices after registration,
+	// e.g. "{{.DeviceType}}-{{last 6 .IMEI}}". See NameData for the fields.
+	NameTemplate string `yaml:"name_template"`
+
+	// ReadTimeout, WriteTimeout and UploadTimeout are applied to requests
+	// whose context has no deadline. Reads default to 30s, writes (creates,
+	// updates, deletes, commands) to 1m and uploads to 10m. A negative
+	// timeout disables it.
+	ReadTimeout   time.Duration `yaml:"read_timeout"`
+	WriteTimeout  time.Duration `yaml:"write_timeout"`
+	UploadTimeout time.Duration `yaml:"upload_timeout"`
+
+	// Pagination bounds the helpers which retrieve all pages of a listing
+	Pagination PaginationLimits `yaml:"pagination"`
+
+	// ReadOnly blocks all calls which change something on the platform, like
+	// registrations, deletes, commands and info updates, with ErrReadOnly.
+	// Meant for jobs which use production credentials for analytics only.
+	ReadOnly bool `yaml:"read_only"`
+
+	// DryRun makes all API calls pass the built request to OnDryRun and
+	// return ErrDryRun instead of sending it
+	DryRun bool `yaml:"dry_run"`
+	// OnDryRun receives the requests built in dry-run mode, they are logged
+	// when it is nil
+	OnDryRun func(*DryRunRequest) `yaml:"-"`
+
+	// Scales normalizes the data returned by history queries
+	Scales *ScaleRegistry `yaml:"-"`
+	// Services decodes the service data of devices and history queries into
+	// registered types, it is shared with the Servers of the client
+	Services *ServiceRegistry `yaml:"-"`
+	// Clock replaces the real time, for tests
+	Clock Clock `yaml:"-"`
+	// Codec replaces encoding/json for requests and responses
+	Codec Codec `yaml:"-"`
+	// Transport replaces the HTTPS transport NewClient builds from the
+	// certificate settings, e.g. for proxies or instrumentation
+	Transport http.RoundTripper `yaml:"-"`
+	// Strict logs ("log") or fails on ("error") unknown fields in responses
+	Strict StrictMode `yaml:"strict"`
+	// Retry retries requests which failed with a transient error, disabled
+	// by default. See WithRetryPolicy to change it per call.
+	Retry RetryPolicy `yaml:"retry"`
+	// MaxRequestRate limits the requests per second sent to the platform,
+	// lowered so the budget announced in the X-RateLimit headers lasts until
+	// the window resets. Disabled when 0.
+	MaxRequestRate float64 `yaml:"max_request_rate"`
+	// CoalesceWindow makes concurrent GetDevice calls for the same device
+	// share one request, and reuses its result for the window after it
+	// finished. Disabled when 0.
+	CoalesceWindow time.Duration `yaml:"coalesce_window"`
+	// Logger receives every request sent to the platform, defaults to
+	// LogrusLogger
+	Logger Logger `yaml:"-"`
+	// LogBodies adds the request and response bodies to the logged
+	// requests, they may contain secrets
+	LogBodies bool `yaml:"log_bodies"`
+	// Payloads records the sizes and fields of the responses, and of the
+	// notifications received by the Server returned by Subscribe, when set
+	Payloads *PayloadRecorder `yaml:"-"`
+	// Metrics receives the requests, token refreshes, and the notifications
+	// handled by the Server returned by Subscribe, when set
+	Metrics Metrics `yaml:"-"`
 }
 
 // Client struct that contains pointer to http client
 type Client struct {
-	c            *http.Client
-	cfg          Config
+	c   *http.Client
+	cfg Config
+
+	// tokenLock guards the token and the http client, requests only hold it
+	// to read them so they run in parallel
+	tokenLock    sync.Mutex
 	token        string
 	tokenExpires time.Time
-	reqLock      sync.Mutex
+	refreshTok   string
+	tokenSource  TokenSource
+	tokenCall    *tokenCall
+
+	hooksLock  sync.Mutex
+	regHooks   []RegistrationHook
+	secretSink SecretSink
+
+	nameTmpl *template.Template
+
+	events watchHub
+
+	rateLock sync.Mutex
+	rate     RateLimit
+	rateNext time.Time // when the limiter lets the next request go
+
+	devCalls deviceCalls
 }
 
 // GetDevicesStruct struct for function GetDevices
 type GetDevicesStruct struct {
 	GatewayID string
-	NodeType  string
+	NodeType  NodeType
 	PageNo    int
 	PageSize  int
-	Status    string
+	Status    DeviceStatus
+	// From and To select the devices registered in the range, zero times
+	// are ignored
+	From time.Time
+	To   time.Time
+	// StartTime and EndTime select the devices registered in the range in
+	// the platform format, e.g. "20170912T120000Z"
+	//
+	// Deprecated: use From and To, which take precedence.
 	StartTime string
 	EndTime   string
-	Sort      string
+	// Sort orders the devices by the time they were registered, the only
+	// field the platform sorts devices by
+	Sort SortOrder
 }
 
-// NewClient creates new client with certification
+// NewClient creates new client with certification. The client certificate is
+// optional for deployments which only use the application ID and secret.
 func NewClient(c Config) (*Client, error) {
-	cert, err := tls.LoadX509KeyPair(c.CertFile, c.CertKeyFile)
+	return NewClientWithHTTPClient(c, nil)
+}
+
+// NewClientWithHTTPClient creates a client which sends its requests with hc.
+// The certificate settings and Transport of the config are ignored unless hc
+// is nil, in which case it is like NewClient.
+func NewClientWithHTTPClient(c Config, hc *http.Client) (*Client, error) {
+	nameTmpl, err := parseNameTemplate(c.NameTemplate)
+	if err != nil {
+		return nil, err
+	}
+	if hc == nil {
+		hc, err = newHTTPClient(c)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	client := &Client{
+		c:        hc,
+		cfg:      c,
+		nameTmpl: nameTmpl,
+	}
+	if c.RegistrationWebhook != "" {
+		client.OnRegistration(WebhookRegistrationHook(c.RegistrationWebhook, c.RegistrationWebhookOptions))
+	}
+	return client, nil
+}
+
+// newHTTPClient creates the http client for the config
+func newHTTPClient(c Config) (*http.Client, error) {
+	if c.Transport != nil {
+		return &http.Client{Transport: c.Transport}, nil
+	}
+	certs, err := clientCertificates(c)
 	if err != nil {
 		return nil, err
 	}
 
 	// Setup HTTPS client
 	tlsConfig := &tls.Config{
-		Certificates:       []tls.Certificate{cert},
-		InsecureSkipVerify: true,
+		Certificates:       certs,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.CAFile != "" {
+		ca, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, errors.New("no CA certificate found in " + c.CAFile)
+		}
 	}
 	tlsConfig.BuildNameToCertificate()
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}, nil
+}
 
-	return &Client{
-		c:   &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}},
-		cfg: c,
-	}, nil
+// clientCertificates loads the client certificate from memory or disk, when configured
+func clientCertificates(c Config) ([]tls.Certificate, error) {
+	var cert tls.Certificate
+	var err error
+	switch {
+	case c.CertSigner != nil:
+		cert, err = signerCertificate(c.CertPEM, c.CertSigner)
+	case len(c.CertPEM) > 0:
+		cert, err = tls.X509KeyPair(c.CertPEM, c.KeyPEM)
+	case c.CertFile != "":
+		cert, err = tls.LoadX509KeyPair(c.CertFile, c.CertKeyFile)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []tls.Certificate{cert}, nil
+}
+
+// signerCertificate combines the PEM certificate chain with the signer
+func signerCertificate(certPEM []byte, signer crypto.Signer) (tls.Certificate, error) {
+	var cert tls.Certificate
+	for {
+		var block *pem.Block
+		block, certPEM = pem.Decode(certPEM)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			cert.Certificate = append(cert.Certificate, block.Bytes)
+		}
+	}
+	if len(cert.Certificate) == 0 {
+		return cert, errors.New("no certificate found in CertPEM for CertSigner")
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return cert, err
+	}
+	cert.Leaf = leaf
+	cert.PrivateKey = signer
+	return cert, nil
 }
 
 func (c *Client) request(method, urlStr string, body io.Reader) (*http.Response, error) {
+	return c.requestCtx(context.Background(), method, urlStr, body)
+}
+
+func (c *Client) requestCtx(ctx context.Context, method, urlStr string, body io.Reader) (*http.Response, error) {
+	return c.requestOp(ctx, methodOperation(method), method, urlStr, body)
+}
+
+// requestOp sends the request with the timeout of the operation type, unless
+// the context has a deadline
+func (c *Client) requestOp(ctx context.Context, op operation, method, urlStr string, body io.Reader) (*http.Response, error) {
+	return c.requestContent(ctx, op, method, urlStr, "", body)
+}
+
+// requestContent is like requestOp but with the content type of the body,
+// which defaults to JSON
+func (c *Client) requestContent(ctx context.Context, op operation, method, urlStr, contentType string, body io.Reader) (*http.Response, error) {
+	if c.cfg.ReadOnly && methodOperation(method) != opRead {
+		return nil, ErrReadOnly
+	}
 	r, err := http.NewRequest(method, c.cfg.URL+urlStr, body)
 	if err != nil {
 		return nil, err
 	}
-	return c.doRequest(r)
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	if c.cfg.DryRun {
+		c.addHeaders(r)
+		return nil, c.dryRun(r)
+	}
+	ctx, cancel := c.withTimeout(ctx, op)
+	resp, err := c.doRequest(r.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, newOpError(r, err)
+	}
+	resp.Body = cancelBody{resp.Body, cancel}
+	return resp, nil
 }
 
-func (c *Client) doRequest(req *http.Request) (*http.Response, error) {
-	c.reqLock.Lock()
-	defer c.reqLock.Unlock()
-	if c.tokenExpires.Before(time.Now().Add(time.Minute * 5)) {
-		err := c.Login()
-		if err != nil {
-			return nil, err
-		}
+// addHeaders adds the headers, except for the authorization, to the request
+func (c *Client) addHeaders(req *http.Request) {
+	req.Header.Set("app_key", c.cfg.AppID)
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.cfg.AcceptLanguage != "" {
+		req.Header.Set("Accept-Language", c.cfg.AcceptLanguage)
+	}
+}
+
+// roundTrip sends the request once with the token
+func (c *Client) roundTrip(req *http.Request) (*http.Response, error) {
+	token, err := c.accessToken(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	if err := c.waitRateLimit(req.Context()); err != nil {
+		return nil, err
+	}
+	c.addHeaders(req)
+	req.Header.Set("Authorization", token)
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		// the platform invalidated the token, get a new one for the next request
+		c.invalidateToken(token)
 	}
-	req.Header.Add("app_key", c.cfg.AppID)
-	req.Header.Add("Authorization", c.token)
-	req.Header.Add("Content-Type", "application/json")
-	return c.c.Do(req)
+	c.updateRateLimit(resp)
+	return resp, nil
 }
 
 func (c *Client) GetDevice(deviceID string) (*Device, error) {
-	resp, err := c.request(http.MethodGet, "/iocm/app/dm/v1.1.0/devices/"+deviceID, nil)
+	return c.GetDeviceCtx(context.Background(), deviceID)
+}
+
+// GetDeviceCtx is like GetDevice but with a context
+func (c *Client) GetDeviceCtx(ctx context.Context, deviceID string) (*Device, error) {
+	if c.cfg.CoalesceWindow > 0 {
+		return c.getDeviceCoalesced(ctx, deviceID)
+	}
+	return c.getDevice(ctx, deviceID)
+}
+
+// getDevice retrieves the device
+func (c *Client) getDevice(ctx context.Context, deviceID string) (*Device, error) {
+	resp, err := c.requestCtx(ctx, http.MethodGet, newEndpoint("/iocm/app/dm/v1.1.0/devices", deviceID).String(), nil)
 	if err != nil {
 		return nil, err
 	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("invalid response code: " + resp.Status)
+		return nil, c.newAPIError(resp)
 	}
 
 	// save device response
 	d := &Device{client: c}
-	if err := json.NewDecoder(resp.Body).Decode(d); err != nil {
+	if err := c.decode(resp, d); err != nil {
+		return nil, err
+	}
+	if err := c.cfg.Services.decodeServices(c.codec(), d.Services); err != nil {
 		return nil, err
 	}
 	return d, nil
 }
 
-// GetDevices returns struct with devices
+// GetDevices returns struct with devices. When some of the devices can't be
+// decoded the other devices are returned together with a DecodeErrors error.
 func (c *Client) GetDevices(dev GetDevicesStruct) ([]Device, error) {
-	resp, err := c.request(http.MethodGet, c.getQueryStringForDeviceGet(dev), nil)
+	return c.GetDevicesCtx(context.Background(), dev)
+}
+
+// GetDevicesCtx is like GetDevices but with a context
+func (c *Client) GetDevicesCtx(ctx context.Context, dev GetDevicesStruct) ([]Device, error) {
+	devs, _, err := c.devicesPage(ctx, dev)
+	return devs, err
+}
+
+// devicesPage retrieves a page of devices and the total number of devices
+func (c *Client) devicesPage(ctx context.Context, dev GetDevicesStruct) ([]Device, int, error) {
+	if err := validateNodeType(dev.NodeType); err != nil {
+		return nil, 0, err
+	}
+	if err := validateDeviceStatus(dev.Status); err != nil {
+		return nil, 0, err
+	}
+	if err := validateSort(dev.Sort); err != nil {
+		return nil, 0, err
+	}
+	resp, err := c.requestCtx(ctx, http.MethodGet, c.getQueryStringForDeviceGet(dev), nil)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("invalid response code: " + resp.Status)
+		return nil, 0, c.newAPIError(resp)
 	}
 
 	// save device response
 	d := deviceResponse{}
-	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
-		return nil, err
+	if err := c.decode(resp, &d); err != nil {
+		return nil, 0, err
 	}
 	var retdevs []Device
-	for _, dev := range d.Devices {
-		dev.client = c
+	var decErrs DecodeErrors
+	for i, raw := range d.Devices {
+		dev := Device{client: c}
+		err := withStrict(c.codec(), c.cfg.Strict).Unmarshal(raw, &dev)
+		if err == nil {
+			err = c.cfg.Services.decodeServices(c.codec(), dev.Services)
+		}
+		if err != nil {
+			decErrs = append(decErrs, newDecodeError(i, raw, err))
+			continue
+		}
 		retdevs = append(retdevs, dev)
 	}
-	return retdevs, err
+	if len(decErrs) > 0 {
+		return retdevs, d.Totalcount, decErrs
+	}
+	return retdevs, d.Totalcount, nil
+}
+
+// MaxCommandExpireTime is the longest time the platform keeps a command for
+// delivery to the device
+const MaxCommandExpireTime = 72 * time.Hour
+
+// CommandOptions for SendCommandWithOptions
+type CommandOptions struct {
+	// ExpireTime is how long the platform keeps the command when it can't be
+	// delivered to the device yet, 0 means the command is only sent when the
+	// device is reachable immediately
+	ExpireTime time.Duration
+	// Timeout bounds the HTTP call to the platform, 0 means no timeout
+	Timeout time.Duration
 }
 
-// SendCommand send command to target device
-func (c *Client) SendCommand(deviceID string, serviceID string, method string, idata interface{}, timeoutSec int64) error {
+// SendCommandWithOptions send command to target device, the expireTime of the
+// command on the platform is set separately from the timeout of the call
+func (c *Client) SendCommandWithOptions(deviceID string, serviceID string, method string, idata interface{}, opts CommandOptions) error {
+	return c.SendCommandWithOptionsCtx(context.Background(), deviceID, serviceID, method, idata, opts)
+}
+
+// SendCommandWithOptionsCtx is like SendCommandWithOptions but with a context
+func (c *Client) SendCommandWithOptionsCtx(ctx context.Context, deviceID string, serviceID string, method string, idata interface{}, opts CommandOptions) error {
+	_, err := c.postCommand(ctx, deviceID, serviceID, method, idata, opts)
+	return err
+}
+
+// postCommand creates the command, it returns the response body
+func (c *Client) postCommand(ctx context.Context, deviceID string, serviceID string, method string, idata interface{}, opts CommandOptions) ([]byte, error) {
+	if opts.ExpireTime < 0 || opts.ExpireTime > MaxCommandExpireTime {
+		return nil, errors.New("invalid command expire time: " + opts.ExpireTime.String())
+	}
+
 	type devCmdBodyCommand struct {
 		ServiceID string      `json:"serviceId"`
 		Method    string      `json:"method"`
@@ -158,53 +513,77 @@ func (c *Client) SendCommand(deviceID string, serviceID string, method string, i
 			Method:    method,
 			Params:    idata,
 		},
-		ExpireTime: timeoutSec,
+		CallbackURL: c.cfg.CommandCallbackURL,
+		ExpireTime:  int64(opts.ExpireTime / time.Second),
 	}
 
-	body, err := json.Marshal(cmd)
+	body, err := c.codec().Marshal(cmd)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	resp, err := c.request(http.MethodPost, "/iocm/app/cmd/v1.4.0/deviceCommands", bytes.NewBuffer(body))
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	resp, err := c.requestCtx(ctx, http.MethodPost, "/iocm/app/cmd/v1.4.0/deviceCommands", bytes.NewBuffer(body))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	httputil.DumpResponse(resp, true)
-
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return errors.New("invalid response code: " + resp.Status)
+		return nil, c.newAPIError(resp)
 	}
 
-	return nil
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
 }
 
-func (c *Client) getQueryStringForDeviceGet(dev GetDevicesStruct) string {
-	s := "/iocm/app/dm/v1.1.0/devices?"
-	if dev.GatewayID != "" {
-		s += "gatewayId=" + dev.GatewayID + "&"
+// allDevices retrieves all devices page by page, devices which can't be
+// decoded are skipped and returned as DecodeErrors
+func (c *Client) allDevices(ctx context.Context, pageSize int) ([]Device, DecodeErrors, error) {
+	if pageSize == 0 {
+		pageSize = 100
 	}
-	if dev.NodeType != "" {
-		s += "nodeType=" + dev.NodeType + "&"
+	var devs []Device
+	var skipped DecodeErrors
+	p := c.newPager()
+	pctx, cancel := p.context(ctx)
+	defer cancel()
+	for page := 0; ; page++ {
+		d, err := c.GetDevicesCtx(pctx, GetDevicesStruct{PageNo: page, PageSize: pageSize})
+		decErrs, ok := err.(DecodeErrors)
+		if !ok && err != nil {
+			return nil, nil, p.limitErr(ctx, err)
+		}
+		if ok {
+			logrus.Warnf("skipping devices: %v", decErrs)
+			skipped = append(skipped, decErrs...)
+		}
+		devs = append(devs, d...)
+		if len(d)+len(decErrs) < pageSize {
+			return devs, skipped, nil
+		}
+		if err := p.next(len(d) + len(decErrs)); err != nil {
+			return devs, skipped, err
+		}
 	}
+}
 
-	s += "pageNo=" + strconv.Itoa(dev.PageNo) + "&"
-
+func (c *Client) getQueryStringForDeviceGet(dev GetDevicesStruct) string {
+	e := newEndpoint("/iocm/app/dm/v1.1.0/devices").
+		Set("gatewayId", dev.GatewayID).
+		Set("nodeType", string(dev.NodeType)).
+		SetInt("pageNo", dev.PageNo).
+		Set("startTime", dev.StartTime).
+		Set("endTime", dev.EndTime).
+		SetTime("startTime", dev.From).
+		SetTime("endTime", dev.To).
+		Set("status", string(dev.Status)).
+		Set("sort", string(dev.Sort))
 	if dev.PageSize != 0 {
-		s += "pageSize=" + strconv.Itoa(dev.PageSize) + "&"
-	}
-	if dev.StartTime != "" {
-		s += "startTime=" + dev.StartTime + "&"
-	}
-	if dev.EndTime != "" {
-		s += "endTime=" + dev.EndTime + "&"
-	}
-	if dev.Status != "" {
-		s += "status=" + dev.Status + "&"
-	}
-	if dev.Sort != "" {
-		s += "sort=" + dev.Sort
+		e.SetInt("pageSize", dev.PageSize)
 	}
-	return s
+	return e.String()
 }
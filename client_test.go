@@ -5,10 +5,27 @@
 package oceanconnect
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -53,3 +70,977 @@ func TestRenewal(t *testing.T) {
 	assert.Nil(t, err, "requestFailed")
 	assert.Equal(t, 5, reqCount, "request-counter should be 5")
 }
+
+func TestGetDevicesPartialDecode(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		fmt.Fprintln(w, `{"totalCount":3,"pageNo":0,"pageSize":10,"devices":[
+			{"deviceId":"dev1","creationTime":"20170912T101530Z"},
+			{"deviceId":"dev2","creationTime":"garbage"},
+			{"deviceId":"dev3","creationTime":"20170912T101530Z"}]}`)
+	}))
+	defer s.Close()
+
+	c := Client{
+		c:   &http.Client{},
+		cfg: Config{URL: s.URL},
+	}
+
+	devs, err := c.GetDevices(GetDevicesStruct{})
+	assert.Equal(t, 2, len(devs), "expected 2 decoded devices")
+	decErrs, ok := err.(DecodeErrors)
+	assert.True(t, ok, "expected DecodeErrors")
+	assert.Equal(t, 1, len(decErrs), "expected 1 decode error")
+	assert.Equal(t, "dev2", decErrs[0].DeviceID)
+	assert.Equal(t, 1, decErrs[0].Index)
+}
+
+func TestGetDevicesQuery(t *testing.T) {
+	c := Client{cfg: Config{}}
+	amsterdam, _ := time.LoadLocation("Europe/Amsterdam")
+	q := c.getQueryStringForDeviceGet(GetDevicesStruct{
+		PageSize: 10,
+		From:     time.Date(2017, 9, 12, 12, 0, 0, 0, amsterdam),
+		To:       time.Date(2017, 9, 13, 10, 0, 0, 0, time.UTC),
+		Sort:     SortDescending,
+	})
+	assert.Equal(t, "/iocm/app/dm/v1.1.0/devices?endTime=20170913T100000Z&pageNo=0&pageSize=10&sort=DESC&startTime=20170912T100000Z", q)
+
+	q = c.getQueryStringForDeviceGet(GetDevicesStruct{StartTime: "20170912T100000Z"})
+	assert.Equal(t, "/iocm/app/dm/v1.1.0/devices?pageNo=0&startTime=20170912T100000Z", q)
+
+	q = c.getQueryStringForDeviceGet(GetDevicesStruct{GatewayID: "gw 1&status=ONLINE"})
+	assert.Equal(t, "/iocm/app/dm/v1.1.0/devices?gatewayId=gw+1%26status%3DONLINE&pageNo=0", q)
+	c.cfg.AppID = "app#1"
+	assert.Equal(t, "/iocm/app/dm/v1.2.0/devices/dev%2F1?appId=app%231", c.appEndpoint("/iocm/app/dm/v1.2.0/devices", "dev/1").String())
+
+	_, err := c.GetDevices(GetDevicesStruct{Sort: "desc"})
+	assert.EqualError(t, err, "invalid sort order: desc")
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time { return f.now }
+
+func (f *fakeClock) After(d time.Duration) <-chan time.Time {
+	f.now = f.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- f.now
+	return ch
+}
+
+func TestRenewalClock(t *testing.T) {
+	reqCount := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqCount++
+		fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+	}))
+	defer s.Close()
+
+	clock := &fakeClock{now: time.Date(2017, 9, 12, 10, 0, 0, 0, time.UTC)}
+	c := Client{
+		c:   &http.Client{},
+		cfg: Config{URL: s.URL, Clock: clock},
+	}
+
+	_, err := c.request(http.MethodGet, "/", nil)
+	assert.Nil(t, err, "requestFailed")
+	assert.Equal(t, 2, reqCount, "expected login and request")
+
+	// just before the 5 minute refresh margin the token is reused
+	clock.now = clock.now.Add(54*time.Minute + 59*time.Second)
+	_, err = c.request(http.MethodGet, "/", nil)
+	assert.Nil(t, err, "requestFailed")
+	assert.Equal(t, 3, reqCount, "expected no login")
+
+	// within the refresh margin a new token is retrieved
+	clock.now = clock.now.Add(2 * time.Second)
+	_, err = c.request(http.MethodGet, "/", nil)
+	assert.Nil(t, err, "requestFailed")
+	assert.Equal(t, 5, reqCount, "expected login and request")
+}
+
+func TestRefreshToken(t *testing.T) {
+	var logins, refreshes int
+	refreshFails := false
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/iocm/app/sec/v1.1.0/login":
+			logins++
+			fmt.Fprintln(w, `{"accessToken":"login","tokenType":"bearer","refreshToken":"r1","expiresIn":3600}`)
+		case "/iocm/app/sec/v1.1.0/refreshToken":
+			refreshes++
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			if refreshFails || body["refreshToken"] != "r1" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintln(w, `{"error_code":"1010009","error_desc":"refresh token invalid"}`)
+				return
+			}
+			fmt.Fprintln(w, `{"accessToken":"refreshed","tokenType":"bearer","refreshToken":"r1","expiresIn":3600}`)
+		default:
+			fmt.Fprintln(w, r.Header.Get("Authorization"))
+		}
+	}))
+	defer s.Close()
+
+	clock := &fakeClock{now: time.Date(2017, 9, 12, 10, 0, 0, 0, time.UTC)}
+	c := Client{c: &http.Client{}, cfg: Config{URL: s.URL, AppID: "app", Clock: clock}}
+	auth := func() string {
+		resp, err := c.request(http.MethodGet, "/", nil)
+		if !assert.Nil(t, err) {
+			return ""
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	assert.Equal(t, "bearer login\n", auth())
+
+	clock.now = clock.now.Add(time.Hour)
+	assert.Equal(t, "bearer refreshed\n", auth())
+	assert.Equal(t, 1, logins)
+	assert.Equal(t, 1, refreshes)
+
+	// a failing refresh falls back to a login
+	refreshFails = true
+	clock.now = clock.now.Add(time.Hour)
+	assert.Equal(t, "bearer login\n", auth())
+	assert.Equal(t, 2, logins)
+	assert.Equal(t, 2, refreshes)
+}
+
+func TestRegisterDeviceProfile(t *testing.T) {
+	var dr *DryRunRequest
+	c := Client{
+		c: &http.Client{},
+		cfg: Config{URL: "http://localhost", DryRun: true, DeviceType: "WaterMeter", Model: "wm1", OnDryRun: func(r *DryRunRequest) {
+			dr = r
+		}},
+	}
+	_, err := c.RegisterDevice("123456789012345")
+	assert.Equal(t, ErrDryRun, err)
+	if assert.NotNil(t, dr, "expected dry-run request") {
+		assert.Equal(t, "POST", dr.Method)
+		assert.Equal(t, "http://localhost/iocm/app/reg/v1.2.0/devices", dr.URL)
+		assert.Equal(t, "application/json", dr.Header.Get("Content-Type"))
+		assert.Empty(t, dr.Header.Get("Authorization"))
+		assert.Contains(t, string(dr.Body), `"deviceInfo":{"deviceType":"WaterMeter","model":"wm1","protocolType":"CoAP"}`)
+	}
+
+	c.cfg.ProductID = "product1"
+	_, err = c.RegisterDevice("123456789012345")
+	assert.Equal(t, ErrDryRun, err)
+	assert.Contains(t, string(dr.Body), `"productId":"product1"`)
+	assert.NotContains(t, string(dr.Body), "deviceInfo")
+}
+
+func TestRateLimit(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		w.Header().Set("X-RateLimit-Limit", "100")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", "30")
+		fmt.Fprintln(w, `{"deviceId":"dev1"}`)
+	}))
+	defer s.Close()
+
+	start := time.Date(2017, 9, 12, 10, 0, 0, 0, time.UTC)
+	clk := &fakeClock{now: start}
+	c := Client{
+		c:   &http.Client{},
+		cfg: Config{URL: s.URL, Clock: clk},
+	}
+	assert.False(t, c.RateLimitStatus().Known)
+
+	_, err := c.GetDevice("dev1")
+	assert.Nil(t, err)
+	rl := c.RateLimitStatus()
+	assert.Equal(t, RateLimit{Known: true, Limit: 100, Remaining: 0, Reset: start.Add(30 * time.Second)}, rl)
+
+	_, err = c.GetDevice("dev1")
+	assert.Nil(t, err)
+	assert.Equal(t, start.Add(30*time.Second), clk.now, "expected to wait for the reset")
+}
+
+func TestRateLimitReset(t *testing.T) {
+	start := time.Date(2017, 9, 12, 10, 0, 0, 0, time.UTC)
+	c := Client{cfg: Config{Clock: &fakeClock{now: start}}}
+	assert.Equal(t, start.Add(30*time.Second), c.resetTime(30))
+	assert.True(t, start.Add(time.Minute).Equal(c.resetTime(start.Add(time.Minute).Unix())))
+	// a window which just reset is no reason to wait for decades
+	assert.True(t, start.Add(-time.Second).Equal(c.resetTime(start.Add(-time.Second).Unix())))
+}
+
+func TestAdaptiveRateLimit(t *testing.T) {
+	var lock sync.Mutex
+	remaining := ""
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		lock.Lock()
+		if remaining != "" {
+			w.Header().Set("X-RateLimit-Remaining", remaining)
+			w.Header().Set("X-RateLimit-Reset", "30")
+		}
+		lock.Unlock()
+		fmt.Fprintln(w, `{"deviceId":"dev1"}`)
+	}))
+	defer s.Close()
+
+	start := time.Date(2017, 9, 12, 10, 0, 0, 0, time.UTC)
+	clk := &fakeClock{now: start}
+	c := Client{
+		c:   &http.Client{},
+		cfg: Config{URL: s.URL, Clock: clk, MaxRequestRate: 10},
+	}
+	assert.Nil(t, c.Login())
+
+	// the configured rate spaces the requests
+	for i := 0; i < 3; i++ {
+		_, err := c.GetDevice("dev1")
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, start.Add(200*time.Millisecond), clk.now)
+
+	// 2 requests left for 30s lowers the rate to one per 15s after the first
+	// response with the headers
+	lock.Lock()
+	remaining = "2"
+	lock.Unlock()
+	for i := 0; i < 3; i++ {
+		_, err := c.GetDevice("dev1")
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, start.Add(15400*time.Millisecond), clk.now)
+}
+
+func TestOperationTimeout(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+		fmt.Fprintln(w, `{"deviceId":"dev1"}`)
+	}))
+	defer s.Close()
+
+	c := Client{
+		c:   &http.Client{},
+		cfg: Config{URL: s.URL, ReadTimeout: 10 * time.Millisecond},
+	}
+	_, err := c.GetDevice("dev1")
+	assert.NotNil(t, err, "expected read timeout")
+
+	c.cfg.ReadTimeout = time.Second
+	_, err = c.GetDevice("dev1")
+	assert.Nil(t, err)
+}
+
+func TestReadOnly(t *testing.T) {
+	c := Client{
+		c:   &http.Client{},
+		cfg: Config{URL: "http://localhost", ReadOnly: true, DryRun: true},
+	}
+	assert.Equal(t, ErrReadOnly, c.DeleteDevice("dev1"))
+	assert.Equal(t, ErrReadOnly, c.SendCommand("dev1", "Config", "SET", nil, 0))
+	_, err := c.RegisterDevice("123456789012345")
+	assert.Equal(t, ErrReadOnly, err)
+
+	_, err = c.GetDevice("dev1")
+	assert.Equal(t, ErrDryRun, err, "expected reads to pass")
+}
+
+func TestPaginationLimits(t *testing.T) {
+	page := benchDevicePage(100)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		// a broken platform returning full pages forever
+		fmt.Fprintln(w, page)
+	}))
+	defer s.Close()
+
+	c := Client{
+		c:   &http.Client{},
+		cfg: Config{URL: s.URL, Pagination: PaginationLimits{MaxPages: 3}},
+	}
+	devs, _, err := c.allDevices(context.Background(), 0)
+	lerr, ok := err.(*PaginationLimitError)
+	assert.True(t, ok, "expected PaginationLimitError")
+	assert.Equal(t, "max_pages", lerr.Limit)
+	assert.Equal(t, 300, len(devs))
+}
+
+func TestPaginationDeadline(t *testing.T) {
+	page := benchDevicePage(100)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		if r.URL.Query().Get("pageNo") == "0" {
+			fmt.Fprintln(w, page)
+			return
+		}
+		// the second page hangs until the deadline cancels it
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer s.Close()
+
+	c := Client{
+		c:   &http.Client{},
+		cfg: Config{URL: s.URL, Pagination: PaginationLimits{Deadline: 100 * time.Millisecond}},
+	}
+	start := time.Now()
+	_, _, err := c.allDevices(context.Background(), 0)
+	assert.True(t, time.Since(start) < 2*time.Second, "expected the page in flight to be canceled")
+	lerr, ok := err.(*PaginationLimitError)
+	if assert.True(t, ok, "expected PaginationLimitError, got %v", err) {
+		assert.Equal(t, "deadline", lerr.Limit)
+		assert.Equal(t, 1, lerr.Pages)
+		assert.Equal(t, 100, lerr.Items)
+	}
+}
+
+func TestSecretSink(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		fmt.Fprintln(w, `{"verifyCode":"123456789012345","deviceId":"dev1","timeout":180,"psk":"a1b2c3d4e5f6"}`)
+	}))
+	defer s.Close()
+
+	c := Client{
+		c:   &http.Client{},
+		cfg: Config{URL: s.URL},
+	}
+	var stored string
+	var sinkPSK []byte
+	c.SetSecretSink(func(deviceID string, psk, verifyCode []byte) error {
+		stored = deviceID + ":" + string(psk)
+		sinkPSK = psk
+		return nil
+	})
+	var hookPSK string
+	c.OnRegistration(func(ev RegistrationEvent) error {
+		hookPSK = ev.PSK
+		return nil
+	})
+
+	reply, err := c.RegisterDevice("123456789012345")
+	assert.Nil(t, err)
+	assert.Equal(t, "dev1:a1b2c3d4e5f6", stored)
+	assert.Equal(t, make([]byte, len(sinkPSK)), sinkPSK, "expected PSK to be zeroed")
+	assert.Equal(t, "", reply.Psk)
+	assert.Equal(t, "", hookPSK)
+	assert.NotContains(t, fmt.Sprint(RegistrationReply{Psk: "secret"}), "secret")
+
+	// the device is registered, the hooks run when the sink fails
+	hookPSK = "unset"
+	sinkErr := errors.New("vault sealed")
+	c.SetSecretSink(func(deviceID string, psk, verifyCode []byte) error {
+		return sinkErr
+	})
+	reply, err = c.RegisterDevice("123456789012345")
+	assert.Equal(t, sinkErr, err)
+	if assert.NotNil(t, reply) {
+		assert.Equal(t, "dev1", reply.DeviceID)
+	}
+	assert.Equal(t, "", hookPSK)
+}
+
+func TestRegisterDeviceWithOptions(t *testing.T) {
+	var req map[string]interface{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		req = nil
+		json.NewDecoder(r.Body).Decode(&req)
+		fmt.Fprintln(w, `{"verifyCode":"factory-1","deviceId":"dev1","timeout":60}`)
+	}))
+	defer s.Close()
+
+	c := Client{c: &http.Client{}, cfg: Config{URL: s.URL, EndUserID: "user", ProductID: "prod"}}
+	_, err := c.RegisterDeviceWithOptions("123456789012345", RegisterOptions{
+		VerifyCode: "factory-1",
+		PSK:        "0123456789abcdef",
+		Timeout:    60,
+		EndUserID:  "operator",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"verifyCode": "factory-1",
+		"nodeId":     "123456789012345",
+		"timeout":    float64(60),
+		"endUserId":  "operator",
+		"psk":        "0123456789abcdef",
+		"productId":  "prod",
+	}, req)
+
+	_, err = c.RegisterDevice("123456789012345")
+	assert.Nil(t, err)
+	assert.Equal(t, "123456789012345", req["verifyCode"])
+	assert.Equal(t, "user", req["endUserId"])
+	assert.NotContains(t, req, "psk")
+
+	_, err = c.RegisterDeviceWithOptions("123456789012345", RegisterOptions{PSK: "not-hex!"})
+	assert.Equal(t, ErrInvalidPSK, err)
+}
+
+func TestSetDeviceInfoOptions(t *testing.T) {
+	var req map[string]interface{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		req = nil
+		json.NewDecoder(r.Body).Decode(&req)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer s.Close()
+
+	c := Client{c: &http.Client{}, cfg: Config{URL: s.URL}}
+	assert.Nil(t, c.SetDeviceInfo("dev1", "meter"))
+	assert.Equal(t, "CoAP", req["protocolType"])
+	assert.Equal(t, "FALSE", req["mute"])
+
+	muted := true
+	c.cfg.ProtocolType = ProtocolLWM2M
+	c.cfg.Devices = map[string]DeviceInfoOptions{"dev2": {ProtocolType: ProtocolMQTT, Mute: &muted}}
+	assert.Nil(t, c.SetDeviceInfo("dev1", "meter"))
+	assert.Equal(t, "LWM2M", req["protocolType"])
+	assert.Equal(t, "FALSE", req["mute"])
+
+	assert.Nil(t, c.SetDeviceInfo("dev2", "valve"))
+	assert.Equal(t, "MQTT", req["protocolType"])
+	assert.Equal(t, "TRUE", req["mute"])
+
+	// the call overrides the per-device config, unset options are kept
+	assert.Nil(t, c.SetDeviceInfoWithOptions("dev2", "valve", DeviceInfoOptions{ProtocolType: ProtocolCoAP}))
+	assert.Equal(t, "CoAP", req["protocolType"])
+	assert.Equal(t, "TRUE", req["mute"])
+}
+
+// closeCounter counts the closed response bodies
+type closeCounter struct {
+	io.ReadCloser
+	closed *int32
+}
+
+func (b closeCounter) Close() error {
+	atomic.AddInt32(b.closed, 1)
+	return b.ReadCloser.Close()
+}
+
+func TestSetAndDeleteDeviceCloseBody(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer s.Close()
+
+	var closed int32
+	rt := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		resp, err := http.DefaultTransport.RoundTrip(r)
+		if err == nil && r.URL.Path != "/iocm/app/sec/v1.1.0/login" {
+			resp.Body = closeCounter{resp.Body, &closed}
+		}
+		return resp, err
+	})
+	c := &Client{c: &http.Client{Transport: rt}, cfg: Config{URL: s.URL}}
+
+	// an open body keeps the timeout of the operation running
+	assert.Nil(t, c.SetDeviceInfo("dev1", "meter"))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&closed))
+	assert.Nil(t, c.DeleteDevice("dev1"))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&closed))
+}
+
+func TestResetDeviceSecret(t *testing.T) {
+	var req map[string]interface{}
+	var path string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		path = r.Method + " " + r.URL.String()
+		req = nil
+		json.NewDecoder(r.Body).Decode(&req)
+		code, _ := req["verifyCode"].(string)
+		fmt.Fprintf(w, `{"verifyCode":%q,"timeout":180}`, code)
+	}))
+	defer s.Close()
+
+	c := Client{c: &http.Client{}, cfg: Config{URL: s.URL, AppID: "app"}}
+	reply, err := c.ResetDeviceSecret("dev1")
+	assert.Nil(t, err)
+	assert.Equal(t, "PUT /iocm/app/reg/v1.1.0/deviceCredentials/dev1?appId=app", path)
+	assert.Equal(t, "dev1", reply.DeviceID)
+	assert.Len(t, reply.Psk, 32)
+	assert.Equal(t, req["psk"], reply.Psk)
+	assert.Nil(t, validatePSK(reply.Psk))
+
+	reply, err = c.RefreshDeviceKey("dev1", "123456789012345", 300)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"verifyCode": "123456789012345", "nodeId": "123456789012345", "timeout": float64(300)}, req)
+	assert.Equal(t, "123456789012345", reply.VerifyCode)
+	assert.Equal(t, "", reply.Psk)
+
+	var stored string
+	var kept []byte
+	c.SetSecretSink(func(deviceID string, psk, verifyCode []byte) error {
+		stored = deviceID + ":" + string(psk)
+		kept = psk
+		return nil
+	})
+	reply, err = c.ResetDeviceSecret("dev1")
+	assert.Nil(t, err)
+	assert.Equal(t, "", reply.Psk)
+	assert.Equal(t, "dev1:"+req["psk"].(string), stored)
+	// the PSK handed to the sink is zeroed when it returns
+	assert.Equal(t, make([]byte, 32), kept)
+}
+
+func TestSubscriptions(t *testing.T) {
+	var deleted []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/iocm/app/sec/v1.1.0/login":
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+		case r.URL.Path == "/iocm/app/sub/v1.2.0/subscribe":
+			// matched by code, the description is localized
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, `{"error_code":"100227","error_desc":"订阅已存在"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/iocm/app/sub/v1.2.0/subscriptions":
+			fmt.Fprintln(w, `{"totalCount":2,"subscriptions":[
+				{"subscriptionId":"s1","notifyType":"deviceAdded","callbackUrl":"http://other"},
+				{"subscriptionId":"s2","notifyType":"deviceAdded","callbackUrl":"http://cb"}]}`)
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, r.URL.Path+"?"+r.URL.Query().Get("notifyType"))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	c := Client{
+		c:   &http.Client{},
+		cfg: Config{URL: s.URL, AppID: "app"},
+	}
+
+	_, err := c.SubscribeTo(NotificationCommandStatus, "http://cb")
+	assert.NotNil(t, err, "expected error for unsubscribable type")
+
+	sub, err := c.SubscribeTo(NotificationDeviceAdded, "http://cb")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "s2", sub.SubscriptionID)
+	}
+
+	subs, err := c.ListSubscriptions("")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(subs))
+
+	assert.Nil(t, c.DeleteSubscription("s1"))
+	assert.Nil(t, c.DeleteAllSubscriptions(NotificationDeviceAdded))
+	assert.Equal(t, []string{"/iocm/app/sub/v1.2.0/subscriptions/s1?", "/iocm/app/sub/v1.2.0/subscriptions?deviceAdded"}, deleted)
+}
+
+func TestSubscribeResponses(t *testing.T) {
+	var status int
+	var body string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	defer s.Close()
+
+	c := Client{
+		c:   &http.Client{},
+		cfg: Config{URL: s.URL, AppID: "app", Strict: StrictError},
+	}
+
+	for _, tc := range []struct {
+		status int
+		body   string
+		id     string
+	}{
+		{http.StatusCreated, ``, ""},
+		{http.StatusCreated, `{"subscriptionId":"s1","notifyType":"deviceDataChanged","callbackUrl":"http://cb"}`, "s1"},
+		{http.StatusOK, `{"subscriptionId":"s2","callbackurl":"http://cb","ownerFlag":true}`, "s2"},
+		{http.StatusOK, `{"subscription":{"subscriptionId":"s3","notifyType":"deviceDataChanged"}}`, "s3"},
+	} {
+		status, body = tc.status, tc.body
+		srv, err := c.Subscribe("http://cb")
+		if assert.Nil(t, err, tc.body) {
+			assert.Equal(t, tc.id, srv.Subscription.SubscriptionID)
+			assert.Equal(t, NotificationDeviceDataChanged, srv.Subscription.NotifyType)
+			assert.Equal(t, "http://cb", srv.Subscription.CallbackURL)
+			assert.Equal(t, tc.body, string(srv.Subscription.Raw))
+		}
+	}
+
+	// unknown fields fail in strict mode
+	status, body = http.StatusOK, `{"subscriptionId":"s4","expires":60}`
+	_, err := c.Subscribe("http://cb")
+	var ferr *UnknownFieldError
+	assert.True(t, errors.As(err, &ferr), "expected an unknown field error")
+}
+
+func TestCommandLifecycle(t *testing.T) {
+	var canceled []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/iocm/app/sec/v1.1.0/login":
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/iocm/app/cmd/v1.4.0/deviceCommands":
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintln(w, `{"commandId":"cmd1","deviceId":"dev1","command":{"serviceId":"Switch","method":"ON","paras":{}},"status":"PENDING","creationTime":"20170801T120000Z"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/iocm/app/cmd/v1.4.0/deviceCommands":
+			assert.Equal(t, "dev1", r.URL.Query().Get("deviceId"))
+			fmt.Fprintln(w, `{"pagination":{"pageNo":0,"pageSize":100,"totalSize":2},"data":[{"commandId":"cmd1","status":"SUCCESSFUL"},{"commandId":"cmd2","status":"EXPIRED"}]}`)
+		case r.Method == http.MethodPut:
+			canceled = append(canceled, r.URL.Path)
+		case r.URL.Path == "/iocm/app/cmd/v1.4.0/deviceCommandCancelTasks":
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintln(w, `{"taskId":"task1","deviceId":"dev1","status":"WAITTING","totalCount":2}`)
+		}
+	}))
+	defer s.Close()
+
+	c := Client{
+		c:   &http.Client{},
+		cfg: Config{URL: s.URL, AppID: "app"},
+	}
+
+	cmd, err := c.SendCommandWithResponse("dev1", "Switch", "ON", nil, CommandOptions{})
+	if assert.Nil(t, err) {
+		assert.Equal(t, "cmd1", cmd.CommandID)
+		assert.Equal(t, CommandPending, cmd.Status)
+		assert.False(t, cmd.Status.Final())
+		assert.Equal(t, 2017, cmd.CreationTime.Year())
+	}
+
+	cmds, err := c.ListCommands(CommandFilter{DeviceID: "dev1"})
+	if assert.Nil(t, err) && assert.Equal(t, 2, len(cmds)) {
+		assert.True(t, cmds[1].Status.Final())
+	}
+
+	assert.Nil(t, c.CancelCommand("cmd1"))
+	assert.Equal(t, []string{"/iocm/app/cmd/v1.4.0/deviceCommands/cmd1"}, canceled)
+
+	task, err := c.CancelAllCommands("dev1")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "task1", task.TaskID)
+	}
+}
+
+func TestSendCommandOptions(t *testing.T) {
+	var req map[string]interface{}
+	block, slow := make(chan struct{}), make(chan map[string]interface{}, 1)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		var b map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&b)
+		if b["deviceId"] == "slow" {
+			slow <- b
+			select {
+			case <-block:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		req = b
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintln(w, `{"commandId":"cmd1","deviceId":"dev1","status":"PENDING"}`)
+	}))
+	defer s.Close()
+	defer close(block)
+
+	c := Client{c: &http.Client{}, cfg: Config{URL: s.URL}}
+	assert.Nil(t, c.SendCommandWithOptions("dev1", "Valve", "OPEN", nil, CommandOptions{ExpireTime: 2 * time.Hour, Timeout: time.Second}))
+	assert.Equal(t, float64(7200), req["expireTime"])
+
+	// the expire time is checked against the platform limits before sending
+	req = nil
+	assert.EqualError(t, c.SendCommandWithOptions("dev1", "Valve", "OPEN", nil, CommandOptions{ExpireTime: MaxCommandExpireTime + time.Hour}), "invalid command expire time: 73h0m0s")
+	assert.EqualError(t, c.SendCommandWithOptions("dev1", "Valve", "OPEN", nil, CommandOptions{ExpireTime: -time.Second}), "invalid command expire time: -1s")
+	assert.Nil(t, req)
+
+	// the timeout only bounds the call, not the command on the platform
+	err := c.SendCommandWithOptions("slow", "Valve", "OPEN", nil, CommandOptions{ExpireTime: MaxCommandExpireTime, Timeout: 20 * time.Millisecond})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "expected deadline exceeded, got %v", err)
+	assert.Equal(t, float64(MaxCommandExpireTime/time.Second), (<-slow)["expireTime"])
+}
+
+func TestGetDeviceDataHistory(t *testing.T) {
+	var pages []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		q := r.URL.Query()
+		assert.Equal(t, "/iocm/app/data/v1.2.0/deviceDataHistory", r.URL.Path)
+		assert.Equal(t, "Meter", q.Get("serviceId"))
+		assert.Equal(t, "20170801T000000Z", q.Get("startTime"))
+		pages = append(pages, q.Get("pageNo"))
+		fmt.Fprintln(w, `{"totalCount":1,"deviceDataHistoryDTOs":[{"deviceId":"dev1","serviceId":"Meter","data":{"value":3},"timestamp":"20170801T120000Z"}]}`)
+	}))
+	defer s.Close()
+
+	c := Client{
+		c:   &http.Client{},
+		cfg: Config{URL: s.URL, AppID: "app"},
+	}
+	recs, err := c.GetDeviceDataHistory("dev1", "Meter", HistoryOptions{StartTime: time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)})
+	if assert.Nil(t, err) && assert.Equal(t, 1, len(recs)) {
+		var data struct{ Value int }
+		assert.Nil(t, recs[0].DecodeData(&data))
+		assert.Equal(t, 3, data.Value)
+		assert.Equal(t, 12, recs[0].Timestamp.Hour())
+	}
+	assert.Equal(t, []string{"0"}, pages)
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestNewClientTransport(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+	}))
+	defer s.Close()
+
+	var paths []string
+	rt := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		paths = append(paths, r.URL.Path)
+		return http.DefaultTransport.RoundTrip(r)
+	})
+
+	c, err := NewClient(Config{URL: s.URL, Transport: rt})
+	assert.Nil(t, err)
+	assert.Nil(t, c.Login())
+	assert.Equal(t, []string{"/iocm/app/sec/v1.1.0/login"}, paths)
+
+	c, err = NewClientWithHTTPClient(Config{URL: s.URL}, &http.Client{Transport: rt})
+	assert.Nil(t, err)
+	assert.Nil(t, c.Login())
+	assert.Equal(t, 2, len(paths))
+}
+
+func TestNewClientTLSVerify(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+	}))
+	defer s.Close()
+
+	c, err := NewClient(Config{URL: s.URL})
+	assert.Nil(t, err)
+	assert.NotNil(t, c.Login(), "expected unknown authority error")
+
+	c, err = NewClient(Config{URL: s.URL, InsecureSkipVerify: true})
+	assert.Nil(t, err)
+	assert.Nil(t, c.Login())
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	assert.Nil(t, ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw}), 0600))
+	c, err = NewClient(Config{URL: s.URL, CAFile: caFile})
+	assert.Nil(t, err)
+	assert.Nil(t, c.Login())
+
+	c, err = NewClient(Config{URL: s.URL, CAFile: caFile, ServerName: "example.org"})
+	assert.Nil(t, err)
+	assert.NotNil(t, c.Login(), "expected server name mismatch")
+
+	_, err = NewClient(Config{URL: s.URL, CAFile: filepath.Join(t.TempDir(), "missing")})
+	assert.NotNil(t, err)
+}
+
+// newTestCertificate creates a self-signed client certificate
+func newTestCertificate(t *testing.T) ([]byte, []byte, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), key
+}
+
+func TestNewClientCertificate(t *testing.T) {
+	var clients []string
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, cert := range r.TLS.PeerCertificates {
+			clients = append(clients, cert.Subject.CommonName)
+		}
+		fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+	}))
+	s.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	s.StartTLS()
+	defer s.Close()
+
+	// the certificate is optional for token-only deployments
+	c, err := NewClient(Config{URL: s.URL, InsecureSkipVerify: true})
+	assert.Nil(t, err)
+	assert.Nil(t, c.Login())
+	assert.Empty(t, clients)
+
+	certPEM, keyPEM, _ := newTestCertificate(t)
+	c, err = NewClient(Config{URL: s.URL, InsecureSkipVerify: true, CertPEM: certPEM, KeyPEM: keyPEM})
+	assert.Nil(t, err)
+	assert.Nil(t, c.Login())
+	assert.Equal(t, []string{"client"}, clients)
+
+	clients = nil
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	assert.Nil(t, ioutil.WriteFile(certFile, certPEM, 0600))
+	assert.Nil(t, ioutil.WriteFile(keyFile, keyPEM, 0600))
+	c, err = NewClient(Config{URL: s.URL, InsecureSkipVerify: true, CertFile: certFile, CertKeyFile: keyFile})
+	assert.Nil(t, err)
+	assert.Nil(t, c.Login())
+	assert.Equal(t, []string{"client"}, clients)
+
+	// the key can stay in a HSM behind a crypto.Signer
+	clients = nil
+	certPEM, _, key := newTestCertificate(t)
+	c, err = NewClient(Config{URL: s.URL, InsecureSkipVerify: true, CertPEM: certPEM, CertSigner: key})
+	assert.Nil(t, err)
+	assert.Nil(t, c.Login())
+	assert.Equal(t, []string{"client"}, clients)
+
+	_, err = NewClient(Config{URL: s.URL, CertSigner: key})
+	assert.EqualError(t, err, "no certificate found in CertPEM for CertSigner")
+	_, err = NewClient(Config{URL: s.URL, CertPEM: certPEM})
+	assert.NotNil(t, err, "expected error for missing key")
+	_, err = NewClient(Config{URL: s.URL, CertFile: filepath.Join(dir, "missing.crt"), CertKeyFile: keyFile})
+	assert.NotNil(t, err, "expected error for missing certificate file")
+}
+
+func TestDeviceShadow(t *testing.T) {
+	var updated string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/iocm/app/sec/v1.1.0/login":
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+		case r.Method == http.MethodGet:
+			assert.Equal(t, "/iocm/app/shadow/v1.5.0/devices/dev1", r.URL.Path)
+			fmt.Fprintln(w, `{"deviceId":"dev1","nodeType":"ENDPOINT","services":[{"serviceId":"Config","serviceType":"Config",
+				"desired":{"data":{"interval":60,"mode":"eco"},"eventTime":"20170912T101530Z"},
+				"reported":{"data":{"interval":30,"mode":"eco"},"eventTime":"20170911T101530Z"}}]}`)
+		default:
+			b, _ := ioutil.ReadAll(r.Body)
+			updated = string(b)
+		}
+	}))
+	defer s.Close()
+
+	c := Client{c: &http.Client{}, cfg: Config{URL: s.URL}}
+
+	shadow, err := c.GetDeviceShadow("dev1")
+	if assert.Nil(t, err) {
+		assert.Nil(t, shadow.Service("Meter"))
+		svc := shadow.Service("Config")
+		if assert.NotNil(t, svc) {
+			var reported struct{ Interval int }
+			assert.Nil(t, svc.Reported.Decode(&reported))
+			assert.Equal(t, 30, reported.Interval)
+			assert.Equal(t, time.Date(2017, 9, 12, 10, 15, 30, 0, time.UTC), svc.Desired.EventTime.Time)
+			pending, err := svc.Pending()
+			assert.Nil(t, err)
+			assert.Equal(t, map[string]interface{}{"interval": float64(60)}, pending)
+		}
+	}
+
+	assert.Nil(t, c.UpdateDeviceShadow("dev1", []ServiceDesired{{ServiceID: "Config", Desired: map[string]interface{}{"interval": 60}}}))
+	assert.Equal(t, `{"serviceDesireds":[{"serviceId":"Config","desired":{"interval":60}}]}`, updated)
+}
+
+func TestConcurrentRequests(t *testing.T) {
+	const n = 8
+	var logins, inFlight int32
+	all := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			atomic.AddInt32(&logins, 1)
+			time.Sleep(10 * time.Millisecond)
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		// the requests only complete when all of them are in flight
+		if atomic.AddInt32(&inFlight, 1) == n {
+			close(all)
+		}
+		select {
+		case <-all:
+		case <-time.After(5 * time.Second):
+			w.WriteHeader(http.StatusGatewayTimeout)
+			return
+		}
+		fmt.Fprintln(w, `{"deviceId":"dev1"}`)
+	}))
+	defer s.Close()
+
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL}}
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.GetDevice("dev1")
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&logins))
+}
@@ -0,0 +1,27 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import "time"
+
+// Clock provides the current time and timers to the client. It is used for
+// the token expiry, retries and polling, so tests can control time.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// clock returns the configured clock or the real time
+func (c *Client) clock() Clock {
+	if c.cfg.Clock != nil {
+		return c.cfg.Clock
+	}
+	return realClock{}
+}
@@ -0,0 +1,94 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// CloudEvent is a CloudEvents 1.0 event in the structured JSON format
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            *time.Time      `json:"time,omitempty"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+}
+
+// CloudEventConverter converts decoded notifications into CloudEvents
+type CloudEventConverter struct {
+	// Source of the events, e.g. "/oceanconnect/<appId>"
+	Source string
+	// TypePrefix is prepended to the notification type (default
+	// "com.huawei.oceanconnect.")
+	TypePrefix string
+}
+
+// Convert converts a decoded notification. The event ID is derived from the
+// platform request or command ID, when there is none a random ID is used. The
+// device ID is the subject of the event.
+func (c *CloudEventConverter) Convert(not Notification, v interface{}) (*CloudEvent, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	prefix := c.TypePrefix
+	if prefix == "" {
+		prefix = "com.huawei.oceanconnect."
+	}
+	e := &CloudEvent{
+		SpecVersion:     "1.0",
+		Source:          c.Source,
+		Type:            prefix + string(not),
+		Subject:         notificationDeviceID(v),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+
+	switch n := v.(type) {
+	case *DeviceDataChanged:
+		e.ID = n.RequestID
+		if t := n.Service.EventTime.Time; !t.IsZero() {
+			e.Time = &t
+		}
+	case *CommandResponse:
+		e.ID = n.Header.RequestID
+	case *MessageConfirm:
+		e.ID = n.Header.RequestID
+	case *CommandStatusUpdate:
+		if n.CommandID != "" {
+			// a command has several status updates
+			e.ID = n.CommandID + "-" + n.Result.ResultCode
+		}
+	}
+	if e.ID == "" {
+		e.ID = randomID()
+	}
+	return e, nil
+}
+
+// Callback returns a NotificationFunc which converts the notifications and
+// passes them to sink, e.g. to forward them to an event broker
+func (c *CloudEventConverter) Callback(not Notification, sink func(*CloudEvent) error) NotificationFunc {
+	return func(v interface{}) error {
+		e, err := c.Convert(not, v)
+		if err != nil {
+			return err
+		}
+		return sink(e)
+	}
+}
+
+func randomID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,32 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloudEventConverter(t *testing.T) {
+	n := &DeviceDataChanged{}
+	err := json.Unmarshal([]byte(`{"deviceId":"dev1","requestId":"req1","service":{"serviceId":"Config","data":{"interval":60},"eventTime":"20170912T101530Z"}}`), n)
+	assert.Nil(t, err)
+
+	c := CloudEventConverter{Source: "/oceanconnect/app1"}
+	e, err := c.Convert(NotificationDeviceDataChanged, n)
+	assert.Nil(t, err)
+	assert.Equal(t, "1.0", e.SpecVersion)
+	assert.Equal(t, "req1", e.ID)
+	assert.Equal(t, "com.huawei.oceanconnect.deviceDataChanged", e.Type)
+	assert.Equal(t, "dev1", e.Subject)
+	assert.Equal(t, "2017-09-12T10:15:30Z", e.Time.Format("2006-01-02T15:04:05Z07:00"))
+	assert.Contains(t, string(e.Data), `"data":{"interval":60}`)
+
+	e, err = c.Convert(NotificationCommandStatus, &CommandStatusUpdate{DeviceID: "dev1"})
+	assert.Nil(t, err)
+	assert.Equal(t, 32, len(e.ID), "expected random ID")
+}
@@ -65,7 +65,9 @@ func main() {
 	}
 
 	devs, err := client.GetDevices(oceanconnect.GetDevicesStruct{PageNo: 0, PageSize: 100})
-	if err != nil {
+	if decErrs, ok := err.(oceanconnect.DecodeErrors); ok {
+		logrus.Warnf("skipping devices: %v", decErrs)
+	} else if err != nil {
 		logrus.Fatalf("problem while retrieving devices: %v", err)
 	}
 
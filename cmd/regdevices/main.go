@@ -58,6 +58,10 @@ func main() {
 
 	name := *devName
 	if name == "" {
+		if len(*devID) == 0 && c.NameTemplate != "" {
+			logrus.Infof("Device named by template")
+			return
+		}
 		name = *imei
 	}
 	err = client.SetDeviceInfo(deviceID, name)
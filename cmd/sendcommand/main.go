@@ -5,8 +5,10 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"io/ioutil"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
@@ -15,15 +17,26 @@ import (
 )
 
 var (
-	devID  = flag.String("devid", "", "Device ID to read data from")
-	name   = flag.String("name", "", "Device name to read data from")
-	txData = flag.String("data", "Hello World", "Data to send")
+	devID   = flag.String("devid", "", "Device ID to send the command to")
+	name    = flag.String("name", "", "Device name to send the command to")
+	service = flag.String("service", "RawData", "Service ID of the command")
+	method  = flag.String("method", "SEND", "Method of the command")
+	txData  = flag.String("data", `{"rawData":"Hello World"}`, "Command parameters as JSON")
+	expire  = flag.Duration("expire", 150*time.Second, "Time the platform keeps the command for delivery")
+	timeout = flag.Duration("timeout", 30*time.Second, "Timeout of the call to the platform")
 
 	cfgFile = flag.String("config", "config.yml", "config-file for the API-settings")
 )
 
 func sendCmd(dat *oceanconnect.Device) {
-	err := dat.Command([]byte(*txData), 150)
+	var params interface{}
+	if err := json.Unmarshal([]byte(*txData), &params); err != nil {
+		logrus.Fatalf("invalid command parameters: %v", err)
+	}
+	err := dat.CommandWithOptions(*service, *method, params, oceanconnect.CommandOptions{
+		ExpireTime: *expire,
+		Timeout:    *timeout,
+	})
 	if err != nil {
 		logrus.Fatalf("command error: %v", err)
 	}
@@ -57,7 +70,9 @@ func main() {
 	}
 
 	devs, err := client.GetDevices(oceanconnect.GetDevicesStruct{PageNo: 0, PageSize: 100})
-	if err != nil {
+	if decErrs, ok := err.(oceanconnect.DecodeErrors); ok {
+		logrus.Warnf("skipping devices: %v", decErrs)
+	} else if err != nil {
 		logrus.Fatalf("problem while retrieving devices: %v", err)
 	}
 
@@ -0,0 +1,110 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// deviceCall is a GetDevice request shared by the concurrent callers
+type deviceCall struct {
+	done     chan struct{}
+	dev      *Device
+	err      error
+	finished time.Time
+}
+
+// deviceCalls holds the running and recently finished GetDevice requests per
+// device, see Config.CoalesceWindow
+type deviceCalls struct {
+	lock  sync.Mutex
+	calls map[string]*deviceCall
+}
+
+// getDeviceCoalesced retrieves the device, sharing the request with the
+// concurrent calls for the device
+func (c *Client) getDeviceCoalesced(ctx context.Context, deviceID string) (*Device, error) {
+	dc := &c.devCalls
+	for {
+		now := c.clock().Now()
+		dc.lock.Lock()
+		if dc.calls == nil {
+			dc.calls = make(map[string]*deviceCall)
+		}
+		call, ok := dc.calls[deviceID]
+		if ok && call.expired(now, c.cfg.CoalesceWindow) {
+			delete(dc.calls, deviceID)
+			ok = false
+		}
+		if !ok {
+			dc.expire(now, c.cfg.CoalesceWindow)
+			call = &deviceCall{done: make(chan struct{})}
+			dc.calls[deviceID] = call
+			dc.lock.Unlock()
+
+			dev, err := c.getDevice(ctx, deviceID)
+			dc.lock.Lock()
+			call.dev, call.err, call.finished = dev, err, c.clock().Now()
+			if err != nil {
+				// errors are only shared with the waiting calls
+				delete(dc.calls, deviceID)
+			}
+			dc.lock.Unlock()
+			close(call.done)
+			return copyDevice(dev), err
+		}
+		dc.lock.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-call.done:
+		}
+		if (errors.Is(call.err, context.Canceled) || errors.Is(call.err, context.DeadlineExceeded)) && ctx.Err() == nil {
+			// the context of the call which made the request is done, not ours
+			continue
+		}
+		return copyDevice(call.dev), call.err
+	}
+}
+
+// expire removes the finished calls older than the window, the lock must be
+// held
+func (dc *deviceCalls) expire(now time.Time, window time.Duration) {
+	for id, call := range dc.calls {
+		if call.expired(now, window) {
+			delete(dc.calls, id)
+		}
+	}
+}
+
+// expired reports whether the call finished longer than the window ago, the
+// lock of the calls must be held
+func (call *deviceCall) expired(now time.Time, window time.Duration) bool {
+	select {
+	case <-call.done:
+		return now.Sub(call.finished) >= window
+	default:
+		return false
+	}
+}
+
+// copyDevice copies the device including the service data, so callers
+// sharing a result can't change each other's devices. The decoded Value of
+// the services is shared.
+func copyDevice(d *Device) *Device {
+	if d == nil {
+		return nil
+	}
+	cp := *d
+	cp.Services = append([]Service(nil), d.Services...)
+	for i := range cp.Services {
+		cp.Services[i].Data = append([]byte(nil), cp.Services[i].Data...)
+	}
+	return &cp
+}
@@ -0,0 +1,119 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDeviceCoalesced(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		atomic.AddInt32(&requests, 1)
+		<-release
+		fmt.Fprintln(w, `{"deviceId":"dev1","deviceInfo":{"name":"meter"},"services":[{"serviceId":"Meter","data":{"value":1}}]}`)
+	}))
+	defer s.Close()
+
+	clock := &fakeClock{now: time.Date(2017, 9, 12, 10, 0, 0, 0, time.UTC)}
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL, Clock: clock, CoalesceWindow: time.Second}}
+	assert.Nil(t, c.Login())
+
+	var wg sync.WaitGroup
+	devs := make([]*Device, 10)
+	for i := range devs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			d, err := c.GetDevice("dev1")
+			assert.Nil(t, err)
+			devs[i] = d
+		}(i)
+	}
+	// wait until the first request is made, the others wait for it
+	for atomic.LoadInt32(&requests) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	for _, d := range devs {
+		assert.Equal(t, "meter", d.DeviceInfo.Name)
+	}
+	devs[0].Services[0].ServiceID = "changed"
+	devs[0].Services[0].Data[len(devs[0].Services[0].Data)-2] = '2'
+	assert.Equal(t, "Meter", devs[1].Services[0].ServiceID)
+	assert.Equal(t, `{"value":1}`, string(devs[1].Services[0].Data))
+
+	// the result is reused within the window
+	_, err := c.GetDevice("dev1")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	clock.now = clock.now.Add(time.Second)
+	_, err = c.GetDevice("dev1")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestGetDeviceCoalescedCanceled(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		atomic.AddInt32(&requests, 1)
+		<-release
+		fmt.Fprintln(w, `{"deviceId":"dev1","deviceInfo":{"name":"meter"}}`)
+	}))
+	defer s.Close()
+
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL, CoalesceWindow: time.Second}}
+	assert.Nil(t, c.Login())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := c.GetDeviceCtx(ctx, "dev1")
+		first <- err
+	}()
+	for atomic.LoadInt32(&requests) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	second := make(chan error)
+	go func() {
+		_, err := c.GetDeviceCtx(context.Background(), "dev1")
+		second <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// the canceled request is wrapped in a *url.Error, the waiting call
+	// makes its own request
+	cancel()
+	assert.True(t, errors.Is(<-first, context.Canceled))
+	for atomic.LoadInt32(&requests) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	assert.Nil(t, <-second)
+}
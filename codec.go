@@ -0,0 +1,50 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+)
+
+// Codec encodes and decodes the JSON of requests, responses and
+// notifications. It can be replaced by a faster JSON library or by a codec
+// which handles platform quirks centrally.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// StdCodec is the Codec using encoding/json
+type StdCodec struct{}
+
+// Marshal implements Codec
+func (StdCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+// Unmarshal implements Codec
+func (StdCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+func codecOrDefault(c Codec) Codec {
+	if c == nil {
+		return StdCodec{}
+	}
+	return c
+}
+
+// codec returns the configured codec
+func (c *Client) codec() Codec {
+	return codecOrDefault(c.cfg.Codec)
+}
+
+// decode reads the response body into v and closes the body
+func (c *Client) decode(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return newResponseOpError(resp, err)
+	}
+	return newResponseOpError(resp, withStrict(c.codec(), c.cfg.Strict).Unmarshal(buf, v))
+}
@@ -0,0 +1,194 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Defaults of the CommandHold
+const (
+	defaultHoldMaxAge      = MaxCommandExpireTime
+	defaultHoldMaxAttempts = 3
+)
+
+// HeldCommand is a command held for a sleeping device
+type HeldCommand struct {
+	DeviceID  string
+	ServiceID string
+	Method    string
+	Params    interface{}
+	Options   CommandOptions
+	// Held is when the command was held
+	Held time.Time
+	// Attempts counts the failed sends after a wake-up
+	Attempts int
+}
+
+// CommandHold holds commands for sleeping devices and sends them when the
+// platform signals the device is reachable: when it comes online or reports
+// data. It is an alternative to the buffered delivery of the platform, whose
+// expire time is limited and which can't be inspected or cancelled per
+// device. Attach it to the Dispatcher of the notification Server or
+// FleetPoller with Dispatcher.HoldCommands.
+type CommandHold struct {
+	// MaxAge drops commands held longer (default MaxCommandExpireTime)
+	MaxAge time.Duration
+	// MaxAttempts drops commands after the number of failed sends (default 3)
+	MaxAttempts int
+	// OnResult is called with the result of every sent command, err is
+	// ErrCommandExpired for dropped commands
+	OnResult func(cmd HeldCommand, err error)
+
+	client *Client
+	lock   sync.Mutex
+	held   map[string][]HeldCommand
+	wg     sync.WaitGroup
+}
+
+// ErrCommandExpired is passed to CommandHold.OnResult for commands dropped
+// because they were held longer than the MaxAge
+var ErrCommandExpired = errors.New("held command expired")
+
+// NewCommandHold creates a hold sending the commands with the client
+func (c *Client) NewCommandHold() *CommandHold {
+	return &CommandHold{client: c, held: make(map[string][]HeldCommand)}
+}
+
+// Hold holds the command until the device wakes up, commands of a device are
+// sent in the order they were held
+func (h *CommandHold) Hold(deviceID, serviceID, method string, params interface{}, opts CommandOptions) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.held[deviceID] = append(h.held[deviceID], HeldCommand{
+		DeviceID:  deviceID,
+		ServiceID: serviceID,
+		Method:    method,
+		Params:    params,
+		Options:   opts,
+		Held:      h.client.clock().Now(),
+	})
+}
+
+// Pending returns the commands held for the device
+func (h *CommandHold) Pending(deviceID string) []HeldCommand {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return append([]HeldCommand(nil), h.held[deviceID]...)
+}
+
+// Cancel drops the commands held for the device and returns them
+func (h *CommandHold) Cancel(deviceID string) []HeldCommand {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	cmds := h.held[deviceID]
+	delete(h.held, deviceID)
+	return cmds
+}
+
+// Wait waits until the commands released by wake-ups are sent
+func (h *CommandHold) Wait() {
+	h.wg.Wait()
+}
+
+// WaitCtx is like Wait but with a context, it returns the error of the
+// context when it is done first
+func (h *CommandHold) WaitCtx(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Wake sends the commands held for the device in the background, it is
+// called by the Dispatcher on wake-up notifications
+func (h *CommandHold) Wake(deviceID string) {
+	h.lock.Lock()
+	cmds := h.held[deviceID]
+	delete(h.held, deviceID)
+	if len(cmds) > 0 {
+		h.wg.Add(1)
+	}
+	h.lock.Unlock()
+	if len(cmds) == 0 {
+		return
+	}
+
+	go func() {
+		defer h.wg.Done()
+		h.send(cmds)
+	}()
+}
+
+// send sends the commands in order, failed commands are held again
+func (h *CommandHold) send(cmds []HeldCommand) {
+	maxAge := h.MaxAge
+	if maxAge <= 0 {
+		maxAge = defaultHoldMaxAge
+	}
+	maxAttempts := h.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultHoldMaxAttempts
+	}
+
+	var failed []HeldCommand
+	for _, cmd := range cmds {
+		if h.client.clock().Now().Sub(cmd.Held) >= maxAge {
+			h.result(cmd, ErrCommandExpired)
+			continue
+		}
+		err := h.client.SendCommandWithOptionsCtx(context.Background(), cmd.DeviceID, cmd.ServiceID, cmd.Method, cmd.Params, cmd.Options)
+		if err != nil {
+			cmd.Attempts++
+			logrus.Warnf("sending held command %s %s to device %s failed (attempt %d): %v", cmd.ServiceID, cmd.Method, cmd.DeviceID, cmd.Attempts, err)
+			if cmd.Attempts < maxAttempts {
+				failed = append(failed, cmd)
+			}
+		}
+		h.result(cmd, err)
+	}
+	if len(failed) == 0 {
+		return
+	}
+
+	h.lock.Lock()
+	deviceID := failed[0].DeviceID
+	h.held[deviceID] = append(failed, h.held[deviceID]...)
+	h.lock.Unlock()
+}
+
+func (h *CommandHold) result(cmd HeldCommand, err error) {
+	if h.OnResult != nil {
+		h.OnResult(cmd, err)
+	}
+}
+
+// wakeDeviceID returns the device a notification shows is reachable, empty
+// for notifications which don't
+func wakeDeviceID(v interface{}) string {
+	switch n := v.(type) {
+	case *DeviceInfoChanged:
+		if n.DeviceInfo.Status == DeviceStatusOnline {
+			return n.DeviceID
+		}
+	case *DeviceDataChanged:
+		return n.DeviceID
+	case *DeviceDatasChanged:
+		return n.DeviceID
+	}
+	return ""
+}
@@ -0,0 +1,91 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandHold(t *testing.T) {
+	var lock sync.Mutex
+	var methods []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		var body struct {
+			Command struct {
+				Method string `json:"method"`
+			} `json:"command"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		lock.Lock()
+		methods = append(methods, body.Command.Method)
+		lock.Unlock()
+		if body.Command.Method == "BAD" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, `{"error_code":"100022","error_desc":"invalid input"}`)
+			return
+		}
+		fmt.Fprintln(w, `{"commandId":"cmd1"}`)
+	}))
+	defer s.Close()
+
+	clock := &fakeClock{now: time.Date(2017, 9, 12, 10, 0, 0, 0, time.UTC)}
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL, Clock: clock}}
+	assert.Nil(t, c.Login())
+
+	var results []error
+	h := c.NewCommandHold()
+	h.MaxAttempts = 2
+	h.OnResult = func(cmd HeldCommand, err error) {
+		results = append(results, err)
+	}
+	d := &Dispatcher{}
+	d.HoldCommands(h)
+	assert.True(t, d.wants(NotificationDeviceInfoChanged))
+
+	h.Hold("dev1", "Valve", "OPEN", nil, CommandOptions{})
+	h.Hold("dev1", "Valve", "BAD", nil, CommandOptions{})
+	h.Hold("dev2", "Valve", "CLOSE", nil, CommandOptions{})
+	assert.Len(t, h.Pending("dev1"), 2)
+
+	// going offline isn't a wake-up
+	assert.Nil(t, d.Dispatch(NotificationDeviceInfoChanged, &DeviceInfoChanged{DeviceID: "dev1", DeviceInfo: DeviceInfo{Status: DeviceStatusOffline}}))
+	h.Wait()
+	assert.Nil(t, methods)
+
+	assert.Nil(t, d.Dispatch(NotificationDeviceDataChanged, &DeviceDataChanged{DeviceID: "dev1"}))
+	assert.Nil(t, h.WaitCtx(context.Background()))
+	assert.Equal(t, []string{"OPEN", "BAD"}, methods)
+	if assert.Len(t, results, 2) {
+		assert.Nil(t, results[0])
+		assert.NotNil(t, results[1])
+	}
+	if pending := h.Pending("dev1"); assert.Len(t, pending, 1) {
+		assert.Equal(t, 1, pending[0].Attempts)
+	}
+
+	// the failed command is dropped after the second attempt
+	assert.Nil(t, d.Dispatch(NotificationDeviceInfoChanged, &DeviceInfoChanged{DeviceID: "dev1", DeviceInfo: DeviceInfo{Status: DeviceStatusOnline}}))
+	h.Wait()
+	assert.Len(t, h.Pending("dev1"), 0)
+
+	clock.now = clock.now.Add(MaxCommandExpireTime)
+	assert.Nil(t, d.Dispatch(NotificationDeviceDataChanged, &DeviceDataChanged{DeviceID: "dev2"}))
+	h.Wait()
+	assert.Equal(t, ErrCommandExpired, results[len(results)-1])
+	assert.Equal(t, []string{"OPEN", "BAD", "BAD"}, methods)
+}
@@ -0,0 +1,146 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"text/template"
+)
+
+// CommandTemplate is a command with text/template parameters which are
+// resolved from the device metadata when the command is sent, e.g. to set a
+// threshold relative to the baseline of the device model:
+//
+//	"threshold": "{{add (index .Vars .DeviceInfo.Model) 5}}"
+//
+// Resolved values which are valid JSON are sent as such, everything else is
+// sent as string.
+type CommandTemplate struct {
+	ServiceID string
+	Method    string
+	Params    map[string]string
+	// Vars is available in the templates as .Vars, e.g. per-model baselines
+	Vars map[string]interface{}
+}
+
+// CommandData is passed to the parameter templates of a CommandTemplate
+type CommandData struct {
+	DeviceID   string
+	DeviceInfo DeviceInfo
+	// Services holds the last reported data per service ID
+	Services map[string]map[string]interface{}
+	Vars     map[string]interface{}
+}
+
+var commandFuncs = template.FuncMap{
+	"add": arith(func(x, y float64) float64 { return x + y }),
+	"sub": arith(func(x, y float64) float64 { return x - y }),
+	"mul": arith(func(x, y float64) float64 { return x * y }),
+}
+
+// arith returns a template function applying op to its arguments, the
+// template fails when one of them isn't a number
+func arith(op func(x, y float64) float64) func(a, b interface{}) (float64, error) {
+	return func(a, b interface{}) (float64, error) {
+		x, err := toFloat(a)
+		if err != nil {
+			return 0, err
+		}
+		y, err := toFloat(b)
+		if err != nil {
+			return 0, err
+		}
+		return op(x, y), nil
+	}
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), nil
+	case int32:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case uint:
+		return float64(n), nil
+	case float32:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	case json.Number:
+		return n.Float64()
+	}
+	return 0, fmt.Errorf("not a number: %v (%T)", v, v)
+}
+
+// ResolveCommand retrieves the device and resolves the parameters of the
+// command template
+func (c *Client) ResolveCommand(deviceID string, t CommandTemplate) (map[string]interface{}, error) {
+	return c.ResolveCommandCtx(context.Background(), deviceID, t)
+}
+
+// ResolveCommandCtx is like ResolveCommand but with a context
+func (c *Client) ResolveCommandCtx(ctx context.Context, deviceID string, t CommandTemplate) (map[string]interface{}, error) {
+	dev, err := c.GetDeviceCtx(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	data := CommandData{
+		DeviceID:   dev.DeviceID,
+		DeviceInfo: dev.DeviceInfo,
+		Services:   make(map[string]map[string]interface{}),
+		Vars:       t.Vars,
+	}
+	for _, s := range dev.Services {
+		var d map[string]interface{}
+		if json.Unmarshal(s.Data, &d) == nil {
+			data.Services[s.ServiceID] = d
+		}
+	}
+	return t.resolve(data)
+}
+
+func (t CommandTemplate) resolve(data CommandData) (map[string]interface{}, error) {
+	params := make(map[string]interface{}, len(t.Params))
+	for k, p := range t.Params {
+		tmpl, err := template.New(k).Funcs(commandFuncs).Option("missingkey=error").Parse(p)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+		var v interface{}
+		if err := json.Unmarshal(buf.Bytes(), &v); err != nil {
+			v = buf.String()
+		}
+		params[k] = v
+	}
+	return params, nil
+}
+
+// SendCommandTemplate resolves the command template for the device and sends
+// the command
+func (c *Client) SendCommandTemplate(deviceID string, t CommandTemplate, opts ...CommandOptions) error {
+	return c.SendCommandTemplateCtx(context.Background(), deviceID, t, opts...)
+}
+
+// SendCommandTemplateCtx is like SendCommandTemplate but with a context
+func (c *Client) SendCommandTemplateCtx(ctx context.Context, deviceID string, t CommandTemplate, opts ...CommandOptions) error {
+	params, err := c.ResolveCommandCtx(ctx, deviceID, t)
+	if err != nil {
+		return err
+	}
+	var o CommandOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	return c.SendCommandWithOptionsCtx(ctx, deviceID, t.ServiceID, t.Method, params, o)
+}
@@ -0,0 +1,43 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandTemplate(t *testing.T) {
+	tmpl := CommandTemplate{
+		ServiceID: "Config",
+		Method:    "SET_THRESHOLD",
+		Params: map[string]string{
+			"threshold": "{{add (index .Vars .DeviceInfo.Model) 5}}",
+			"interval":  "{{index .Services.Config \"interval\"}}",
+			"label":     "{{.DeviceID}}-{{.DeviceInfo.Model}}",
+		},
+		Vars: map[string]interface{}{"wm1": 20, "wm2": 30},
+	}
+	params, err := tmpl.resolve(CommandData{
+		DeviceID:   "dev1",
+		DeviceInfo: DeviceInfo{Model: "wm2"},
+		Services:   map[string]map[string]interface{}{"Config": {"interval": 60.0}},
+		Vars:       tmpl.Vars,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"threshold": 35.0, "interval": 60.0, "label": "dev1-wm2"}, params)
+
+	tmpl.Params = map[string]string{"missing": "{{.Vars.unknown.field}}"}
+	_, err = tmpl.resolve(CommandData{Vars: tmpl.Vars})
+	assert.NotNil(t, err, "expected error for missing variable")
+
+	// a value which isn't a number fails instead of counting as 0
+	tmpl.Params = map[string]string{"threshold": "{{add .DeviceInfo.Model 5}}"}
+	_, err = tmpl.resolve(CommandData{DeviceInfo: DeviceInfo{Model: "wm2"}, Vars: tmpl.Vars})
+	if assert.NotNil(t, err, "expected error for a string operand") {
+		assert.Contains(t, err.Error(), "not a number: wm2 (string)")
+	}
+}
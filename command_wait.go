@@ -0,0 +1,91 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"time"
+)
+
+// defaultCommandPollInterval is the interval SendCommandAndWait polls the
+// command status at when not configured
+const defaultCommandPollInterval = 5 * time.Second
+
+// CommandError is returned by SendCommandAndWait for commands which didn't
+// succeed, e.g. because they failed on the device or expired
+type CommandError struct {
+	Command *DeviceCommand
+}
+
+// Error implements the error interface
+func (e *CommandError) Error() string {
+	return "command " + e.Command.CommandID + " " + string(e.Command.Status)
+}
+
+// CommandWaitOptions for SendCommandAndWait
+type CommandWaitOptions struct {
+	CommandOptions
+	// PollInterval is the interval the command status is queried at, in
+	// case no status callback arrives (default 5s). A negative interval
+	// disables polling, the status callbacks must be received then.
+	PollInterval time.Duration
+}
+
+// SendCommandAndWait sends the command and blocks until it reached a final
+// status: the device responded, or the command failed or expired. The status
+// is taken from the command callbacks handled by the Server (see
+// Config.CommandCallbackURL) and from polling the command. The returned
+// command holds the response of the device in Result.ResultDetail, a
+// *CommandError is returned with it when the command didn't succeed.
+func (c *Client) SendCommandAndWait(ctx context.Context, deviceID string, serviceID string, method string, idata interface{}, opts CommandWaitOptions) (*DeviceCommand, error) {
+	// watch before sending, so a fast callback can't be missed
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events := c.events.watchFunc(watchCtx, func(ev Event) bool {
+		_, ok := ev.Data.(*CommandStatusUpdate)
+		return ok && ev.DeviceID == deviceID
+	})
+
+	cmd, err := c.SendCommandWithResponseCtx(ctx, deviceID, serviceID, method, idata, opts.CommandOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := opts.PollInterval
+	if interval == 0 {
+		interval = defaultCommandPollInterval
+	}
+	var poll <-chan time.Time
+	if interval > 0 {
+		poll = c.clock().After(interval)
+	}
+	for !cmd.Status.Final() {
+		select {
+		case <-ctx.Done():
+			return cmd, ctx.Err()
+		case ev, ok := <-events:
+			if !ok {
+				return cmd, ctx.Err()
+			}
+			u := ev.Data.(*CommandStatusUpdate)
+			if u.CommandID != cmd.CommandID {
+				continue
+			}
+			cmd.Status = CommandStatus(u.Result.ResultCode)
+			cmd.Result = u.Result
+		case <-poll:
+			polled, err := c.GetCommandCtx(ctx, cmd.CommandID)
+			if err != nil {
+				return cmd, err
+			}
+			cmd = polled
+			poll = c.clock().After(interval)
+		}
+	}
+	if cmd.Status != CommandSuccessful {
+		return cmd, &CommandError{Command: cmd}
+	}
+	return cmd, nil
+}
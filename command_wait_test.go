@@ -0,0 +1,66 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendCommandAndWait(t *testing.T) {
+	var d *Dispatcher
+	polled := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/iocm/app/sec/v1.1.0/login":
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+		case r.Method == http.MethodPost && d != nil:
+			// the callback arrives before the command is returned
+			d.Dispatch(NotificationCommandStatus, &CommandStatusUpdate{DeviceID: "dev1", CommandID: "cmd1",
+				Result: CommandResult{ResultCode: "SUCCESSFUL", ResultDetail: json.RawMessage(`{"level":80}`)}})
+			fallthrough
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintln(w, `{"commandId":"cmd1","deviceId":"dev1","status":"PENDING"}`)
+		default:
+			polled++
+			status := "SENT"
+			if polled > 1 {
+				status = "EXPIRED"
+			}
+			fmt.Fprintf(w, `{"commandId":"cmd1","deviceId":"dev1","status":%q}`, status)
+		}
+	}))
+	defer s.Close()
+
+	clock := &fakeClock{now: time.Date(2017, 9, 12, 10, 0, 0, 0, time.UTC)}
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL, Clock: clock}}
+	ctx := context.Background()
+
+	// polled until the command expired
+	cmd, err := c.SendCommandAndWait(ctx, "dev1", "Battery", "GET", nil, CommandWaitOptions{})
+	var cmdErr *CommandError
+	if assert.True(t, errors.As(err, &cmdErr)) {
+		assert.Equal(t, CommandExpired, cmdErr.Command.Status)
+	}
+	assert.Equal(t, CommandExpired, cmd.Status)
+	assert.Equal(t, 2, polled)
+
+	// completed by the status callback
+	d = &Dispatcher{hub: &c.events}
+	cmd, err = c.SendCommandAndWait(ctx, "dev1", "Battery", "GET", nil, CommandWaitOptions{PollInterval: -1})
+	assert.Nil(t, err)
+	assert.Equal(t, CommandSuccessful, cmd.Status)
+	assert.Equal(t, `{"level":80}`, string(cmd.Result.ResultDetail))
+	assert.Equal(t, 2, polled)
+}
@@ -0,0 +1,116 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+)
+
+// ErrNotReflected is returned when the device didn't report the expected
+// values before the timeout
+var ErrNotReflected = errors.New("expected values not reflected by device")
+
+// Expectation watches the data changes of a device service for the values a
+// command should result in. Create it before sending the command, so a fast
+// device report can't be missed.
+type Expectation struct {
+	DeviceID  string
+	ServiceID string
+	Values    map[string]interface{}
+
+	events <-chan Event
+	cancel context.CancelFunc
+	clock  Clock
+}
+
+// ExpectData starts watching the data changes of the device, fed by the
+// Server returned by Subscribe or a FleetPoller
+func (c *Client) ExpectData(deviceID, serviceID string, values map[string]interface{}) *Expectation {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Expectation{
+		DeviceID:  deviceID,
+		ServiceID: serviceID,
+		Values:    values,
+		events:    c.WatchDevice(ctx, deviceID),
+		cancel:    cancel,
+		clock:     c.clock(),
+	}
+}
+
+// ExpectData starts watching the data changes of the device dispatched by d
+func (d *Dispatcher) ExpectData(deviceID, serviceID string, values map[string]interface{}) *Expectation {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Expectation{
+		DeviceID:  deviceID,
+		ServiceID: serviceID,
+		Values:    values,
+		events:    d.WatchDevice(ctx, deviceID),
+		cancel:    cancel,
+		clock:     realClock{},
+	}
+}
+
+// Wait returns the data change which reflects the expected values, or
+// ErrNotReflected when none arrived within the timeout. The expectation
+// stops watching when Wait returns.
+func (e *Expectation) Wait(timeout time.Duration) (*DeviceDataChanged, error) {
+	return e.WaitCtx(context.Background(), timeout)
+}
+
+// WaitCtx is like Wait but with a context
+func (e *Expectation) WaitCtx(ctx context.Context, timeout time.Duration) (*DeviceDataChanged, error) {
+	defer e.cancel()
+
+	deadline := e.clock.After(timeout)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-deadline:
+			return nil, ErrNotReflected
+		case ev, ok := <-e.events:
+			if !ok {
+				return nil, ErrNotReflected
+			}
+			n, ok := ev.Data.(*DeviceDataChanged)
+			if ok && n.Service.ServiceID == e.ServiceID && e.matches(n.Service.Data) {
+				return n, nil
+			}
+		}
+	}
+}
+
+// Cancel stops watching without waiting
+func (e *Expectation) Cancel() {
+	e.cancel()
+}
+
+// matches compares the decoded JSON values, so e.g. an int expectation
+// matches the float64 of a decoded report
+func (e *Expectation) matches(data []byte) bool {
+	var got map[string]json.RawMessage
+	if err := json.Unmarshal(data, &got); err != nil {
+		return false
+	}
+	for k, want := range e.Values {
+		w, err := json.Marshal(want)
+		if err != nil {
+			return false
+		}
+		g, ok := got[k]
+		if !ok {
+			return false
+		}
+		var wv, gv interface{}
+		if json.Unmarshal(w, &wv) != nil || json.Unmarshal(g, &gv) != nil || !reflect.DeepEqual(wv, gv) {
+			return false
+		}
+	}
+	return true
+}
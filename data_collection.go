@@ -6,39 +6,46 @@ package oceanconnect
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
 )
 
 type deviceResponse struct {
 	Totalcount int
 	PageNo     int
 	Pagesize   int
-	Devices    []Device
+	Devices    []json.RawMessage
 }
 
-// Subscribe to notifications
+// Subscribe to notifications. When the subscription already exists the
+// existing subscription is returned, so Subscribe can be called at every
+// startup.
 func (c *Client) Subscribe(url string) (*Server, error) {
-	b := struct {
-		NotifyType  string `json:"notifyType"`
-		CallbackURL string `json:"callbackurl"`
-	}{
-		NotifyType:  "deviceDataChanged",
-		CallbackURL: url,
-	}
-	body, err := json.Marshal(b)
-	if err != nil {
-		return nil, err
-	}
-	resp, err := c.request(http.MethodPost, "/iocm/app/sub/v1.2.0/subscribe", bytes.NewBuffer(body))
+	return c.SubscribeCtx(context.Background(), url)
+}
+
+// SubscribeCtx is like Subscribe but with a context
+func (c *Client) SubscribeCtx(ctx context.Context, url string) (*Server, error) {
+	sub, err := c.SubscribeToCtx(ctx, NotificationDeviceDataChanged, url)
 	if err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusCreated {
-		return nil, errors.New("invalid response code: " + resp.Status)
-	}
-	return &Server{}, nil
+	return c.newServer(sub), nil
+}
+
+// newServer creates a Server which feeds the watchers of the client
+func (c *Client) newServer(sub *Subscription) *Server {
+	s := &Server{Subscription: sub, Codec: c.cfg.Codec, Strict: c.cfg.Strict}
+	s.Services = c.cfg.Services
+	s.Payloads = c.cfg.Payloads
+	s.Metrics = c.cfg.Metrics
+	s.hub = &c.events
+	return s
 }
 
 // RegistrationReply for RegisterDevice
@@ -49,89 +56,263 @@ type RegistrationReply struct {
 	Psk        string `json:"psk"`
 }
 
-// RegisterDevice registers a device with a corresponding IMEI number
+// regDeviceInfo is the device info sent with a registration, so the device
+// is bound to its profile at creation
+type regDeviceInfo struct {
+	ManufacturerID   string       `json:"manufacturerId,omitempty"`
+	ManufacturerName string       `json:"manufacturerName,omitempty"`
+	DeviceType       string       `json:"deviceType"`
+	Model            string       `json:"model,omitempty"`
+	ProtocolType     ProtocolType `json:"protocolType,omitempty"`
+}
+
+// RegisterDevice registers a device with a corresponding IMEI number. The
+// device is bound to the configured ProductID, or else to the profile of the
+// configured DeviceType, at registration. When a naming template is
+// configured the device is named after registration, if that fails the
+// registration reply is returned together with the error. The same holds
+// when the secret sink fails, its error is returned then.
 func (c *Client) RegisterDevice(imei string, timeoutV ...uint) (*RegistrationReply, error) {
-	type regDevice struct {
-		VerifyCode string `json:"verifyCode"`
-		NodeID     string `json:"nodeId"`
-		Timeout    uint   `json:"timeout"`
-		EndUserID  string `json:"endUserId"`
+	return c.RegisterDeviceCtx(context.Background(), imei, timeoutV...)
+}
+
+// RegisterDeviceCtx is like RegisterDevice but with a context
+func (c *Client) RegisterDeviceCtx(ctx context.Context, imei string, timeoutV ...uint) (*RegistrationReply, error) {
+	var opts RegisterOptions
+	if len(timeoutV) > 0 {
+		opts.Timeout = timeoutV[0]
 	}
+	return c.RegisterDeviceWithOptionsCtx(ctx, imei, opts)
+}
 
-	var timeout uint
+// RegisterOptions overrides the defaults of a device registration, empty
+// fields are left at their default
+type RegisterOptions struct {
+	// VerifyCode the device uses to bind, defaults to the IMEI
+	VerifyCode string
+	// NodeID identifies the device, defaults to the IMEI
+	NodeID string
+	// PSK is the pre-shared key of DTLS devices, the platform generates one
+	// when empty. It must be 8 to 32 hexadecimal characters.
+	PSK string
+	// ProductID defaults to the configured ProductID
+	ProductID string
+	// Timeout is the number of seconds the device has to bind, 0 is the
+	// platform default
+	Timeout uint
+	// EndUserID defaults to the configured EndUserID
+	EndUserID string
+}
 
-	if len(timeoutV) > 0 {
-		timeout = timeoutV[0]
+// ErrInvalidPSK is returned when registering a device with a PSK which isn't
+// 8 to 32 hexadecimal characters
+var ErrInvalidPSK = errors.New("PSK must be 8 to 32 hexadecimal characters")
+
+func validatePSK(psk string) error {
+	if psk == "" {
+		return nil
+	}
+	if len(psk) < 8 || len(psk) > 32 || strings.Trim(psk, "0123456789abcdefABCDEF") != "" {
+		return ErrInvalidPSK
+	}
+	return nil
+}
+
+func firstNonEmpty(s ...string) string {
+	for _, v := range s {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// RegisterDeviceWithOptions is like RegisterDevice but with operator-chosen
+// credentials, e.g. to register DTLS-PSK devices with the PSK provisioned in
+// the factory
+func (c *Client) RegisterDeviceWithOptions(imei string, opts RegisterOptions) (*RegistrationReply, error) {
+	return c.RegisterDeviceWithOptionsCtx(context.Background(), imei, opts)
+}
+
+// RegisterDeviceWithOptionsCtx is like RegisterDeviceWithOptions but with a
+// context
+func (c *Client) RegisterDeviceWithOptionsCtx(ctx context.Context, imei string, opts RegisterOptions) (*RegistrationReply, error) {
+	type regDevice struct {
+		VerifyCode string         `json:"verifyCode"`
+		NodeID     string         `json:"nodeId"`
+		Timeout    uint           `json:"timeout"`
+		EndUserID  string         `json:"endUserId"`
+		PSK        string         `json:"psk,omitempty"`
+		ProductID  string         `json:"productId,omitempty"`
+		DeviceInfo *regDeviceInfo `json:"deviceInfo,omitempty"`
 	}
 
+	if err := validatePSK(opts.PSK); err != nil {
+		return nil, err
+	}
 	b := regDevice{
-		VerifyCode: imei,
-		NodeID:     imei,
-		Timeout:    timeout,
-		EndUserID:  c.cfg.EndUserID,
+		VerifyCode: firstNonEmpty(opts.VerifyCode, imei),
+		NodeID:     firstNonEmpty(opts.NodeID, imei),
+		Timeout:    opts.Timeout,
+		EndUserID:  firstNonEmpty(opts.EndUserID, c.cfg.EndUserID),
+		PSK:        opts.PSK,
+		ProductID:  firstNonEmpty(opts.ProductID, c.cfg.ProductID),
 	}
-	body, err := json.Marshal(b)
+	if b.ProductID == "" && c.cfg.DeviceType != "" {
+		o := c.deviceInfoOptions("", nil)
+		if err := validateProtocolType(o.ProtocolType); err != nil {
+			return nil, err
+		}
+		b.DeviceInfo = &regDeviceInfo{
+			ManufacturerID:   c.cfg.ManufacturerID,
+			ManufacturerName: c.cfg.ManufacturerName,
+			DeviceType:       c.cfg.DeviceType,
+			Model:            c.cfg.Model,
+			ProtocolType:     o.ProtocolType,
+		}
+	}
+	body, err := c.codec().Marshal(b)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.request(http.MethodPost, "/iocm/app/reg/v1.2.0/devices?appId="+c.cfg.AppID, bytes.NewBuffer(body))
+	resp, err := c.requestCtx(ctx, http.MethodPost, c.appEndpoint("/iocm/app/reg/v1.2.0/devices").String(), bytes.NewBuffer(body))
 	if err != nil {
 		return nil, err
 	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("invalid response code: " + resp.Status)
+		return nil, c.newAPIError(resp)
 	}
-	d := RegistrationReply{}
-	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
-		return nil, err
+	d, sinkErr := c.decodeRegistration(resp)
+	if d == nil {
+		return nil, sinkErr
 	}
-	return &d, nil
+	// d is set when the device is registered but the secret sink failed, the
+	// device is named and the hooks run all the same
+	name, err := c.deviceName(imei, d.DeviceID)
+	if err == nil && name != "" {
+		err = c.SetDeviceInfoCtx(ctx, d.DeviceID, name)
+	}
+	// the device is registered whether naming it worked or not
+	c.runRegistrationHooks(RegistrationEvent{
+		IMEI:       imei,
+		DeviceID:   d.DeviceID,
+		VerifyCode: d.VerifyCode,
+		PSK:        d.Psk,
+	})
+	if sinkErr != nil {
+		if err != nil {
+			logrus.Errorf("naming device %s failed: %v", d.DeviceID, err)
+		}
+		return d, sinkErr
+	}
+	// a naming error is returned with the reply, so naming can be retried
+	return d, err
+}
+
+// DeviceInfoOptions overrides the device info defaults from the Config
+type DeviceInfoOptions struct {
+	ProtocolType ProtocolType `yaml:"protocol_type"`
+	Mute         *bool        `yaml:"mute"`
 }
 
+// deviceInfoOptions merges the options, in order of precedence, from the
+// call, the per-device config and the global config
+func (c *Client) deviceInfoOptions(deviceID string, opts []DeviceInfoOptions) DeviceInfoOptions {
+	o := DeviceInfoOptions{ProtocolType: c.cfg.ProtocolType}
+	merge := func(m DeviceInfoOptions) {
+		if m.ProtocolType != "" {
+			o.ProtocolType = m.ProtocolType
+		}
+		if m.Mute != nil {
+			o.Mute = m.Mute
+		}
+	}
+	if d, ok := c.cfg.Devices[deviceID]; ok {
+		merge(d)
+	}
+	for _, m := range opts {
+		merge(m)
+	}
+	if o.ProtocolType == "" {
+		o.ProtocolType = ProtocolCoAP
+	}
+	return o
+}
+
+// SetDeviceInfo sets the name of the device and the device info from the
+// config. The protocol type and mute setting can be overridden per device
+// in the config.
 func (c *Client) SetDeviceInfo(deviceID, name string) error {
+	return c.SetDeviceInfoCtx(context.Background(), deviceID, name)
+}
+
+// SetDeviceInfoWithOptions is like SetDeviceInfo with the protocol type and
+// mute setting overridden by opts
+func (c *Client) SetDeviceInfoWithOptions(deviceID, name string, opts DeviceInfoOptions) error {
+	return c.SetDeviceInfoCtx(context.Background(), deviceID, name, opts)
+}
+
+// SetDeviceInfoCtx is like SetDeviceInfo but with a context, opts override
+// the protocol type and mute setting like SetDeviceInfoWithOptions
+func (c *Client) SetDeviceInfoCtx(ctx context.Context, deviceID, name string, opts ...DeviceInfoOptions) error {
+	o := c.deviceInfoOptions(deviceID, opts)
+	if err := validateProtocolType(o.ProtocolType); err != nil {
+		return err
+	}
+	mute := "FALSE"
+	if o.Mute != nil && *o.Mute {
+		mute = "TRUE"
+	}
+
 	b := struct {
-		Name             string `json:"name"`
-		Mute             string `json:"mute"`
-		ManufacturerID   string `json:"manufacturerId"`
-		ManufacturerName string `json:"manufacturerName"`
-		Location         string `json:"location"`
-		DeviceType       string `json:"deviceType"`
-		ProtocolType     string `json:"protocolType"`
-		Model            string `json:"model"`
+		Name             string       `json:"name"`
+		Mute             string       `json:"mute"`
+		ManufacturerID   string       `json:"manufacturerId"`
+		ManufacturerName string       `json:"manufacturerName"`
+		Location         string       `json:"location"`
+		DeviceType       string       `json:"deviceType"`
+		ProtocolType     ProtocolType `json:"protocolType"`
+		Model            string       `json:"model"`
 	}{
 		Name:             name,
-		Mute:             "FALSE",
+		Mute:             mute,
 		ManufacturerID:   c.cfg.ManufacturerID,
 		ManufacturerName: c.cfg.ManufacturerName,
 		Location:         c.cfg.Location,
 		DeviceType:       c.cfg.DeviceType,
-		ProtocolType:     "CoAP",
+		ProtocolType:     o.ProtocolType,
 		Model:            c.cfg.Model,
 	}
 
-	body, err := json.Marshal(b)
+	body, err := c.codec().Marshal(b)
 	if err != nil {
 		return err
 	}
-	resp, err := c.request(http.MethodPut, "/iocm/app/dm/v1.2.0/devices/"+deviceID+"?appId="+c.cfg.AppID, bytes.NewBuffer(body))
+	resp, err := c.requestCtx(ctx, http.MethodPut, c.appEndpoint("/iocm/app/dm/v1.2.0/devices", deviceID).String(), bytes.NewBuffer(body))
 	if err != nil {
 		return err
 	}
 	if resp.StatusCode != http.StatusNoContent {
-		return errors.New("invalid response code: " + resp.Status)
+		return c.newAPIError(resp)
 	}
+	resp.Body.Close()
 	return nil
 }
 
 func (c *Client) DeleteDevice(deviceID string) error {
+	return c.DeleteDeviceCtx(context.Background(), deviceID)
+}
+
+// DeleteDeviceCtx is like DeleteDevice but with a context
+func (c *Client) DeleteDeviceCtx(ctx context.Context, deviceID string) error {
 
-	resp, err := c.request(http.MethodDelete, "/iocm/app/dm/v1.1.0/devices/"+deviceID, nil)
+	resp, err := c.requestCtx(ctx, http.MethodDelete, newEndpoint("/iocm/app/dm/v1.1.0/devices", deviceID).String(), nil)
 	if err != nil {
 		return err
 	}
 	if resp.StatusCode != http.StatusNoContent {
-		return errors.New("invalid response code: " + resp.Status)
+		return c.newAPIError(resp)
 	}
-
+	resp.Body.Close()
 	return nil
 }
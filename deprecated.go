@@ -0,0 +1,47 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"time"
+)
+
+// The wrappers in this file keep integrations written against earlier
+// versions compiling. They are kept for the current major version, see
+// Version.
+
+// SendCommand send command to target device, timeoutSec is the expireTime of
+// the command in seconds
+//
+// Deprecated: use SendCommandWithOptions, which separates the expire time of
+// the command from the timeout of the call.
+func (c *Client) SendCommand(deviceID string, serviceID string, method string, idata interface{}, timeoutSec int64) error {
+	return c.SendCommandCtx(context.Background(), deviceID, serviceID, method, idata, timeoutSec)
+}
+
+// SendCommandCtx is like SendCommand but with a context
+//
+// Deprecated: use SendCommandWithOptionsCtx.
+func (c *Client) SendCommandCtx(ctx context.Context, deviceID string, serviceID string, method string, idata interface{}, timeoutSec int64) error {
+	return c.SendCommandWithOptionsCtx(ctx, deviceID, serviceID, method, idata, CommandOptions{
+		ExpireTime: time.Duration(timeoutSec) * time.Second,
+	})
+}
+
+// Command send command to device, timeoutSec is the expireTime of the command
+// in seconds
+//
+// Deprecated: use SendCommand, which returns the created command.
+func (d *Device) Command(serviceID string, method string, idata interface{}, timeoutSec int64) error {
+	return d.CommandCtx(context.Background(), serviceID, method, idata, timeoutSec)
+}
+
+// CommandCtx is like Command but with a context
+//
+// Deprecated: use SendCommandCtx.
+func (d *Device) CommandCtx(ctx context.Context, serviceID string, method string, idata interface{}, timeoutSec int64) error {
+	return d.client.SendCommandCtx(ctx, d.DeviceID, serviceID, method, idata, timeoutSec)
+}
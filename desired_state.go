@@ -0,0 +1,165 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// DesiredStateOptions controls how ApplyDesiredState performs the shadow updates
+type DesiredStateOptions struct {
+	Concurrency int           // Concurrency is the number of devices updated in parallel (default 4)
+	Retries     int           // Retries is the number of retries after a failed update (default 2), negative disables them
+	RetryDelay  time.Duration // RetryDelay is the time between retries (default 1s)
+}
+
+// DesiredStateResult is the result of the shadow update of a single device
+type DesiredStateResult struct {
+	DeviceID string
+	Services []ServiceDesired
+	Attempts int
+	Err      error
+}
+
+// DesiredStateReport contains the results of ApplyDesiredState, in the order
+// the devices first appeared in the input
+type DesiredStateReport struct {
+	Results []DesiredStateResult
+}
+
+// Failed returns the results of the devices which couldn't be updated
+func (r *DesiredStateReport) Failed() []DesiredStateResult {
+	var failed []DesiredStateResult
+	for _, res := range r.Results {
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// ApplyDesiredState reads a desired-state file and updates the device shadows
+// accordingly. The file is CSV formatted with the columns deviceId, serviceId,
+// property and value. An optional header line and lines starting with # are
+// skipped. Values which are valid JSON (numbers, booleans, quoted strings,
+// objects) are sent as such, everything else is sent as string.
+func (c *Client) ApplyDesiredState(r io.Reader, opts ...DesiredStateOptions) (*DesiredStateReport, error) {
+	return c.ApplyDesiredStateCtx(context.Background(), r, opts...)
+}
+
+// ApplyDesiredStateCtx is like ApplyDesiredState but with a context. When
+// the context is done the report of the devices updated so far is returned
+// with the context error.
+func (c *Client) ApplyDesiredStateCtx(ctx context.Context, r io.Reader, opts ...DesiredStateOptions) (*DesiredStateReport, error) {
+	o := DesiredStateOptions{
+		Concurrency: 4,
+		Retries:     2,
+		RetryDelay:  time.Second,
+	}
+	if len(opts) > 0 {
+		if opts[0].Concurrency > 0 {
+			o.Concurrency = opts[0].Concurrency
+		}
+		if opts[0].Retries > 0 {
+			o.Retries = opts[0].Retries
+		} else if opts[0].Retries < 0 {
+			o.Retries = 0
+		}
+		if opts[0].RetryDelay > 0 {
+			o.RetryDelay = opts[0].RetryDelay
+		}
+	}
+
+	results, err := parseDesiredState(r)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, len(results))
+	services := make(map[string][]ServiceDesired, len(results))
+	for i, res := range results {
+		keys[i] = res.DeviceID
+		services[res.DeviceID] = res.Services
+	}
+	retries := o.Retries
+	if retries == 0 {
+		// 0 is the default of the executor
+		retries = -1
+	}
+	e := c.NewBulkExecutor(BulkOptions{
+		Concurrency: o.Concurrency,
+		Retries:     retries,
+		RetryDelay:  o.RetryDelay,
+	})
+	report, err := e.Run(ctx, keys, func(_ context.Context, deviceID string) error {
+		return c.UpdateDeviceShadowCtx(ctx, deviceID, services[deviceID])
+	})
+	if report == nil {
+		return nil, err
+	}
+	for i, res := range report.Results {
+		results[i].Attempts = res.Attempts
+		results[i].Err = res.Err
+	}
+
+	return &DesiredStateReport{Results: results}, err
+}
+
+// parseDesiredState groups the desired-state lines per device and service
+func parseDesiredState(r io.Reader) ([]DesiredStateResult, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = 4
+	cr.TrimLeadingSpace = true
+
+	var results []DesiredStateResult
+	devices := make(map[string]int)
+	for line := 0; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 0 && strings.EqualFold(rec[0], "deviceId") {
+			continue
+		}
+		if rec[0] == "" || rec[1] == "" || rec[2] == "" {
+			return nil, errors.New("desired state: empty deviceId, serviceId or property")
+		}
+
+		var value interface{}
+		if err := json.Unmarshal([]byte(rec[3]), &value); err != nil {
+			value = rec[3]
+		}
+
+		idx, ok := devices[rec[0]]
+		if !ok {
+			idx = len(results)
+			devices[rec[0]] = idx
+			results = append(results, DesiredStateResult{DeviceID: rec[0]})
+		}
+		res := &results[idx]
+		var svc *ServiceDesired
+		for i := range res.Services {
+			if res.Services[i].ServiceID == rec[1] {
+				svc = &res.Services[i]
+			}
+		}
+		if svc == nil {
+			res.Services = append(res.Services, ServiceDesired{ServiceID: rec[1], Desired: make(map[string]interface{})})
+			svc = &res.Services[len(res.Services)-1]
+		}
+		svc.Desired[rec[2]] = value
+	}
+	return results, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDesiredState(t *testing.T) {
+	in := `deviceId,serviceId,property,value
+# comment lines are skipped
+dev1,Config,interval,60
+dev1,Config,mode,eco
+dev2,Alarm,enabled,true
+dev1,Alarm,threshold,"""12"""
+`
+	res, err := parseDesiredState(strings.NewReader(in))
+	assert.Nil(t, err, "expected no error for parsing")
+	assert.Equal(t, 2, len(res), "expected 2 devices")
+
+	assert.Equal(t, "dev1", res[0].DeviceID)
+	assert.Equal(t, 2, len(res[0].Services), "expected 2 services for dev1")
+	assert.Equal(t, float64(60), res[0].Services[0].Desired["interval"])
+	assert.Equal(t, "eco", res[0].Services[0].Desired["mode"])
+	assert.Equal(t, "12", res[0].Services[1].Desired["threshold"])
+	assert.Equal(t, true, res[1].Services[0].Desired["enabled"])
+
+	_, err = parseDesiredState(strings.NewReader("dev1,Config,interval\n"))
+	assert.NotNil(t, err, "expected error for missing column")
+}
+
+func TestApplyDesiredStateRetries(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer s.Close()
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL, Clock: &fakeClock{now: time.Now()}}}
+
+	for _, tc := range []struct {
+		retries  int
+		attempts int
+	}{
+		{0, 3}, // unset keeps the default of 2 retries
+		{-1, 1},
+		{1, 2},
+	} {
+		report, err := c.ApplyDesiredState(strings.NewReader("dev1,Config,interval,60\n"), DesiredStateOptions{Concurrency: 1, Retries: tc.retries})
+		if assert.Nil(t, err) && assert.Equal(t, 1, len(report.Results)) {
+			assert.Equal(t, tc.attempts, report.Results[0].Attempts, "retries %d", tc.retries)
+		}
+	}
+}
+
+func TestApplyDesiredStateCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		// the operation is canceled while dev2 is updated
+		if strings.HasSuffix(r.URL.Path, "/dev2") {
+			ioutil.ReadAll(r.Body)
+			cancel()
+			<-r.Context().Done()
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL}}
+
+	in := "dev1,Config,interval,60\ndev2,Config,interval,60\ndev3,Config,interval,60\ndev4,Config,interval,60\n"
+	report, err := c.ApplyDesiredStateCtx(ctx, strings.NewReader(in), DesiredStateOptions{Concurrency: 1})
+	assert.Equal(t, context.Canceled, err)
+	if assert.NotNil(t, report, "expected the results so far") && assert.Equal(t, 4, len(report.Results)) {
+		assert.Nil(t, report.Results[0].Err, "expected dev1 to be updated")
+		assert.Equal(t, 1, report.Results[0].Attempts)
+		assert.Equal(t, 3, len(report.Failed()))
+		assert.Equal(t, 0, report.Results[3].Attempts)
+	}
+}
@@ -5,30 +5,59 @@
 package oceanconnect
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
+	"time"
 )
 
 // Device struct with device data
 type Device struct {
-	DeviceID         string     `json:"deviceId"`
-	GatewayID        string     `json:"gatewayId"`
-	NodeType         string     `json:"nodeType"`
-	CreateTime       OcTime     `json:"creationTime"`
-	LastModifiedTime OcTime     `json:"lastModifiedTime"`
-	DeviceInfo       DeviceInfo `json:"deviceInfo"`
-	Services         []Service  `json:"services"`
+	DeviceID         string         `json:"deviceId"`
+	GatewayID        string         `json:"gatewayId"`
+	NodeType         NodeType       `json:"nodeType"`
+	CreateTime       OcTime         `json:"creationTime"`
+	LastModifiedTime OcTime         `json:"lastModifiedTime"`
+	DeviceInfo       DeviceInfo     `json:"deviceInfo"`
+	Services         []Service      `json:"services"`
+	ConnectionInfo   ConnectionInfo `json:"connectionInfo"`
 	client           *Client
 }
 
+// ConnectionInfo struct with the connectionInfo of a device. The platform
+// documents only the protocol type, the address and NAT binding fields are
+// only filled by platform versions which return them. Raw holds the object
+// as returned, for fields these versions name differently.
+type ConnectionInfo struct {
+	ProtocolType   ProtocolType    `json:"protocolType"`
+	IP             string          `json:"ip"`
+	Port           int             `json:"port"`
+	LastNATBinding OcTime          `json:"lastNatBindingTime"`
+	Raw            json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes the known fields and keeps the object in Raw
+func (ci *ConnectionInfo) UnmarshalJSON(data []byte) error {
+	type Alias ConnectionInfo
+
+	if err := json.Unmarshal(data, (*Alias)(ci)); err != nil {
+		return err
+	}
+	ci.Raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
 // Service struct which holds service information data
 type Service struct {
 	ServiceID   string `json:"serviceId"`
 	ServiceType string `json:"serviceType"`
 	Data        []byte `json:"data"`
-	EventTime   OcTime `json:"eventTime`
+	EventTime   OcTime `json:"eventTime"`
 	ServiceInfo string `json:"serviceInfo"`
+	// Value is the data decoded into the type registered in the
+	// ServiceRegistry, nil when there is none
+	Value interface{} `json:"-"`
 }
 
 func (u *Service) UnmarshalJSON(data []byte) error {
@@ -50,6 +79,24 @@ func (u *Service) UnmarshalJSON(data []byte) error {
 	return err
 }
 
+// MarshalJSON writes Data as JSON instead of base64, the inverse of
+// UnmarshalJSON
+func (u Service) MarshalJSON() ([]byte, error) {
+	type Alias Service
+
+	data := json.RawMessage(u.Data)
+	if len(data) == 0 {
+		data = json.RawMessage("null")
+	}
+	return json.Marshal(&struct {
+		Data json.RawMessage `json:"data"`
+		*Alias
+	}{
+		Data:  data,
+		Alias: (*Alias)(&u),
+	})
+}
+
 // DeviceInfo struct with device info data
 type DeviceInfo struct {
 	NodeID            string
@@ -64,9 +111,9 @@ type DeviceInfo struct {
 	Swversion         string
 	FwVersion         string
 	HwVersion         string
-	ProtocolType      string
+	ProtocolType      ProtocolType
 	BridgeID          string
-	Status            string
+	Status            DeviceStatus
 	StatusDetail      string
 	Mute              string
 	SupportedSecurity string
@@ -92,6 +139,9 @@ type DeviceData struct {
 	ServiceID string
 	Data      []byte `json:"data"`
 	Timestamp OcTime
+	// Value is the data decoded into the type registered in the
+	// ServiceRegistry, nil when there is none
+	Value interface{} `json:"-"`
 }
 
 func (u *DeviceData) UnmarshalJSON(data []byte) error {
@@ -112,24 +162,159 @@ func (u *DeviceData) UnmarshalJSON(data []byte) error {
 
 // GetHistoricalData returns data from specific device
 func (d *Device) GetHistoricalData() ([]DeviceData, error) {
-	resp, err := d.client.request(http.MethodGet, "/iocm/app/data/v1.1.0/deviceDataHistory?deviceId="+d.DeviceID+"&gatewayId="+d.GatewayID, nil)
+	return d.GetHistoricalDataCtx(context.Background())
+}
+
+// GetHistoricalDataCtx is like GetHistoricalData but with a context
+func (d *Device) GetHistoricalDataCtx(ctx context.Context) ([]DeviceData, error) {
+	resp, err := d.client.requestCtx(ctx, http.MethodGet, newEndpoint("/iocm/app/data/v1.1.0/deviceDataHistory").Set("deviceId", d.DeviceID).Set("gatewayId", d.GatewayID).String(), nil)
 	if err != nil {
 		return nil, err
 	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("invalid response code: " + resp.Status)
+		return nil, d.client.newAPIError(resp)
 	}
 
 	// save device response
 	dh := deviceHistory{}
-	if err := json.NewDecoder(resp.Body).Decode(&dh); err != nil {
+	if err := d.client.decode(resp, &dh); err != nil {
 		return nil, err
 	}
+	for i := range dh.DeviceData {
+		dd := &dh.DeviceData[i]
+		if dd.Data, err = d.client.cfg.Scales.Normalize(dd.ServiceID, dd.Data); err != nil {
+			return nil, err
+		}
+		if dd.Value, err = d.client.cfg.Services.decode(d.client.codec(), dd.ServiceID, dd.Data); err != nil {
+			return nil, err
+		}
+	}
 
 	return dh.DeviceData, nil
 }
 
-// Command send command to device
-func (d *Device) Command(serviceID string, method string, idata interface{}, timeoutSec int64) error {
-	return d.client.SendCommand(d.DeviceID, serviceID, method, idata, timeoutSec)
+// RefreshDeviceConnectionInfo retrieves the current connection details of a
+// device, e.g. to debug unreachable CoAP devices behind NAT
+func (c *Client) RefreshDeviceConnectionInfo(deviceID string) (*ConnectionInfo, error) {
+	return c.RefreshDeviceConnectionInfoCtx(context.Background(), deviceID)
+}
+
+// RefreshDeviceConnectionInfoCtx is like RefreshDeviceConnectionInfo but with a context
+func (c *Client) RefreshDeviceConnectionInfoCtx(ctx context.Context, deviceID string) (*ConnectionInfo, error) {
+	// not coalesced, the result must not be older than the call
+	d, err := c.getDevice(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	return &d.ConnectionInfo, nil
+}
+
+// CommandWithOptions send command to device
+func (d *Device) CommandWithOptions(serviceID string, method string, idata interface{}, opts CommandOptions) error {
+	return d.CommandWithOptionsCtx(context.Background(), serviceID, method, idata, opts)
+}
+
+// CommandWithOptionsCtx is like CommandWithOptions but with a context
+func (d *Device) CommandWithOptionsCtx(ctx context.Context, serviceID string, method string, idata interface{}, opts CommandOptions) error {
+	return d.client.SendCommandWithOptionsCtx(ctx, d.DeviceID, serviceID, method, idata, opts)
+}
+
+// ErrDetachedDevice is returned by the methods of a Device which wasn't
+// retrieved with a Client
+var ErrDetachedDevice = errors.New("device isn't retrieved with a client")
+
+// Status returns the status of the device
+func (d *Device) Status() DeviceStatus {
+	return d.DeviceInfo.Status
+}
+
+// Online reports whether the device is online
+func (d *Device) Online() bool {
+	return d.DeviceInfo.Status == DeviceStatusOnline
+}
+
+// LastSeen returns the most recent event time of the service data of the
+// device, the zero time when it never reported data
+func (d *Device) LastSeen() time.Time {
+	var t time.Time
+	for _, s := range d.Services {
+		if s.EventTime.After(t) {
+			t = s.EventTime.Time
+		}
+	}
+	return t
+}
+
+// Service returns the most recent data of the service, nil when the device
+// didn't report it
+func (d *Device) Service(serviceID string) *Service {
+	return DeviceServices(d.Services).Service(serviceID)
+}
+
+// DecodeService decodes the most recent data of the service into v, see
+// Service.DecodeInto
+func (d *Device) DecodeService(serviceID string, v interface{}) error {
+	return DeviceServices(d.Services).DecodeInto(serviceID, v)
+}
+
+// Refresh retrieves the device again and replaces d with it
+func (d *Device) Refresh() error {
+	return d.RefreshCtx(context.Background())
+}
+
+// RefreshCtx is like Refresh but with a context
+func (d *Device) RefreshCtx(ctx context.Context) error {
+	if d.client == nil {
+		return ErrDetachedDevice
+	}
+	fresh, err := d.client.GetDeviceCtx(ctx, d.DeviceID)
+	if err != nil {
+		return err
+	}
+	*d = *fresh
+	return nil
+}
+
+// SendCommand sends a command to the device and returns the created command
+func (d *Device) SendCommand(serviceID string, method string, params interface{}, opts CommandOptions) (*DeviceCommand, error) {
+	return d.SendCommandCtx(context.Background(), serviceID, method, params, opts)
+}
+
+// SendCommandCtx is like SendCommand but with a context
+func (d *Device) SendCommandCtx(ctx context.Context, serviceID string, method string, params interface{}, opts CommandOptions) (*DeviceCommand, error) {
+	if d.client == nil {
+		return nil, ErrDetachedDevice
+	}
+	return d.client.SendCommandWithResponseCtx(ctx, d.DeviceID, serviceID, method, params, opts)
+}
+
+// SetName sets the name of the device, the other device info is set from
+// the config like SetDeviceInfo
+func (d *Device) SetName(name string) error {
+	return d.SetNameCtx(context.Background(), name)
+}
+
+// SetNameCtx is like SetName but with a context
+func (d *Device) SetNameCtx(ctx context.Context, name string) error {
+	if d.client == nil {
+		return ErrDetachedDevice
+	}
+	if err := d.client.SetDeviceInfoCtx(ctx, d.DeviceID, name); err != nil {
+		return err
+	}
+	d.DeviceInfo.Name = name
+	return nil
+}
+
+// Delete deletes the device from the platform
+func (d *Device) Delete() error {
+	return d.DeleteCtx(context.Background())
+}
+
+// DeleteCtx is like Delete but with a context
+func (d *Device) DeleteCtx(ctx context.Context) error {
+	if d.client == nil {
+		return ErrDetachedDevice
+	}
+	return d.client.DeleteDeviceCtx(ctx, d.DeviceID)
 }
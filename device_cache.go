@@ -0,0 +1,283 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachedDevice is a device in the DeviceCache with its local tags
+type CachedDevice struct {
+	Device
+	// Tags are local to the cache, the platform doesn't know them
+	Tags map[string]bool
+	// Fetched is when the device was retrieved from the platform
+	Fetched time.Time
+}
+
+// DeviceCache keeps the devices of the application in memory and allows
+// queries the platform filters don't support
+type DeviceCache struct {
+	client *Client
+
+	lock    sync.RWMutex
+	devices map[string]*CachedDevice
+	tags    map[string]map[string]bool // deviceID -> tags, kept over refreshes
+}
+
+// NewDeviceCache creates an empty cache, call Refresh to fill it
+func NewDeviceCache(c *Client) *DeviceCache {
+	return &DeviceCache{
+		client:  c,
+		devices: make(map[string]*CachedDevice),
+		tags:    make(map[string]map[string]bool),
+	}
+}
+
+// Refresh retrieves all devices of the application, devices which no longer
+// exist are removed
+func (dc *DeviceCache) Refresh() error {
+	return dc.RefreshCtx(context.Background())
+}
+
+// RefreshCtx is like Refresh but with a context
+func (dc *DeviceCache) RefreshCtx(ctx context.Context) error {
+	devs, _, err := dc.client.allDevices(ctx, 0)
+	if err != nil {
+		return err
+	}
+	now := dc.client.clock().Now()
+
+	dc.lock.Lock()
+	defer dc.lock.Unlock()
+	dc.devices = make(map[string]*CachedDevice, len(devs))
+	for _, d := range devs {
+		dc.put(d, now)
+	}
+	return nil
+}
+
+// Update replaces a single device in the cache, e.g. after GetDevice
+func (dc *DeviceCache) Update(d Device) {
+	dc.lock.Lock()
+	dc.put(d, dc.client.clock().Now())
+	dc.lock.Unlock()
+}
+
+func (dc *DeviceCache) put(d Device, fetched time.Time) {
+	tags, ok := dc.tags[d.DeviceID]
+	if !ok {
+		tags = make(map[string]bool)
+		dc.tags[d.DeviceID] = tags
+	}
+	dc.devices[d.DeviceID] = &CachedDevice{Device: d, Tags: tags, Fetched: fetched}
+}
+
+// applyNotification updates the cached device from a device notification,
+// notifications of devices which aren't cached add them. The services are
+// replaced rather than modified, copies returned earlier share them.
+func (dc *DeviceCache) applyNotification(v interface{}) {
+	dc.lock.Lock()
+	defer dc.lock.Unlock()
+
+	deviceID := notificationDeviceID(v)
+	if deviceID == "" {
+		return
+	}
+	if _, ok := v.(*DeviceDeleted); ok {
+		delete(dc.devices, deviceID)
+		delete(dc.tags, deviceID)
+		return
+	}
+	d := Device{DeviceID: deviceID, client: dc.client}
+	if cd, ok := dc.devices[deviceID]; ok {
+		d = cd.Device
+	}
+	switch n := v.(type) {
+	case *DeviceAdded:
+		d.GatewayID, d.NodeType, d.DeviceInfo = n.GatewayID, n.NodeType, n.DeviceInfo
+	case *DeviceInfoChanged:
+		d.DeviceInfo = n.DeviceInfo
+	case *DeviceDataChanged:
+		d.Services = withService(d.Services, n.Service)
+	case *DeviceDatasChanged:
+		for _, s := range n.Services {
+			d.Services = withService(d.Services, s)
+		}
+	default:
+		return
+	}
+	dc.put(d, dc.client.clock().Now())
+}
+
+// withService returns a copy of the services with s added or replacing the
+// service with its ID
+func withService(svcs []Service, s Service) []Service {
+	ret := make([]Service, 0, len(svcs)+1)
+	for _, x := range svcs {
+		if x.ServiceID != s.ServiceID {
+			ret = append(ret, x)
+		}
+	}
+	return append(ret, s)
+}
+
+// Get returns a copy of the cached device
+func (dc *DeviceCache) Get(deviceID string) (CachedDevice, bool) {
+	dc.lock.RLock()
+	defer dc.lock.RUnlock()
+	d, ok := dc.devices[deviceID]
+	if !ok {
+		return CachedDevice{}, false
+	}
+	return dc.copy(d), true
+}
+
+// Tag adds local tags to a device
+func (dc *DeviceCache) Tag(deviceID string, tags ...string) {
+	dc.lock.Lock()
+	defer dc.lock.Unlock()
+	t, ok := dc.tags[deviceID]
+	if !ok {
+		t = make(map[string]bool)
+		dc.tags[deviceID] = t
+	}
+	for _, tag := range tags {
+		t[tag] = true
+	}
+}
+
+// Untag removes local tags from a device
+func (dc *DeviceCache) Untag(deviceID string, tags ...string) {
+	dc.lock.Lock()
+	defer dc.lock.Unlock()
+	for _, tag := range tags {
+		delete(dc.tags[deviceID], tag)
+	}
+}
+
+// Query returns copies of the devices matching all predicates, ordered by
+// device ID
+func (dc *DeviceCache) Query(preds ...DevicePredicate) []CachedDevice {
+	match := And(preds...)
+
+	dc.lock.RLock()
+	var out []CachedDevice
+	for _, d := range dc.devices {
+		if match(d) {
+			out = append(out, dc.copy(d))
+		}
+	}
+	dc.lock.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool { return out[i].DeviceID < out[j].DeviceID })
+	return out
+}
+
+// copy returns the device with a copy of the tags, the caller holds the lock
+func (dc *DeviceCache) copy(d *CachedDevice) CachedDevice {
+	c := *d
+	c.Tags = make(map[string]bool, len(d.Tags))
+	for t := range d.Tags {
+		c.Tags[t] = true
+	}
+	return c
+}
+
+// DevicePredicate selects devices in a DeviceCache query
+type DevicePredicate func(*CachedDevice) bool
+
+// And matches devices matching all predicates
+func And(preds ...DevicePredicate) DevicePredicate {
+	return func(d *CachedDevice) bool {
+		for _, p := range preds {
+			if !p(d) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or matches devices matching any of the predicates
+func Or(preds ...DevicePredicate) DevicePredicate {
+	return func(d *CachedDevice) bool {
+		for _, p := range preds {
+			if p(d) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not matches devices not matching the predicate
+func Not(p DevicePredicate) DevicePredicate {
+	return func(d *CachedDevice) bool { return !p(d) }
+}
+
+// NameContains matches devices whose name contains s, case insensitive
+func NameContains(s string) DevicePredicate {
+	s = strings.ToLower(s)
+	return func(d *CachedDevice) bool {
+		return strings.Contains(strings.ToLower(d.DeviceInfo.Name), s)
+	}
+}
+
+// HasTag matches devices with the local tag
+func HasTag(tag string) DevicePredicate {
+	return func(d *CachedDevice) bool { return d.Tags[tag] }
+}
+
+// ModelIs matches devices of the model
+func ModelIs(model string) DevicePredicate {
+	return func(d *CachedDevice) bool { return d.DeviceInfo.Model == model }
+}
+
+// FirmwareIs matches devices running the firmware version
+func FirmwareIs(version string) DevicePredicate {
+	return func(d *CachedDevice) bool { return d.DeviceInfo.FwVersion == version }
+}
+
+// StatusIs matches devices with the status
+func StatusIs(status DeviceStatus) DevicePredicate {
+	return func(d *CachedDevice) bool { return d.DeviceInfo.Status == status }
+}
+
+// LastSeenBetween matches devices whose last activity (Device.LastSeen) is
+// in the range, a zero from or to leaves that side open. Devices which never
+// reported data only match when from is zero.
+func LastSeenBetween(from, to time.Time) DevicePredicate {
+	return func(d *CachedDevice) bool {
+		return lastSeenBetween(&d.Device, from, to)
+	}
+}
+
+// InactiveFor matches devices not seen for at least min but seen within max
+// before now, e.g. InactiveFor(now, 48*time.Hour, 30*24*time.Hour). A zero
+// max includes the devices which never reported data.
+func InactiveFor(now time.Time, min, max time.Duration) DevicePredicate {
+	return LastSeenBetween(inactiveRange(now, min, max))
+}
+
+func inactiveRange(now time.Time, min, max time.Duration) (time.Time, time.Time) {
+	var from time.Time
+	if max > 0 {
+		from = now.Add(-max)
+	}
+	return from, now.Add(-min)
+}
+
+func lastSeenBetween(d *Device, from, to time.Time) bool {
+	seen := d.LastSeen()
+	if seen.IsZero() {
+		return from.IsZero()
+	}
+	return !seen.Before(from) && (to.IsZero() || !seen.After(to))
+}
@@ -0,0 +1,63 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceCacheQuery(t *testing.T) {
+	dc := NewDeviceCache(&Client{})
+	dc.Update(Device{DeviceID: "dev1", DeviceInfo: DeviceInfo{Name: "Meter North", Model: "wm1", FwVersion: "1.0", Status: DeviceStatusOnline}})
+	dc.Update(Device{DeviceID: "dev2", DeviceInfo: DeviceInfo{Name: "Meter South", Model: "wm2", FwVersion: "1.1", Status: DeviceStatusOffline}})
+	dc.Update(Device{DeviceID: "dev3", DeviceInfo: DeviceInfo{Name: "Valve", Model: "wm1", FwVersion: "1.1", Status: DeviceStatusOnline}})
+	dc.Tag("dev3", "pilot")
+
+	ids := func(devs []CachedDevice) []string {
+		var out []string
+		for _, d := range devs {
+			out = append(out, d.DeviceID)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"dev1", "dev2"}, ids(dc.Query(NameContains("meter"))))
+	assert.Equal(t, []string{"dev3"}, ids(dc.Query(ModelIs("wm1"), FirmwareIs("1.1"))))
+	assert.Equal(t, []string{"dev2", "dev3"}, ids(dc.Query(Or(HasTag("pilot"), StatusIs(DeviceStatusOffline)))))
+	assert.Equal(t, []string{"dev1", "dev2"}, ids(dc.Query(Not(HasTag("pilot")))))
+
+	// tags survive updates of the device
+	dc.Update(Device{DeviceID: "dev3"})
+	d, ok := dc.Get("dev3")
+	assert.True(t, ok)
+	assert.True(t, d.Tags["pilot"])
+}
+
+func TestLastSeenBetween(t *testing.T) {
+	now := time.Date(2017, 9, 12, 10, 0, 0, 0, time.UTC)
+	seen := func(id string, ago time.Duration) Device {
+		return Device{DeviceID: id, Services: []Service{{EventTime: OcTime{now.Add(-ago)}}}}
+	}
+	dc := NewDeviceCache(&Client{})
+	dc.Update(seen("dev1", time.Hour))
+	dc.Update(seen("dev2", 72*time.Hour))
+	dc.Update(seen("dev3", 60*24*time.Hour))
+	dc.Update(Device{DeviceID: "dev4"})
+
+	var ids []string
+	for _, d := range dc.Query(InactiveFor(now, 48*time.Hour, 30*24*time.Hour)) {
+		ids = append(ids, d.DeviceID)
+	}
+	assert.Equal(t, []string{"dev2"}, ids)
+
+	ids = nil
+	for _, d := range dc.Query(InactiveFor(now, 48*time.Hour, 0)) {
+		ids = append(ids, d.DeviceID)
+	}
+	assert.Equal(t, []string{"dev2", "dev3", "dev4"}, ids)
+	assert.Len(t, dc.Query(LastSeenBetween(now.Add(-2*time.Hour), time.Time{})), 1)
+}
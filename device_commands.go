@@ -0,0 +1,243 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// CommandStatus is the status of a command on the platform
+type CommandStatus string
+
+const (
+	// CommandPending is used while the command waits for the device
+	CommandPending CommandStatus = "PENDING"
+	// CommandExpired is used when the command wasn't delivered within its
+	// expire time
+	CommandExpired CommandStatus = "EXPIRED"
+	// CommandSuccessful is used when the device executed the command
+	CommandSuccessful CommandStatus = "SUCCESSFUL"
+	// CommandFailed is used when the device failed to execute the command
+	CommandFailed CommandStatus = "FAILED"
+	// CommandTimeout is used when the device didn't respond in time
+	CommandTimeout CommandStatus = "TIMEOUT"
+	// CommandCanceled is used for commands canceled by the application
+	CommandCanceled CommandStatus = "CANCELED"
+	// CommandDelivered is used when the device received the command
+	CommandDelivered CommandStatus = "DELIVERED"
+	// CommandSent is used when the command is sent to the device
+	CommandSent CommandStatus = "SENT"
+)
+
+// Final reports whether the status doesn't change anymore
+func (s CommandStatus) Final() bool {
+	switch s {
+	case CommandSuccessful, CommandFailed, CommandTimeout, CommandCanceled, CommandExpired:
+		return true
+	}
+	return false
+}
+
+// CommandBody is the command as sent to the device
+type CommandBody struct {
+	ServiceID string          `json:"serviceId"`
+	Method    string          `json:"method"`
+	Params    json.RawMessage `json:"paras"`
+}
+
+// DeviceCommand struct with a command of the application
+type DeviceCommand struct {
+	CommandID          string        `json:"commandId"`
+	AppID              string        `json:"appId"`
+	DeviceID           string        `json:"deviceId"`
+	Command            CommandBody   `json:"command"`
+	CallbackURL        string        `json:"callbackUrl"`
+	ExpireTime         int64         `json:"expireTime"`
+	Status             CommandStatus `json:"status"`
+	Result             CommandResult `json:"result"`
+	CreationTime       OcTime        `json:"creationTime"`
+	ExecuteTime        OcTime        `json:"executeTime"`
+	PlatformIssuedTime OcTime        `json:"platformIssuedTime"`
+	DeliveredTime      OcTime        `json:"deliveredTime"`
+	IssuedTimes        int           `json:"issuedTimes"`
+	MaxRetransmit      int           `json:"maxRetransmit"`
+}
+
+// CommandFilter selects the commands returned by ListCommands, empty fields
+// match all commands
+type CommandFilter struct {
+	DeviceID  string
+	StartTime time.Time
+	EndTime   time.Time
+	// PageNo and PageSize select a single page, all pages are returned when
+	// PageSize is 0
+	PageNo   int
+	PageSize int
+}
+
+// CancelTask struct with the task canceling the pending commands of a device
+type CancelTask struct {
+	TaskID         string          `json:"taskId"`
+	AppID          string          `json:"appId"`
+	DeviceID       string          `json:"deviceId"`
+	Status         string          `json:"status"`
+	TotalCount     int             `json:"totalCount"`
+	DeviceCommands []DeviceCommand `json:"deviceCommands"`
+}
+
+// SendCommandWithResponse sends the command like SendCommandWithOptions and
+// returns the created command, so its status can be queried
+func (c *Client) SendCommandWithResponse(deviceID string, serviceID string, method string, idata interface{}, opts CommandOptions) (*DeviceCommand, error) {
+	return c.SendCommandWithResponseCtx(context.Background(), deviceID, serviceID, method, idata, opts)
+}
+
+// SendCommandWithResponseCtx is like SendCommandWithResponse but with a context
+func (c *Client) SendCommandWithResponseCtx(ctx context.Context, deviceID string, serviceID string, method string, idata interface{}, opts CommandOptions) (*DeviceCommand, error) {
+	buf, err := c.postCommand(ctx, deviceID, serviceID, method, idata, opts)
+	if err != nil {
+		return nil, err
+	}
+	cmd := &DeviceCommand{}
+	if err := withStrict(c.codec(), c.cfg.Strict).Unmarshal(buf, cmd); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// GetCommand returns the command with the ID
+func (c *Client) GetCommand(commandID string) (*DeviceCommand, error) {
+	return c.GetCommandCtx(context.Background(), commandID)
+}
+
+// GetCommandCtx is like GetCommand but with a context
+func (c *Client) GetCommandCtx(ctx context.Context, commandID string) (*DeviceCommand, error) {
+	resp, err := c.requestCtx(ctx, http.MethodGet, c.appEndpoint("/iocm/app/cmd/v1.4.0/deviceCommands", commandID).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.newAPIError(resp)
+	}
+	cmd := &DeviceCommand{}
+	if err := c.decode(resp, cmd); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+type commandsResponse struct {
+	Pagination struct {
+		PageNo    int   `json:"pageNo"`
+		PageSize  int   `json:"pageSize"`
+		TotalSize int64 `json:"totalSize"`
+	} `json:"pagination"`
+	Data []DeviceCommand `json:"data"`
+}
+
+// ListCommands returns the commands of the application selected by the filter
+func (c *Client) ListCommands(f CommandFilter) ([]DeviceCommand, error) {
+	return c.ListCommandsCtx(context.Background(), f)
+}
+
+// ListCommandsCtx is like ListCommands but with a context
+func (c *Client) ListCommandsCtx(ctx context.Context, f CommandFilter) ([]DeviceCommand, error) {
+	if f.PageSize > 0 {
+		return c.commandsPage(ctx, f)
+	}
+	const pageSize = 100
+	var cmds []DeviceCommand
+	p := c.newPager()
+	pctx, cancel := p.context(ctx)
+	defer cancel()
+	for f.PageSize = pageSize; ; f.PageNo++ {
+		page, err := c.commandsPage(pctx, f)
+		if err != nil {
+			return cmds, p.limitErr(ctx, err)
+		}
+		cmds = append(cmds, page...)
+		if len(page) < pageSize {
+			return cmds, nil
+		}
+		if err := p.next(len(page)); err != nil {
+			return cmds, err
+		}
+	}
+}
+
+func (c *Client) commandsPage(ctx context.Context, f CommandFilter) ([]DeviceCommand, error) {
+	e := c.appEndpoint("/iocm/app/cmd/v1.4.0/deviceCommands").
+		Set("deviceId", f.DeviceID).
+		SetTime("startTime", f.StartTime).
+		SetTime("endTime", f.EndTime).
+		SetInt("pageNo", f.PageNo).
+		SetInt("pageSize", f.PageSize)
+	resp, err := c.requestCtx(ctx, http.MethodGet, e.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.newAPIError(resp)
+	}
+	r := commandsResponse{}
+	if err := c.decode(resp, &r); err != nil {
+		return nil, err
+	}
+	return r.Data, nil
+}
+
+// CancelCommand cancels the pending command with the ID
+func (c *Client) CancelCommand(commandID string) error {
+	return c.CancelCommandCtx(context.Background(), commandID)
+}
+
+// CancelCommandCtx is like CancelCommand but with a context
+func (c *Client) CancelCommandCtx(ctx context.Context, commandID string) error {
+	body, err := c.codec().Marshal(struct {
+		Status CommandStatus `json:"status"`
+	}{CommandCanceled})
+	if err != nil {
+		return err
+	}
+	resp, err := c.requestCtx(ctx, http.MethodPut, c.appEndpoint("/iocm/app/cmd/v1.4.0/deviceCommands", commandID).String(), bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return c.newAPIError(resp)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// CancelAllCommands cancels all pending commands of the device
+func (c *Client) CancelAllCommands(deviceID string) (*CancelTask, error) {
+	return c.CancelAllCommandsCtx(context.Background(), deviceID)
+}
+
+// CancelAllCommandsCtx is like CancelAllCommands but with a context
+func (c *Client) CancelAllCommandsCtx(ctx context.Context, deviceID string) (*CancelTask, error) {
+	body, err := c.codec().Marshal(struct {
+		DeviceID string `json:"deviceId"`
+	}{deviceID})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.requestCtx(ctx, http.MethodPost, c.appEndpoint("/iocm/app/cmd/v1.4.0/deviceCommandCancelTasks").String(), bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return nil, c.newAPIError(resp)
+	}
+	task := &CancelTask{}
+	if err := c.decode(resp, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
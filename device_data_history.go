@@ -0,0 +1,126 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// DeviceDataHistory is a historical data record of a service of a device
+type DeviceDataHistory struct {
+	DeviceID  string          `json:"deviceId"`
+	GatewayID string          `json:"gatewayId"`
+	AppID     string          `json:"appId"`
+	ServiceID string          `json:"serviceId"`
+	Data      json.RawMessage `json:"data"`
+	Timestamp OcTime          `json:"timestamp"`
+	// Value is the data decoded into the type registered in the
+	// ServiceRegistry, nil when there is none
+	Value interface{} `json:"-"`
+}
+
+// DecodeData decodes the data of the record into v
+func (h *DeviceDataHistory) DecodeData(v interface{}) error {
+	return json.Unmarshal(h.Data, v)
+}
+
+// HistoryOptions filters the records returned by GetDeviceDataHistory
+type HistoryOptions struct {
+	GatewayID string
+	// Property only returns the records with the property of the service
+	Property  string
+	StartTime time.Time
+	EndTime   time.Time
+	// PageNo and PageSize select a single page, all pages are returned when
+	// PageSize is 0
+	PageNo   int
+	PageSize int
+}
+
+type deviceDataHistoryResponse struct {
+	TotalCount int                 `json:"totalCount"`
+	PageNo     int                 `json:"pageNo"`
+	PageSize   int                 `json:"pageSize"`
+	Records    []DeviceDataHistory `json:"deviceDataHistoryDTOs"`
+}
+
+// GetDeviceDataHistory returns the historical data of the service of the
+// device, all services when serviceID is empty
+func (c *Client) GetDeviceDataHistory(deviceID, serviceID string, opts ...HistoryOptions) ([]DeviceDataHistory, error) {
+	return c.GetDeviceDataHistoryCtx(context.Background(), deviceID, serviceID, opts...)
+}
+
+// GetDeviceDataHistoryCtx is like GetDeviceDataHistory but with a context
+func (c *Client) GetDeviceDataHistoryCtx(ctx context.Context, deviceID, serviceID string, opts ...HistoryOptions) ([]DeviceDataHistory, error) {
+	var o HistoryOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.PageSize > 0 {
+		return c.deviceDataHistoryPage(ctx, deviceID, serviceID, o)
+	}
+	const pageSize = 100
+	var records []DeviceDataHistory
+	p := c.newPager()
+	pctx, cancel := p.context(ctx)
+	defer cancel()
+	for o.PageSize = pageSize; ; o.PageNo++ {
+		page, err := c.deviceDataHistoryPage(pctx, deviceID, serviceID, o)
+		if err != nil {
+			return records, p.limitErr(ctx, err)
+		}
+		records = append(records, page...)
+		if len(page) < pageSize {
+			return records, nil
+		}
+		if err := p.next(len(page)); err != nil {
+			return records, err
+		}
+	}
+}
+
+func (c *Client) deviceDataHistoryPage(ctx context.Context, deviceID, serviceID string, o HistoryOptions) ([]DeviceDataHistory, error) {
+	gatewayID := o.GatewayID
+	if gatewayID == "" {
+		// directly connected devices are their own gateway
+		gatewayID = deviceID
+	}
+	e := c.appEndpoint("/iocm/app/data/v1.2.0/deviceDataHistory").
+		Set("deviceId", deviceID).
+		Set("gatewayId", gatewayID).
+		Set("serviceId", serviceID).
+		Set("property", o.Property).
+		SetTime("startTime", o.StartTime).
+		SetTime("endTime", o.EndTime).
+		SetInt("pageNo", o.PageNo).
+		SetInt("pageSize", o.PageSize)
+
+	resp, err := c.requestCtx(ctx, http.MethodGet, e.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.newAPIError(resp)
+	}
+	r := deviceDataHistoryResponse{}
+	if err := c.decode(resp, &r); err != nil {
+		return nil, err
+	}
+	for i := range r.Records {
+		rec := &r.Records[i]
+		data, err := c.cfg.Scales.Normalize(rec.ServiceID, rec.Data)
+		if err != nil {
+			return nil, err
+		}
+		rec.Data = data
+		if rec.Value, err = c.cfg.Services.decode(c.codec(), rec.ServiceID, rec.Data); err != nil {
+			return nil, err
+		}
+	}
+	return r.Records, nil
+}
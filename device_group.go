@@ -0,0 +1,337 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+)
+
+// DeviceGroup is a group of devices, groups can be nested by ParentID
+type DeviceGroup struct {
+	ID          string `json:"id,omitempty"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	AppID       string `json:"appId,omitempty"`
+	MaxDevNum   int    `json:"maxDevNum,omitempty"`
+	CurDevNum   int    `json:"curDevNum,omitempty"`
+	Creator     string `json:"creator,omitempty"`
+	ParentID    string `json:"parentDevGroupId,omitempty"`
+}
+
+type deviceGroupsResponse struct {
+	TotalCount int           `json:"totalCount"`
+	PageNo     int           `json:"pageNo"`
+	PageSize   int           `json:"pageSize"`
+	List       []DeviceGroup `json:"list"`
+}
+
+type groupDevicesResponse struct {
+	TotalCount int      `json:"totalCount"`
+	PageNo     int      `json:"pageNo"`
+	PageSize   int      `json:"pageSize"`
+	DeviceIDs  []string `json:"deviceIds"`
+}
+
+// CreateDeviceGroup creates a device group, set ParentID to nest it
+func (c *Client) CreateDeviceGroup(g DeviceGroup) (*DeviceGroup, error) {
+	return c.CreateDeviceGroupCtx(context.Background(), g)
+}
+
+// CreateDeviceGroupCtx is like CreateDeviceGroup but with a context
+func (c *Client) CreateDeviceGroupCtx(ctx context.Context, g DeviceGroup) (*DeviceGroup, error) {
+	body, err := c.codec().Marshal(g)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.requestCtx(ctx, http.MethodPost, newEndpoint("/iocm/app/devgroup/v1.3.0/devGroups").Set("accessAppId", c.cfg.AppID).String(), bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, c.newAPIError(resp)
+	}
+	created := &DeviceGroup{}
+	if err := c.decode(resp, created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// GetDeviceGroup returns a device group
+func (c *Client) GetDeviceGroup(groupID string) (*DeviceGroup, error) {
+	return c.GetDeviceGroupCtx(context.Background(), groupID)
+}
+
+// GetDeviceGroupCtx is like GetDeviceGroup but with a context
+func (c *Client) GetDeviceGroupCtx(ctx context.Context, groupID string) (*DeviceGroup, error) {
+	resp, err := c.requestCtx(ctx, http.MethodGet, newEndpoint("/iocm/app/devgroup/v1.3.0/devGroups", groupID).Set("accessAppId", c.cfg.AppID).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.newAPIError(resp)
+	}
+	g := &DeviceGroup{}
+	if err := c.decode(resp, g); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// ListDeviceGroups returns all device groups of the application
+func (c *Client) ListDeviceGroups() ([]DeviceGroup, error) {
+	return c.ListDeviceGroupsCtx(context.Background())
+}
+
+// ListDeviceGroupsCtx is like ListDeviceGroups but with a context
+func (c *Client) ListDeviceGroupsCtx(ctx context.Context) ([]DeviceGroup, error) {
+	const pageSize = 100
+	var groups []DeviceGroup
+	p := c.newPager()
+	pctx, cancel := p.context(ctx)
+	defer cancel()
+	for page := 0; ; page++ {
+		e := newEndpoint("/iocm/app/devgroup/v1.3.0/devGroups").
+			Set("accessAppId", c.cfg.AppID).
+			SetInt("pageNo", page).
+			SetInt("pageSize", pageSize)
+		resp, err := c.requestCtx(pctx, http.MethodGet, e.String(), nil)
+		if err != nil {
+			return nil, p.limitErr(ctx, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, c.newAPIError(resp)
+		}
+		r := deviceGroupsResponse{}
+		if err := c.decode(resp, &r); err != nil {
+			return nil, p.limitErr(ctx, err)
+		}
+		groups = append(groups, r.List...)
+		if len(r.List) < pageSize {
+			return groups, nil
+		}
+		if err := p.next(len(r.List)); err != nil {
+			return groups, err
+		}
+	}
+}
+
+// DeleteDeviceGroup deletes a device group, the devices are not deleted
+func (c *Client) DeleteDeviceGroup(groupID string) error {
+	return c.DeleteDeviceGroupCtx(context.Background(), groupID)
+}
+
+// DeleteDeviceGroupCtx is like DeleteDeviceGroup but with a context
+func (c *Client) DeleteDeviceGroupCtx(ctx context.Context, groupID string) error {
+	resp, err := c.requestCtx(ctx, http.MethodDelete, newEndpoint("/iocm/app/devgroup/v1.3.0/devGroups", groupID).Set("accessAppId", c.cfg.AppID).String(), nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return c.newAPIError(resp)
+	}
+	return nil
+}
+
+// AddGroupDevices adds devices to a device group
+func (c *Client) AddGroupDevices(groupID string, deviceIDs []string) error {
+	return c.AddGroupDevicesCtx(context.Background(), groupID, deviceIDs)
+}
+
+// AddGroupDevicesCtx is like AddGroupDevices but with a context
+func (c *Client) AddGroupDevicesCtx(ctx context.Context, groupID string, deviceIDs []string) error {
+	return c.groupDevices(ctx, groupID, "addDevices", deviceIDs)
+}
+
+// RemoveGroupDevices removes devices from a device group
+func (c *Client) RemoveGroupDevices(groupID string, deviceIDs []string) error {
+	return c.RemoveGroupDevicesCtx(context.Background(), groupID, deviceIDs)
+}
+
+// RemoveGroupDevicesCtx is like RemoveGroupDevices but with a context
+func (c *Client) RemoveGroupDevicesCtx(ctx context.Context, groupID string, deviceIDs []string) error {
+	return c.groupDevices(ctx, groupID, "deleteDevices", deviceIDs)
+}
+
+func (c *Client) groupDevices(ctx context.Context, groupID, action string, deviceIDs []string) error {
+	body, err := c.codec().Marshal(struct {
+		DeviceIDs []string `json:"deviceIds"`
+	}{deviceIDs})
+	if err != nil {
+		return err
+	}
+	resp, err := c.requestCtx(ctx, http.MethodPost, newEndpoint("/iocm/app/dm/v1.2.0/devgroups", groupID, action).Set("accessAppId", c.cfg.AppID).String(), bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return c.newAPIError(resp)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// GroupDeviceIDs returns the IDs of the devices directly in a device group
+func (c *Client) GroupDeviceIDs(groupID string) ([]string, error) {
+	return c.GroupDeviceIDsCtx(context.Background(), groupID)
+}
+
+// GroupDeviceIDsCtx is like GroupDeviceIDs but with a context
+func (c *Client) GroupDeviceIDsCtx(ctx context.Context, groupID string) ([]string, error) {
+	const pageSize = 100
+	var ids []string
+	p := c.newPager()
+	pctx, cancel := p.context(ctx)
+	defer cancel()
+	for page := 0; ; page++ {
+		e := newEndpoint("/iocm/app/dm/v1.2.0/devgroups", groupID, "devices").
+			Set("accessAppId", c.cfg.AppID).
+			SetInt("pageNo", page).
+			SetInt("pageSize", pageSize)
+		resp, err := c.requestCtx(pctx, http.MethodGet, e.String(), nil)
+		if err != nil {
+			return nil, p.limitErr(ctx, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, c.newAPIError(resp)
+		}
+		r := groupDevicesResponse{}
+		if err := c.decode(resp, &r); err != nil {
+			return nil, p.limitErr(ctx, err)
+		}
+		ids = append(ids, r.DeviceIDs...)
+		if len(r.DeviceIDs) < pageSize {
+			return ids, nil
+		}
+		if err := p.next(len(r.DeviceIDs)); err != nil {
+			return ids, err
+		}
+	}
+}
+
+// GroupTree is the hierarchy of device groups
+type GroupTree struct {
+	groups   map[string]DeviceGroup
+	children map[string][]string
+}
+
+// NewGroupTree builds the hierarchy from a list of groups, groups with an
+// unknown parent are roots
+func NewGroupTree(groups []DeviceGroup) *GroupTree {
+	t := &GroupTree{
+		groups:   make(map[string]DeviceGroup, len(groups)),
+		children: make(map[string][]string),
+	}
+	for _, g := range groups {
+		t.groups[g.ID] = g
+	}
+	for _, g := range groups {
+		parent := g.ParentID
+		if _, ok := t.groups[parent]; !ok {
+			parent = ""
+		}
+		t.children[parent] = append(t.children[parent], g.ID)
+	}
+	return t
+}
+
+// Group returns the group with the ID
+func (t *GroupTree) Group(id string) (DeviceGroup, bool) {
+	g, ok := t.groups[id]
+	return g, ok
+}
+
+// Roots returns the groups without parent
+func (t *GroupTree) Roots() []DeviceGroup {
+	return t.list(t.children[""])
+}
+
+// Children returns the direct child groups of the group
+func (t *GroupTree) Children(id string) []DeviceGroup {
+	return t.list(t.children[id])
+}
+
+// Subtree returns the group and all its descendants, parents before children
+func (t *GroupTree) Subtree(id string) []DeviceGroup {
+	g, ok := t.groups[id]
+	if !ok {
+		return nil
+	}
+	out := []DeviceGroup{g}
+	seen := map[string]bool{id: true}
+	for i := 0; i < len(out); i++ {
+		for _, child := range t.children[out[i].ID] {
+			// guard against parent cycles in the platform data
+			if !seen[child] {
+				seen[child] = true
+				out = append(out, t.groups[child])
+			}
+		}
+	}
+	return out
+}
+
+func (t *GroupTree) list(ids []string) []DeviceGroup {
+	out := make([]DeviceGroup, len(ids))
+	for i, id := range ids {
+		out[i] = t.groups[id]
+	}
+	return out
+}
+
+// GroupTree returns the hierarchy of the device groups of the application
+func (c *Client) GroupTree() (*GroupTree, error) {
+	return c.GroupTreeCtx(context.Background())
+}
+
+// GroupTreeCtx is like GroupTree but with a context
+func (c *Client) GroupTreeCtx(ctx context.Context) (*GroupTree, error) {
+	groups, err := c.ListDeviceGroupsCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewGroupTree(groups), nil
+}
+
+// SubtreeDeviceIDs returns the IDs of the devices in the group and all its
+// descendant groups, devices in several groups are returned once
+func (c *Client) SubtreeDeviceIDs(groupID string) ([]string, error) {
+	return c.SubtreeDeviceIDsCtx(context.Background(), groupID)
+}
+
+// SubtreeDeviceIDsCtx is like SubtreeDeviceIDs but with a context
+func (c *Client) SubtreeDeviceIDsCtx(ctx context.Context, groupID string) ([]string, error) {
+	t, err := c.GroupTreeCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	seen := make(map[string]bool)
+	for _, g := range t.Subtree(groupID) {
+		groupIDs, err := c.GroupDeviceIDsCtx(ctx, g.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range groupIDs {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// ApplyToSubtree runs fn for every device in the group and its descendant
+// groups with a BulkExecutor
+func (c *Client) ApplyToSubtree(ctx context.Context, groupID string, fn func(ctx context.Context, deviceID string) error, opts ...BulkOptions) (*BulkReport, error) {
+	ids, err := c.SubtreeDeviceIDsCtx(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	return c.NewBulkExecutor(opts...).Run(ctx, ids, fn)
+}
@@ -0,0 +1,100 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupTree(t *testing.T) {
+	tree := NewGroupTree([]DeviceGroup{
+		{ID: "eu", Name: "Europe"},
+		{ID: "nl", Name: "Netherlands", ParentID: "eu"},
+		{ID: "ams", Name: "Amsterdam", ParentID: "nl"},
+		{ID: "de", Name: "Germany", ParentID: "eu"},
+		{ID: "orphan", Name: "Orphan", ParentID: "gone"},
+	})
+
+	assert.Equal(t, []DeviceGroup{{ID: "eu", Name: "Europe"}, {ID: "orphan", Name: "Orphan", ParentID: "gone"}}, tree.Roots())
+	assert.Equal(t, 2, len(tree.Children("eu")))
+
+	var ids []string
+	for _, g := range tree.Subtree("eu") {
+		ids = append(ids, g.ID)
+	}
+	assert.Equal(t, []string{"eu", "nl", "de", "ams"}, ids)
+	assert.Nil(t, tree.Subtree("unknown"))
+}
+
+func TestGroupRouter(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/iocm/app/sec/v1.1.0/login":
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+		case "/iocm/app/devgroup/v1.3.0/devGroups":
+			fmt.Fprintln(w, `{"totalCount":3,"list":[{"id":"eu","name":"Europe"},{"id":"nl","name":"Netherlands","parentDevGroupId":"eu"},{"id":"us","name":"US"}]}`)
+		case "/iocm/app/dm/v1.2.0/devgroups/nl/devices":
+			fmt.Fprintln(w, `{"totalCount":2,"deviceIds":["dev1","dev2"]}`)
+		case "/iocm/app/dm/v1.2.0/devgroups/us/devices":
+			fmt.Fprintln(w, `{"totalCount":2,"deviceIds":["dev2","dev3"]}`)
+		default:
+			fmt.Fprintln(w, `{"totalCount":0,"deviceIds":[]}`)
+		}
+	}))
+	defer s.Close()
+
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL}}
+	gc := NewGroupCache(c)
+	assert.Nil(t, gc.Refresh())
+	assert.Equal(t, []string{"nl", "us"}, gc.Groups("dev2"))
+	assert.True(t, gc.InGroup("dev1", "eu"))
+	assert.False(t, gc.InGroup("dev3", "eu"))
+
+	var eu, us, other []string
+	r := NewGroupRouter(gc)
+	r.Route("eu").OnDeviceDataChanged(func(n *DeviceDataChanged) error {
+		eu = append(eu, n.DeviceID)
+		return nil
+	})
+	r.Route("us").OnDeviceDataChanged(func(n *DeviceDataChanged) error {
+		us = append(us, n.DeviceID)
+		return errors.New("us pipeline failed")
+	})
+	r.Default = &Dispatcher{}
+	r.Default.OnDeviceDataChanged(func(n *DeviceDataChanged) error {
+		other = append(other, n.DeviceID)
+		return nil
+	})
+
+	d := &Dispatcher{}
+	r.Attach(d, NotificationDeviceDataChanged)
+	for _, id := range []string{"dev1", "dev2", "dev3", "dev4"} {
+		err := d.Dispatch(NotificationDeviceDataChanged, &DeviceDataChanged{DeviceID: id})
+		assert.Equal(t, id == "dev2" || id == "dev3", err != nil, id)
+	}
+	assert.Equal(t, []string{"dev1", "dev2"}, eu)
+	assert.Equal(t, []string{"dev2", "dev3"}, us)
+	assert.Equal(t, []string{"dev4"}, other)
+
+	// each group scales the data of a device in several groups once
+	scales := NewScaleRegistry()
+	scales.Register("Meter", "value", Scale{Factor: 10})
+	var data []string
+	for _, g := range []string{"eu", "us"} {
+		r.Route(g).Scales = scales
+		r.Route(g).AddHandler(NotificationDeviceDataChanged, func(v interface{}) error {
+			data = append(data, string(v.(*DeviceDataChanged).Service.Data))
+			return nil
+		})
+	}
+	r.Dispatch(NotificationDeviceDataChanged, &DeviceDataChanged{DeviceID: "dev2", Service: Service{ServiceID: "Meter", Data: []byte(`{"value":2}`)}})
+	assert.Equal(t, []string{`{"value":20}`, `{"value":20}`}, data)
+}
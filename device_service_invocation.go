@@ -3,3 +3,101 @@
 // license that can be found in the LICENSE file.
 
 package oceanconnect
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+)
+
+// messageEndpoint returns the endpoint of the action on an upstream message
+// of a device service
+func (c *Client) messageEndpoint(deviceID, serviceID, requestID, action string) *endpoint {
+	return c.appEndpoint("/iocm/app/signaltrans/v1.1.0/devices", deviceID, "services", serviceID, "messages", requestID, action)
+}
+
+// ConfirmDeviceMessage acknowledges an upstream message of a device, for
+// services which require the application to confirm reported messages. The
+// request ID is the RequestID of the notification.
+func (c *Client) ConfirmDeviceMessage(deviceID, serviceID, requestID string) error {
+	return c.ConfirmDeviceMessageCtx(context.Background(), deviceID, serviceID, requestID)
+}
+
+// ConfirmDeviceMessageCtx is like ConfirmDeviceMessage but with a context
+func (c *Client) ConfirmDeviceMessageCtx(ctx context.Context, deviceID, serviceID, requestID string) error {
+	resp, err := c.requestCtx(ctx, http.MethodPost, c.messageEndpoint(deviceID, serviceID, requestID, "confirm").String(), nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return c.newAPIError(resp)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// RespondToDeviceMessage sends a response with data to an upstream message
+// of a device, which confirms the message as well
+func (c *Client) RespondToDeviceMessage(deviceID, serviceID, requestID string, data interface{}) error {
+	return c.RespondToDeviceMessageCtx(context.Background(), deviceID, serviceID, requestID, data)
+}
+
+// RespondToDeviceMessageCtx is like RespondToDeviceMessage but with a context
+func (c *Client) RespondToDeviceMessageCtx(ctx context.Context, deviceID, serviceID, requestID string, data interface{}) error {
+	body, err := c.codec().Marshal(struct {
+		Body interface{} `json:"body"`
+	}{data})
+	if err != nil {
+		return err
+	}
+	resp, err := c.requestCtx(ctx, http.MethodPost, c.messageEndpoint(deviceID, serviceID, requestID, "response").String(), bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return c.newAPIError(resp)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// SendDeviceMessage sends a message to a service of a pass-through device
+// with the message delivery API, the gateway confirms it with a
+// messageConfirm notification. It returns the request ID of the message, pass
+// it to Dispatcher.TrackDelivery to follow the delivery.
+func (c *Client) SendDeviceMessage(deviceID, serviceID, method string, data interface{}) (string, error) {
+	return c.SendDeviceMessageCtx(context.Background(), deviceID, serviceID, method, data)
+}
+
+// SendDeviceMessageCtx is like SendDeviceMessage but with a context
+func (c *Client) SendDeviceMessageCtx(ctx context.Context, deviceID, serviceID, method string, data interface{}) (string, error) {
+	b := struct {
+		Header struct {
+			Mode   string `json:"mode"`
+			Method string `json:"method"`
+		} `json:"header"`
+		Body interface{} `json:"body"`
+	}{Body: data}
+	// ACK makes the gateway confirm the message
+	b.Header.Mode = "ACK"
+	b.Header.Method = method
+	body, err := c.codec().Marshal(b)
+	if err != nil {
+		return "", err
+	}
+	e := c.appEndpoint("/iocm/app/signaltrans/v1.1.0/devices", deviceID, "services", serviceID, "sendCommand")
+	resp, err := c.requestCtx(ctx, http.MethodPost, e.String(), bytes.NewBuffer(body))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return "", c.newAPIError(resp)
+	}
+	r := struct {
+		RequestID string `json:"requestId"`
+	}{}
+	if err := c.decode(resp, &r); err != nil {
+		return "", err
+	}
+	return r.RequestID, nil
+}
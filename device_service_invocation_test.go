@@ -0,0 +1,50 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendDeviceMessage(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/iocm/app/signaltrans/v1.1.0/devices/dev1/services/Valve/sendCommand", r.URL.Path)
+		var b map[string]interface{}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&b))
+		assert.Equal(t, map[string]interface{}{
+			"header": map[string]interface{}{"mode": "ACK", "method": "OPEN"},
+			"body":   map[string]interface{}{"position": 50.0},
+		}, b)
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintln(w, `{"status":"SENT","timestamp":"20170912T101530Z","requestId":"req1"}`)
+	}))
+	defer s.Close()
+
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL}}
+	assert.Nil(t, c.Login())
+	requestID, err := c.SendDeviceMessage("dev1", "Valve", "OPEN", map[string]interface{}{"position": 50})
+	assert.Nil(t, err)
+	assert.Equal(t, "req1", requestID)
+
+	// the returned request ID is the one the confirmation refers to
+	d := Dispatcher{}
+	ch := d.TrackDelivery(requestID)
+	assert.Nil(t, d.Dispatch(NotificationMessageConfirm, &MessageConfirm{Header: NotificationHeader{RequestID: "req1"}, Body: []byte(`{"status":"DELIVERED"}`)}))
+	assert.Equal(t, DeliveryPending, <-ch)
+	assert.Equal(t, DeliveryDelivered, <-ch)
+	_, ok := <-ch
+	assert.False(t, ok, "expected channel to be closed")
+}
@@ -0,0 +1,62 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// ErrServiceNotFound is returned when decoding a service the device didn't
+// report
+var ErrServiceNotFound = errors.New("service not found")
+
+// DeviceServices holds the most recent data reported for each service of a
+// device
+type DeviceServices []Service
+
+// Service returns the data of the service, nil when it wasn't reported
+func (s DeviceServices) Service(serviceID string) *Service {
+	for i := range s {
+		if s[i].ServiceID == serviceID {
+			return &s[i]
+		}
+	}
+	return nil
+}
+
+// DecodeInto decodes the data of the service into v, see Service.DecodeInto
+func (s DeviceServices) DecodeInto(serviceID string, v interface{}) error {
+	svc := s.Service(serviceID)
+	if svc == nil {
+		return ErrServiceNotFound
+	}
+	return svc.DecodeInto(v)
+}
+
+// DecodeInto decodes the data of the service into v, which must be a pointer
+// like for json.Unmarshal. v is left untouched when there is no data.
+func (u Service) DecodeInto(v interface{}) error {
+	if len(u.Data) == 0 || string(u.Data) == "null" {
+		return nil
+	}
+	return json.Unmarshal(u.Data, v)
+}
+
+// GetDeviceServices returns the most recent data reported for each service of
+// the device, with its event time
+func (c *Client) GetDeviceServices(deviceID string) (DeviceServices, error) {
+	return c.GetDeviceServicesCtx(context.Background(), deviceID)
+}
+
+// GetDeviceServicesCtx is like GetDeviceServices but with a context
+func (c *Client) GetDeviceServicesCtx(ctx context.Context, deviceID string) (DeviceServices, error) {
+	d, err := c.GetDeviceCtx(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	return DeviceServices(d.Services), nil
+}
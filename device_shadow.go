@@ -0,0 +1,143 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// ServiceDesired holds the desired properties of one service in the device shadow
+type ServiceDesired struct {
+	ServiceID string                 `json:"serviceId"`
+	Desired   map[string]interface{} `json:"desired"`
+}
+
+// ShadowState struct with the desired or reported properties of a service
+type ShadowState struct {
+	Data      json.RawMessage `json:"data"`
+	EventTime OcTime          `json:"eventTime"`
+}
+
+// Decode decodes the properties into v
+func (s ShadowState) Decode(v interface{}) error {
+	if len(s.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(s.Data, v)
+}
+
+// ServiceShadow struct with the shadow of one service of a device
+type ServiceShadow struct {
+	ServiceID   string      `json:"serviceId"`
+	ServiceType string      `json:"serviceType"`
+	Desired     ShadowState `json:"desired"`
+	Reported    ShadowState `json:"reported"`
+}
+
+// Pending returns the desired properties which differ from the reported
+// properties, these are delivered when the device comes online
+func (s ServiceShadow) Pending() (map[string]interface{}, error) {
+	var desired, reported map[string]interface{}
+	if err := s.Desired.Decode(&desired); err != nil {
+		return nil, err
+	}
+	if err := s.Reported.Decode(&reported); err != nil {
+		return nil, err
+	}
+	pending := make(map[string]interface{})
+	for k, v := range desired {
+		if !jsonEqual(v, reported[k]) {
+			pending[k] = v
+		}
+	}
+	return pending, nil
+}
+
+// jsonEqual reports whether the decoded JSON values are equal
+func jsonEqual(a, b interface{}) bool {
+	x, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	y, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(x, y)
+}
+
+// DeviceShadow struct with the desired and reported state of a device
+type DeviceShadow struct {
+	DeviceID         string          `json:"deviceId"`
+	GatewayID        string          `json:"gatewayId"`
+	NodeType         NodeType        `json:"nodeType"`
+	CreateTime       OcTime          `json:"createTime"`
+	LastModifiedTime OcTime          `json:"lastModifiedTime"`
+	Services         []ServiceShadow `json:"services"`
+}
+
+// Service returns the shadow of the service, nil when the device doesn't have
+// the service
+func (d *DeviceShadow) Service(serviceID string) *ServiceShadow {
+	for i := range d.Services {
+		if d.Services[i].ServiceID == serviceID {
+			return &d.Services[i]
+		}
+	}
+	return nil
+}
+
+// GetDeviceShadow returns the desired and reported state of the services of
+// a device
+func (c *Client) GetDeviceShadow(deviceID string) (*DeviceShadow, error) {
+	return c.GetDeviceShadowCtx(context.Background(), deviceID)
+}
+
+// GetDeviceShadowCtx is like GetDeviceShadow but with a context
+func (c *Client) GetDeviceShadowCtx(ctx context.Context, deviceID string) (*DeviceShadow, error) {
+	resp, err := c.requestCtx(ctx, http.MethodGet, newEndpoint("/iocm/app/shadow/v1.5.0/devices", deviceID).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.newAPIError(resp)
+	}
+	s := &DeviceShadow{}
+	if err := c.decode(resp, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// UpdateDeviceShadow sets the desired properties for a device, they are
+// delivered by the platform when the device comes online
+func (c *Client) UpdateDeviceShadow(deviceID string, desired []ServiceDesired) error {
+	return c.UpdateDeviceShadowCtx(context.Background(), deviceID, desired)
+}
+
+// UpdateDeviceShadowCtx is like UpdateDeviceShadow but with a context
+func (c *Client) UpdateDeviceShadowCtx(ctx context.Context, deviceID string, desired []ServiceDesired) error {
+	b := struct {
+		ServiceDesireds []ServiceDesired `json:"serviceDesireds"`
+	}{
+		ServiceDesireds: desired,
+	}
+	body, err := c.codec().Marshal(b)
+	if err != nil {
+		return err
+	}
+	resp, err := c.requestCtx(ctx, http.MethodPut, newEndpoint("/iocm/app/shadow/v1.5.0/devices", deviceID).String(), bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return c.newAPIError(resp)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceMethods(t *testing.T) {
+	name := "meter"
+	var requests []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprintf(w, `{"deviceId":"dev1","deviceInfo":{"name":%q,"status":"ONLINE"},"services":[
+				{"serviceId":"Meter","data":{"volume":42},"eventTime":"20170912T101530Z"},
+				{"serviceId":"Battery","data":{"level":80},"eventTime":"20170912T111530Z"}]}`, name)
+		case http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintln(w, `{"commandId":"cmd1","deviceId":"dev1","status":"PENDING"}`)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer s.Close()
+
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL}}
+	d, err := c.GetDevice("dev1")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, d.Online())
+	assert.Equal(t, DeviceStatusOnline, d.Status())
+	assert.Equal(t, time.Date(2017, 9, 12, 11, 15, 30, 0, time.UTC), d.LastSeen())
+	var m meterData
+	assert.Nil(t, d.DecodeService("Meter", &m))
+	assert.Equal(t, 42.0, m.Volume)
+	assert.Nil(t, d.Service("Valve"))
+
+	cmd, err := d.SendCommand("Valve", "OPEN", nil, CommandOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "cmd1", cmd.CommandID)
+
+	assert.Nil(t, d.SetName("valve"))
+	assert.Equal(t, "valve", d.DeviceInfo.Name)
+	name = "renamed"
+	assert.Nil(t, d.Refresh())
+	assert.Equal(t, "renamed", d.DeviceInfo.Name)
+	assert.Nil(t, d.Delete())
+
+	assert.Equal(t, []string{
+		"GET /iocm/app/dm/v1.1.0/devices/dev1",
+		"POST /iocm/app/cmd/v1.4.0/deviceCommands",
+		"PUT /iocm/app/dm/v1.2.0/devices/dev1",
+		"GET /iocm/app/dm/v1.1.0/devices/dev1",
+		"DELETE /iocm/app/dm/v1.1.0/devices/dev1",
+	}, requests)
+
+	assert.Equal(t, ErrDetachedDevice, (&Device{DeviceID: "dev1"}).Delete())
+}
+
+func TestRefreshDeviceConnectionInfo(t *testing.T) {
+	port := 5683
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		switch r.URL.Path {
+		case "/iocm/app/dm/v1.1.0/devices/dev1":
+			fmt.Fprintf(w, `{"deviceId":"dev1","deviceInfo":{"status":"OFFLINE"},"connectionInfo":{"protocolType":"CoAP","ip":"10.1.2.3","port":%d,"lastNatBindingTime":"20170912T101530Z","imsi":"204080000000001"}}`, port)
+		case "/iocm/app/dm/v1.1.0/devices/dev2":
+			fmt.Fprintln(w, `{"deviceId":"dev2","deviceInfo":{"status":"ONLINE"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	clock := &fakeClock{now: time.Date(2017, 9, 12, 10, 0, 0, 0, time.UTC)}
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL, Clock: clock, CoalesceWindow: time.Minute}}
+	ci, err := c.RefreshDeviceConnectionInfo("dev1")
+	if assert.Nil(t, err) {
+		assert.Equal(t, ProtocolCoAP, ci.ProtocolType)
+		assert.Equal(t, "10.1.2.3", ci.IP)
+		assert.Equal(t, 5683, ci.Port)
+		assert.Equal(t, time.Date(2017, 9, 12, 10, 15, 30, 0, time.UTC), ci.LastNATBinding.UTC())
+		assert.Contains(t, string(ci.Raw), `"imsi":"204080000000001"`)
+	}
+
+	// a refresh isn't served from the coalesced results
+	_, err = c.GetDevice("dev1")
+	assert.Nil(t, err)
+	port = 5684
+	ci, err = c.RefreshDeviceConnectionInfo("dev1")
+	if assert.Nil(t, err) {
+		assert.Equal(t, 5684, ci.Port)
+	}
+
+	// devices without connection details return the zero value
+	ci, err = c.RefreshDeviceConnectionInfo("dev2")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "", ci.IP)
+		assert.True(t, ci.LastNATBinding.IsZero())
+	}
+
+	_, err = c.RefreshDeviceConnectionInfo("dev3")
+	assert.True(t, IsNotFound(err))
+}
@@ -0,0 +1,209 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DevicesIterator walks all devices selected by a GetDevicesStruct page by
+// page. Pages which failed with a transient error are retried, unless the
+// RetryPolicy of the call retries the requests already. Devices which can't
+// be decoded are logged and skipped.
+//
+//	it := c.IterateDevices(GetDevicesStruct{Status: DeviceStatusOnline})
+//	for it.Next(ctx) {
+//		d := it.Device()
+//	}
+//	if err := it.Err(); err != nil {
+type DevicesIterator struct {
+	// Retries is the number of retries of a failed page (default 2)
+	Retries int
+	// RetryDelay is the time between the retries of a page (default 1s)
+	RetryDelay time.Duration
+
+	client  *Client
+	filter  GetDevicesStruct
+	pager   *pager
+	page    []Device
+	idx     int
+	total   int
+	fetched int
+	last    bool
+	err     error
+}
+
+// IterateDevices returns an iterator over the devices selected by the
+// filter, starting at its PageNo. PageSize defaults to 100.
+func (c *Client) IterateDevices(f GetDevicesStruct) *DevicesIterator {
+	if f.PageSize <= 0 {
+		f.PageSize = 100
+	}
+	return &DevicesIterator{
+		Retries:    2,
+		RetryDelay: time.Second,
+		client:     c,
+		filter:     f,
+		pager:      c.newPager(),
+		idx:        -1,
+	}
+}
+
+// Next advances to the next device, retrieving the next page when needed. It
+// returns false when all devices are visited, the context is done or a page
+// failed, see Err.
+func (it *DevicesIterator) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	if err := ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+	it.idx++
+	for it.idx >= len(it.page) {
+		if it.last {
+			return false
+		}
+		if err := it.fetch(ctx); err != nil {
+			it.err = err
+			return false
+		}
+		it.idx = 0
+	}
+	return true
+}
+
+// Device returns the current device
+func (it *DevicesIterator) Device() Device {
+	return it.page[it.idx]
+}
+
+// Total returns the total number of devices reported by the platform, known
+// after the first call to Next
+func (it *DevicesIterator) Total() int {
+	return it.total
+}
+
+// Err returns the error which stopped the iteration, nil when all devices
+// were visited
+func (it *DevicesIterator) Err() error {
+	return it.err
+}
+
+// fetch retrieves the next page
+func (it *DevicesIterator) fetch(ctx context.Context) error {
+	if it.fetched > 0 {
+		if err := it.pager.next(it.fetched); err != nil {
+			return err
+		}
+	}
+	pctx, cancel := it.pager.context(ctx)
+	defer cancel()
+	var devs []Device
+	var total int
+	var err error
+	retries := it.Retries
+	if it.client.retryPolicy(ctx).MaxAttempts > 1 {
+		retries = 0
+	}
+	for attempt := 0; ; attempt++ {
+		devs, total, err = it.client.devicesPage(pctx, it.filter)
+		if _, ok := err.(DecodeErrors); ok || err == nil || attempt >= retries || !retryable(pctx, err) {
+			break
+		}
+		logrus.Warnf("retrieving devices page %d failed, retrying: %v", it.filter.PageNo, err)
+		select {
+		case <-pctx.Done():
+			return it.pager.limitErr(ctx, pctx.Err())
+		case <-it.client.clock().After(it.RetryDelay):
+		}
+	}
+	decErrs, ok := err.(DecodeErrors)
+	if !ok && err != nil {
+		return it.pager.limitErr(ctx, err)
+	}
+	if ok {
+		logrus.Warnf("skipping devices: %v", decErrs)
+	}
+
+	n := len(devs) + len(decErrs)
+	it.page = devs
+	it.total = total
+	it.fetched = n
+	it.filter.PageNo++
+	// the total stops the walk without requesting an empty last page, the
+	// short page stops it when the platform doesn't report the total
+	consumed := it.filter.PageNo * it.filter.PageSize
+	it.last = n < it.filter.PageSize || (total > 0 && consumed >= total)
+	return nil
+}
+
+// retryable reports whether a failed request may succeed when retried: the
+// platform failed or throttled it, or the network failed
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500 || IsRateLimited(apiErr)
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// ForEachDevice calls fn for all devices selected by the filter, until fn
+// returns an error or the context is done
+func (c *Client) ForEachDevice(ctx context.Context, f GetDevicesStruct, fn func(Device) error) error {
+	it := c.IterateDevices(f)
+	for it.Next(ctx) {
+		if err := fn(it.Device()); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// GetAllDevices returns all devices selected by the filter, with the devices
+// retrieved so far when a page failed
+func (c *Client) GetAllDevices(ctx context.Context, f GetDevicesStruct) ([]Device, error) {
+	var devs []Device
+	err := c.ForEachDevice(ctx, f, func(d Device) error {
+		devs = append(devs, d)
+		return nil
+	})
+	return devs, err
+}
+
+// FindDevicesLastSeen returns the devices selected by the filter whose last
+// activity is in the range, see LastSeenBetween. The platform can't filter on
+// activity, but devices registered after the range can't have been seen in
+// it, so only the registration time is filtered by the platform.
+func (c *Client) FindDevicesLastSeen(ctx context.Context, f GetDevicesStruct, from, to time.Time) ([]Device, error) {
+	if !to.IsZero() && (f.To.IsZero() || f.To.After(to)) {
+		f.To = to
+	}
+	var devs []Device
+	err := c.ForEachDevice(ctx, f, func(d Device) error {
+		if lastSeenBetween(&d, from, to) {
+			devs = append(devs, d)
+		}
+		return nil
+	})
+	return devs, err
+}
+
+// FindInactiveDevices returns the devices selected by the filter which were
+// not seen for at least min but were seen within max, see InactiveFor
+func (c *Client) FindInactiveDevices(ctx context.Context, f GetDevicesStruct, min, max time.Duration) ([]Device, error) {
+	from, to := inactiveRange(c.clock().Now(), min, max)
+	return c.FindDevicesLastSeen(ctx, f, from, to)
+}
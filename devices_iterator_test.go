@@ -0,0 +1,133 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDevicesIterator(t *testing.T) {
+	requests := map[int]int{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("pageNo"))
+		requests[page]++
+		switch page {
+		case 0:
+			fmt.Fprintln(w, `{"totalCount":5,"devices":[{"deviceId":"dev0"},{"deviceId":"dev1"}]}`)
+		case 1:
+			if requests[page] == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprintln(w, `{"totalCount":5,"devices":[{"deviceId":"dev2"},{"deviceId":"dev3","creationTime":"garbage"}]}`)
+		case 2:
+			fmt.Fprintln(w, `{"totalCount":5,"devices":[{"deviceId":"dev4"}]}`)
+		default:
+			t.Errorf("unexpected page %d", page)
+		}
+	}))
+	defer s.Close()
+
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL, Clock: &fakeClock{}}}
+
+	devs, err := c.GetAllDevices(context.Background(), GetDevicesStruct{PageSize: 2})
+	assert.Nil(t, err)
+	var ids []string
+	for _, d := range devs {
+		ids = append(ids, d.DeviceID)
+	}
+	assert.Equal(t, []string{"dev0", "dev1", "dev2", "dev4"}, ids)
+	assert.Equal(t, map[int]int{0: 1, 1: 2, 2: 1}, requests)
+
+	stop := errors.New("stop")
+	n := 0
+	err = c.ForEachDevice(context.Background(), GetDevicesStruct{PageSize: 2}, func(d Device) error {
+		n++
+		if d.DeviceID == "dev1" {
+			return stop
+		}
+		return nil
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 2, n)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	it := c.IterateDevices(GetDevicesStruct{PageSize: 2})
+	assert.True(t, it.Next(ctx))
+	assert.Equal(t, 5, it.Total())
+	cancel()
+	assert.False(t, it.Next(ctx))
+	assert.Equal(t, context.Canceled, it.Err())
+}
+
+func TestFindInactiveDevices(t *testing.T) {
+	var endTime string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		endTime = r.URL.Query().Get("endTime")
+		fmt.Fprintln(w, `{"totalCount":3,"devices":[
+			{"deviceId":"dev1","services":[{"serviceId":"Meter","eventTime":"20170912T090000Z"}]},
+			{"deviceId":"dev2","services":[{"serviceId":"Meter","eventTime":"20170905T090000Z"}]},
+			{"deviceId":"dev3"}]}`)
+	}))
+	defer s.Close()
+
+	clock := &fakeClock{now: time.Date(2017, 9, 12, 10, 0, 0, 0, time.UTC)}
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL, Clock: clock}}
+
+	devs, err := c.FindInactiveDevices(context.Background(), GetDevicesStruct{}, 48*time.Hour, 30*24*time.Hour)
+	assert.Nil(t, err)
+	if assert.Len(t, devs, 1) {
+		assert.Equal(t, "dev2", devs[0].DeviceID)
+	}
+	assert.Equal(t, "20170910T100000Z", endTime)
+}
+
+func TestRetryable(t *testing.T) {
+	ctx := context.Background()
+	assert.True(t, retryable(ctx, &APIError{StatusCode: http.StatusServiceUnavailable}))
+	assert.True(t, retryable(ctx, &APIError{StatusCode: http.StatusTooManyRequests}))
+	assert.True(t, retryable(ctx, &url.Error{Op: "Get", URL: "http://platform", Err: errors.New("connection refused")}))
+	assert.False(t, retryable(ctx, &APIError{StatusCode: http.StatusBadRequest}))
+	assert.False(t, retryable(ctx, &OpError{Op: OperationLogin, Err: &APIError{StatusCode: http.StatusUnauthorized}}))
+	assert.False(t, retryable(ctx, ErrInvalidPSK))
+	assert.False(t, retryable(ctx, &PaginationLimitError{}))
+}
+
+func TestDevicesIteratorRetryPolicy(t *testing.T) {
+	requests := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer s.Close()
+
+	// the pages are retried by the policy only
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL, Clock: &fakeClock{}, Retry: RetryPolicy{MaxAttempts: 3}}}
+	_, err := c.GetAllDevices(context.Background(), GetDevicesStruct{})
+	assert.NotNil(t, err)
+	assert.Equal(t, 3, requests)
+}
@@ -0,0 +1,348 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Confirmations of messages which aren't tracked yet are kept for
+// TrackDelivery, as the request ID is only known after the message is sent
+const (
+	unmatchedConfirmWindow = time.Minute
+	maxUnmatchedConfirms   = 1024
+)
+
+// NotificationFunc is called with the decoded notification
+type NotificationFunc func(interface{}) error
+
+// Dispatcher dispatches decoded notifications to the registered callbacks.
+// It is shared by the notification Server and the FleetPoller so both sources
+// deliver the same typed events.
+type Dispatcher struct {
+	// Scales normalizes the service data of DeviceDataChanged and
+	// DeviceDatasChanged notifications
+	Scales *ScaleRegistry
+	// Services decodes the service data of DeviceDataChanged and
+	// DeviceDatasChanged notifications into registered types
+	Services *ServiceRegistry
+
+	cbsLock sync.RWMutex
+	cbs     map[Notification]NotificationFunc
+	mws     []Middleware
+	typeMws map[Notification][]Middleware
+
+	progressLock sync.Mutex
+	progress     map[string]chan *CommandStatusUpdate
+	deliveries   map[string]chan DeliveryState
+	unmatched    map[string]*unmatchedConfirm
+
+	// hub receives all dispatched notifications for the watchers
+	hub *watchHub
+
+	holds []*CommandHold
+
+	handlers map[Notification][]*Registration
+	filters  []*Registration
+}
+
+// RegisterCallback registers the callback for a notification type, an earlier
+// registered callback for the same type is replaced
+func (d *Dispatcher) RegisterCallback(not Notification, cb NotificationFunc) {
+	d.cbsLock.Lock()
+	if d.cbs == nil {
+		d.cbs = make(map[Notification]NotificationFunc)
+	}
+	d.cbs[not] = cb
+	d.cbsLock.Unlock()
+}
+
+// Use adds middleware which wraps the callbacks of all notification types.
+// Middleware added first is run first.
+func (d *Dispatcher) Use(mw ...Middleware) {
+	d.cbsLock.Lock()
+	d.mws = append(d.mws, mw...)
+	d.cbsLock.Unlock()
+}
+
+// UseFor adds middleware which only wraps the callback of the notification
+// type, it runs after the middleware added with Use
+func (d *Dispatcher) UseFor(not Notification, mw ...Middleware) {
+	d.cbsLock.Lock()
+	if d.typeMws == nil {
+		d.typeMws = make(map[Notification][]Middleware)
+	}
+	d.typeMws[not] = append(d.typeMws[not], mw...)
+	d.cbsLock.Unlock()
+}
+
+func (d *Dispatcher) callback(not Notification) (NotificationFunc, bool) {
+	d.cbsLock.RLock()
+	defer d.cbsLock.RUnlock()
+
+	if d.cbs == nil && d.handlers == nil {
+		logrus.Infof("no callbacks registered, callback received")
+	}
+	cb, ok := d.cbs[not]
+	if !ok {
+		return nil, false
+	}
+	return d.wrap(not, cb), true
+}
+
+// wrap wraps the callback in the middleware, cbsLock must be held
+func (d *Dispatcher) wrap(not Notification, cb NotificationFunc) NotificationFunc {
+	mws := d.typeMws[not]
+	for i := len(mws) - 1; i >= 0; i-- {
+		cb = mws[i](not, cb)
+	}
+	for i := len(d.mws) - 1; i >= 0; i-- {
+		cb = d.mws[i](not, cb)
+	}
+	return cb
+}
+
+// Dispatch calls the callback registered and the handlers added for the
+// notification type with v, unless a filter drops it. Data notifications are
+// passed on as copy with the service data scaled and decoded.
+func (d *Dispatcher) Dispatch(not Notification, v interface{}) error {
+	return d.dispatch(StdCodec{}, not, v)
+}
+
+// dispatch is like Dispatch, the service data is decoded with the codec
+func (d *Dispatcher) dispatch(codec Codec, not Notification, v interface{}) error {
+	switch n := v.(type) {
+	case *CommandStatusUpdate:
+		d.dispatchProgress(n)
+	case *MessageConfirm:
+		d.dispatchDelivery(n)
+	case *DeviceDataChanged:
+		svcs, err := d.normalize(codec, []Service{n.Service})
+		if err != nil {
+			return err
+		}
+		cp := *n
+		cp.Service = svcs[0]
+		v = &cp
+	case *DeviceDatasChanged:
+		svcs, err := d.normalize(codec, n.Services)
+		if err != nil {
+			return err
+		}
+		cp := *n
+		cp.Services = svcs
+		v = &cp
+	}
+	if deviceID := wakeDeviceID(v); deviceID != "" {
+		for _, h := range d.commandHolds() {
+			h.Wake(deviceID)
+		}
+	}
+	if hub := d.watchers(); hub != nil {
+		hub.publish(Event{Type: not, DeviceID: notificationDeviceID(v), Data: v})
+	}
+	return d.runHandlers(not, v)
+}
+
+// normalize returns a copy of the services with the data scaled and decoded,
+// the notification of the caller is left as is. Data which isn't a JSON
+// object can't be scaled and is passed on unscaled.
+func (d *Dispatcher) normalize(codec Codec, svcs []Service) ([]Service, error) {
+	ret := make([]Service, len(svcs))
+	copy(ret, svcs)
+	for i := range ret {
+		data, err := d.Scales.Normalize(ret[i].ServiceID, ret[i].Data)
+		if err != nil {
+			logrus.Warnf("service %s data not scaled: %v", ret[i].ServiceID, err)
+		} else {
+			ret[i].Data = data
+		}
+	}
+	if err := d.Services.decodeServices(codec, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// CommandProgress returns a channel which receives the status updates of the
+// command, e.g. to show the download progress of a firmware update. The
+// channel is closed after the final update. Updates are only received when
+// the command is sent with a callback URL handled by the Server.
+func (d *Dispatcher) CommandProgress(commandID string) <-chan *CommandStatusUpdate {
+	d.progressLock.Lock()
+	defer d.progressLock.Unlock()
+
+	if d.progress == nil {
+		d.progress = make(map[string]chan *CommandStatusUpdate)
+	}
+	ch, ok := d.progress[commandID]
+	if !ok {
+		ch = make(chan *CommandStatusUpdate, 16)
+		d.progress[commandID] = ch
+	}
+	return ch
+}
+
+// unmatchedConfirm holds the delivery states of a message which isn't
+// tracked yet
+type unmatchedConfirm struct {
+	states []DeliveryState
+	at     time.Time
+}
+
+// TrackDelivery returns a channel which receives the delivery state
+// transitions of the message with the request ID returned by
+// SendDeviceMessage, starting with DeliveryPending. The channel is closed
+// when the message is delivered or failed. Confirmations dispatched up to a
+// minute before TrackDelivery is called are replayed.
+func (d *Dispatcher) TrackDelivery(requestID string) <-chan DeliveryState {
+	d.progressLock.Lock()
+	defer d.progressLock.Unlock()
+
+	if ch, ok := d.deliveries[requestID]; ok {
+		return ch
+	}
+	u := d.unmatched[requestID]
+	delete(d.unmatched, requestID)
+	if u != nil && time.Since(u.at) > unmatchedConfirmWindow {
+		u = nil
+	}
+	var states []DeliveryState
+	if u != nil {
+		states = u.states
+	}
+	ch := make(chan DeliveryState, 4+len(states))
+	ch <- DeliveryPending
+	for _, state := range states {
+		ch <- state
+		if state != DeliveryPending {
+			close(ch)
+			return ch
+		}
+	}
+	if d.deliveries == nil {
+		d.deliveries = make(map[string]chan DeliveryState)
+	}
+	d.deliveries[requestID] = ch
+	return ch
+}
+
+// HoldCommands releases the commands held by h when a dispatched notification
+// shows the device is reachable
+func (d *Dispatcher) HoldCommands(h *CommandHold) {
+	d.cbsLock.Lock()
+	d.holds = append(d.holds, h)
+	d.cbsLock.Unlock()
+}
+
+func (d *Dispatcher) commandHolds() []*CommandHold {
+	d.cbsLock.RLock()
+	defer d.cbsLock.RUnlock()
+	return d.holds
+}
+
+func (d *Dispatcher) watchers() *watchHub {
+	d.cbsLock.RLock()
+	defer d.cbsLock.RUnlock()
+	return d.hub
+}
+
+// wants reports whether a notification of the type is handled by a
+// callback, a handler, a tracked command, a command hold or a watcher
+func (d *Dispatcher) wants(not Notification) bool {
+	if _, ok := d.callback(not); ok || d.hasHandlers(not) || d.tracksCommands(not) {
+		return true
+	}
+	switch not {
+	case NotificationDeviceInfoChanged, NotificationDeviceDataChanged, NotificationDeviceDatasChanged:
+		if len(d.commandHolds()) > 0 {
+			return true
+		}
+	}
+	hub := d.watchers()
+	return hub != nil && hub.active()
+}
+
+func (d *Dispatcher) tracksCommands(not Notification) bool {
+	d.progressLock.Lock()
+	defer d.progressLock.Unlock()
+
+	switch not {
+	case NotificationCommandStatus:
+		return len(d.progress) > 0
+	case NotificationMessageConfirm:
+		// kept for TrackDelivery when not tracked yet
+		return true
+	}
+	return false
+}
+
+func (d *Dispatcher) dispatchDelivery(m *MessageConfirm) {
+	d.progressLock.Lock()
+	defer d.progressLock.Unlock()
+
+	state := m.State()
+	ch, ok := d.deliveries[m.Header.RequestID]
+	if !ok {
+		d.keepUnmatched(m.Header.RequestID, state)
+		return
+	}
+	select {
+	case ch <- state:
+	default:
+		logrus.Warnf("delivery channel of message %s is full, dropping state", m.Header.RequestID)
+	}
+	if state != DeliveryPending {
+		close(ch)
+		delete(d.deliveries, m.Header.RequestID)
+	}
+}
+
+// keepUnmatched keeps the delivery state of a message which isn't tracked,
+// the confirmations older than the window are dropped
+func (d *Dispatcher) keepUnmatched(requestID string, state DeliveryState) {
+	now := time.Now()
+	for id, u := range d.unmatched {
+		if now.Sub(u.at) > unmatchedConfirmWindow {
+			delete(d.unmatched, id)
+		}
+	}
+	u, ok := d.unmatched[requestID]
+	if !ok {
+		if len(d.unmatched) >= maxUnmatchedConfirms {
+			logrus.Warnf("too many untracked message confirmations, dropping %s", requestID)
+			return
+		}
+		if d.unmatched == nil {
+			d.unmatched = make(map[string]*unmatchedConfirm)
+		}
+		u = &unmatchedConfirm{}
+		d.unmatched[requestID] = u
+	}
+	u.states = append(u.states, state)
+	u.at = now
+}
+
+func (d *Dispatcher) dispatchProgress(u *CommandStatusUpdate) {
+	d.progressLock.Lock()
+	defer d.progressLock.Unlock()
+
+	ch, ok := d.progress[u.CommandID]
+	if !ok {
+		return
+	}
+	select {
+	case ch <- u:
+	default:
+		logrus.Warnf("progress channel of command %s is full, dropping update", u.CommandID)
+	}
+	if u.Final() {
+		close(ch)
+		delete(d.progress, u.CommandID)
+	}
+}
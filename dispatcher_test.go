@@ -0,0 +1,211 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDispatcherMiddleware(t *testing.T) {
+	var order []string
+	mw := func(name string) Middleware {
+		return func(not Notification, next NotificationFunc) NotificationFunc {
+			return func(v interface{}) error {
+				order = append(order, name)
+				return next(v)
+			}
+		}
+	}
+
+	d := Dispatcher{}
+	d.RegisterCallback(NotificationDeviceDataChanged, func(interface{}) error {
+		order = append(order, "callback")
+		return nil
+	})
+	d.RegisterCallback(NotificationDeviceAdded, func(interface{}) error {
+		panic("faulty callback")
+	})
+	d.Use(RecoverMiddleware, mw("global"))
+	d.UseFor(NotificationDeviceDataChanged, mw("typed"))
+
+	assert.Nil(t, d.Dispatch(NotificationDeviceDataChanged, nil), "expected no error for dispatch")
+	assert.Equal(t, []string{"global", "typed", "callback"}, order)
+
+	order = nil
+	assert.NotNil(t, d.Dispatch(NotificationDeviceAdded, nil), "expected error for panicking callback")
+	assert.Equal(t, []string{"global"}, order)
+}
+
+func TestWatchDevice(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := Dispatcher{}
+	ch := d.WatchDevice(ctx, "dev-1")
+
+	assert.True(t, d.wants(NotificationDeviceDataChanged), "expected watcher to want notifications")
+	assert.Nil(t, d.Dispatch(NotificationDeviceDataChanged, &DeviceDataChanged{DeviceID: "dev-2"}))
+	assert.Nil(t, d.Dispatch(NotificationDeviceDataChanged, &DeviceDataChanged{DeviceID: "dev-1"}))
+	assert.Nil(t, d.Dispatch(NotificationCommandResponse, &CommandResponse{Header: NotificationHeader{DeviceID: "dev-1"}}))
+
+	ev := <-ch
+	assert.Equal(t, NotificationDeviceDataChanged, ev.Type)
+	assert.Equal(t, "dev-1", ev.Data.(*DeviceDataChanged).DeviceID)
+	ev = <-ch
+	assert.Equal(t, NotificationCommandResponse, ev.Type)
+
+	cancel()
+	_, ok := <-ch
+	assert.False(t, ok, "expected channel to be closed")
+}
+
+func TestExpectData(t *testing.T) {
+	d := Dispatcher{}
+	exp := d.ExpectData("dev-1", "Config", map[string]interface{}{"interval": 60})
+
+	go func() {
+		d.Dispatch(NotificationDeviceDataChanged, &DeviceDataChanged{DeviceID: "dev-1", Service: Service{ServiceID: "Config", Data: []byte(`{"interval":30}`)}})
+		d.Dispatch(NotificationDeviceDataChanged, &DeviceDataChanged{DeviceID: "dev-1", Service: Service{ServiceID: "Config", Data: []byte(`{"interval":60.0,"mode":"eco"}`)}})
+	}()
+	n, err := exp.Wait(time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"interval":60.0,"mode":"eco"}`, string(n.Service.Data))
+
+	exp = d.ExpectData("dev-1", "Config", map[string]interface{}{"interval": 90})
+	_, err = exp.Wait(10 * time.Millisecond)
+	assert.Equal(t, ErrNotReflected, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	exp = d.ExpectData("dev-1", "Config", map[string]interface{}{"interval": 90})
+	_, err = exp.WaitCtx(ctx, time.Minute)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestDispatcherHandlers(t *testing.T) {
+	d := Dispatcher{}
+	var calls []string
+	d.RegisterCallback(NotificationDeviceAdded, func(interface{}) error {
+		calls = append(calls, "callback")
+		return nil
+	})
+	a := d.AddHandler(NotificationDeviceAdded, func(interface{}) error {
+		calls = append(calls, "a")
+		return errors.New("a failed")
+	})
+	d.AddHandler(NotificationDeviceAdded, func(interface{}) error {
+		calls = append(calls, "b")
+		return nil
+	})
+	f := d.AddFilter(func(not Notification, v interface{}) bool {
+		return v != "dropped"
+	})
+
+	assert.True(t, d.wants(NotificationDeviceAdded))
+	assert.EqualError(t, d.Dispatch(NotificationDeviceAdded, "kept"), "a failed")
+	assert.Equal(t, []string{"callback", "a", "b"}, calls)
+
+	calls = nil
+	assert.Nil(t, d.Dispatch(NotificationDeviceAdded, "dropped"))
+	assert.Empty(t, calls)
+
+	calls = nil
+	assert.Nil(t, a.Remove(context.Background()))
+	assert.Nil(t, f.Remove(context.Background()))
+	assert.Nil(t, d.Dispatch(NotificationDeviceAdded, "dropped"))
+	assert.Equal(t, []string{"callback", "b"}, calls)
+
+	// removing waits for the running calls of the handler
+	started, unblock := make(chan struct{}), make(chan struct{})
+	slow := d.AddHandler(NotificationDeviceDeleted, func(interface{}) error {
+		close(started)
+		<-unblock
+		return nil
+	})
+	go d.Dispatch(NotificationDeviceDeleted, nil)
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, slow.Remove(ctx))
+	assert.False(t, d.wants(NotificationDeviceDeleted))
+	close(unblock)
+	assert.Nil(t, slow.Remove(context.Background()))
+
+	// a panicking filter is released
+	panicking := d.AddFilter(func(Notification, interface{}) bool {
+		panic("faulty filter")
+	})
+	assert.Panics(t, func() { d.Dispatch(NotificationDeviceAdded, "kept") })
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Nil(t, panicking.Remove(ctx))
+}
+
+func TestDispatcherScaling(t *testing.T) {
+	scales := NewScaleRegistry()
+	scales.Register("Sensor", "temperature", Scale{Factor: 0.1})
+	d := Dispatcher{Scales: scales}
+	var got []interface{}
+	d.AddHandler(NotificationDeviceDataChanged, func(v interface{}) error {
+		got = append(got, v)
+		return nil
+	})
+	d.AddHandler(NotificationDeviceDatasChanged, func(v interface{}) error {
+		got = append(got, v)
+		return nil
+	})
+
+	n := &DeviceDataChanged{DeviceID: "dev-1", Service: Service{ServiceID: "Sensor", Data: []byte(`{"temperature":215}`)}}
+	assert.Nil(t, d.Dispatch(NotificationDeviceDataChanged, n))
+	assert.Equal(t, `{"temperature":215}`, string(n.Service.Data), "expected the notification of the caller unchanged")
+	if assert.Equal(t, 1, len(got)) {
+		assert.Equal(t, `{"temperature":21.5}`, string(got[0].(*DeviceDataChanged).Service.Data))
+	}
+
+	// data which isn't an object is passed on unscaled
+	batch := &DeviceDatasChanged{DeviceID: "dev-1", Services: []Service{
+		{ServiceID: "Sensor", Data: []byte(`{"temperature":100}`)},
+		{ServiceID: "Sensor", Data: []byte(`[1,2]`)},
+	}}
+	assert.Nil(t, d.Dispatch(NotificationDeviceDatasChanged, batch))
+	assert.Equal(t, `{"temperature":100}`, string(batch.Services[0].Data))
+	if assert.Equal(t, 2, len(got)) {
+		svcs := got[1].(*DeviceDatasChanged).Services
+		assert.Equal(t, `{"temperature":10}`, string(svcs[0].Data))
+		assert.Equal(t, `[1,2]`, string(svcs[1].Data))
+	}
+}
+
+func TestTrackDeliveryEarlyConfirm(t *testing.T) {
+	// the confirmation can arrive before the sender knows the request ID
+	s := &Server{}
+	w := httptest.NewRecorder()
+	s.handler(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"notifyType":"messageConfirm","header":{"requestId":"req1"},"body":{"status":"DELIVERED"}}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var states []DeliveryState
+	for state := range s.TrackDelivery("req1") {
+		states = append(states, state)
+	}
+	assert.Equal(t, []DeliveryState{DeliveryPending, DeliveryDelivered}, states)
+
+	// a replayed state which isn't final keeps the message tracked
+	d := Dispatcher{}
+	assert.Nil(t, d.Dispatch(NotificationMessageConfirm, &MessageConfirm{Header: NotificationHeader{RequestID: "req2"}, Body: []byte(`{"status":"SENT"}`)}))
+	ch := d.TrackDelivery("req2")
+	assert.Nil(t, d.Dispatch(NotificationMessageConfirm, &MessageConfirm{Header: NotificationHeader{RequestID: "req2"}, Body: []byte(`{"status":"FAILED"}`)}))
+	states = nil
+	for state := range ch {
+		states = append(states, state)
+	}
+	assert.Equal(t, []DeliveryState{DeliveryPending, DeliveryPending, DeliveryFailed}, states)
+	assert.Empty(t, d.unmatched)
+}
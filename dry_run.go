@@ -0,0 +1,58 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DryRunRequest is passed to Config.OnDryRun by all API calls when the
+// client is in dry-run mode. It holds the request which would have been sent,
+// without the authorization header.
+type DryRunRequest struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// String returns the request in a human readable form
+func (r *DryRunRequest) String() string {
+	s := r.Method + " " + r.URL + "\n"
+	for k, v := range r.Header {
+		for _, vv := range v {
+			s += k + ": " + vv + "\n"
+		}
+	}
+	if len(r.Body) > 0 {
+		s += "\n" + string(r.Body) + "\n"
+	}
+	return s
+}
+
+// dryRun passes the request to the OnDryRun hook, it returns ErrDryRun
+// unless the body can't be read
+func (c *Client) dryRun(req *http.Request) error {
+	r := &DryRunRequest{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: req.Header,
+	}
+	if req.Body != nil {
+		var err error
+		if r.Body, err = ioutil.ReadAll(req.Body); err != nil {
+			return err
+		}
+	}
+	if c.cfg.OnDryRun != nil {
+		c.cfg.OnDryRun(r)
+	} else {
+		logrus.Infof("dry-run: %s %s", r.Method, r.URL)
+	}
+	return ErrDryRun
+}
@@ -0,0 +1,86 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// endpoint builds the path and query of an API request. The path segments
+// and query values are escaped, so IDs and times with special characters
+// can't break the request:
+//
+//	newEndpoint("/iocm/app/dm/v1.2.0/devices", deviceID).Set("appId", appID).String()
+type endpoint struct {
+	path  string
+	query url.Values
+}
+
+// newEndpoint creates an endpoint of the base path, which isn't escaped,
+// followed by the escaped segments
+func newEndpoint(base string, segments ...string) *endpoint {
+	e := &endpoint{path: base, query: url.Values{}}
+	return e.Path(segments...)
+}
+
+// Path appends the escaped segments to the path
+func (e *endpoint) Path(segments ...string) *endpoint {
+	var b strings.Builder
+	b.WriteString(e.path)
+	for _, s := range segments {
+		b.WriteByte('/')
+		b.WriteString(url.PathEscape(s))
+	}
+	e.path = b.String()
+	return e
+}
+
+// Set sets the query parameter, empty values are left out
+func (e *endpoint) Set(key, value string) *endpoint {
+	if value != "" {
+		e.query.Set(key, value)
+	}
+	return e
+}
+
+// Add adds the values of the query parameter
+func (e *endpoint) Add(key string, values ...string) *endpoint {
+	for _, v := range values {
+		e.query.Add(key, v)
+	}
+	return e
+}
+
+// SetInt sets the query parameter to n
+func (e *endpoint) SetInt(key string, n int) *endpoint {
+	e.query.Set(key, strconv.Itoa(n))
+	return e
+}
+
+// SetTime sets the query parameter to t formatted as OcTime, zero times are
+// left out
+func (e *endpoint) SetTime(key string, t time.Time) *endpoint {
+	if !t.IsZero() {
+		e.query.Set(key, FormatOcTime(t))
+	}
+	return e
+}
+
+// String returns the path with the encoded query
+func (e *endpoint) String() string {
+	if len(e.query) == 0 {
+		return e.path
+	}
+	return e.path + "?" + e.query.Encode()
+}
+
+// appEndpoint is like newEndpoint with the appId query parameter of the
+// application
+func (c *Client) appEndpoint(base string, segments ...string) *endpoint {
+	return newEndpoint(base, segments...).Set("appId", c.cfg.AppID)
+}
@@ -0,0 +1,67 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// EnvelopeParser extracts the notifications from the body of a POST to the
+// Server, for platform variants which don't post a single notification
+type EnvelopeParser interface {
+	Parse(body []byte) ([][]byte, error)
+}
+
+// EnvelopeFunc is an EnvelopeParser function
+type EnvelopeFunc func(body []byte) ([][]byte, error)
+
+// Parse implements the EnvelopeParser interface
+func (f EnvelopeFunc) Parse(body []byte) ([][]byte, error) {
+	return f(body)
+}
+
+// PlainEnvelope is the default EnvelopeParser, the body is one notification
+var PlainEnvelope = EnvelopeFunc(func(body []byte) ([][]byte, error) {
+	return [][]byte{body}, nil
+})
+
+// ArrayEnvelope accepts a JSON array of notifications as well as a single
+// notification
+var ArrayEnvelope = EnvelopeFunc(splitArray)
+
+// WrappedEnvelope extracts the notification, or array of notifications, from
+// a field of a wrapping object, e.g. WrappedEnvelope("data") for
+// {"data": {...}}
+func WrappedEnvelope(field string) EnvelopeParser {
+	return EnvelopeFunc(func(body []byte) ([][]byte, error) {
+		var w map[string]json.RawMessage
+		if err := json.Unmarshal(body, &w); err != nil {
+			return nil, err
+		}
+		inner, ok := w[field]
+		if !ok {
+			return nil, errors.New("envelope field " + field + " missing")
+		}
+		return splitArray(inner)
+	})
+}
+
+func splitArray(body []byte) ([][]byte, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '[' {
+		return [][]byte{body}, nil
+	}
+	var raws []json.RawMessage
+	if err := json.Unmarshal(body, &raws); err != nil {
+		return nil, err
+	}
+	out := make([][]byte, len(raws))
+	for i, r := range raws {
+		out[i] = r
+	}
+	return out, nil
+}
@@ -0,0 +1,177 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Errors matched by APIError with errors.Is, see IsNotFound, IsUnauthorized
+// and IsRateLimited
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrRateLimited  = errors.New("rate limited")
+)
+
+// ErrReadOnly is returned by calls which change something on the platform
+// when the client is read-only, see Config.ReadOnly
+var ErrReadOnly = errors.New("client is read-only")
+
+// ErrDryRun is returned by the calls of a client in dry-run mode instead of
+// sending the request, see Config.DryRun
+var ErrDryRun = errors.New("dry-run: request not sent")
+
+// defaultErrorBodyLimit is the number of bytes of an error response body
+// kept in the APIError when not configured
+const defaultErrorBodyLimit = 512
+
+// APIError is returned when the OceanConnect API responds with an unexpected
+// status code. When the platform supplies an error body the error code and
+// the (possibly localized) description are available as well.
+type APIError struct {
+	StatusCode  int    `json:"-"`
+	Status      string `json:"-"`
+	Code        string `json:"error_code"`
+	Description string `json:"error_desc"`
+	// Body is the start of the response body, see Config.ErrorBodyLimit
+	Body string `json:"-"`
+	// RequestID is the ID the platform assigned to the request, if returned
+	RequestID string `json:"-"`
+	// Op is the operation of the failed request
+	Op Operation `json:"-"`
+}
+
+// Platform error codes which are reported with a generic HTTP status
+var (
+	notFoundCodes     = map[string]bool{"100403": true, "100418": true, "100431": true}
+	unauthorizedCodes = map[string]bool{"100002": true, "1010005": true}
+	rateLimitedCodes  = map[string]bool{"1010009": true}
+	// alreadyExistsCodes are the codes of a subscription which exists
+	alreadyExistsCodes = map[string]bool{"100227": true}
+)
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	s := "invalid response code: " + e.Status
+	if e.Code != "" {
+		s += " (" + e.Code + ": " + e.Description + ")"
+	} else if e.Body != "" {
+		s += ": " + e.Body
+	}
+	if e.RequestID != "" {
+		s += " [request " + e.RequestID + "]"
+	}
+	return s
+}
+
+// NumericCode returns the platform error code as number, 0 when there is no
+// numeric code
+func (e *APIError) NumericCode() int {
+	n, err := strconv.Atoi(e.Code)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// Is reports whether the error matches ErrNotFound, ErrUnauthorized or
+// ErrRateLimited, by HTTP status or platform error code
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound || notFoundCodes[e.Code]
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || unauthorizedCodes[e.Code]
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests || rateLimitedCodes[e.Code]
+	}
+	return false
+}
+
+// IsNotFound reports whether err is an APIError for a missing resource
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsUnauthorized reports whether err is an APIError for an invalid or
+// expired token or credentials
+func IsUnauthorized(err error) bool {
+	return errors.Is(err, ErrUnauthorized)
+}
+
+// IsRateLimited reports whether err is an APIError for a throttled request
+func IsRateLimited(err error) bool {
+	return errors.Is(err, ErrRateLimited)
+}
+
+// newAPIError creates an APIError from the response and closes the body
+func (c *Client) newAPIError(resp *http.Response) error {
+	defer resp.Body.Close()
+
+	e := &APIError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		RequestID:  responseRequestID(resp),
+		Op:         requestOperation(resp.Request),
+	}
+	limit := c.cfg.ErrorBodyLimit
+	if limit == 0 {
+		limit = defaultErrorBodyLimit
+	}
+	if limit < 0 {
+		return e
+	}
+	buf, err := ioutil.ReadAll(io.LimitReader(resp.Body, int64(limit)))
+	if err == nil && len(buf) > 0 {
+		e.Body = strings.TrimSpace(string(buf))
+		// the body is optional, ignore anything we can't decode
+		json.Unmarshal(buf, e)
+	}
+	return e
+}
+
+// DecodeError describes an entry in a list response which couldn't be decoded
+type DecodeError struct {
+	Index    int    // Index of the entry in the response
+	DeviceID string // DeviceID of the entry, when it could be determined
+	Err      error
+}
+
+// Error implements the error interface
+func (e DecodeError) Error() string {
+	s := "decoding entry " + strconv.Itoa(e.Index)
+	if e.DeviceID != "" {
+		s += " (device " + e.DeviceID + ")"
+	}
+	return s + " failed: " + e.Err.Error()
+}
+
+func newDecodeError(index int, raw json.RawMessage, err error) DecodeError {
+	var id struct {
+		DeviceID string `json:"deviceId"`
+	}
+	// best effort only, the entry is malformed after all
+	json.Unmarshal(raw, &id)
+	return DecodeError{Index: index, DeviceID: id.DeviceID, Err: err}
+}
+
+// DecodeErrors is returned together with the successfully decoded entries
+// when some entries of a list response couldn't be decoded
+type DecodeErrors []DecodeError
+
+// Error implements the error interface
+func (e DecodeErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	return strconv.Itoa(len(e)) + " entries could not be decoded, first: " + e[0].Error()
+}
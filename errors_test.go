@@ -0,0 +1,150 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestResponse(code int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: code,
+		Status:     http.StatusText(code),
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	c := Client{}
+
+	err := c.newAPIError(newTestResponse(http.StatusBadRequest, `{"error_code":"100022","error_desc":"The input is invalid."}`))
+	apiErr, ok := err.(*APIError)
+	assert.True(t, ok, "expected *APIError")
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, "100022", apiErr.Code)
+	assert.Equal(t, "The input is invalid.", apiErr.Description)
+	assert.Equal(t, "invalid response code: Bad Request (100022: The input is invalid.)", err.Error())
+
+	err = c.newAPIError(newTestResponse(http.StatusBadGateway, "upstream unavailable\n"))
+	assert.Equal(t, "invalid response code: Bad Gateway: upstream unavailable", err.Error())
+
+	c.cfg.ErrorBodyLimit = 8
+	err = c.newAPIError(newTestResponse(http.StatusBadGateway, "upstream unavailable"))
+	assert.Equal(t, "upstream", err.(*APIError).Body)
+
+	c.cfg.ErrorBodyLimit = -1
+	err = c.newAPIError(newTestResponse(http.StatusBadGateway, "upstream unavailable"))
+	assert.Equal(t, "", err.(*APIError).Body)
+}
+
+func TestAPIErrorIs(t *testing.T) {
+	c := Client{}
+
+	resp := newTestResponse(http.StatusBadRequest, `{"error_code":"100403","error_desc":"The device is not existed."}`)
+	resp.Header.Set("X-Request-Id", "req-1")
+	err := c.newAPIError(resp)
+	assert.True(t, IsNotFound(err))
+	assert.False(t, IsUnauthorized(err))
+	assert.Equal(t, 100403, err.(*APIError).NumericCode())
+	assert.Equal(t, "req-1", err.(*APIError).RequestID)
+	assert.Contains(t, err.Error(), "[request req-1]")
+
+	assert.True(t, IsUnauthorized(c.newAPIError(newTestResponse(http.StatusUnauthorized, ""))))
+	assert.True(t, IsRateLimited(c.newAPIError(newTestResponse(http.StatusTooManyRequests, ""))))
+	assert.False(t, IsNotFound(errors.New("other")))
+}
+
+func TestErrorOperation(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/iocm/app/sec/v1.1.0/login":
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+		case "/iocm/app/dm/v1.1.0/devices":
+			fmt.Fprintln(w, `{"totalCount":`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer s.Close()
+
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL}}
+	err := c.SendCommand("dev1", "Valve", "OPEN", nil, 0)
+	assert.Equal(t, OperationSendCommand, ErrorOperation(err))
+	var apiErr *APIError
+	assert.True(t, errors.As(err, &apiErr))
+
+	_, err = c.GetDevices(GetDevicesStruct{})
+	assert.Equal(t, OperationListDevices, ErrorOperation(err))
+	var opErr *OpError
+	if assert.True(t, errors.As(err, &opErr)) {
+		assert.True(t, strings.HasPrefix(opErr.Error(), "ListDevices: "), opErr.Error())
+	}
+
+	_, err = c.GetDevice("dev1")
+	assert.Equal(t, OperationGetDevice, ErrorOperation(err))
+	assert.Equal(t, OperationDeleteDevice, ErrorOperation(c.DeleteDevice("dev1")))
+
+	s.Close()
+	_, err = c.GetDevice("dev1")
+	assert.Equal(t, OperationGetDevice, ErrorOperation(err))
+	assert.Equal(t, OperationUnknown, ErrorOperation(errors.New("other")))
+}
+
+func TestErrorRequestID(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/iocm/app/sec/v1.1.0/login":
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+		case "/iocm/app/dm/v1.1.0/devices":
+			w.Header().Set("X-Request-Id", "req-list")
+			fmt.Fprintln(w, `{"totalCount":`)
+		default:
+			w.Header().Set("X-Request-Id", "req-get")
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer s.Close()
+
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL}}
+	_, err := c.GetDevice("dev1")
+	assert.Equal(t, "req-get", ErrorRequestID(err))
+
+	_, err = c.GetDevices(GetDevicesStruct{})
+	assert.Equal(t, "req-list", ErrorRequestID(err))
+	assert.True(t, strings.HasSuffix(err.Error(), " [request req-list]"), err.Error())
+
+	assert.Equal(t, "", ErrorRequestID(errors.New("other")))
+}
+
+func TestAcceptLanguage(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "zh-CN", r.Header.Get("Accept-Language"))
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, `{"error_code":"100403","error_desc":"设备不存在"}`)
+	}))
+	defer s.Close()
+
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL, AcceptLanguage: "zh-CN"}}
+	_, err := c.GetDevice("dev1")
+	var apiErr *APIError
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, "100403", apiErr.Code)
+		assert.Equal(t, "设备不存在", apiErr.Description)
+	}
+	assert.True(t, IsNotFound(err), "expected the code to be matched regardless of the language")
+}
@@ -0,0 +1,151 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FaultInjector is a http.RoundTripper which makes requests fail at random
+// like a misbehaving platform, to test how an application copes with it. The
+// probabilities are between 0 and 1, at most one fault is injected per
+// request.
+type FaultInjector struct {
+	// Next sends the requests which aren't failed, defaults to
+	// http.DefaultTransport
+	Next http.RoundTripper
+	// Match selects the requests faults are injected in, all when nil
+	Match func(*http.Request) bool
+
+	// Timeout is the probability a request hangs until its context is done
+	// or TimeoutAfter passed, and then fails with a timeout error
+	Timeout float64
+	// TimeoutAfter bounds the hang of an injected timeout (default 30s)
+	TimeoutAfter time.Duration
+	// ServerError is the probability of a 503 Service Unavailable response
+	ServerError float64
+	// MalformedJSON is the probability the response body is truncated
+	MalformedJSON float64
+	// InvalidToken is the probability of a 401 response reporting the access
+	// token as invalid
+	InvalidToken float64
+
+	// Seed seeds the random source, for reproducible runs
+	Seed int64
+
+	lock sync.Mutex
+	rnd  *rand.Rand
+}
+
+// InjectFaults routes the requests of the client through f, f.Next is set to
+// the current transport of the client when empty
+func (c *Client) InjectFaults(f *FaultInjector) {
+	c.tokenLock.Lock()
+	defer c.tokenLock.Unlock()
+
+	if f.Next == nil {
+		f.Next = c.c.Transport
+	}
+	hc := *c.c
+	hc.Transport = f
+	c.c = &hc
+}
+
+type faultTimeoutError struct{}
+
+func (faultTimeoutError) Error() string   { return "injected fault: timeout" }
+func (faultTimeoutError) Timeout() bool   { return true }
+func (faultTimeoutError) Temporary() bool { return true }
+
+// RoundTrip implements the http.RoundTripper interface
+func (f *FaultInjector) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := f.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if f.Match != nil && !f.Match(req) {
+		return next.RoundTrip(req)
+	}
+
+	p := f.float()
+	switch {
+	case p < f.Timeout:
+		logrus.Debugf("injecting timeout in %s %s", req.Method, req.URL.Path)
+		wait := f.TimeoutAfter
+		if wait <= 0 {
+			wait = 30 * time.Second
+		}
+		t := time.NewTimer(wait)
+		defer t.Stop()
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-t.C:
+			return nil, faultTimeoutError{}
+		}
+	case p < f.Timeout+f.ServerError:
+		logrus.Debugf("injecting server error in %s %s", req.Method, req.URL.Path)
+		return faultResponse(req, http.StatusServiceUnavailable, `{"error_code":"100001","error_desc":"injected fault: service unavailable"}`), nil
+	case p < f.Timeout+f.ServerError+f.InvalidToken:
+		logrus.Debugf("injecting invalid token in %s %s", req.Method, req.URL.Path)
+		return faultResponse(req, http.StatusUnauthorized, `{"error_code":"1010005","error_desc":"injected fault: invalid access token"}`), nil
+	case p < f.Timeout+f.ServerError+f.InvalidToken+f.MalformedJSON:
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		logrus.Debugf("injecting malformed body in %s %s", req.Method, req.URL.Path)
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = body[:len(body)/2]
+		if len(body) == 0 {
+			body = []byte("{")
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		resp.ContentLength = int64(len(body))
+		resp.Header.Del("Content-Length")
+		return resp, nil
+	}
+	return next.RoundTrip(req)
+}
+
+func (f *FaultInjector) float() float64 {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.rnd == nil {
+		seed := f.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		f.rnd = rand.New(rand.NewSource(seed))
+	}
+	return f.rnd.Float64()
+}
+
+func faultResponse(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader([]byte(body))),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFaultInjector(t *testing.T) {
+	logins := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			logins++
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		fmt.Fprintln(w, `{"deviceId":"dev1","deviceInfo":{"name":"meter"}}`)
+	}))
+	defer s.Close()
+
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL}}
+	_, err := c.GetDevice("dev1")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, logins)
+
+	f := &FaultInjector{
+		Match: func(r *http.Request) bool { return r.URL.Path != "/iocm/app/sec/v1.1.0/login" },
+		Seed:  1,
+	}
+	c.InjectFaults(f)
+
+	f.ServerError = 1
+	_, err = c.GetDevice("dev1")
+	apiErr, ok := err.(*APIError)
+	if assert.True(t, ok, "expected APIError") {
+		assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+		assert.Equal(t, "503 Service Unavailable", apiErr.Status)
+	}
+
+	f.ServerError, f.InvalidToken = 0, 1
+	_, err = c.GetDevice("dev1")
+	assert.True(t, IsUnauthorized(err))
+	f.InvalidToken = 0
+	_, err = c.GetDevice("dev1")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, logins, "expected login after invalidated token")
+
+	f.MalformedJSON = 1
+	_, err = c.GetDevice("dev1")
+	assert.NotNil(t, err)
+
+	f.MalformedJSON, f.Timeout, f.TimeoutAfter = 0, 1, time.Minute
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = c.GetDeviceCtx(ctx, "dev1")
+	assert.NotNil(t, err)
+}
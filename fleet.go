@@ -0,0 +1,125 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+)
+
+// fleetNotifications are subscribed by Fleet.Start, they keep the cache of
+// the Fleet up to date
+var fleetNotifications = []Notification{
+	NotificationDeviceAdded,
+	NotificationDeviceInfoChanged,
+	NotificationDeviceDataChanged,
+	NotificationDeviceDatasChanged,
+	NotificationDeviceDeleted,
+}
+
+// DataFunc handles the data a device reported for a service
+type DataFunc func(deviceID string, svc Service) error
+
+// Fleet bundles a client, a device cache kept up to date by notifications, a
+// notification server and the reconciler behind a small API for the common
+// workflows. The parts stay available for anything the Fleet doesn't cover:
+//
+//	f, err := oceanconnect.NewFleet(cfg)
+//	f.OnData("Meter", func(deviceID string, svc oceanconnect.Service) error { ... })
+//	err = f.Start(ctx, "https://example.com/notifications")
+//	go http.ListenAndServe(":8080", f.Server)
+type Fleet struct {
+	Client *Client
+	Cache  *DeviceCache
+	// Server receives the notifications, it must be served at the callback
+	// URL passed to Start
+	Server *Server
+	// CommandOptions are used by Send
+	CommandOptions CommandWaitOptions
+}
+
+// NewFleet creates the client for the config and a Fleet with it
+func NewFleet(cfg Config) (*Fleet, error) {
+	c, err := NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return c.NewFleet(), nil
+}
+
+// NewFleet creates a Fleet with the client, call Start to fill the cache and
+// subscribe to the notifications
+func (c *Client) NewFleet() *Fleet {
+	f := &Fleet{
+		Client: c,
+		Cache:  NewDeviceCache(c),
+		Server: c.newServer(nil),
+	}
+	f.Server.addHandlers(func(v interface{}) error {
+		f.Cache.applyNotification(v)
+		return nil
+	}, fleetNotifications...)
+	return f
+}
+
+// Start subscribes the callback URL to the device notifications and fills the
+// cache, which the notifications keep up to date from then on
+func (f *Fleet) Start(ctx context.Context, callbackURL string) error {
+	for _, not := range fleetNotifications {
+		sub, err := f.Client.SubscribeToCtx(ctx, not, callbackURL)
+		if err != nil {
+			return err
+		}
+		if not == NotificationDeviceDataChanged {
+			f.Server.Subscription = sub
+		}
+	}
+	return f.Cache.RefreshCtx(ctx)
+}
+
+// Devices returns the cached devices matching all predicates, ordered by
+// device ID
+func (f *Fleet) Devices(preds ...DevicePredicate) []CachedDevice {
+	return f.Cache.Query(preds...)
+}
+
+// Send sends the command with the CommandOptions and waits until the device
+// responded, see Client.SendCommandAndWait
+func (f *Fleet) Send(ctx context.Context, deviceID, serviceID, method string, params interface{}) (*DeviceCommand, error) {
+	return f.Client.SendCommandAndWait(ctx, deviceID, serviceID, method, params, f.CommandOptions)
+}
+
+// OnData calls fn for the data reported for the service, or for all services
+// when serviceID is empty, from both single and batched data notifications.
+// The service data is decoded into Value when its type is registered in
+// Config.Services.
+func (f *Fleet) OnData(serviceID string, fn DataFunc) *Registration {
+	return f.Server.addHandlers(func(v interface{}) error {
+		switch n := v.(type) {
+		case *DeviceDataChanged:
+			return fleetData(serviceID, fn, n.DeviceID, n.Service)
+		case *DeviceDatasChanged:
+			for _, s := range n.Services {
+				if err := fleetData(serviceID, fn, n.DeviceID, s); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}, NotificationDeviceDataChanged, NotificationDeviceDatasChanged)
+}
+
+func fleetData(serviceID string, fn DataFunc, deviceID string, svc Service) error {
+	if serviceID != "" && svc.ServiceID != serviceID {
+		return nil
+	}
+	return fn(deviceID, svc)
+}
+
+// Reconcile plans the changes bringing the devices in line with the desired
+// specs, see Client.PlanFleet. Apply the plan to make the changes, the
+// credentials of the registered devices are in Plan.Registrations then.
+func (f *Fleet) Reconcile(ctx context.Context, desired []DeviceSpec, prune bool) (*Plan, error) {
+	return f.Client.PlanFleet(ctx, desired, prune)
+}
@@ -0,0 +1,222 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultPollInterval is the interval of a FleetPoller without Interval
+const defaultPollInterval = time.Minute
+
+// FleetPoller periodically retrieves the devices and their latest service data
+// and dispatches the notifications the platform would have pushed: a
+// DeviceDataChanged for every service with a newer event time than seen
+// before, and DeviceAdded, DeviceInfoChanged and DeviceDeleted for the
+// changes of the devices. It is meant for setups where the platform can't
+// deliver callbacks to the notification Server.
+type FleetPoller struct {
+	// Interval between polls (default 1m)
+	Interval time.Duration
+	// DeviceIDs to poll, when empty all devices of the application are polled
+	DeviceIDs []string
+	// PageSize used when polling all devices (default 100)
+	PageSize int
+
+	client     *Client
+	dispatcher *Dispatcher
+
+	lastLock sync.Mutex
+	last     map[string]*polledDevice
+}
+
+// polledDevice is the state of a device at the previous poll
+type polledDevice struct {
+	gatewayID string
+	info      DeviceInfo
+	services  map[string]time.Time // serviceID -> eventTime
+}
+
+// NewFleetPoller creates a poller which dispatches to d, use the Dispatcher of
+// a Server to handle polled and pushed notifications with the same callbacks.
+// When d has no watchers yet the polled notifications feed the watchers of c.
+func NewFleetPoller(c *Client, d *Dispatcher, interval time.Duration) *FleetPoller {
+	d.cbsLock.Lock()
+	if d.hub == nil {
+		d.hub = &c.events
+	}
+	d.cbsLock.Unlock()
+	return &FleetPoller{
+		Interval:   interval,
+		client:     c,
+		dispatcher: d,
+	}
+}
+
+// Run polls until the context is done. The first poll only records the
+// current state, notifications are dispatched for changes after that.
+func (p *FleetPoller) Run(ctx context.Context) error {
+	interval := p.Interval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	for {
+		next := p.client.clock().After(interval)
+		if err := p.PollCtx(ctx); err != nil {
+			logrus.Errorf("polling devices failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-next:
+		}
+	}
+}
+
+// Poll retrieves the devices once and dispatches the changes since the
+// previous poll. Devices which can't be retrieved keep their state and are
+// polled again the next time, the first of their errors is returned after
+// the other devices are handled.
+func (p *FleetPoller) Poll() error {
+	return p.PollCtx(context.Background())
+}
+
+// PollCtx is like Poll but with a context
+func (p *FleetPoller) PollCtx(ctx context.Context) error {
+	devs, gone, err := p.devices(ctx)
+	if devs == nil && gone == nil && err != nil {
+		return err
+	}
+
+	p.lastLock.Lock()
+	defer p.lastLock.Unlock()
+
+	initial := p.last == nil
+	if initial {
+		p.last = make(map[string]*polledDevice)
+	}
+	for _, d := range devs {
+		prev, ok := p.last[d.DeviceID]
+		if !ok {
+			prev = &polledDevice{services: make(map[string]time.Time)}
+			p.last[d.DeviceID] = prev
+			if !initial {
+				p.dispatch(NotificationDeviceAdded, &DeviceAdded{
+					NotifyType: NotificationDeviceAdded,
+					DeviceID:   d.DeviceID,
+					GatewayID:  d.GatewayID,
+					NodeType:   d.NodeType,
+					DeviceInfo: d.DeviceInfo,
+				})
+			}
+		} else if prev.info != d.DeviceInfo {
+			p.dispatch(NotificationDeviceInfoChanged, &DeviceInfoChanged{
+				NotifyType: NotificationDeviceInfoChanged,
+				DeviceID:   d.DeviceID,
+				GatewayID:  d.GatewayID,
+				DeviceInfo: d.DeviceInfo,
+			})
+		}
+		prev.gatewayID, prev.info = d.GatewayID, d.DeviceInfo
+
+		for _, svc := range d.Services {
+			seen, ok := prev.services[svc.ServiceID]
+			if ok && !svc.EventTime.After(seen) {
+				continue
+			}
+			prev.services[svc.ServiceID] = svc.EventTime.Time
+			if initial {
+				continue
+			}
+			p.dispatch(NotificationDeviceDataChanged, &DeviceDataChanged{
+				NotifyType: NotificationDeviceDataChanged,
+				DeviceID:   d.DeviceID,
+				GatewayID:  d.GatewayID,
+				Service:    svc,
+			})
+		}
+	}
+	for _, id := range gone {
+		prev, ok := p.last[id]
+		if !ok {
+			continue
+		}
+		delete(p.last, id)
+		p.dispatch(NotificationDeviceDeleted, &DeviceDeleted{
+			NotifyType: NotificationDeviceDeleted,
+			DeviceID:   id,
+			GatewayID:  prev.gatewayID,
+		})
+	}
+	return err
+}
+
+func (p *FleetPoller) dispatch(not Notification, v interface{}) {
+	if err := p.dispatcher.dispatch(p.client.codec(), not, v); err != nil {
+		logrus.Errorf("Error running callback: %v", err)
+	}
+}
+
+// devices returns the polled devices and the IDs of the devices which are
+// gone. A device which failed or couldn't be decoded is left out of both, its
+// error is returned.
+func (p *FleetPoller) devices(ctx context.Context) ([]Device, []string, error) {
+	var devs []Device
+	var gone []string
+	if len(p.DeviceIDs) > 0 {
+		var first error
+		for _, id := range p.DeviceIDs {
+			d, err := p.client.GetDeviceCtx(ctx, id)
+			switch {
+			case err == nil:
+				devs = append(devs, *d)
+			case IsNotFound(err):
+				gone = append(gone, id)
+			default:
+				if ctx.Err() != nil {
+					return devs, gone, ctx.Err()
+				}
+				logrus.Warnf("polling device %s failed: %v", id, err)
+				if first == nil {
+					first = err
+				}
+			}
+		}
+		return devs, gone, first
+	}
+
+	devs, skipped, err := p.client.allDevices(ctx, p.PageSize)
+	if err != nil {
+		// without the full list it isn't known which devices are gone
+		return nil, nil, err
+	}
+	listed := make(map[string]bool, len(devs)+len(skipped))
+	for _, d := range devs {
+		listed[d.DeviceID] = true
+	}
+	// devices which couldn't be decoded still exist and keep their state
+	for _, e := range skipped {
+		if e.DeviceID == "" {
+			// it isn't known which device it is, so none is gone
+			return devs, nil, skipped
+		}
+		listed[e.DeviceID] = true
+	}
+	p.lastLock.Lock()
+	for id := range p.last {
+		if !listed[id] {
+			gone = append(gone, id)
+		}
+	}
+	p.lastLock.Unlock()
+	if len(skipped) > 0 {
+		return devs, gone, skipped
+	}
+	return devs, gone, nil
+}
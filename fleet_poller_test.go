@@ -0,0 +1,178 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFleetPoller(t *testing.T) {
+	var lock sync.Mutex
+	devs := map[string]string{
+		"dev1": `{"deviceId":"dev1","deviceInfo":{"name":"meter"},"services":[{"serviceId":"Meter","data":{"value":1},"eventTime":"20170912T101530Z"}]}`,
+		"dev2": `{"deviceId":"dev2","deviceInfo":{"name":"valve"}}`,
+	}
+	failing := ""
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		id := strings.TrimPrefix(r.URL.Path, "/iocm/app/dm/v1.1.0/devices/")
+		lock.Lock()
+		defer lock.Unlock()
+		if id == failing {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		d, ok := devs[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintln(w, d)
+	}))
+	defer s.Close()
+
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL}}
+	assert.Nil(t, c.Login())
+
+	var events []string
+	d := &Dispatcher{}
+	record := func(v interface{}) error {
+		switch n := v.(type) {
+		case *DeviceAdded:
+			events = append(events, "added "+n.DeviceID)
+		case *DeviceInfoChanged:
+			events = append(events, "info "+n.DeviceID+" "+n.DeviceInfo.Name)
+		case *DeviceDeleted:
+			events = append(events, "deleted "+n.DeviceID)
+		case *DeviceDataChanged:
+			events = append(events, "data "+n.DeviceID+" "+string(n.Service.Data))
+		}
+		return nil
+	}
+	for _, not := range []Notification{NotificationDeviceAdded, NotificationDeviceInfoChanged, NotificationDeviceDeleted, NotificationDeviceDataChanged} {
+		d.RegisterCallback(not, record)
+	}
+
+	p := NewFleetPoller(c, d, 0)
+	p.DeviceIDs = []string{"dev1", "dev2", "dev3"}
+
+	// the first poll only records the state
+	assert.Nil(t, p.Poll())
+	assert.Empty(t, events)
+
+	lock.Lock()
+	devs["dev1"] = `{"deviceId":"dev1","deviceInfo":{"name":"meter"},"services":[{"serviceId":"Meter","data":{"value":2},"eventTime":"20170912T101630Z"}]}`
+	devs["dev3"] = `{"deviceId":"dev3","deviceInfo":{"name":"pump"}}`
+	failing = "dev2"
+	lock.Unlock()
+
+	// the failing device doesn't stop the others and keeps its state
+	assert.NotNil(t, p.Poll())
+	assert.Equal(t, []string{`data dev1 {"value":2}`, "added dev3"}, events)
+
+	events = nil
+	lock.Lock()
+	devs["dev2"] = `{"deviceId":"dev2","deviceInfo":{"name":"closed valve"}}`
+	delete(devs, "dev3")
+	failing = ""
+	lock.Unlock()
+
+	assert.Nil(t, p.Poll())
+	assert.Equal(t, []string{"info dev2 closed valve", "deleted dev3"}, events)
+}
+
+func TestFleetPollerDefaultInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var polls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		if atomic.AddInt32(&polls, 1) == 3 {
+			cancel()
+		}
+		fmt.Fprintln(w, `{"totalCount":0,"pageNo":0,"pageSize":100,"devices":[]}`)
+	}))
+	defer s.Close()
+
+	start := time.Date(2017, 9, 12, 10, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start}
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL, Clock: clock}}
+	assert.Nil(t, c.Login())
+
+	// without an interval the poller waits the default between the polls
+	// instead of polling in a busy loop
+	p := NewFleetPoller(c, &Dispatcher{}, 0)
+	assert.Equal(t, context.Canceled, p.Run(ctx))
+	waited := clock.now.Sub(start)
+	assert.True(t, waited >= 3*defaultPollInterval, "waited %v", waited)
+	assert.Equal(t, time.Duration(0), waited%defaultPollInterval)
+}
+
+func TestFleetPollerUndecodable(t *testing.T) {
+	var lock sync.Mutex
+	dev2 := `{"deviceId":"dev2","creationTime":"20170912T101530Z"}`
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		fmt.Fprintf(w, `{"totalCount":2,"devices":[{"deviceId":"dev1"},%s]}`, dev2)
+	}))
+	defer s.Close()
+
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL}}
+	var events []string
+	d := &Dispatcher{}
+	d.RegisterCallback(NotificationDeviceAdded, func(v interface{}) error {
+		events = append(events, "added "+v.(*DeviceAdded).DeviceID)
+		return nil
+	})
+	d.RegisterCallback(NotificationDeviceDeleted, func(v interface{}) error {
+		events = append(events, "deleted "+v.(*DeviceDeleted).DeviceID)
+		return nil
+	})
+
+	p := NewFleetPoller(c, d, 0)
+	assert.Nil(t, p.Poll())
+
+	// a device which can't be decoded isn't reported as deleted
+	lock.Lock()
+	dev2 = `{"deviceId":"dev2","creationTime":"garbage"}`
+	lock.Unlock()
+	assert.IsType(t, DecodeErrors{}, p.Poll())
+	assert.Empty(t, events)
+
+	// without its ID no device is reported as deleted
+	lock.Lock()
+	dev2 = `{"deviceId":2}`
+	lock.Unlock()
+	assert.IsType(t, DecodeErrors{}, p.Poll())
+	assert.Empty(t, events)
+
+	// and it isn't reported as added when it can be decoded again
+	lock.Lock()
+	dev2 = `{"deviceId":"dev2","creationTime":"20170912T101530Z"}`
+	lock.Unlock()
+	assert.Nil(t, p.Poll())
+	assert.Empty(t, events)
+}
@@ -0,0 +1,75 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFleet(t *testing.T) {
+	var subscribed []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/iocm/app/sec/v1.1.0/login":
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/iocm/app/sub/v1.2.0/subscribe":
+			var req Subscription
+			json.NewDecoder(r.Body).Decode(&req)
+			subscribed = append(subscribed, string(req.NotifyType))
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"subscriptionId":"s%d","notifyType":"%s"}`, len(subscribed), req.NotifyType)
+		case r.URL.Path == "/iocm/app/sub/v1.2.0/subscriptions":
+			fmt.Fprintln(w, `{"totalCount":0,"subscriptions":[]}`)
+		case r.URL.Path == "/iocm/app/dm/v1.1.0/devices":
+			fmt.Fprintln(w, `{"totalCount":1,"devices":[{"deviceId":"dev1","deviceInfo":{"nodeId":"111","status":"ONLINE"}}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL}}
+	f := c.NewFleet()
+	var got []string
+	reg := f.OnData("Meter", func(deviceID string, svc Service) error {
+		got = append(got, deviceID+" "+string(svc.Data))
+		return nil
+	})
+	assert.Nil(t, f.Start(context.Background(), "http://example.com/cb"))
+	assert.Equal(t, 5, len(subscribed))
+	assert.Equal(t, "s3", f.Server.Subscription.SubscriptionID)
+	assert.Equal(t, 1, len(f.Devices(StatusIs(DeviceStatusOnline))))
+
+	post := func(body string) {
+		w := httptest.NewRecorder()
+		f.Server.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	post(`{"notifyType":"deviceAdded","deviceId":"dev2","deviceInfo":{"nodeId":"222","status":"INBOX"}}`)
+	post(`{"notifyType":"deviceDatasChanged","deviceId":"dev2","services":[{"serviceId":"Meter","data":{"v":1}},{"serviceId":"Other","data":{}}]}`)
+	assert.Equal(t, []string{`dev2 {"v":1}`}, got)
+	if d, ok := f.Cache.Get("dev2"); assert.True(t, ok) {
+		assert.Equal(t, DeviceStatusInbox, d.DeviceInfo.Status)
+		assert.Equal(t, 2, len(d.Services))
+	}
+
+	post(`{"notifyType":"deviceDeleted","deviceId":"dev1"}`)
+	assert.Equal(t, 1, len(f.Devices()))
+
+	assert.Nil(t, reg.Remove(context.Background()))
+	post(`{"notifyType":"deviceDataChanged","deviceId":"dev2","service":{"serviceId":"Meter","data":{"v":2}}}`)
+	assert.Equal(t, 1, len(got))
+	if d, ok := f.Cache.Get("dev2"); assert.True(t, ok) {
+		assert.JSONEq(t, `{"v":2}`, string(d.Services[1].Data))
+	}
+}
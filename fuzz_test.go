@@ -0,0 +1,78 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func FuzzServerHandler(f *testing.F) {
+	f.Add([]byte(benchNotification))
+	f.Add([]byte(`{"notifyType":"commandRsp","header":{"requestId":"r1","deviceId":"dev1"},"body":{"result":"ok"}}`))
+	f.Add([]byte(`{"deviceId":"dev1","commandId":"c1","result":{"resultCode":"DELIVERED","resultDetail":{"progress":40}}}`))
+	f.Add([]byte(`{"notifyType":"messageConfirm","header":{"requestId":"r1"},"body":{"status":"DELIVERED"}}`))
+	f.Add([]byte(`[]`))
+	f.Add([]byte(`null`))
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		s := &Server{}
+		s.Scales = NewScaleRegistry()
+		s.Scales.Register("Temperature", "temperature", Scale{Factor: 0.1, Unit: "°C"})
+		for _, not := range []Notification{
+			NotificationDeviceDataChanged,
+			NotificationCommandResponse,
+			NotificationCommandStatus,
+			NotificationMessageConfirm,
+		} {
+			s.RegisterCallback(not, func(interface{}) error { return nil })
+		}
+		s.CommandProgress("c1")
+		s.TrackDelivery("r1")
+
+		r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		s.handler(httptest.NewRecorder(), r)
+	})
+}
+
+func FuzzDecodeDevice(f *testing.F) {
+	f.Add([]byte(`{"deviceId":"dev1","creationTime":"20170912T101530Z","services":[{"serviceId":"a","data":{"b":1}}]}`))
+	f.Add([]byte(`{"services":[{"data":null,"eventTime":""}]}`))
+
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		var d Device
+		StdCodec{}.Unmarshal(raw, &d)
+		var dd DeviceData
+		StdCodec{}.Unmarshal(raw, &dd)
+	})
+}
+
+func FuzzOcTime(f *testing.F) {
+	f.Add("20170912T101530Z")
+	f.Add("20170912T101530+02:00")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		tm, err := ParseOcTime(s)
+		if err != nil {
+			return
+		}
+		if _, err := ParseOcTime(FormatOcTime(tm)); err != nil && tm.Year() >= 0 && tm.Year() <= 9999 {
+			t.Errorf("formatted time %q can't be parsed: %v", FormatOcTime(tm), err)
+		}
+	})
+}
+
+func FuzzScaleNormalize(f *testing.F) {
+	f.Add([]byte(`{"temperature":215}`))
+	f.Add([]byte(`null`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := NewScaleRegistry()
+		r.Register("Temperature", "temperature", Scale{Factor: 0.1})
+		r.Normalize("Temperature", data)
+	})
+}
@@ -0,0 +1,167 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// GroupCache keeps the device groups and the group memberships of the
+// devices in memory
+type GroupCache struct {
+	client *Client
+
+	lock    sync.RWMutex
+	tree    *GroupTree
+	devices map[string][]string // deviceID -> IDs of the groups it is directly in
+}
+
+// NewGroupCache creates an empty cache, call Refresh to fill it
+func NewGroupCache(c *Client) *GroupCache {
+	return &GroupCache{client: c, tree: NewGroupTree(nil), devices: make(map[string][]string)}
+}
+
+// Refresh retrieves the groups of the application and their devices
+func (gc *GroupCache) Refresh() error {
+	return gc.RefreshCtx(context.Background())
+}
+
+// RefreshCtx is like Refresh but with a context
+func (gc *GroupCache) RefreshCtx(ctx context.Context) error {
+	groups, err := gc.client.ListDeviceGroupsCtx(ctx)
+	if err != nil {
+		return err
+	}
+	devices := make(map[string][]string)
+	for _, g := range groups {
+		ids, err := gc.client.GroupDeviceIDsCtx(ctx, g.ID)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			devices[id] = append(devices[id], g.ID)
+		}
+	}
+
+	gc.lock.Lock()
+	gc.tree = NewGroupTree(groups)
+	gc.devices = devices
+	gc.lock.Unlock()
+	return nil
+}
+
+// Groups returns the IDs of the groups the device is directly in
+func (gc *GroupCache) Groups(deviceID string) []string {
+	gc.lock.RLock()
+	defer gc.lock.RUnlock()
+	return append([]string(nil), gc.devices[deviceID]...)
+}
+
+// InGroup reports whether the device is in the group or in one of its
+// subgroups
+func (gc *GroupCache) InGroup(deviceID, groupID string) bool {
+	gc.lock.RLock()
+	defer gc.lock.RUnlock()
+
+	for _, id := range gc.devices[deviceID] {
+		// walk up to the root, the visited set guards against cycles
+		visited := make(map[string]bool)
+		for id != "" && !visited[id] {
+			if id == groupID {
+				return true
+			}
+			visited[id] = true
+			g, ok := gc.tree.Group(id)
+			if !ok {
+				break
+			}
+			id = g.ParentID
+		}
+	}
+	return false
+}
+
+// GroupRouter routes notifications to a Dispatcher per device group, so the
+// pipelines of several teams can be served by one notification server with
+// their own callbacks and middleware. A device in several routed groups is
+// dispatched to all of them, each dispatcher scales and decodes the service
+// data into its own copy of the notification.
+type GroupRouter struct {
+	// Default receives the notifications of devices which aren't in a routed
+	// group, they are dropped when nil
+	Default *Dispatcher
+
+	groups *GroupCache
+	lock   sync.RWMutex
+	routes []groupRoute
+}
+
+type groupRoute struct {
+	groupID string
+	d       *Dispatcher
+}
+
+// NewGroupRouter creates a router resolving the group memberships with gc
+func NewGroupRouter(gc *GroupCache) *GroupRouter {
+	return &GroupRouter{groups: gc}
+}
+
+// Route returns the dispatcher of the group, the notifications of the
+// devices in the group and its subgroups are dispatched to it
+func (r *GroupRouter) Route(groupID string) *Dispatcher {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, rt := range r.routes {
+		if rt.groupID == groupID {
+			return rt.d
+		}
+	}
+	d := &Dispatcher{}
+	r.routes = append(r.routes, groupRoute{groupID: groupID, d: d})
+	return d
+}
+
+// Attach registers the router as callback of the notification types on d,
+// e.g. the notification Server
+func (r *GroupRouter) Attach(d *Dispatcher, nots ...Notification) {
+	for _, not := range nots {
+		not := not
+		d.RegisterCallback(not, func(v interface{}) error {
+			return r.Dispatch(not, v)
+		})
+	}
+}
+
+// Dispatch dispatches the notification to the dispatchers of the groups of
+// its device. The first error of the dispatchers is returned.
+func (r *GroupRouter) Dispatch(not Notification, v interface{}) error {
+	deviceID := notificationDeviceID(v)
+	r.lock.RLock()
+	var ds []*Dispatcher
+	for _, rt := range r.routes {
+		if deviceID != "" && r.groups.InGroup(deviceID, rt.groupID) {
+			ds = append(ds, rt.d)
+		}
+	}
+	r.lock.RUnlock()
+
+	if len(ds) == 0 {
+		if r.Default == nil {
+			logrus.Debugf("no group route for %s of device %s", not, deviceID)
+			return nil
+		}
+		ds = append(ds, r.Default)
+	}
+	var first error
+	for _, d := range ds {
+		if err := d.Dispatch(not, v); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
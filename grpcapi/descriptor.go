@@ -0,0 +1,134 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package grpcapi
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// The descriptor of oceanconnect.proto is built here instead of generated, so
+// the package builds without protoc. Keep both in sync.
+
+const protoPackage = "oceanconnect.v1"
+
+// ServiceName is the full name of the gRPC service
+const ServiceName = protoPackage + ".OceanConnect"
+
+type fieldDef struct {
+	name     string
+	typ      descriptorpb.FieldDescriptorProto_Type
+	message  string // message type name for TYPE_MESSAGE
+	repeated bool
+}
+
+var (
+	tString  = descriptorpb.FieldDescriptorProto_TYPE_STRING
+	tInt32   = descriptorpb.FieldDescriptorProto_TYPE_INT32
+	tInt64   = descriptorpb.FieldDescriptorProto_TYPE_INT64
+	tUint32  = descriptorpb.FieldDescriptorProto_TYPE_UINT32
+	tMessage = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+)
+
+// messages in field number order, as in oceanconnect.proto
+var messages = []struct {
+	name   string
+	fields []fieldDef
+}{
+	{"Empty", nil},
+	{"Service", []fieldDef{{"service_id", tString, "", false}, {"service_type", tString, "", false}, {"data_json", tString, "", false}, {"event_time", tInt64, "", false}}},
+	{"Device", []fieldDef{
+		{"device_id", tString, "", false}, {"gateway_id", tString, "", false}, {"node_type", tString, "", false},
+		{"name", tString, "", false}, {"manufacturer_name", tString, "", false}, {"device_type", tString, "", false},
+		{"model", tString, "", false}, {"fw_version", tString, "", false}, {"status", tString, "", false},
+		{"services", tMessage, "Service", true},
+	}},
+	{"GetDeviceRequest", []fieldDef{{"device_id", tString, "", false}}},
+	{"ListDevicesRequest", []fieldDef{{"page_no", tInt32, "", false}, {"page_size", tInt32, "", false}, {"status", tString, "", false}, {"gateway_id", tString, "", false}}},
+	{"ListDevicesResponse", []fieldDef{{"devices", tMessage, "Device", true}}},
+	{"RegisterDeviceRequest", []fieldDef{{"imei", tString, "", false}, {"timeout", tUint32, "", false}}},
+	{"RegisterDeviceResponse", []fieldDef{{"device_id", tString, "", false}, {"verify_code", tString, "", false}, {"timeout", tUint32, "", false}, {"psk", tString, "", false}, {"error", tString, "", false}}},
+	{"UpdateDeviceRequest", []fieldDef{{"device_id", tString, "", false}, {"name", tString, "", false}, {"protocol_type", tString, "", false}}},
+	{"DeleteDeviceRequest", []fieldDef{{"device_id", tString, "", false}}},
+	{"SendCommandRequest", []fieldDef{{"device_id", tString, "", false}, {"service_id", tString, "", false}, {"method", tString, "", false}, {"params_json", tString, "", false}, {"expire_seconds", tInt64, "", false}}},
+	{"SubscribeRequest", []fieldDef{{"callback_url", tString, "", false}}},
+	{"WatchDeviceRequest", []fieldDef{{"device_id", tString, "", false}}},
+	{"Event", []fieldDef{{"type", tString, "", false}, {"device_id", tString, "", false}, {"data_json", tString, "", false}}},
+}
+
+// methods of the service, as in oceanconnect.proto
+var methods = []struct {
+	name, input, output string
+	stream              bool
+}{
+	{"GetDevice", "GetDeviceRequest", "Device", false},
+	{"ListDevices", "ListDevicesRequest", "ListDevicesResponse", false},
+	{"RegisterDevice", "RegisterDeviceRequest", "RegisterDeviceResponse", false},
+	{"UpdateDevice", "UpdateDeviceRequest", "Empty", false},
+	{"DeleteDevice", "DeleteDeviceRequest", "Empty", false},
+	{"SendCommand", "SendCommandRequest", "Empty", false},
+	{"Subscribe", "SubscribeRequest", "Empty", false},
+	{"WatchDevice", "WatchDeviceRequest", "Event", true},
+}
+
+// fileDescriptor is the descriptor of oceanconnect.proto
+var fileDescriptor = buildFile()
+
+func buildFile() protoreflect.FileDescriptor {
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("oceanconnect.proto"),
+		Package: proto.String(protoPackage),
+		Syntax:  proto.String("proto3"),
+		Options: &descriptorpb.FileOptions{GoPackage: proto.String("github.com/dualinventive/go-oceanconnect/grpcapi")},
+	}
+	for _, m := range messages {
+		dp := &descriptorpb.DescriptorProto{Name: proto.String(m.name)}
+		for i, f := range m.fields {
+			label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+			if f.repeated {
+				label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+			}
+			fp := &descriptorpb.FieldDescriptorProto{
+				Name:   proto.String(f.name),
+				Number: proto.Int32(int32(i + 1)),
+				Label:  label.Enum(),
+				Type:   f.typ.Enum(),
+			}
+			if f.message != "" {
+				fp.TypeName = proto.String("." + protoPackage + "." + f.message)
+			}
+			dp.Field = append(dp.Field, fp)
+		}
+		fdp.MessageType = append(fdp.MessageType, dp)
+	}
+	sp := &descriptorpb.ServiceDescriptorProto{Name: proto.String("OceanConnect")}
+	for _, m := range methods {
+		mp := &descriptorpb.MethodDescriptorProto{
+			Name:       proto.String(m.name),
+			InputType:  proto.String("." + protoPackage + "." + m.input),
+			OutputType: proto.String("." + protoPackage + "." + m.output),
+		}
+		if m.stream {
+			mp.ServerStreaming = proto.Bool(true)
+		}
+		sp.Method = append(sp.Method, mp)
+	}
+	fdp.Service = append(fdp.Service, sp)
+
+	fd, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	if err != nil {
+		panic("grpcapi: invalid descriptor: " + err.Error())
+	}
+	return fd
+}
+
+// MessageDescriptor returns the descriptor of a message of the service, e.g.
+// for clients using dynamic messages
+func MessageDescriptor(name string) protoreflect.MessageDescriptor {
+	return fileDescriptor.Messages().ByName(protoreflect.Name(name))
+}
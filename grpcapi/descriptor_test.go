@@ -0,0 +1,87 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package grpcapi
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var (
+	protoMessage = regexp.MustCompile(`^message (\w+) \{`)
+	protoField   = regexp.MustCompile(`^(repeated )?(\w+) (\w+) = (\d+);$`)
+	protoRPC     = regexp.MustCompile(`^rpc (\w+)\((\w+)\) returns \((stream )?(\w+)\);$`)
+)
+
+// parseProto lists the messages, fields and methods of oceanconnect.proto,
+// in the format of describeFile
+func parseProto(src string) []string {
+	var out []string
+	var msg string
+	for _, l := range strings.Split(src, "\n") {
+		if i := strings.Index(l, "//"); i >= 0 {
+			l = l[:i]
+		}
+		l = strings.TrimSpace(l)
+		if m := protoMessage.FindStringSubmatch(l); m != nil {
+			msg = m[1]
+			out = append(out, "message "+msg)
+		} else if m := protoField.FindStringSubmatch(l); m != nil {
+			out = append(out, fmt.Sprintf("field %s.%s %s%s = %s", msg, m[3], m[1], m[2], m[4]))
+		} else if m := protoRPC.FindStringSubmatch(l); m != nil {
+			out = append(out, fmt.Sprintf("rpc %s(%s) returns (%s%s)", m[1], m[2], m[3], m[4]))
+		}
+	}
+	return out
+}
+
+// describeFile lists the messages, fields and methods of the descriptor
+func describeFile(fd protoreflect.FileDescriptor) []string {
+	var out []string
+	for i := 0; i < fd.Messages().Len(); i++ {
+		m := fd.Messages().Get(i)
+		out = append(out, fmt.Sprintf("message %s", m.Name()))
+		for j := 0; j < m.Fields().Len(); j++ {
+			f := m.Fields().Get(j)
+			typ := f.Kind().String()
+			if f.Kind() == protoreflect.MessageKind {
+				typ = string(f.Message().Name())
+			}
+			if f.Cardinality() == protoreflect.Repeated {
+				typ = "repeated " + typ
+			}
+			out = append(out, fmt.Sprintf("field %s.%s %s = %d", m.Name(), f.Name(), typ, f.Number()))
+		}
+	}
+	svc := fd.Services().Get(0)
+	for i := 0; i < svc.Methods().Len(); i++ {
+		m := svc.Methods().Get(i)
+		stream := ""
+		if m.IsStreamingServer() {
+			stream = "stream "
+		}
+		out = append(out, fmt.Sprintf("rpc %s(%s) returns (%s%s)", m.Name(), m.Input().Name(), stream, m.Output().Name()))
+	}
+	return out
+}
+
+func TestDescriptorMatchesProto(t *testing.T) {
+	src, err := ioutil.ReadFile("oceanconnect.proto")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 1, fileDescriptor.Services().Len())
+	want, have := parseProto(string(src)), describeFile(fileDescriptor)
+	sort.Strings(want)
+	sort.Strings(have)
+	assert.Equal(t, want, have)
+}
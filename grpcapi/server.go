@@ -0,0 +1,257 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package grpcapi exposes an OceanConnect client as gRPC service, see
+// oceanconnect.proto for the service definition.
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/dualinventive/go-oceanconnect"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Server implements the OceanConnect gRPC service with a client
+type Server struct {
+	client *oceanconnect.Client
+}
+
+// NewServer creates the service for the client. Events are streamed from the
+// client's watchers, so run the Server returned by Subscribe or a FleetPoller
+// next to it.
+func NewServer(c *oceanconnect.Client) *Server {
+	return &Server{client: c}
+}
+
+// Register registers the service with the gRPC server
+func (s *Server) Register(g *grpc.Server) {
+	g.RegisterService(&grpc.ServiceDesc{
+		ServiceName: ServiceName,
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{
+			unary("GetDevice", "GetDeviceRequest", s.getDevice),
+			unary("ListDevices", "ListDevicesRequest", s.listDevices),
+			unary("RegisterDevice", "RegisterDeviceRequest", s.registerDevice),
+			unary("UpdateDevice", "UpdateDeviceRequest", s.updateDevice),
+			unary("DeleteDevice", "DeleteDeviceRequest", s.deleteDevice),
+			unary("SendCommand", "SendCommandRequest", s.sendCommand),
+			unary("Subscribe", "SubscribeRequest", s.subscribe),
+		},
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "WatchDevice",
+			Handler:       s.watchDevice,
+			ServerStreams: true,
+		}},
+		Metadata: "oceanconnect.proto",
+	}, s)
+}
+
+type unaryFunc func(ctx context.Context, req *dynamicpb.Message) (proto.Message, error)
+
+func unary(name, input string, fn unaryFunc) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, ic grpc.UnaryServerInterceptor) (interface{}, error) {
+			req := NewMessage(input)
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			h := func(ctx context.Context, r interface{}) (interface{}, error) {
+				resp, err := fn(ctx, r.(*dynamicpb.Message))
+				if err != nil {
+					return nil, statusError(err)
+				}
+				return resp, nil
+			}
+			if ic == nil {
+				return h(ctx, req)
+			}
+			return ic(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/" + name}, h)
+		},
+	}
+}
+
+// statusError maps client errors to gRPC status codes
+func statusError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	switch {
+	case oceanconnect.IsNotFound(err):
+		return status.Error(codes.NotFound, err.Error())
+	case oceanconnect.IsUnauthorized(err):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case oceanconnect.IsRateLimited(err):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, oceanconnect.ErrReadOnly):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	}
+	var apiErr *oceanconnect.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode < 500 {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
+
+func (s *Server) getDevice(ctx context.Context, req *dynamicpb.Message) (proto.Message, error) {
+	d, err := s.client.GetDeviceCtx(ctx, getString(req, "device_id"))
+	if err != nil {
+		return nil, err
+	}
+	return deviceMessage(d), nil
+}
+
+func (s *Server) listDevices(ctx context.Context, req *dynamicpb.Message) (proto.Message, error) {
+	devs, err := s.client.GetDevicesCtx(ctx, oceanconnect.GetDevicesStruct{
+		PageNo:    int(req.Get(field(req, "page_no")).Int()),
+		PageSize:  int(req.Get(field(req, "page_size")).Int()),
+		Status:    oceanconnect.DeviceStatus(getString(req, "status")),
+		GatewayID: getString(req, "gateway_id"),
+	})
+	if _, ok := err.(oceanconnect.DecodeErrors); err != nil && !ok {
+		return nil, err
+	}
+	resp := NewMessage("ListDevicesResponse")
+	list := resp.Mutable(field(resp, "devices")).List()
+	for i := range devs {
+		list.Append(protoreflect.ValueOfMessage(deviceMessage(&devs[i])))
+	}
+	return resp, nil
+}
+
+func (s *Server) registerDevice(ctx context.Context, req *dynamicpb.Message) (proto.Message, error) {
+	r, err := s.client.RegisterDeviceCtx(ctx, getString(req, "imei"), uint(req.Get(field(req, "timeout")).Uint()))
+	if r == nil {
+		return nil, err
+	}
+	// a registered device is returned even when naming it failed, gRPC drops
+	// the response of a failed call so the error goes in the response
+	resp := NewMessage("RegisterDeviceResponse")
+	set(resp, "device_id", r.DeviceID)
+	set(resp, "verify_code", r.VerifyCode)
+	set(resp, "timeout", uint32(r.Timeout))
+	set(resp, "psk", r.Psk)
+	if err != nil {
+		set(resp, "error", err.Error())
+	}
+	return resp, nil
+}
+
+func (s *Server) updateDevice(ctx context.Context, req *dynamicpb.Message) (proto.Message, error) {
+	var opts []oceanconnect.DeviceInfoOptions
+	if p := getString(req, "protocol_type"); p != "" {
+		opts = append(opts, oceanconnect.DeviceInfoOptions{ProtocolType: oceanconnect.ProtocolType(p)})
+	}
+	if err := s.client.SetDeviceInfoCtx(ctx, getString(req, "device_id"), getString(req, "name"), opts...); err != nil {
+		return nil, err
+	}
+	return NewMessage("Empty"), nil
+}
+
+func (s *Server) deleteDevice(ctx context.Context, req *dynamicpb.Message) (proto.Message, error) {
+	if err := s.client.DeleteDeviceCtx(ctx, getString(req, "device_id")); err != nil {
+		return nil, err
+	}
+	return NewMessage("Empty"), nil
+}
+
+func (s *Server) sendCommand(ctx context.Context, req *dynamicpb.Message) (proto.Message, error) {
+	var params interface{}
+	if p := getString(req, "params_json"); p != "" {
+		if err := json.Unmarshal([]byte(p), &params); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid params_json: "+err.Error())
+		}
+	}
+	err := s.client.SendCommandWithOptionsCtx(ctx, getString(req, "device_id"), getString(req, "service_id"), getString(req, "method"), params, oceanconnect.CommandOptions{
+		ExpireTime: time.Duration(req.Get(field(req, "expire_seconds")).Int()) * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewMessage("Empty"), nil
+}
+
+func (s *Server) subscribe(ctx context.Context, req *dynamicpb.Message) (proto.Message, error) {
+	if _, err := s.client.SubscribeCtx(ctx, getString(req, "callback_url")); err != nil {
+		return nil, err
+	}
+	return NewMessage("Empty"), nil
+}
+
+func (s *Server) watchDevice(srv interface{}, stream grpc.ServerStream) error {
+	req := NewMessage("WatchDeviceRequest")
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+	for ev := range s.client.WatchDevice(stream.Context(), getString(req, "device_id")) {
+		data, err := json.Marshal(ev.Data)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		m := NewMessage("Event")
+		set(m, "type", string(ev.Type))
+		set(m, "device_id", ev.DeviceID)
+		set(m, "data_json", string(data))
+		if err := stream.SendMsg(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deviceMessage(d *oceanconnect.Device) *dynamicpb.Message {
+	m := NewMessage("Device")
+	set(m, "device_id", d.DeviceID)
+	set(m, "gateway_id", d.GatewayID)
+	set(m, "node_type", string(d.NodeType))
+	set(m, "name", d.DeviceInfo.Name)
+	set(m, "manufacturer_name", d.DeviceInfo.ManufacturerName)
+	set(m, "device_type", d.DeviceInfo.DeviceType)
+	set(m, "model", d.DeviceInfo.Model)
+	set(m, "fw_version", d.DeviceInfo.FwVersion)
+	set(m, "status", string(d.DeviceInfo.Status))
+	list := m.Mutable(field(m, "services")).List()
+	for _, svc := range d.Services {
+		sm := NewMessage("Service")
+		set(sm, "service_id", svc.ServiceID)
+		set(sm, "service_type", svc.ServiceType)
+		set(sm, "data_json", string(svc.Data))
+		if !svc.EventTime.IsZero() {
+			set(sm, "event_time", svc.EventTime.Unix())
+		}
+		list.Append(protoreflect.ValueOfMessage(sm))
+	}
+	return m
+}
+
+// NewMessage creates an empty message of the service, e.g. for clients using
+// dynamic messages
+func NewMessage(name string) *dynamicpb.Message {
+	return dynamicpb.NewMessage(MessageDescriptor(name))
+}
+
+func field(m *dynamicpb.Message, name string) protoreflect.FieldDescriptor {
+	return m.Descriptor().Fields().ByName(protoreflect.Name(name))
+}
+
+func set(m *dynamicpb.Message, name string, v interface{}) {
+	m.Set(field(m, name), protoreflect.ValueOf(v))
+}
+
+func getString(m *dynamicpb.Message, name string) string {
+	return m.Get(field(m, name)).String()
+}
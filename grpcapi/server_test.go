@@ -0,0 +1,135 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dualinventive/go-oceanconnect"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dial serves the service for the client and connects to it, the server
+// stops when the test ends
+func dial(t *testing.T, c *oceanconnect.Client) *grpc.ClientConn {
+	lis := bufconn.Listen(1 << 20)
+	g := grpc.NewServer()
+	NewServer(c).Register(g)
+	go g.Serve(lis)
+	t.Cleanup(g.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Nil(t, err)
+	return conn
+}
+
+func TestServer(t *testing.T) {
+	platform := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/login"):
+			fmt.Fprint(w, `{"accessToken":"token","tokenType":"bearer","expiresIn":3600}`)
+		case r.URL.Path == "/iocm/app/dm/v1.1.0/devices/dev1":
+			fmt.Fprint(w, `{"deviceId":"dev1","nodeType":"ENDPOINT","deviceInfo":{"name":"meter","status":"ONLINE"},
+				"services":[{"serviceId":"Meter","data":{"value":3}}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error_code":"100403","error_desc":"device not exist"}`)
+		}
+	}))
+	defer platform.Close()
+
+	c, err := oceanconnect.NewClient(oceanconnect.Config{URL: platform.URL, AppID: "app"})
+	assert.Nil(t, err)
+
+	conn := dial(t, c)
+	defer conn.Close()
+
+	req := NewMessage("GetDeviceRequest")
+	set(req, "device_id", "dev1")
+	resp := NewMessage("Device")
+	err = conn.Invoke(context.Background(), "/"+ServiceName+"/GetDevice", req, resp)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "meter", getString(resp, "name"))
+		assert.Equal(t, "ONLINE", getString(resp, "status"))
+		services := resp.Get(field(resp, "services")).List()
+		if assert.Equal(t, 1, services.Len()) {
+			assert.Equal(t, `{"value":3}`, services.Get(0).Message().Get(field(NewMessage("Service"), "data_json")).String())
+		}
+	}
+
+	set(req, "device_id", "dev2")
+	err = conn.Invoke(context.Background(), "/"+ServiceName+"/GetDevice", req, NewMessage("Device"))
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	cmd := NewMessage("SendCommandRequest")
+	set(cmd, "device_id", "dev1")
+	set(cmd, "params_json", "{")
+	err = conn.Invoke(context.Background(), "/"+ServiceName+"/SendCommand", cmd, NewMessage("Empty"))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServerDevices(t *testing.T) {
+	var named string
+	platform := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/login"):
+			fmt.Fprint(w, `{"accessToken":"token","tokenType":"bearer","expiresIn":3600}`)
+		case r.URL.Path == "/iocm/app/reg/v1.2.0/devices":
+			fmt.Fprint(w, `{"deviceId":"dev1","verifyCode":"123456789012345","timeout":180,"psk":"secret"}`)
+		case r.URL.Path == "/iocm/app/dm/v1.2.0/devices/dev1" && r.Method == http.MethodPut:
+			if named == "" {
+				named = "failed"
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			b, _ := ioutil.ReadAll(r.Body)
+			named = string(b)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer platform.Close()
+
+	c, err := oceanconnect.NewClient(oceanconnect.Config{URL: platform.URL, AppID: "app", NameTemplate: "meter-{{.IMEI}}"})
+	assert.Nil(t, err)
+	conn := dial(t, c)
+	defer conn.Close()
+
+	// the credentials of the registered device are returned when naming fails
+	req := NewMessage("RegisterDeviceRequest")
+	set(req, "imei", "123456789012345")
+	resp := NewMessage("RegisterDeviceResponse")
+	err = conn.Invoke(context.Background(), "/"+ServiceName+"/RegisterDevice", req, resp)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "dev1", getString(resp, "device_id"))
+		assert.Equal(t, "secret", getString(resp, "psk"))
+		assert.NotEmpty(t, getString(resp, "error"))
+	}
+
+	upd := NewMessage("UpdateDeviceRequest")
+	set(upd, "device_id", "dev1")
+	set(upd, "name", "meter-1")
+	set(upd, "protocol_type", "LWM2M")
+	err = conn.Invoke(context.Background(), "/"+ServiceName+"/UpdateDevice", upd, NewMessage("Empty"))
+	if assert.Nil(t, err) {
+		assert.Contains(t, named, `"name":"meter-1"`)
+		assert.Contains(t, named, `"protocolType":"LWM2M"`)
+	}
+}
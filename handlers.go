@@ -0,0 +1,206 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// NotificationFilter decides whether a notification is passed to the
+// callbacks and handlers, notifications it returns false for are dropped
+type NotificationFilter func(not Notification, v interface{}) bool
+
+// Registration is a handler or filter added to a Dispatcher, it can be added
+// and removed while notifications are dispatched
+type Registration struct {
+	d      *Dispatcher
+	not    Notification
+	fn     NotificationFunc
+	filter NotificationFilter
+	// group holds the registrations of addHandlers, which are removed
+	// together
+	group []*Registration
+
+	// inflight counts the running calls, it is only added to while the
+	// registration is in the Dispatcher
+	inflight sync.WaitGroup
+	once     sync.Once
+}
+
+// AddHandler adds a handler for the notification type. Unlike
+// RegisterCallback the handlers of a type don't replace each other, all are
+// called in the order they were added, after the registered callback. The
+// handlers are wrapped by the middleware like the callbacks.
+func (d *Dispatcher) AddHandler(not Notification, fn NotificationFunc) *Registration {
+	r := &Registration{d: d, not: not, fn: fn}
+	d.cbsLock.Lock()
+	if d.handlers == nil {
+		d.handlers = make(map[Notification][]*Registration)
+	}
+	d.handlers[not] = append(d.handlers[not][:len(d.handlers[not]):len(d.handlers[not])], r)
+	d.cbsLock.Unlock()
+	return r
+}
+
+// addHandlers adds the handler for each of the notification types, removing
+// the returned registration removes all of them
+func (d *Dispatcher) addHandlers(fn NotificationFunc, nots ...Notification) *Registration {
+	r := &Registration{d: d}
+	for _, not := range nots {
+		r.group = append(r.group, d.AddHandler(not, fn))
+	}
+	return r
+}
+
+// AddFilter adds a filter which is run before the callbacks and handlers of
+// all notification types. Watchers, command progress and delivery tracking
+// still receive the filtered notifications.
+func (d *Dispatcher) AddFilter(f NotificationFilter) *Registration {
+	r := &Registration{d: d, filter: f}
+	d.cbsLock.Lock()
+	d.filters = append(d.filters[:len(d.filters):len(d.filters)], r)
+	d.cbsLock.Unlock()
+	return r
+}
+
+// UnregisterCallback removes the callback registered for the notification
+// type
+func (d *Dispatcher) UnregisterCallback(not Notification) {
+	d.cbsLock.Lock()
+	delete(d.cbs, not)
+	d.cbsLock.Unlock()
+}
+
+// Remove removes the handler or filter, it isn't called for notifications
+// dispatched afterwards. It then waits until the running calls are done, or
+// until the context is done. A handler removing itself must not wait for its
+// own call, it can use an already canceled context.
+func (r *Registration) Remove(ctx context.Context) error {
+	rs := r.group
+	if rs == nil {
+		rs = []*Registration{r}
+	}
+	for _, x := range rs {
+		x.once.Do(x.remove)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for _, x := range rs {
+			x.inflight.Wait()
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// remove deletes the registration from the Dispatcher, the slices are
+// copied so the snapshots of running dispatches stay valid
+func (r *Registration) remove() {
+	d := r.d
+	d.cbsLock.Lock()
+	defer d.cbsLock.Unlock()
+
+	if r.filter != nil {
+		d.filters = without(d.filters, r)
+		return
+	}
+	hs := without(d.handlers[r.not], r)
+	if len(hs) == 0 {
+		delete(d.handlers, r.not)
+		return
+	}
+	d.handlers[r.not] = hs
+}
+
+func without(rs []*Registration, r *Registration) []*Registration {
+	ret := make([]*Registration, 0, len(rs))
+	for _, x := range rs {
+		if x != r {
+			ret = append(ret, x)
+		}
+	}
+	return ret
+}
+
+// acquire returns the filters and the handlers of the notification type
+// wrapped by the middleware, the running calls are counted until release
+func (d *Dispatcher) acquire(not Notification) ([]*Registration, []*Registration, []NotificationFunc) {
+	d.cbsLock.RLock()
+	defer d.cbsLock.RUnlock()
+
+	filters := d.filters
+	handlers := d.handlers[not]
+	fns := make([]NotificationFunc, len(handlers))
+	for i, r := range handlers {
+		fns[i] = d.wrap(not, r.fn)
+	}
+	for _, r := range filters {
+		r.inflight.Add(1)
+	}
+	for _, r := range handlers {
+		r.inflight.Add(1)
+	}
+	return filters, handlers, fns
+}
+
+func release(rs []*Registration) {
+	for _, r := range rs {
+		r.inflight.Done()
+	}
+}
+
+// hasHandlers reports whether handlers are added for the notification type
+func (d *Dispatcher) hasHandlers(not Notification) bool {
+	d.cbsLock.RLock()
+	defer d.cbsLock.RUnlock()
+	return len(d.handlers[not]) > 0
+}
+
+// passes reports whether the notification passes the filters, the filters
+// are released also when one panics
+func passes(filters []*Registration, not Notification, v interface{}) bool {
+	defer release(filters)
+	for _, r := range filters {
+		if !r.filter(not, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// runHandlers runs the filters, then the registered callback and the
+// handlers of the notification type. All are called, the first error is
+// returned.
+func (d *Dispatcher) runHandlers(not Notification, v interface{}) error {
+	filters, handlers, fns := d.acquire(not)
+	defer release(handlers)
+
+	if !passes(filters, not, v) {
+		return nil
+	}
+
+	var first error
+	cb, ok := d.callback(not)
+	if ok {
+		first = cb(v)
+	} else if len(fns) == 0 {
+		logrus.Debugf("no callback registered for %s", string(not))
+	}
+	for _, fn := range fns {
+		if err := fn(v); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,100 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// InventoryReport is a snapshot of the fleet with device counts per model,
+// firmware version, status and region (the device location)
+type InventoryReport struct {
+	Generated  time.Time      `json:"generated"`
+	Total      int            `json:"total"`
+	ByModel    map[string]int `json:"byModel"`
+	ByFirmware map[string]int `json:"byFirmware"`
+	ByStatus   map[string]int `json:"byStatus"`
+	ByRegion   map[string]int `json:"byRegion"`
+}
+
+// FleetInventory retrieves all devices and returns the inventory report
+func (c *Client) FleetInventory() (*InventoryReport, error) {
+	return c.FleetInventoryCtx(context.Background())
+}
+
+// FleetInventoryCtx is like FleetInventory but with a context
+func (c *Client) FleetInventoryCtx(ctx context.Context) (*InventoryReport, error) {
+	devs, _, err := c.allDevices(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	return NewInventoryReport(devs, c.clock().Now()), nil
+}
+
+// NewInventoryReport creates the inventory report of the devices
+func NewInventoryReport(devs []Device, generated time.Time) *InventoryReport {
+	r := &InventoryReport{
+		Generated:  generated.UTC(),
+		Total:      len(devs),
+		ByModel:    make(map[string]int),
+		ByFirmware: make(map[string]int),
+		ByStatus:   make(map[string]int),
+		ByRegion:   make(map[string]int),
+	}
+	for _, d := range devs {
+		r.ByModel[inventoryKey(d.DeviceInfo.Model)]++
+		r.ByFirmware[inventoryKey(d.DeviceInfo.FwVersion)]++
+		r.ByStatus[inventoryKey(string(d.DeviceInfo.Status))]++
+		r.ByRegion[inventoryKey(d.DeviceInfo.Location)]++
+	}
+	return r
+}
+
+func inventoryKey(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
+
+// WriteJSON writes the report as JSON
+func (r *InventoryReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV writes the report as CSV with the columns category, value and count
+func (r *InventoryReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"category", "value", "count"})
+	cw.Write([]string{"total", "", strconv.Itoa(r.Total)})
+	for _, cat := range []struct {
+		name   string
+		counts map[string]int
+	}{
+		{"model", r.ByModel},
+		{"firmware", r.ByFirmware},
+		{"status", r.ByStatus},
+		{"region", r.ByRegion},
+	} {
+		keys := make([]string, 0, len(cat.counts))
+		for k := range cat.counts {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			cw.Write([]string{cat.name, k, strconv.Itoa(cat.counts[k])})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,82 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFleetInventory(t *testing.T) {
+	var pages []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		page := r.URL.Query().Get("pageNo")
+		pages = append(pages, page)
+		var devs []string
+		if page == "0" {
+			// a full page makes the report fetch the next one
+			for i := 0; i < 100; i++ {
+				fw := "1.0"
+				if i%4 == 0 {
+					fw = "1.1"
+				}
+				devs = append(devs, fmt.Sprintf(`{"deviceId":"dev%d","deviceInfo":{"model":"wm1","fwVersion":%q,"status":"ONLINE","location":"Breda"}}`, i, fw))
+			}
+		} else {
+			devs = append(devs, `{"deviceId":"dev100","deviceInfo":{"status":"OFFLINE"}}`)
+		}
+		fmt.Fprintf(w, `{"totalCount":101,"devices":[%s]}`, strings.Join(devs, ","))
+	}))
+	defer s.Close()
+
+	clock := &fakeClock{now: time.Date(2017, 9, 12, 10, 0, 0, 0, time.UTC)}
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL, Clock: clock}}
+	r, err := c.FleetInventory()
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"0", "1"}, pages)
+	assert.Equal(t, &InventoryReport{
+		Generated:  clock.now,
+		Total:      101,
+		ByModel:    map[string]int{"wm1": 100, "unknown": 1},
+		ByFirmware: map[string]int{"1.0": 75, "1.1": 25, "unknown": 1},
+		ByStatus:   map[string]int{"ONLINE": 100, "OFFLINE": 1},
+		ByRegion:   map[string]int{"Breda": 100, "unknown": 1},
+	}, r)
+
+	var buf bytes.Buffer
+	assert.Nil(t, r.WriteCSV(&buf))
+	assert.Equal(t, `category,value,count
+total,,101
+model,unknown,1
+model,wm1,100
+firmware,1.0,75
+firmware,1.1,25
+firmware,unknown,1
+status,OFFLINE,1
+status,ONLINE,100
+region,Breda,100
+region,unknown,1
+`, buf.String())
+
+	buf.Reset()
+	assert.Nil(t, r.WriteJSON(&buf))
+	var decoded InventoryReport
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, *r, decoded)
+}
@@ -0,0 +1,123 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// KeepAliveState is the state of the connection to the platform as seen by
+// the KeepAlive
+type KeepAliveState string
+
+const (
+	// KeepAliveUnknown is the state before the first ping
+	KeepAliveUnknown KeepAliveState = ""
+	// KeepAliveHealthy is used when the last ping succeeded
+	KeepAliveHealthy KeepAliveState = "healthy"
+	// KeepAliveUnauthorized is used when the platform refused the credentials
+	KeepAliveUnauthorized KeepAliveState = "unauthorized"
+	// KeepAliveUnreachable is used when the last ping failed otherwise
+	KeepAliveUnreachable KeepAliveState = "unreachable"
+)
+
+// defaultKeepAliveInterval is the interval of a KeepAlive without Interval
+const defaultKeepAliveInterval = time.Minute
+
+// KeepAlive periodically performs a cheap authenticated call, to keep idle
+// NAT and firewall sessions open and to detect credential or connectivity
+// problems before real traffic needs the platform
+type KeepAlive struct {
+	// Interval between pings (default 1m)
+	Interval time.Duration
+	// OnStateChange is called when the state changes, with the error of the
+	// ping which failed
+	OnStateChange func(state KeepAliveState, err error)
+
+	client *Client
+	lock   sync.Mutex
+	state  KeepAliveState
+}
+
+// NewKeepAlive creates a keep-alive pinging the platform at the interval
+func (c *Client) NewKeepAlive(interval time.Duration) *KeepAlive {
+	return &KeepAlive{Interval: interval, client: c}
+}
+
+// State returns the state after the last ping
+func (k *KeepAlive) State() KeepAliveState {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	return k.state
+}
+
+// Run pings until the context is done
+func (k *KeepAlive) Run(ctx context.Context) error {
+	interval := k.Interval
+	if interval <= 0 {
+		interval = defaultKeepAliveInterval
+	}
+	for {
+		next := k.client.clock().After(interval)
+		if err := k.PingCtx(ctx); err != nil {
+			logrus.Warnf("keep-alive ping failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-next:
+		}
+	}
+}
+
+// Ping retrieves a single device to check the connection and credentials
+func (k *KeepAlive) Ping() error {
+	return k.PingCtx(context.Background())
+}
+
+// PingCtx is like Ping but with a context
+func (k *KeepAlive) PingCtx(ctx context.Context) error {
+	err := k.ping(ctx)
+	state := KeepAliveHealthy
+	switch {
+	case err == nil:
+	case IsUnauthorized(err):
+		state = KeepAliveUnauthorized
+	case ctx.Err() != nil:
+		// the ping was canceled, it says nothing about the platform
+		return err
+	default:
+		state = KeepAliveUnreachable
+	}
+
+	k.lock.Lock()
+	changed := state != k.state
+	k.state = state
+	k.lock.Unlock()
+	if changed && k.OnStateChange != nil {
+		k.OnStateChange(state, err)
+	}
+	return err
+}
+
+func (k *KeepAlive) ping(ctx context.Context) error {
+	e := k.client.appEndpoint("/iocm/app/dm/v1.1.0/devices").
+		SetInt("pageNo", 0).
+		SetInt("pageSize", 1)
+	resp, err := k.client.requestCtx(ctx, http.MethodGet, e.String(), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return k.client.newAPIError(resp)
+	}
+	return nil
+}
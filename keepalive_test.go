@@ -0,0 +1,87 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeepAlive(t *testing.T) {
+	status := http.StatusOK
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		assert.Equal(t, "1", r.URL.Query().Get("pageSize"))
+		w.WriteHeader(status)
+		switch status {
+		case http.StatusOK:
+			fmt.Fprintln(w, `{"totalCount":1,"devices":[{"deviceId":"dev1"}]}`)
+		case http.StatusUnauthorized:
+			fmt.Fprintln(w, `{"error_code":"1010005","error_desc":"invalid access token"}`)
+		}
+	}))
+	defer s.Close()
+
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL}}
+	var states []KeepAliveState
+	k := c.NewKeepAlive(time.Minute)
+	k.OnStateChange = func(state KeepAliveState, err error) {
+		assert.Equal(t, state != KeepAliveHealthy, err != nil)
+		states = append(states, state)
+	}
+
+	assert.Equal(t, KeepAliveUnknown, k.State())
+	assert.Nil(t, k.Ping())
+	assert.Nil(t, k.Ping())
+	status = http.StatusUnauthorized
+	assert.NotNil(t, k.Ping())
+	status = http.StatusBadGateway
+	assert.NotNil(t, k.Ping())
+	assert.NotNil(t, k.Ping())
+	status = http.StatusOK
+	assert.Nil(t, k.Ping())
+
+	assert.Equal(t, []KeepAliveState{KeepAliveHealthy, KeepAliveUnauthorized, KeepAliveUnreachable, KeepAliveHealthy}, states)
+	assert.Equal(t, KeepAliveHealthy, k.State())
+}
+
+func TestKeepAliveDefaultInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var pings int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		if atomic.AddInt32(&pings, 1) == 3 {
+			cancel()
+		}
+		fmt.Fprintln(w, `{"totalCount":1,"devices":[{"deviceId":"dev1"}]}`)
+	}))
+	defer s.Close()
+
+	start := time.Date(2017, 9, 12, 10, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start}
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL, Clock: clock}}
+
+	// without an interval the pings are the default apart instead of
+	// following each other in a busy loop
+	k := c.NewKeepAlive(0)
+	assert.Equal(t, context.Canceled, k.Run(ctx))
+	waited := clock.now.Sub(start)
+	assert.True(t, waited >= 3*defaultKeepAliveInterval, "waited %v", waited)
+	assert.Equal(t, time.Duration(0), waited%defaultKeepAliveInterval)
+}
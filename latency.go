@@ -0,0 +1,146 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultLatencyWindow is the default number of samples kept per device type
+const defaultLatencyWindow = 1000
+
+// LatencyStats struct with the latency percentiles of a device type
+type LatencyStats struct {
+	Count int
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// LatencyRecorder measures the time between the eventTime reported by a
+// device and the arrival of the data at the notification server, per device
+// type, to monitor the performance of the NB-IoT network. Add its Middleware
+// to the Server.
+type LatencyRecorder struct {
+	// DeviceType returns the type of a device, e.g. from a DeviceCache. The
+	// samples of all devices are recorded as "" when nil.
+	DeviceType func(deviceID string) string
+	// Window is the number of most recent samples kept per device type
+	// (default 1000)
+	Window int
+	// Clock replaces the real time, for tests
+	Clock Clock
+
+	lock    sync.Mutex
+	samples map[string]*latencyWindow
+}
+
+// latencyWindow is a ring buffer with the most recent samples
+type latencyWindow struct {
+	samples []time.Duration
+	next    int
+	count   int
+}
+
+// DeviceType returns the device type of a cached device, for the
+// LatencyRecorder
+func (dc *DeviceCache) DeviceType(deviceID string) string {
+	d, ok := dc.Get(deviceID)
+	if !ok {
+		return ""
+	}
+	return d.DeviceInfo.DeviceType
+}
+
+// Middleware records the latency of the deviceDataChanged and
+// deviceDatasChanged notifications before calling the callback
+func (l *LatencyRecorder) Middleware(not Notification, next NotificationFunc) NotificationFunc {
+	return func(v interface{}) error {
+		switch n := v.(type) {
+		case *DeviceDataChanged:
+			l.Record(n.DeviceID, n.Service.EventTime.Time)
+		case *DeviceDatasChanged:
+			for _, s := range n.Services {
+				l.Record(n.DeviceID, s.EventTime.Time)
+			}
+		}
+		return next(v)
+	}
+}
+
+// Record records the latency of data of the device with the event time,
+// arriving now. Samples without event time are ignored.
+func (l *LatencyRecorder) Record(deviceID string, eventTime time.Time) {
+	if eventTime.IsZero() {
+		return
+	}
+	now := time.Now()
+	if l.Clock != nil {
+		now = l.Clock.Now()
+	}
+	d := now.Sub(eventTime)
+	if d < 0 {
+		// the clock of the device or platform is ahead
+		d = 0
+	}
+	typ := ""
+	if l.DeviceType != nil {
+		typ = l.DeviceType(deviceID)
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.samples == nil {
+		l.samples = make(map[string]*latencyWindow)
+	}
+	w, ok := l.samples[typ]
+	if !ok {
+		size := l.Window
+		if size <= 0 {
+			size = defaultLatencyWindow
+		}
+		w = &latencyWindow{samples: make([]time.Duration, size)}
+		l.samples[typ] = w
+	}
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % len(w.samples)
+	if w.count < len(w.samples) {
+		w.count++
+	}
+}
+
+// Stats returns the latency percentiles of the samples in the window per
+// device type
+func (l *LatencyRecorder) Stats() map[string]LatencyStats {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	stats := make(map[string]LatencyStats, len(l.samples))
+	for typ, w := range l.samples {
+		s := append([]time.Duration(nil), w.samples[:w.count]...)
+		sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+		stats[typ] = LatencyStats{
+			Count: len(s),
+			P50:   percentile(s, 50),
+			P90:   percentile(s, 90),
+			P99:   percentile(s, 99),
+			Max:   s[len(s)-1],
+		}
+	}
+	return stats
+}
+
+// percentile returns the nearest rank percentile of the sorted samples
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,44 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyRecorder(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2017, 9, 12, 10, 0, 0, 0, time.UTC)}
+	l := &LatencyRecorder{
+		Window: 100,
+		Clock:  clock,
+		DeviceType: func(deviceID string) string {
+			if deviceID == "meter" {
+				return "WaterMeter"
+			}
+			return "Tracker"
+		},
+	}
+
+	d := &Dispatcher{}
+	d.Use(l.Middleware)
+	d.RegisterCallback(NotificationDeviceDataChanged, func(interface{}) error { return nil })
+
+	// 200 samples of 1..200 seconds, the window keeps 101..200
+	for i := 1; i <= 200; i++ {
+		d.Dispatch(NotificationDeviceDataChanged, &DeviceDataChanged{
+			DeviceID: "meter",
+			Service:  Service{EventTime: OcTime{clock.now.Add(-time.Duration(i) * time.Second)}},
+		})
+	}
+	l.Record("tracker", clock.now.Add(-time.Minute))
+	l.Record("tracker", time.Time{})
+
+	stats := l.Stats()
+	assert.Equal(t, LatencyStats{Count: 100, P50: 150 * time.Second, P90: 190 * time.Second, P99: 199 * time.Second, Max: 200 * time.Second}, stats["WaterMeter"])
+	assert.Equal(t, LatencyStats{Count: 1, P50: time.Minute, P90: time.Minute, P99: time.Minute, Max: time.Minute}, stats["Tracker"])
+}
@@ -0,0 +1,142 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RequestLog describes a request sent to the platform, every attempt of a
+// retried request is logged separately
+type RequestLog struct {
+	Method string
+	Path   string
+	// Status is the status code of the response, 0 when the request failed
+	Status int
+	// Latency is the time between sending the request and receiving the
+	// response headers
+	Latency time.Duration
+	// Attempt counts the attempts of a retried request, starting at 1
+	Attempt int
+	// RequestID is the X-Request-Id of the response, if returned
+	RequestID string
+	// Err is the error of a failed request
+	Err error
+	// RequestBody and ResponseBody are only set when Config.LogBodies is
+	// enabled
+	RequestBody  []byte
+	ResponseBody []byte
+}
+
+// Logger receives a RequestLog of every request sent to the platform
+type Logger interface {
+	LogRequest(RequestLog)
+}
+
+// LoggerFunc is a function implementing the Logger interface
+type LoggerFunc func(RequestLog)
+
+// LogRequest calls fn
+func (fn LoggerFunc) LogRequest(l RequestLog) {
+	fn(l)
+}
+
+// LogrusLogger logs the requests at debug level with logrus, it is the Logger
+// of clients without one configured
+var LogrusLogger Logger = LoggerFunc(func(l RequestLog) {
+	if !logrus.IsLevelEnabled(logrus.DebugLevel) {
+		return
+	}
+	fields := logrus.Fields{
+		"method":  l.Method,
+		"path":    l.Path,
+		"status":  l.Status,
+		"latency": l.Latency,
+	}
+	if l.Attempt > 1 {
+		fields["attempt"] = l.Attempt
+	}
+	if l.RequestID != "" {
+		fields["request_id"] = l.RequestID
+	}
+	if l.RequestBody != nil {
+		fields["request_body"] = string(l.RequestBody)
+	}
+	if l.ResponseBody != nil {
+		fields["response_body"] = string(l.ResponseBody)
+	}
+	if l.Err != nil {
+		logrus.WithFields(fields).Debugf("request failed: %v", l.Err)
+		return
+	}
+	logrus.WithFields(fields).Debug("request")
+})
+
+// requestIDHeader is the response header with the ID the platform assigned
+// to the request, Huawei support asks for it when investigating failed calls
+const requestIDHeader = "X-Request-Id"
+
+// responseRequestID returns the request ID of the response, if returned
+func responseRequestID(resp *http.Response) string {
+	if resp == nil {
+		return ""
+	}
+	return resp.Header.Get(requestIDHeader)
+}
+
+// logger returns the logger of the requests
+func (c *Client) logger() Logger {
+	if c.cfg.Logger != nil {
+		return c.cfg.Logger
+	}
+	return LogrusLogger
+}
+
+// logRequest logs an attempt of the request and reports it to the metrics,
+// the response body is read and replaced when the bodies are logged. The
+// bodies of logins and registrations are never logged, they hold the secret,
+// tokens and PSKs.
+func (c *Client) logRequest(req *http.Request, attempt int, start time.Time, resp *http.Response, err error) {
+	l := RequestLog{
+		Method:  req.Method,
+		Path:    req.URL.Path,
+		Latency: c.clock().Now().Sub(start),
+		Attempt: attempt,
+		Err:     err,
+	}
+	c.observeRequest(req, l.Latency, resp, err)
+	if resp != nil {
+		l.Status = resp.StatusCode
+		l.RequestID = responseRequestID(resp)
+	}
+	if c.cfg.LogBodies && !secretPath(req.URL.Path) {
+		if req.GetBody != nil {
+			if body, err := req.GetBody(); err == nil {
+				l.RequestBody, _ = ioutil.ReadAll(body)
+				body.Close()
+			}
+		}
+		if resp != nil {
+			body, err := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+			if err == nil {
+				l.ResponseBody = body
+			}
+		}
+	}
+	c.logger().LogRequest(l)
+}
+
+// secretPath reports whether the bodies of requests to the path hold secrets
+func secretPath(path string) bool {
+	return strings.HasPrefix(path, "/iocm/app/sec/") || strings.HasPrefix(path, "/iocm/app/reg/")
+}
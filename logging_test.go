@@ -0,0 +1,70 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		w.Header().Set("X-Request-Id", "req1")
+		fmt.Fprint(w, `{"deviceId":"dev1"}`)
+	}))
+	defer s.Close()
+
+	var logs []RequestLog
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL, AppID: "app", Logger: LoggerFunc(func(l RequestLog) {
+		logs = append(logs, l)
+	})}}
+	resp, err := c.request(http.MethodPut, "/iocm/app/dm/v1.1.0/devices/dev1", strings.NewReader(`{"name":"meter"}`))
+	if assert.Nil(t, err) {
+		resp.Body.Close()
+	}
+	if assert.Len(t, logs, 2) {
+		assert.Equal(t, "/iocm/app/sec/v1.1.0/login", logs[0].Path)
+		assert.Equal(t, http.MethodPut, logs[1].Method)
+		assert.Equal(t, "/iocm/app/dm/v1.1.0/devices/dev1", logs[1].Path)
+		assert.Equal(t, http.StatusOK, logs[1].Status)
+		assert.Equal(t, 1, logs[1].Attempt)
+		assert.Equal(t, "req1", logs[1].RequestID)
+		assert.Nil(t, logs[1].RequestBody)
+	}
+
+	logs = nil
+	c.cfg.LogBodies = true
+	resp, err = c.request(http.MethodPut, "/iocm/app/dm/v1.1.0/devices/dev1", strings.NewReader(`{"name":"meter"}`))
+	if assert.Nil(t, err) {
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, `{"deviceId":"dev1"}`, string(body))
+	}
+	if assert.Len(t, logs, 1) {
+		assert.Equal(t, `{"name":"meter"}`, string(logs[0].RequestBody))
+		assert.Equal(t, `{"deviceId":"dev1"}`, string(logs[0].ResponseBody))
+	}
+
+	// the bodies of registrations hold the PSK
+	logs = nil
+	resp, err = c.request(http.MethodPost, "/iocm/app/reg/v1.2.0/devices", strings.NewReader(`{"psk":"0123456789abcdef"}`))
+	if assert.Nil(t, err) {
+		resp.Body.Close()
+	}
+	if assert.Len(t, logs, 1) {
+		assert.Nil(t, logs[0].RequestBody)
+		assert.Nil(t, logs[0].ResponseBody)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidLWM2MPath is returned for LWM2M paths which don't address an
+// object, instance or resource
+var ErrInvalidLWM2MPath = errors.New("invalid LWM2M path")
+
+// lwm2mServiceID is the service ID of commands addressing LWM2M paths
+// instead of a service of the device profile
+const lwm2mServiceID = "LWM2M"
+
+// LWM2MPath addresses an LWM2M object, object instance or resource. Instance
+// and Resource are -1 when the path doesn't include them.
+type LWM2MPath struct {
+	Object   int
+	Instance int
+	Resource int
+}
+
+// ParseLWM2MPath parses a path like "/3/0/1"
+func ParseLWM2MPath(s string) (LWM2MPath, error) {
+	p := LWM2MPath{Instance: -1, Resource: -1}
+	parts := strings.Split(strings.Trim(s, "/"), "/")
+	if len(parts) > 3 || parts[0] == "" {
+		return p, ErrInvalidLWM2MPath
+	}
+	ids := make([]int, len(parts))
+	for i, part := range parts {
+		id, err := strconv.Atoi(part)
+		if err != nil || id < 0 || id > 65535 {
+			return p, ErrInvalidLWM2MPath
+		}
+		ids[i] = id
+	}
+	p.Object = ids[0]
+	if len(ids) > 1 {
+		p.Instance = ids[1]
+	}
+	if len(ids) > 2 {
+		p.Resource = ids[2]
+	}
+	return p, nil
+}
+
+// String returns the path like "/3/0/1"
+func (p LWM2MPath) String() string {
+	s := "/" + strconv.Itoa(p.Object)
+	if p.Instance >= 0 {
+		s += "/" + strconv.Itoa(p.Instance)
+		if p.Resource >= 0 {
+			s += "/" + strconv.Itoa(p.Resource)
+		}
+	}
+	return s
+}
+
+// LWM2MOperation is an operation on an LWM2M path
+type LWM2MOperation string
+
+const (
+	// LWM2MRead reads the value of the path
+	LWM2MRead LWM2MOperation = "READ"
+	// LWM2MWrite writes the value of a resource, or the resources of an
+	// instance given as map of resource ID to value
+	LWM2MWrite LWM2MOperation = "WRITE"
+	// LWM2MExecute executes a resource, the value holds the optional arguments
+	LWM2MExecute LWM2MOperation = "EXECUTE"
+	// LWM2MObserve observes the path, changes are reported as data changes
+	LWM2MObserve LWM2MOperation = "OBSERVE"
+	// LWM2MDiscover returns the attributes of the path
+	LWM2MDiscover LWM2MOperation = "DISCOVER"
+)
+
+// lwm2mParams returns the parameters of a command on the path
+func lwm2mParams(op LWM2MOperation, p LWM2MPath, value interface{}) (map[string]interface{}, error) {
+	if p.Object < 0 || (p.Instance < 0 && p.Resource >= 0) {
+		return nil, ErrInvalidLWM2MPath
+	}
+	switch op {
+	case LWM2MWrite:
+		if p.Instance < 0 || value == nil {
+			return nil, errors.New("LWM2M write needs an instance or resource and a value")
+		}
+	case LWM2MExecute:
+		if p.Resource < 0 {
+			return nil, errors.New("LWM2M execute needs a resource")
+		}
+	case LWM2MRead, LWM2MObserve, LWM2MDiscover:
+		if value != nil {
+			return nil, errors.New("LWM2M " + string(op) + " takes no value")
+		}
+	default:
+		return nil, errors.New("unknown LWM2M operation: " + string(op))
+	}
+
+	params := map[string]interface{}{"objectId": p.Object}
+	if p.Instance >= 0 {
+		params["objectInstanceId"] = p.Instance
+	}
+	if p.Resource >= 0 {
+		params["resourceId"] = p.Resource
+	}
+	if value != nil {
+		params["value"] = value
+	}
+	return params, nil
+}
+
+// SendLWM2MCommand sends an operation on an LWM2M path to a device without
+// profile-defined services. Opaque values given as []byte are sent base64
+// encoded.
+func (c *Client) SendLWM2MCommand(deviceID string, op LWM2MOperation, path LWM2MPath, value interface{}, opts CommandOptions) (*DeviceCommand, error) {
+	return c.SendLWM2MCommandCtx(context.Background(), deviceID, op, path, value, opts)
+}
+
+// SendLWM2MCommandCtx is like SendLWM2MCommand but with a context
+func (c *Client) SendLWM2MCommandCtx(ctx context.Context, deviceID string, op LWM2MOperation, path LWM2MPath, value interface{}, opts CommandOptions) (*DeviceCommand, error) {
+	params, err := lwm2mParams(op, path, value)
+	if err != nil {
+		return nil, err
+	}
+	return c.SendCommandWithResponseCtx(ctx, deviceID, lwm2mServiceID, string(op), params, opts)
+}
@@ -0,0 +1,57 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLWM2MPath(t *testing.T) {
+	tests := []struct {
+		in   string
+		want LWM2MPath
+		err  error
+	}{
+		{"/3/0/1", LWM2MPath{3, 0, 1}, nil},
+		{"/19/0", LWM2MPath{19, 0, -1}, nil},
+		{"5", LWM2MPath{5, -1, -1}, nil},
+		{"/", LWM2MPath{0, -1, -1}, ErrInvalidLWM2MPath},
+		{"/3/x", LWM2MPath{0, -1, -1}, ErrInvalidLWM2MPath},
+		{"/3/0/1/2", LWM2MPath{0, -1, -1}, ErrInvalidLWM2MPath},
+	}
+	for _, tt := range tests {
+		p, err := ParseLWM2MPath(tt.in)
+		assert.Equal(t, tt.err, err, tt.in)
+		if err == nil {
+			assert.Equal(t, tt.want, p, tt.in)
+			assert.Equal(t, "/"+trimSlash(tt.in), p.String())
+		}
+	}
+}
+
+func trimSlash(s string) string {
+	if len(s) > 0 && s[0] == '/' {
+		return s[1:]
+	}
+	return s
+}
+
+func TestLWM2MParams(t *testing.T) {
+	params, err := lwm2mParams(LWM2MWrite, LWM2MPath{19, 1, 0}, []byte{0x01, 0x02})
+	if assert.Nil(t, err) {
+		b, _ := json.Marshal(params)
+		assert.JSONEq(t, `{"objectId":19,"objectInstanceId":1,"resourceId":0,"value":"AQI="}`, string(b))
+	}
+
+	_, err = lwm2mParams(LWM2MExecute, LWM2MPath{5, 0, -1}, nil)
+	assert.NotNil(t, err, "execute needs a resource")
+	_, err = lwm2mParams(LWM2MRead, LWM2MPath{3, 0, 1}, 1)
+	assert.NotNil(t, err, "read takes no value")
+	_, err = lwm2mParams(LWM2MOperation("DELETE"), LWM2MPath{3, 0, -1}, nil)
+	assert.NotNil(t, err)
+}
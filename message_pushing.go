@@ -24,6 +24,9 @@ const (
 	// NotificationDeviceDataChanged is used after receiving device data changes
 	// (dynamic changes such as changes of service attribute values).
 	NotificationDeviceDataChanged Notification = "deviceDataChanged"
+	// NotificationDeviceDatasChanged is used for batches of device data changes,
+	// it carries the changes of several services at once
+	NotificationDeviceDatasChanged Notification = "deviceDatasChanged"
 	// NotificationDeviceDeleted is used when learning that a
 	// non-directly-connected device is deleted
 	NotificationDeviceDeleted Notification = "deviceDeleted"
@@ -46,33 +49,162 @@ const (
 	// NotificationRuleEvent is used when generates the corresponding rule event
 	// notification to NA when the rule is triggered
 	NotificationRuleEvent Notification = "ruleEvent"
+	// NotificationCommandStatus is used for the status updates the platform
+	// posts to the callbackUrl of a command. These carry no notifyType, the
+	// Server recognizes them by their commandId.
+	NotificationCommandStatus Notification = "commandStatus"
+	// NotificationSwUpgradeState is used for state changes of software upgrade
+	// tasks
+	NotificationSwUpgradeState Notification = "swUpgradeStateChangeNotify"
+	// NotificationFwUpgradeState is used for state changes of firmware upgrade
+	// tasks
+	NotificationFwUpgradeState Notification = "fwUpgradeStateChangeNotify"
 )
 
-func notificationDeserializer(not Notification, in []byte) (interface{}, error) {
-	switch not {
-	case NotificationDeviceDataChanged:
-		s := &DeviceDataChanged{}
-		if err := json.Unmarshal(in, s); err != nil {
-			return nil, err
+// SubscribableNotifications are the notification types an application can
+// subscribe to
+var SubscribableNotifications = []Notification{
+	NotificationDeviceAdded,
+	NotificationDeviceDeleted,
+	NotificationDeviceInfoChanged,
+	NotificationDeviceDataChanged,
+	NotificationDeviceDatasChanged,
+	NotificationServiceInfoChanged,
+	NotificationCommandResponse,
+	NotificationMessageConfirm,
+	NotificationSwUpgradeState,
+	NotificationFwUpgradeState,
+}
+
+// Subscribable reports whether the application can subscribe to n
+func (n Notification) Subscribable() bool {
+	for _, s := range SubscribableNotifications {
+		if n == s {
+			return true
 		}
-		return s, nil
-	case NotificationDeviceAdded:
-	case NotificationDeviceInfoChanged:
-	case NotificationDeviceDeleted:
-	case NotificationMessageConfirm:
-	case NotificationCommandResponse:
-	case NotificationDeviceEvent:
-	case NotificationServiceInfoChanged:
-	case NotificationRuleEvent:
-		break
 	}
-	return nil, errors.New("not implemented")
+	return false
 }
 
-//DeviceDataChanged struct with device data
+// notificationTypes creates the typed value of each notification type
+var notificationTypes = map[Notification]func() interface{}{
+	NotificationDeviceAdded:        func() interface{} { return &DeviceAdded{} },
+	NotificationDeviceInfoChanged:  func() interface{} { return &DeviceInfoChanged{} },
+	NotificationDeviceDataChanged:  func() interface{} { return &DeviceDataChanged{} },
+	NotificationDeviceDatasChanged: func() interface{} { return &DeviceDatasChanged{} },
+	NotificationDeviceDeleted:      func() interface{} { return &DeviceDeleted{} },
+	NotificationMessageConfirm:     func() interface{} { return &MessageConfirm{} },
+	NotificationCommandResponse:    func() interface{} { return &CommandResponse{} },
+	NotificationDeviceEvent:        func() interface{} { return &DeviceEvent{} },
+	NotificationServiceInfoChanged: func() interface{} { return &ServiceInfoChanged{} },
+	NotificationRuleEvent:          func() interface{} { return &RuleEvent{} },
+	NotificationCommandStatus:      func() interface{} { return &CommandStatusUpdate{} },
+	NotificationSwUpgradeState:     func() interface{} { return &SwUpgradeStateChanged{} },
+	NotificationFwUpgradeState:     func() interface{} { return &FwUpgradeStateChanged{} },
+}
+
+func notificationDeserializer(codec Codec, not Notification, in []byte) (interface{}, error) {
+	newValue, ok := notificationTypes[not]
+	if !ok {
+		return nil, errors.New("not implemented")
+	}
+	v := newValue()
+	if err := codec.Unmarshal(in, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// DeviceDataChanged struct with device data
 type DeviceDataChanged struct {
-	DeviceID  string
-	GatewayID string
-	RequestID string
-	Service   Service `json:"service"`
+	NotifyType Notification `json:"notifyType"`
+	DeviceID   string
+	GatewayID  string
+	RequestID  string
+	Service    Service `json:"service"`
+}
+
+// NotificationHeader is the header of command related notifications
+type NotificationHeader struct {
+	RequestID   string `json:"requestId"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	DeviceID    string `json:"deviceId"`
+	ServiceType string `json:"serviceType"`
+	Method      string `json:"method"`
+}
+
+// CommandResponse struct with the response of a device to a command
+type CommandResponse struct {
+	NotifyType Notification       `json:"notifyType"`
+	Header     NotificationHeader `json:"header"`
+	Body       json.RawMessage    `json:"body"`
+}
+
+// CommandStatusUpdate struct with a command status update as posted to the
+// callbackUrl of the command
+type CommandStatusUpdate struct {
+	DeviceID  string        `json:"deviceId"`
+	CommandID string        `json:"commandId"`
+	Result    CommandResult `json:"result"`
+}
+
+// CommandResult holds the result code and the optional details of a command
+type CommandResult struct {
+	ResultCode   string          `json:"resultCode"`
+	ResultDetail json.RawMessage `json:"resultDetail"`
+}
+
+// Final reports whether no more updates follow for the command
+func (u *CommandStatusUpdate) Final() bool {
+	return CommandStatus(u.Result.ResultCode).Final()
+}
+
+// Progress returns the progress percentage of a long-running command, when
+// the device reports one in the result details
+func (u *CommandStatusUpdate) Progress() (int, bool) {
+	var d struct {
+		Progress *float64 `json:"progress"`
+	}
+	if len(u.Result.ResultDetail) == 0 || json.Unmarshal(u.Result.ResultDetail, &d) != nil || d.Progress == nil {
+		return 0, false
+	}
+	return int(*d.Progress), true
+}
+
+// DeliveryState is the delivery state of a message sent to a device
+type DeliveryState string
+
+const (
+	// DeliveryPending is used while the message is not delivered yet
+	DeliveryPending DeliveryState = "PENDING"
+	// DeliveryDelivered is used when the gateway or device confirmed the message
+	DeliveryDelivered DeliveryState = "DELIVERED"
+	// DeliveryFailed is used when the message couldn't be delivered
+	DeliveryFailed DeliveryState = "FAILED"
+)
+
+// MessageConfirm struct with the acknowledgment of a message by the gateway
+type MessageConfirm struct {
+	NotifyType Notification       `json:"notifyType"`
+	Header     NotificationHeader `json:"header"`
+	Body       json.RawMessage    `json:"body"`
+}
+
+// State returns the delivery state reported in the confirmation
+func (m *MessageConfirm) State() DeliveryState {
+	var b struct {
+		Status string `json:"status"`
+	}
+	if len(m.Body) > 0 {
+		json.Unmarshal(m.Body, &b)
+	}
+	switch b.Status {
+	case "", "DELIVERED", "SUCCESSFUL":
+		// a confirmation without status is an acknowledgment
+		return DeliveryDelivered
+	case "FAILED", "TIMEOUT", "EXPIRED":
+		return DeliveryFailed
+	}
+	return DeliveryPending
 }
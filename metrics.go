@@ -0,0 +1,247 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics receives the measurements of a client and its notification
+// servers, to monitor the health of the integration. Set it as
+// Config.Metrics, or as Server.Metrics for the notifications only. The
+// methods are called concurrently and must not block.
+type Metrics interface {
+	// ObserveRequest is called for every attempt of a request to the
+	// platform, status is 0 when the request failed without a response
+	ObserveRequest(op Operation, status int, latency time.Duration, err error)
+	// ObserveTokenRefresh is called for every refresh of the access token
+	ObserveTokenRefresh(err error)
+	// ObserveNotification is called for every notification handled by a
+	// Server, latency is the time the callbacks took
+	ObserveNotification(not Notification, latency time.Duration, err error)
+}
+
+// OperationStats struct with the requests of an operation
+type OperationStats struct {
+	Requests int
+	// Errors counts the requests which failed or got a status of 400 and
+	// over
+	Errors  int
+	Latency time.Duration
+}
+
+// NotificationStats struct with the handled notifications of a type
+type NotificationStats struct {
+	Count  int
+	Errors int
+	// Latency is the total time the callbacks took
+	Latency time.Duration
+}
+
+// requestBuckets are the upper bounds in seconds of the buckets of the
+// request latency histogram
+var requestBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// MetricsRecorder is a Metrics which keeps the counters in memory, to report
+// them or to serve them to Prometheus with ServeHTTP
+type MetricsRecorder struct {
+	lock          sync.Mutex
+	requests      map[Operation]*OperationStats
+	statuses      map[Operation]map[int]int
+	buckets       map[Operation][]int // counts per requestBuckets bound
+	refreshes     int
+	refreshErrors int
+	notifications map[Notification]*NotificationStats
+}
+
+// ObserveRequest implements the Metrics interface
+func (m *MetricsRecorder) ObserveRequest(op Operation, status int, latency time.Duration, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.requests == nil {
+		m.requests = make(map[Operation]*OperationStats)
+		m.statuses = make(map[Operation]map[int]int)
+		m.buckets = make(map[Operation][]int)
+	}
+	s, ok := m.requests[op]
+	if !ok {
+		s = &OperationStats{}
+		m.requests[op] = s
+		m.statuses[op] = make(map[int]int)
+		m.buckets[op] = make([]int, len(requestBuckets))
+	}
+	s.Requests++
+	if err != nil || status >= 400 {
+		s.Errors++
+	}
+	s.Latency += latency
+	m.statuses[op][status]++
+	for i, le := range requestBuckets {
+		if latency.Seconds() <= le {
+			m.buckets[op][i]++
+			break
+		}
+	}
+}
+
+// ObserveTokenRefresh implements the Metrics interface
+func (m *MetricsRecorder) ObserveTokenRefresh(err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.refreshes++
+	if err != nil {
+		m.refreshErrors++
+	}
+}
+
+// ObserveNotification implements the Metrics interface
+func (m *MetricsRecorder) ObserveNotification(not Notification, latency time.Duration, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.notifications == nil {
+		m.notifications = make(map[Notification]*NotificationStats)
+	}
+	s, ok := m.notifications[not]
+	if !ok {
+		s = &NotificationStats{}
+		m.notifications[not] = s
+	}
+	s.Count++
+	if err != nil {
+		s.Errors++
+	}
+	s.Latency += latency
+}
+
+// Requests returns the request statistics per operation
+func (m *MetricsRecorder) Requests() map[Operation]OperationStats {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	ret := make(map[Operation]OperationStats, len(m.requests))
+	for op, s := range m.requests {
+		ret[op] = *s
+	}
+	return ret
+}
+
+// TokenRefreshes returns the number of token refreshes and how many of them
+// failed
+func (m *MetricsRecorder) TokenRefreshes() (int, int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.refreshes, m.refreshErrors
+}
+
+// Notifications returns the notification statistics per type
+func (m *MetricsRecorder) Notifications() map[Notification]NotificationStats {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	ret := make(map[Notification]NotificationStats, len(m.notifications))
+	for not, s := range m.notifications {
+		ret[not] = *s
+	}
+	return ret
+}
+
+// WritePrometheus writes the counters and the request latency histograms in
+// the Prometheus text format
+func (m *MetricsRecorder) WritePrometheus(w io.Writer) error {
+	m.lock.Lock()
+	var b strings.Builder
+
+	ops := make([]string, 0, len(m.requests))
+	for op := range m.requests {
+		ops = append(ops, string(op))
+	}
+	sort.Strings(ops)
+	b.WriteString("# TYPE oceanconnect_requests_total counter\n")
+	for _, op := range ops {
+		statuses := make([]int, 0, len(m.statuses[Operation(op)]))
+		for status := range m.statuses[Operation(op)] {
+			statuses = append(statuses, status)
+		}
+		sort.Ints(statuses)
+		for _, status := range statuses {
+			fmt.Fprintf(&b, "oceanconnect_requests_total{operation=%q,status=\"%d\"} %d\n", metricsLabel(op), status, m.statuses[Operation(op)][status])
+		}
+	}
+	b.WriteString("# TYPE oceanconnect_request_errors_total counter\n")
+	for _, op := range ops {
+		fmt.Fprintf(&b, "oceanconnect_request_errors_total{operation=%q} %d\n", metricsLabel(op), m.requests[Operation(op)].Errors)
+	}
+	b.WriteString("# TYPE oceanconnect_request_seconds histogram\n")
+	for _, op := range ops {
+		s := m.requests[Operation(op)]
+		n := 0
+		for i, le := range requestBuckets {
+			n += m.buckets[Operation(op)][i]
+			fmt.Fprintf(&b, "oceanconnect_request_seconds_bucket{operation=%q,le=\"%g\"} %d\n", metricsLabel(op), le, n)
+		}
+		fmt.Fprintf(&b, "oceanconnect_request_seconds_bucket{operation=%q,le=\"+Inf\"} %d\n", metricsLabel(op), s.Requests)
+		fmt.Fprintf(&b, "oceanconnect_request_seconds_sum{operation=%q} %g\n", metricsLabel(op), s.Latency.Seconds())
+		fmt.Fprintf(&b, "oceanconnect_request_seconds_count{operation=%q} %d\n", metricsLabel(op), s.Requests)
+	}
+
+	b.WriteString("# TYPE oceanconnect_token_refreshes_total counter\n")
+	fmt.Fprintf(&b, "oceanconnect_token_refreshes_total %d\n", m.refreshes)
+	b.WriteString("# TYPE oceanconnect_token_refresh_errors_total counter\n")
+	fmt.Fprintf(&b, "oceanconnect_token_refresh_errors_total %d\n", m.refreshErrors)
+
+	nots := make([]string, 0, len(m.notifications))
+	for not := range m.notifications {
+		nots = append(nots, string(not))
+	}
+	sort.Strings(nots)
+	b.WriteString("# TYPE oceanconnect_notifications_total counter\n")
+	for _, not := range nots {
+		fmt.Fprintf(&b, "oceanconnect_notifications_total{type=%q} %d\n", not, m.notifications[Notification(not)].Count)
+	}
+	b.WriteString("# TYPE oceanconnect_notification_errors_total counter\n")
+	for _, not := range nots {
+		fmt.Fprintf(&b, "oceanconnect_notification_errors_total{type=%q} %d\n", not, m.notifications[Notification(not)].Errors)
+	}
+	b.WriteString("# TYPE oceanconnect_notification_seconds_total counter\n")
+	for _, not := range nots {
+		fmt.Fprintf(&b, "oceanconnect_notification_seconds_total{type=%q} %g\n", not, m.notifications[Notification(not)].Latency.Seconds())
+	}
+	m.lock.Unlock()
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ServeHTTP serves the counters in the Prometheus text format, mount it as
+// the metrics endpoint scraped by Prometheus
+func (m *MetricsRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.WritePrometheus(w)
+}
+
+// metricsLabel returns the label of an operation, requests of unknown
+// operations are counted together
+func metricsLabel(op string) string {
+	if op == "" {
+		return "unknown"
+	}
+	return op
+}
+
+// observeRequest reports an attempt of the request to the metrics
+func (c *Client) observeRequest(req *http.Request, latency time.Duration, resp *http.Response, err error) {
+	if c.cfg.Metrics == nil {
+		return
+	}
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	c.cfg.Metrics.ObserveRequest(requestOperation(req), status, latency, err)
+}
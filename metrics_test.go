@@ -0,0 +1,85 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsRecorder(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/iocm/app/sec/v1.1.0/login":
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+		case "/iocm/app/dm/v1.1.0/devices/dev1":
+			fmt.Fprintln(w, `{"deviceId":"dev1"}`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer s.Close()
+
+	m := &MetricsRecorder{}
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL, Metrics: m}}
+	_, err := c.GetDevice("dev1")
+	assert.Nil(t, err)
+	_, err = c.GetDevice("dev2")
+	assert.NotNil(t, err)
+
+	reqs := m.Requests()
+	assert.Equal(t, 2, reqs[OperationGetDevice].Requests)
+	assert.Equal(t, 1, reqs[OperationGetDevice].Errors)
+	assert.Equal(t, 1, reqs[OperationLogin].Requests)
+	refreshes, failed := m.TokenRefreshes()
+	assert.Equal(t, 1, refreshes)
+	assert.Equal(t, 0, failed)
+
+	srv := c.newServer(nil)
+	srv.RegisterCallback(NotificationDeviceDeleted, func(v interface{}) error {
+		return errors.New("failed")
+	})
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"notifyType":"deviceDeleted","deviceId":"dev1"}`)))
+	}
+	nots := m.Notifications()
+	assert.Equal(t, 2, nots[NotificationDeviceDeleted].Count)
+	assert.Equal(t, 2, nots[NotificationDeviceDeleted].Errors)
+
+	var b strings.Builder
+	assert.Nil(t, m.WritePrometheus(&b))
+	out := b.String()
+	assert.Contains(t, out, `oceanconnect_requests_total{operation="GetDevice",status="500"} 1`+"\n")
+	assert.Contains(t, out, `oceanconnect_request_errors_total{operation="GetDevice"} 1`+"\n")
+	assert.Contains(t, out, "oceanconnect_token_refreshes_total 1\n")
+	assert.Contains(t, out, `oceanconnect_notification_errors_total{type="deviceDeleted"} 2`+"\n")
+}
+
+func TestMetricsRecorderLatencyHistogram(t *testing.T) {
+	m := &MetricsRecorder{}
+	for _, latency := range []time.Duration{20 * time.Millisecond, 300 * time.Millisecond, 400 * time.Millisecond, 20 * time.Second} {
+		m.ObserveRequest(OperationGetDevice, http.StatusOK, latency, nil)
+	}
+
+	var b strings.Builder
+	assert.Nil(t, m.WritePrometheus(&b))
+	out := b.String()
+	assert.Contains(t, out, "# TYPE oceanconnect_request_seconds histogram\n")
+	assert.Contains(t, out, `oceanconnect_request_seconds_bucket{operation="GetDevice",le="0.05"} 1`+"\n")
+	assert.Contains(t, out, `oceanconnect_request_seconds_bucket{operation="GetDevice",le="0.25"} 1`+"\n")
+	assert.Contains(t, out, `oceanconnect_request_seconds_bucket{operation="GetDevice",le="0.5"} 3`+"\n")
+	assert.Contains(t, out, `oceanconnect_request_seconds_bucket{operation="GetDevice",le="10"} 3`+"\n")
+	assert.Contains(t, out, `oceanconnect_request_seconds_bucket{operation="GetDevice",le="+Inf"} 4`+"\n")
+	assert.Contains(t, out, `oceanconnect_request_seconds_sum{operation="GetDevice"} 20.72`+"\n")
+	assert.Contains(t, out, `oceanconnect_request_seconds_count{operation="GetDevice"} 4`+"\n")
+}
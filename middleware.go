@@ -0,0 +1,48 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Middleware wraps the callback of a notification type, like http middleware
+// wraps a http.Handler, for cross-cutting concerns such as logging, metrics,
+// tracing and panic recovery
+type Middleware func(not Notification, next NotificationFunc) NotificationFunc
+
+// RecoverMiddleware converts a panic in the callback into an error, so a
+// faulty callback can't crash the notification server
+func RecoverMiddleware(not Notification, next NotificationFunc) NotificationFunc {
+	return func(v interface{}) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("callback for %s panicked: %v", not, r)
+			}
+		}()
+		return next(v)
+	}
+}
+
+// LoggingMiddleware logs every callback with its duration and result
+func LoggingMiddleware(not Notification, next NotificationFunc) NotificationFunc {
+	return func(v interface{}) error {
+		start := time.Now()
+		err := next(v)
+		l := logrus.WithFields(logrus.Fields{
+			"notification": string(not),
+			"duration":     time.Since(start),
+		})
+		if err != nil {
+			l.Errorf("callback failed: %v", err)
+		} else {
+			l.Debugf("callback handled")
+		}
+		return err
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"bytes"
+	"text/template"
+)
+
+// NameData is passed to the naming template from the Config
+type NameData struct {
+	IMEI             string
+	DeviceID         string
+	DeviceType       string
+	Model            string
+	ManufacturerName string
+	Location         string
+}
+
+var nameFuncs = template.FuncMap{
+	// last returns the last n characters of s, e.g. {{last 6 .IMEI}}
+	"last": func(n int, s string) string {
+		if n >= len(s) {
+			return s
+		}
+		return s[len(s)-n:]
+	},
+	// first returns the first n characters of s
+	"first": func(n int, s string) string {
+		if n >= len(s) {
+			return s
+		}
+		return s[:n]
+	},
+}
+
+func parseNameTemplate(tmpl string) (*template.Template, error) {
+	if tmpl == "" {
+		return nil, nil
+	}
+	return template.New("name").Funcs(nameFuncs).Option("missingkey=error").Parse(tmpl)
+}
+
+// deviceName returns the name for a newly registered device according to the
+// naming template, or an empty string when no template is configured
+func (c *Client) deviceName(imei, deviceID string) (string, error) {
+	if c.nameTmpl == nil {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	err := c.nameTmpl.Execute(&buf, NameData{
+		IMEI:             imei,
+		DeviceID:         deviceID,
+		DeviceType:       c.cfg.DeviceType,
+		Model:            c.cfg.Model,
+		ManufacturerName: c.cfg.ManufacturerName,
+		Location:         c.cfg.Location,
+	})
+	return buf.String(), err
+}
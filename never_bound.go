@@ -0,0 +1,66 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"time"
+)
+
+// NeverBound matches devices which never came online: devices still in the
+// inbox, or devices without reported data and NAT binding which aren't online
+func NeverBound() DevicePredicate {
+	return func(d *CachedDevice) bool {
+		return neverBound(&d.Device)
+	}
+}
+
+func neverBound(d *Device) bool {
+	switch d.DeviceInfo.Status {
+	case DeviceStatusInbox:
+		return true
+	case DeviceStatusOnline:
+		return false
+	}
+	return d.LastSeen().IsZero() && d.ConnectionInfo.LastNATBinding.IsZero()
+}
+
+// FindNeverBoundDevices returns the devices registered longer than olderThan
+// ago which never came online, see NeverBound. They count against the device
+// quota of the application without being used.
+func (c *Client) FindNeverBoundDevices(ctx context.Context, olderThan time.Duration) ([]Device, error) {
+	cutoff := c.clock().Now().Add(-olderThan)
+	var devs []Device
+	err := c.ForEachDevice(ctx, GetDevicesStruct{To: cutoff}, func(d Device) error {
+		if neverBound(&d) && !d.CreateTime.IsZero() && d.CreateTime.Before(cutoff) {
+			devs = append(devs, d)
+		}
+		return nil
+	})
+	return devs, err
+}
+
+// PlanNeverBoundCleanup plans the deletion of the devices returned by
+// FindNeverBoundDevices, so they can be reviewed before the plan is applied
+func (c *Client) PlanNeverBoundCleanup(ctx context.Context, olderThan time.Duration) (*Plan, error) {
+	devs, err := c.FindNeverBoundDevices(ctx, olderThan)
+	if err != nil {
+		return nil, err
+	}
+	p := &Plan{}
+	for _, d := range devs {
+		deviceID := d.DeviceID
+		p.Changes = append(p.Changes, Change{
+			Action: ChangeDelete,
+			Kind:   "device",
+			ID:     d.DeviceInfo.NodeID,
+			Before: d.DeviceInfo.Name,
+			apply: func(ctx context.Context) (interface{}, error) {
+				return nil, c.DeleteDeviceCtx(ctx, deviceID)
+			},
+		})
+	}
+	return p, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNeverBoundCleanup(t *testing.T) {
+	var endTime string
+	var deleted []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/iocm/app/sec/v1.1.0/login":
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			endTime = r.URL.Query().Get("endTime")
+			fmt.Fprintln(w, `{"totalCount":4,"devices":[
+				{"deviceId":"dev1","creationTime":"20170801T100000Z","deviceInfo":{"nodeId":"111","status":"INBOX"}},
+				{"deviceId":"dev2","creationTime":"20170801T100000Z","deviceInfo":{"nodeId":"222","status":"OFFLINE"}},
+				{"deviceId":"dev3","creationTime":"20170801T100000Z","deviceInfo":{"nodeId":"333","status":"OFFLINE"},
+					"services":[{"serviceId":"Meter","eventTime":"20170802T100000Z"}]},
+				{"deviceId":"dev4","creationTime":"20170801T100000Z","deviceInfo":{"nodeId":"444","status":"ONLINE"}}]}`)
+		}
+	}))
+	defer s.Close()
+
+	clock := &fakeClock{now: time.Date(2017, 9, 12, 10, 0, 0, 0, time.UTC)}
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL, Clock: clock}}
+	ctx := context.Background()
+
+	p, err := c.PlanNeverBoundCleanup(ctx, 30*24*time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, "20170813T100000Z", endTime)
+	assert.Equal(t, "- device 111\n- device 222\n", p.String())
+	assert.Nil(t, p.Apply(ctx))
+	assert.Equal(t, []string{"/iocm/app/dm/v1.1.0/devices/dev1", "/iocm/app/dm/v1.1.0/devices/dev2"}, deleted)
+
+	// devices registered after the cutoff are kept
+	p, err = c.PlanNeverBoundCleanup(ctx, 60*24*time.Hour)
+	assert.Nil(t, err)
+	assert.True(t, p.Empty())
+}
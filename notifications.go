@@ -0,0 +1,159 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import "encoding/json"
+
+// DeviceAdded struct with a device added to the application
+type DeviceAdded struct {
+	NotifyType Notification `json:"notifyType"`
+	DeviceID   string       `json:"deviceId"`
+	GatewayID  string       `json:"gatewayId"`
+	NodeType   NodeType     `json:"nodeType"`
+	DeviceInfo DeviceInfo   `json:"deviceInfo"`
+}
+
+// DeviceInfoChanged struct with the changed static information of a device
+type DeviceInfoChanged struct {
+	NotifyType Notification `json:"notifyType"`
+	DeviceID   string       `json:"deviceId"`
+	GatewayID  string       `json:"gatewayId"`
+	DeviceInfo DeviceInfo   `json:"deviceInfo"`
+}
+
+// DeviceDatasChanged struct with the data changes of several services
+type DeviceDatasChanged struct {
+	NotifyType Notification `json:"notifyType"`
+	RequestID  string       `json:"requestId"`
+	DeviceID   string       `json:"deviceId"`
+	GatewayID  string       `json:"gatewayId"`
+	Services   []Service    `json:"services"`
+}
+
+// DeviceDeleted struct with a deleted device
+type DeviceDeleted struct {
+	NotifyType Notification `json:"notifyType"`
+	DeviceID   string       `json:"deviceId"`
+	GatewayID  string       `json:"gatewayId"`
+}
+
+// DeviceEvent struct with an event reported by a device
+type DeviceEvent struct {
+	NotifyType Notification       `json:"notifyType"`
+	DeviceID   string             `json:"deviceId"`
+	Header     NotificationHeader `json:"header"`
+	Body       json.RawMessage    `json:"body"`
+}
+
+// ServiceInfoChanged struct with the changed information of a service
+type ServiceInfoChanged struct {
+	NotifyType  Notification    `json:"notifyType"`
+	DeviceID    string          `json:"deviceId"`
+	GatewayID   string          `json:"gatewayId"`
+	ServiceID   string          `json:"serviceId"`
+	ServiceType string          `json:"serviceType"`
+	ServiceInfo json.RawMessage `json:"serviceInfo"`
+}
+
+// RuleEvent struct with a triggered rule
+type RuleEvent struct {
+	NotifyType  Notification    `json:"notifyType"`
+	Author      string          `json:"author"`
+	RuleID      string          `json:"ruleId"`
+	RuleName    string          `json:"ruleName"`
+	Logic       json.RawMessage `json:"logic"`
+	Reasons     json.RawMessage `json:"reasons"`
+	TriggerTime OcTime          `json:"triggerTime"`
+}
+
+// SwUpgradeStateChanged struct with the state of a software upgrade task
+type SwUpgradeStateChanged struct {
+	NotifyType     Notification `json:"notifyType"`
+	DeviceID       string       `json:"deviceId"`
+	AppID          string       `json:"appId"`
+	OperationID    string       `json:"operationId"`
+	SubOperationID string       `json:"subOperationId"`
+	SwUpgradeState string       `json:"swUpgradeState"`
+}
+
+// FwUpgradeStateChanged struct with the state of a firmware upgrade task
+type FwUpgradeStateChanged struct {
+	NotifyType     Notification `json:"notifyType"`
+	DeviceID       string       `json:"deviceId"`
+	AppID          string       `json:"appId"`
+	OperationID    string       `json:"operationId"`
+	SubOperationID string       `json:"subOperationId"`
+	Step           string       `json:"step"`
+	StepDesc       string       `json:"stepDesc"`
+	ResultCode     string       `json:"resultCode"`
+	ResultDesc     string       `json:"resultDesc"`
+}
+
+// OnDeviceAdded registers the callback for deviceAdded notifications
+func (d *Dispatcher) OnDeviceAdded(fn func(*DeviceAdded) error) {
+	d.RegisterCallback(NotificationDeviceAdded, func(v interface{}) error { return fn(v.(*DeviceAdded)) })
+}
+
+// OnDeviceInfoChanged registers the callback for deviceInfoChanged notifications
+func (d *Dispatcher) OnDeviceInfoChanged(fn func(*DeviceInfoChanged) error) {
+	d.RegisterCallback(NotificationDeviceInfoChanged, func(v interface{}) error { return fn(v.(*DeviceInfoChanged)) })
+}
+
+// OnDeviceDataChanged registers the callback for deviceDataChanged notifications
+func (d *Dispatcher) OnDeviceDataChanged(fn func(*DeviceDataChanged) error) {
+	d.RegisterCallback(NotificationDeviceDataChanged, func(v interface{}) error { return fn(v.(*DeviceDataChanged)) })
+}
+
+// OnDeviceDatasChanged registers the callback for deviceDatasChanged notifications
+func (d *Dispatcher) OnDeviceDatasChanged(fn func(*DeviceDatasChanged) error) {
+	d.RegisterCallback(NotificationDeviceDatasChanged, func(v interface{}) error { return fn(v.(*DeviceDatasChanged)) })
+}
+
+// OnDeviceDeleted registers the callback for deviceDeleted notifications
+func (d *Dispatcher) OnDeviceDeleted(fn func(*DeviceDeleted) error) {
+	d.RegisterCallback(NotificationDeviceDeleted, func(v interface{}) error { return fn(v.(*DeviceDeleted)) })
+}
+
+// OnDeviceEvent registers the callback for deviceEvent notifications
+func (d *Dispatcher) OnDeviceEvent(fn func(*DeviceEvent) error) {
+	d.RegisterCallback(NotificationDeviceEvent, func(v interface{}) error { return fn(v.(*DeviceEvent)) })
+}
+
+// OnServiceInfoChanged registers the callback for serviceInfoChanged notifications
+func (d *Dispatcher) OnServiceInfoChanged(fn func(*ServiceInfoChanged) error) {
+	d.RegisterCallback(NotificationServiceInfoChanged, func(v interface{}) error { return fn(v.(*ServiceInfoChanged)) })
+}
+
+// OnRuleEvent registers the callback for ruleEvent notifications
+func (d *Dispatcher) OnRuleEvent(fn func(*RuleEvent) error) {
+	d.RegisterCallback(NotificationRuleEvent, func(v interface{}) error { return fn(v.(*RuleEvent)) })
+}
+
+// OnMessageConfirm registers the callback for messageConfirm notifications
+func (d *Dispatcher) OnMessageConfirm(fn func(*MessageConfirm) error) {
+	d.RegisterCallback(NotificationMessageConfirm, func(v interface{}) error { return fn(v.(*MessageConfirm)) })
+}
+
+// OnCommandResponse registers the callback for commandRsp notifications
+func (d *Dispatcher) OnCommandResponse(fn func(*CommandResponse) error) {
+	d.RegisterCallback(NotificationCommandResponse, func(v interface{}) error { return fn(v.(*CommandResponse)) })
+}
+
+// OnCommandStatus registers the callback for the status updates of commands
+func (d *Dispatcher) OnCommandStatus(fn func(*CommandStatusUpdate) error) {
+	d.RegisterCallback(NotificationCommandStatus, func(v interface{}) error { return fn(v.(*CommandStatusUpdate)) })
+}
+
+// OnSwUpgradeState registers the callback for swUpgradeStateChangeNotify
+// notifications
+func (d *Dispatcher) OnSwUpgradeState(fn func(*SwUpgradeStateChanged) error) {
+	d.RegisterCallback(NotificationSwUpgradeState, func(v interface{}) error { return fn(v.(*SwUpgradeStateChanged)) })
+}
+
+// OnFwUpgradeState registers the callback for fwUpgradeStateChangeNotify
+// notifications
+func (d *Dispatcher) OnFwUpgradeState(fn func(*FwUpgradeStateChanged) error) {
+	d.RegisterCallback(NotificationFwUpgradeState, func(v interface{}) error { return fn(v.(*FwUpgradeStateChanged)) })
+}
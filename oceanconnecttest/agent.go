@@ -0,0 +1,180 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnecttest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dualinventive/go-oceanconnect"
+)
+
+// ErrNotRegistered is returned by DeviceAgent.Register when the application
+// didn't register the IMEI of the device
+var ErrNotRegistered = errors.New("device is not registered by the application")
+
+// ErrNotConnected is returned when reporting data with a DeviceAgent which
+// isn't connected
+var ErrNotConnected = errors.New("device is not connected")
+
+// CommandHandler handles a command sent to a DeviceAgent, the result is
+// reported as result detail of the command. A returned error fails the
+// command.
+type CommandHandler func(method string, params json.RawMessage) (interface{}, error)
+
+// DeviceAgent emulates a device on a Platform: it connects like a device
+// registered by the application, reports data and responds to commands
+type DeviceAgent struct {
+	IMEI string
+
+	platform *Platform
+	lock     sync.Mutex
+	deviceID string
+	handlers map[string]CommandHandler
+	delivery sync.Mutex
+}
+
+// NewDeviceAgent creates an agent for the device with the IMEI
+func (p *Platform) NewDeviceAgent(imei string) *DeviceAgent {
+	return &DeviceAgent{IMEI: imei, platform: p, handlers: make(map[string]CommandHandler)}
+}
+
+// DeviceID returns the ID of the device, empty before Register
+func (a *DeviceAgent) DeviceID() string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.deviceID
+}
+
+// HandleCommand sets the handler of the commands of the service, commands
+// of services without handler fail
+func (a *DeviceAgent) HandleCommand(serviceID string, h CommandHandler) {
+	a.lock.Lock()
+	a.handlers[serviceID] = h
+	a.lock.Unlock()
+}
+
+// Register connects the device to the platform, like a device using the
+// verify code of its registration. The device comes online and receives its
+// pending commands.
+func (a *DeviceAgent) Register() error {
+	p := a.platform
+	p.lock.Lock()
+	var d *oceanconnect.Device
+	for _, dev := range p.devices {
+		if dev.DeviceInfo.NodeID == a.IMEI {
+			d = dev
+		}
+	}
+	if d == nil {
+		p.lock.Unlock()
+		return ErrNotRegistered
+	}
+	d.DeviceInfo.Status = oceanconnect.DeviceStatusOnline
+	p.agents[d.DeviceID] = a
+	deviceID := d.DeviceID
+	p.lock.Unlock()
+
+	a.lock.Lock()
+	a.deviceID = deviceID
+	a.lock.Unlock()
+
+	p.Notify(oceanconnect.NotificationDeviceInfoChanged, DeviceInfoChanged(deviceID, a.IMEI, oceanconnect.DeviceStatusOnline))
+	a.deliverPending()
+	return nil
+}
+
+// Disconnect takes the device offline, commands stay pending until the next
+// Register
+func (a *DeviceAgent) Disconnect() {
+	deviceID := a.DeviceID()
+	p := a.platform
+	p.lock.Lock()
+	if d, ok := p.devices[deviceID]; ok {
+		d.DeviceInfo.Status = oceanconnect.DeviceStatusOffline
+	}
+	delete(p.agents, deviceID)
+	p.lock.Unlock()
+}
+
+// Report reports the data of the service, the platform stores it and notifies
+// the deviceDataChanged subscribers
+func (a *DeviceAgent) Report(serviceID string, data interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	deviceID := a.DeviceID()
+	p := a.platform
+	svc := oceanconnect.Service{
+		ServiceID:   serviceID,
+		ServiceType: serviceID,
+		Data:        raw,
+		EventTime:   oceanconnect.OcTime{Time: time.Now().UTC().Truncate(time.Second)},
+	}
+
+	p.lock.Lock()
+	d, ok := p.devices[deviceID]
+	if !ok || p.agents[deviceID] != a {
+		p.lock.Unlock()
+		return ErrNotConnected
+	}
+	replaced := false
+	for i := range d.Services {
+		if d.Services[i].ServiceID == serviceID {
+			d.Services[i] = svc
+			replaced = true
+		}
+	}
+	if !replaced {
+		d.Services = append(d.Services, svc)
+	}
+	p.lock.Unlock()
+
+	p.Notify(oceanconnect.NotificationDeviceDataChanged, deviceDataChanged(deviceID, svc))
+	return nil
+}
+
+// ReportEvery reports the data returned by fn at every interval, until the
+// context is done
+func (a *DeviceAgent) ReportEvery(ctx context.Context, interval time.Duration, serviceID string, fn func() interface{}) {
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				a.Report(serviceID, fn())
+			}
+		}
+	}()
+}
+
+// deliverPending runs the handlers of the pending commands in creation order
+func (a *DeviceAgent) deliverPending() {
+	a.delivery.Lock()
+	defer a.delivery.Unlock()
+
+	p := a.platform
+	cmds := p.pendingCommands(a.DeviceID())
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].CommandID < cmds[j].CommandID })
+	for _, cmd := range cmds {
+		a.lock.Lock()
+		h, ok := a.handlers[cmd.Command.ServiceID]
+		a.lock.Unlock()
+		if !ok {
+			p.completeCommand(cmd, nil, errors.New("unsupported service "+cmd.Command.ServiceID))
+			continue
+		}
+		res, err := h(cmd.Command.Method, cmd.Command.Params)
+		p.completeCommand(cmd, res, err)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnecttest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dualinventive/go-oceanconnect"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceAgent(t *testing.T) {
+	p := NewPlatform()
+	defer p.Close()
+
+	var notifications http.Handler = http.NotFoundHandler()
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		notifications.ServeHTTP(w, r)
+	}))
+	defer callback.Close()
+
+	cfg := p.Config()
+	cfg.CommandCallbackURL = callback.URL
+	c, err := oceanconnect.NewClient(cfg)
+	assert.Nil(t, err)
+	srv, err := c.Subscribe(callback.URL)
+	if !assert.Nil(t, err) {
+		return
+	}
+	notifications = srv
+
+	agent := p.NewDeviceAgent("123456789012345")
+	assert.Equal(t, ErrNotRegistered, agent.Register())
+
+	reply, err := c.RegisterDevice("123456789012345")
+	if !assert.Nil(t, err) {
+		return
+	}
+	agent.HandleCommand("Switch", func(method string, params json.RawMessage) (interface{}, error) {
+		return map[string]string{"state": method}, nil
+	})
+	assert.Nil(t, agent.Register())
+	assert.Equal(t, reply.DeviceID, agent.DeviceID())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := c.WatchDevice(ctx, reply.DeviceID)
+
+	assert.Nil(t, agent.Report("Meter", map[string]int{"value": 3}))
+	ev := next(t, events)
+	if assert.Equal(t, oceanconnect.NotificationDeviceDataChanged, ev.Type) {
+		assert.JSONEq(t, `{"value":3}`, string(ev.Data.(*oceanconnect.DeviceDataChanged).Service.Data))
+	}
+	d, err := c.GetDevice(reply.DeviceID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, oceanconnect.DeviceStatusOnline, d.DeviceInfo.Status)
+		assert.Equal(t, 1, len(d.Services))
+	}
+
+	cmd, err := c.SendCommandWithResponse(reply.DeviceID, "Switch", "ON", nil, oceanconnect.CommandOptions{})
+	if !assert.Nil(t, err) {
+		return
+	}
+	ev = next(t, events)
+	if assert.Equal(t, oceanconnect.NotificationCommandStatus, ev.Type) {
+		u := ev.Data.(*oceanconnect.CommandStatusUpdate)
+		assert.Equal(t, cmd.CommandID, u.CommandID)
+		assert.Equal(t, "SUCCESSFUL", u.Result.ResultCode)
+		assert.JSONEq(t, `{"state":"ON"}`, string(u.Result.ResultDetail))
+	}
+
+	agent.Disconnect()
+	assert.Equal(t, ErrNotConnected, agent.Report("Meter", map[string]int{"value": 4}))
+	cmd, err = c.SendCommandWithResponse(reply.DeviceID, "Switch", "OFF", nil, oceanconnect.CommandOptions{ExpireTime: time.Hour})
+	if assert.Nil(t, err) {
+		assert.Equal(t, oceanconnect.CommandPending, p.Command(cmd.CommandID).Status)
+		assert.Nil(t, agent.Register())
+		assert.Equal(t, oceanconnect.CommandSuccessful, p.Command(cmd.CommandID).Status)
+	}
+}
+
+func next(t *testing.T, events <-chan oceanconnect.Event) oceanconnect.Event {
+	select {
+	case ev := <-events:
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+	return oceanconnect.Event{}
+}
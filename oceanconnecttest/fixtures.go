@@ -0,0 +1,115 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnecttest
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/dualinventive/go-oceanconnect"
+)
+
+// The fixtures return notification payloads as the platform pushes them, to
+// post to a Server in tests or to publish with Platform.Notify
+
+// DeviceAdded returns a deviceAdded notification
+func DeviceAdded(deviceID, nodeID string) []byte {
+	return fixture(map[string]interface{}{
+		"notifyType": oceanconnect.NotificationDeviceAdded,
+		"deviceId":   deviceID,
+		"nodeType":   oceanconnect.NodeTypeEndpoint,
+		"deviceInfo": map[string]interface{}{"nodeId": nodeID, "status": oceanconnect.DeviceStatusInbox},
+	})
+}
+
+// DeviceInfoChanged returns a deviceInfoChanged notification with the status
+func DeviceInfoChanged(deviceID, nodeID string, status oceanconnect.DeviceStatus) []byte {
+	return fixture(map[string]interface{}{
+		"notifyType": oceanconnect.NotificationDeviceInfoChanged,
+		"deviceId":   deviceID,
+		"deviceInfo": map[string]interface{}{"nodeId": nodeID, "status": status},
+	})
+}
+
+// DeviceDataChanged returns a deviceDataChanged notification with the data of
+// the service, reported now
+func DeviceDataChanged(deviceID, serviceID string, data interface{}) []byte {
+	return deviceDataChanged(deviceID, service(serviceID, data, time.Now()))
+}
+
+func deviceDataChanged(deviceID string, svc oceanconnect.Service) []byte {
+	return fixture(map[string]interface{}{
+		"notifyType": oceanconnect.NotificationDeviceDataChanged,
+		"deviceId":   deviceID,
+		"service":    svc,
+	})
+}
+
+// DeviceDatasChanged returns a deviceDatasChanged notification with the data
+// of the services by service ID, reported now
+func DeviceDatasChanged(deviceID string, data map[string]interface{}) []byte {
+	now := time.Now()
+	svcs := make([]oceanconnect.Service, 0, len(data))
+	for id, d := range data {
+		svcs = append(svcs, service(id, d, now))
+	}
+	return fixture(map[string]interface{}{
+		"notifyType": oceanconnect.NotificationDeviceDatasChanged,
+		"deviceId":   deviceID,
+		"services":   svcs,
+	})
+}
+
+// DeviceDeleted returns a deviceDeleted notification
+func DeviceDeleted(deviceID string) []byte {
+	return fixture(map[string]interface{}{
+		"notifyType": oceanconnect.NotificationDeviceDeleted,
+		"deviceId":   deviceID,
+	})
+}
+
+// CommandResponse returns a commandRsp notification with the response body
+// of the device to the command
+func CommandResponse(deviceID, serviceID, method, commandID string, body interface{}) []byte {
+	return fixture(map[string]interface{}{
+		"notifyType": oceanconnect.NotificationCommandResponse,
+		"header": oceanconnect.NotificationHeader{
+			RequestID:   commandID,
+			From:        "/devices/" + deviceID + "/services/" + serviceID,
+			DeviceID:    deviceID,
+			ServiceType: serviceID,
+			Method:      method,
+		},
+		"body": body,
+	})
+}
+
+// CommandStatus returns the status update of a command as posted to its
+// callback URL
+func CommandStatus(deviceID, commandID string, status oceanconnect.CommandStatus, detail interface{}) []byte {
+	return fixture(oceanconnect.CommandStatusUpdate{
+		DeviceID:  deviceID,
+		CommandID: commandID,
+		Result:    oceanconnect.CommandResult{ResultCode: string(status), ResultDetail: fixture(detail)},
+	})
+}
+
+func service(serviceID string, data interface{}, eventTime time.Time) oceanconnect.Service {
+	return oceanconnect.Service{
+		ServiceID:   serviceID,
+		ServiceType: serviceID,
+		Data:        fixture(data),
+		EventTime:   oceanconnect.OcTime{Time: eventTime.UTC().Truncate(time.Second)},
+	}
+}
+
+// fixture encodes v, the fixtures only hold encodable values
+func fixture(v interface{}) []byte {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		panic("oceanconnecttest: encoding fixture: " + err.Error())
+	}
+	return buf
+}
@@ -0,0 +1,607 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package oceanconnecttest provides an in-memory OceanConnect platform and
+// emulated devices, for closed-loop tests of applications using the client.
+package oceanconnecttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dualinventive/go-oceanconnect"
+	"github.com/sirupsen/logrus"
+)
+
+// AppID and Secret are the credentials the Platform accepts
+const (
+	AppID  = "test-app"
+	Secret = "test-secret"
+)
+
+const accessToken = "test-token"
+
+// Platform is an in-memory fake of the OceanConnect platform, serving the
+// endpoints the client calls on an httptest.Server
+type Platform struct {
+	*httptest.Server
+
+	lock     sync.Mutex
+	nextID   int
+	devices  map[string]*oceanconnect.Device
+	commands map[string]*oceanconnect.DeviceCommand
+	subs     []oceanconnect.Subscription
+	agents   map[string]*DeviceAgent
+}
+
+// NewPlatform starts a Platform, call Close when done
+func NewPlatform() *Platform {
+	p := &Platform{
+		devices:  make(map[string]*oceanconnect.Device),
+		commands: make(map[string]*oceanconnect.DeviceCommand),
+		agents:   make(map[string]*DeviceAgent),
+	}
+	p.Server = httptest.NewServer(p)
+	return p
+}
+
+// Config returns a client config for the platform
+func (p *Platform) Config() oceanconnect.Config {
+	return oceanconnect.Config{URL: p.URL, AppID: AppID, Secret: Secret}
+}
+
+// Device returns a copy of the device, or nil when it doesn't exist
+func (p *Platform) Device(deviceID string) *oceanconnect.Device {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	d, ok := p.devices[deviceID]
+	if !ok {
+		return nil
+	}
+	cp := *d
+	cp.Services = append([]oceanconnect.Service(nil), d.Services...)
+	return &cp
+}
+
+// Command returns a copy of the command, or nil when it doesn't exist
+func (p *Platform) Command(commandID string) *oceanconnect.DeviceCommand {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	cmd, ok := p.commands[commandID]
+	if !ok {
+		return nil
+	}
+	cp := *cmd
+	return &cp
+}
+
+// ServeHTTP implements the http.Handler interface
+func (p *Platform) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+		p.login(w, r)
+		return
+	}
+	if r.Header.Get("Authorization") != "bearer "+accessToken {
+		writeError(w, http.StatusUnauthorized, "1010005", "invalid access token")
+		return
+	}
+
+	path := r.URL.Path
+	switch {
+	case r.Method == http.MethodPost && path == "/iocm/app/reg/v1.2.0/devices":
+		p.registerDevice(w, r)
+	case r.Method == http.MethodGet && path == "/iocm/app/dm/v1.1.0/devices":
+		p.listDevices(w, r)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/iocm/app/dm/v1.1.0/devices/"):
+		p.getDevice(w, strings.TrimPrefix(path, "/iocm/app/dm/v1.1.0/devices/"))
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/iocm/app/dm/v1.1.0/devices/"):
+		p.deleteDevice(w, strings.TrimPrefix(path, "/iocm/app/dm/v1.1.0/devices/"))
+	case r.Method == http.MethodPut && strings.HasPrefix(path, "/iocm/app/dm/v1.2.0/devices/"):
+		p.setDeviceInfo(w, r, strings.TrimPrefix(path, "/iocm/app/dm/v1.2.0/devices/"))
+	case r.Method == http.MethodPost && path == "/iocm/app/sub/v1.2.0/subscribe":
+		p.subscribe(w, r)
+	case r.Method == http.MethodGet && path == "/iocm/app/sub/v1.2.0/subscriptions":
+		p.listSubscriptions(w, r)
+	case r.Method == http.MethodDelete && path == "/iocm/app/sub/v1.2.0/subscriptions":
+		p.deleteSubscriptions(w, oceanconnect.Notification(r.FormValue("notifyType")))
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/iocm/app/sub/v1.2.0/subscriptions/"):
+		p.getSubscription(w, strings.TrimPrefix(path, "/iocm/app/sub/v1.2.0/subscriptions/"))
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/iocm/app/sub/v1.2.0/subscriptions/"):
+		p.deleteSubscription(w, strings.TrimPrefix(path, "/iocm/app/sub/v1.2.0/subscriptions/"))
+	case r.Method == http.MethodPost && path == "/iocm/app/cmd/v1.4.0/deviceCommands":
+		p.createCommand(w, r)
+	case r.Method == http.MethodGet && path == "/iocm/app/cmd/v1.4.0/deviceCommands":
+		p.listCommands(w, r)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/iocm/app/cmd/v1.4.0/deviceCommands/"):
+		p.getCommand(w, strings.TrimPrefix(path, "/iocm/app/cmd/v1.4.0/deviceCommands/"))
+	case r.Method == http.MethodPut && strings.HasPrefix(path, "/iocm/app/cmd/v1.4.0/deviceCommands/"):
+		p.updateCommand(w, r, strings.TrimPrefix(path, "/iocm/app/cmd/v1.4.0/deviceCommands/"))
+	case r.Method == http.MethodPost && path == "/iocm/app/cmd/v1.4.0/deviceCommandCancelTasks":
+		p.cancelCommands(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "100001", "endpoint not supported by the test platform")
+	}
+}
+
+func (p *Platform) login(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("appId") != AppID || r.FormValue("Secret") != Secret {
+		writeError(w, http.StatusUnauthorized, "100208", "appId or secret is not right")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"accessToken": accessToken,
+		"tokenType":   "bearer",
+		"expiresIn":   3600,
+		"scope":       "default",
+	})
+}
+
+func (p *Platform) registerDevice(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		VerifyCode string `json:"verifyCode"`
+		NodeID     string `json:"nodeId"`
+		Timeout    uint   `json:"timeout"`
+		DeviceInfo *struct {
+			ManufacturerID   string `json:"manufacturerId"`
+			ManufacturerName string `json:"manufacturerName"`
+			DeviceType       string `json:"deviceType"`
+			Model            string `json:"model"`
+		} `json:"deviceInfo"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.NodeID == "" {
+		writeError(w, http.StatusBadRequest, "100022", "the input is invalid")
+		return
+	}
+
+	p.lock.Lock()
+	for _, d := range p.devices {
+		if d.DeviceInfo.NodeID == req.NodeID {
+			p.lock.Unlock()
+			writeError(w, http.StatusBadRequest, "100416", "the device has already been bound")
+			return
+		}
+	}
+	p.nextID++
+	d := &oceanconnect.Device{
+		DeviceID:   fmt.Sprintf("device-%04d", p.nextID),
+		NodeType:   oceanconnect.NodeTypeEndpoint,
+		CreateTime: oceanconnect.OcTime{Time: time.Now().UTC()},
+	}
+	d.DeviceInfo.NodeID = req.NodeID
+	d.DeviceInfo.Status = oceanconnect.DeviceStatusInbox
+	if req.DeviceInfo != nil {
+		d.DeviceInfo.ManufacturerID = req.DeviceInfo.ManufacturerID
+		d.DeviceInfo.ManufacturerName = req.DeviceInfo.ManufacturerName
+		d.DeviceInfo.DeviceType = req.DeviceInfo.DeviceType
+		d.DeviceInfo.Model = req.DeviceInfo.Model
+	}
+	p.devices[d.DeviceID] = d
+	psk := fmt.Sprintf("%032x", p.nextID)
+	p.lock.Unlock()
+
+	if req.Timeout == 0 {
+		req.Timeout = 180
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"deviceId":   d.DeviceID,
+		"verifyCode": req.VerifyCode,
+		"timeout":    req.Timeout,
+		"psk":        psk,
+	})
+	p.Notify(oceanconnect.NotificationDeviceAdded, DeviceAdded(d.DeviceID, req.NodeID))
+}
+
+func (p *Platform) getDevice(w http.ResponseWriter, deviceID string) {
+	d := p.Device(deviceID)
+	if d == nil {
+		writeError(w, http.StatusNotFound, "100403", "the device is not existed")
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+func (p *Platform) listDevices(w http.ResponseWriter, r *http.Request) {
+	pageNo, pageSize := page(r, 25)
+	status := oceanconnect.DeviceStatus(r.FormValue("status"))
+	gatewayID := r.FormValue("gatewayId")
+
+	p.lock.Lock()
+	var devs []oceanconnect.Device
+	for _, d := range p.devices {
+		if (status == "" || d.DeviceInfo.Status == status) && (gatewayID == "" || d.GatewayID == gatewayID) {
+			devs = append(devs, *d)
+		}
+	}
+	p.lock.Unlock()
+	sort.Slice(devs, func(i, j int) bool { return devs[i].DeviceID < devs[j].DeviceID })
+
+	lo, hi := pageRange(len(devs), pageNo, pageSize)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"totalCount": len(devs),
+		"pageNo":     pageNo,
+		"pageSize":   pageSize,
+		"devices":    devs[lo:hi],
+	})
+}
+
+func (p *Platform) deleteDevice(w http.ResponseWriter, deviceID string) {
+	p.lock.Lock()
+	_, ok := p.devices[deviceID]
+	delete(p.devices, deviceID)
+	delete(p.agents, deviceID)
+	p.lock.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "100403", "the device is not existed")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+	p.Notify(oceanconnect.NotificationDeviceDeleted, DeviceDeleted(deviceID))
+}
+
+func (p *Platform) setDeviceInfo(w http.ResponseWriter, r *http.Request, deviceID string) {
+	var req struct {
+		Name             string `json:"name"`
+		ManufacturerID   string `json:"manufacturerId"`
+		ManufacturerName string `json:"manufacturerName"`
+		DeviceType       string `json:"deviceType"`
+		Model            string `json:"model"`
+		Location         string `json:"location"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "100022", "the input is invalid")
+		return
+	}
+	p.lock.Lock()
+	d, ok := p.devices[deviceID]
+	if ok {
+		d.DeviceInfo.Name = req.Name
+		d.DeviceInfo.ManufacturerID = req.ManufacturerID
+		d.DeviceInfo.ManufacturerName = req.ManufacturerName
+		d.DeviceInfo.DeviceType = req.DeviceType
+		d.DeviceInfo.Model = req.Model
+		d.DeviceInfo.Location = req.Location
+	}
+	p.lock.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "100403", "the device is not existed")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p *Platform) subscribe(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		NotifyType  oceanconnect.Notification `json:"notifyType"`
+		CallbackURL string                    `json:"callbackurl"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.CallbackURL == "" {
+		writeError(w, http.StatusBadRequest, "100022", "the input is invalid")
+		return
+	}
+	p.lock.Lock()
+	p.nextID++
+	sub := oceanconnect.Subscription{
+		SubscriptionID: fmt.Sprintf("subscription-%04d", p.nextID),
+		NotifyType:     req.NotifyType,
+		CallbackURL:    req.CallbackURL,
+	}
+	p.subs = append(p.subs, sub)
+	p.lock.Unlock()
+
+	writeJSON(w, http.StatusCreated, sub)
+}
+
+func (p *Platform) listSubscriptions(w http.ResponseWriter, r *http.Request) {
+	pageNo, pageSize := page(r, 10)
+	not := oceanconnect.Notification(r.FormValue("notifyType"))
+
+	p.lock.Lock()
+	var subs []oceanconnect.Subscription
+	for _, s := range p.subs {
+		if not == "" || s.NotifyType == not {
+			subs = append(subs, s)
+		}
+	}
+	p.lock.Unlock()
+
+	lo, hi := pageRange(len(subs), pageNo, pageSize)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"totalCount":    len(subs),
+		"pageNo":        pageNo,
+		"pageSize":      pageSize,
+		"subscriptions": subs[lo:hi],
+	})
+}
+
+func (p *Platform) getSubscription(w http.ResponseWriter, subscriptionID string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, s := range p.subs {
+		if s.SubscriptionID == subscriptionID {
+			writeJSON(w, http.StatusOK, s)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "100225", "the subscription is not existed")
+}
+
+func (p *Platform) deleteSubscription(w http.ResponseWriter, subscriptionID string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for i, s := range p.subs {
+		if s.SubscriptionID == subscriptionID {
+			p.subs = append(p.subs[:i], p.subs[i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "100225", "the subscription is not existed")
+}
+
+// deleteSubscriptions deletes the subscriptions of the type, or all of them
+// when not is empty
+func (p *Platform) deleteSubscriptions(w http.ResponseWriter, not oceanconnect.Notification) {
+	p.lock.Lock()
+	subs := p.subs[:0]
+	for _, s := range p.subs {
+		if not != "" && s.NotifyType != not {
+			subs = append(subs, s)
+		}
+	}
+	p.subs = subs
+	p.lock.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p *Platform) createCommand(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		DeviceID    string                   `json:"deviceId"`
+		Command     oceanconnect.CommandBody `json:"command"`
+		CallbackURL string                   `json:"callbackUrl"`
+		ExpireTime  int64                    `json:"expireTime"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "100022", "the input is invalid")
+		return
+	}
+
+	p.lock.Lock()
+	if _, ok := p.devices[req.DeviceID]; !ok {
+		p.lock.Unlock()
+		writeError(w, http.StatusNotFound, "100403", "the device is not existed")
+		return
+	}
+	p.nextID++
+	cmd := &oceanconnect.DeviceCommand{
+		CommandID:    fmt.Sprintf("command-%04d", p.nextID),
+		AppID:        AppID,
+		DeviceID:     req.DeviceID,
+		Command:      req.Command,
+		CallbackURL:  req.CallbackURL,
+		ExpireTime:   req.ExpireTime,
+		Status:       oceanconnect.CommandPending,
+		CreationTime: oceanconnect.OcTime{Time: time.Now().UTC()},
+	}
+	p.commands[cmd.CommandID] = cmd
+	agent := p.agents[req.DeviceID]
+	resp := *cmd
+	p.lock.Unlock()
+
+	writeJSON(w, http.StatusCreated, resp)
+	if agent != nil {
+		go agent.deliverPending()
+	}
+}
+
+func (p *Platform) listCommands(w http.ResponseWriter, r *http.Request) {
+	pageNo, pageSize := page(r, 25)
+	deviceID := r.FormValue("deviceId")
+
+	p.lock.Lock()
+	var cmds []oceanconnect.DeviceCommand
+	for _, cmd := range p.commands {
+		if deviceID == "" || cmd.DeviceID == deviceID {
+			cmds = append(cmds, *cmd)
+		}
+	}
+	p.lock.Unlock()
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].CommandID < cmds[j].CommandID })
+
+	lo, hi := pageRange(len(cmds), pageNo, pageSize)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"pagination": map[string]interface{}{"pageNo": pageNo, "pageSize": pageSize, "totalSize": len(cmds)},
+		"data":       cmds[lo:hi],
+	})
+}
+
+func (p *Platform) getCommand(w http.ResponseWriter, commandID string) {
+	cmd := p.Command(commandID)
+	if cmd == nil {
+		writeError(w, http.StatusNotFound, "100434", "the command is not existed")
+		return
+	}
+	writeJSON(w, http.StatusOK, cmd)
+}
+
+// updateCommand cancels the command, the only update the platform supports
+func (p *Platform) updateCommand(w http.ResponseWriter, r *http.Request, commandID string) {
+	var req struct {
+		Status oceanconnect.CommandStatus `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Status != oceanconnect.CommandCanceled {
+		writeError(w, http.StatusBadRequest, "100022", "the input is invalid")
+		return
+	}
+
+	p.lock.Lock()
+	cmd, ok := p.commands[commandID]
+	var resp oceanconnect.DeviceCommand
+	if ok && cmd.Status == oceanconnect.CommandPending {
+		cmd.Status = oceanconnect.CommandCanceled
+		resp = *cmd
+	}
+	p.lock.Unlock()
+	switch {
+	case !ok:
+		writeError(w, http.StatusNotFound, "100434", "the command is not existed")
+	case resp.Status != oceanconnect.CommandCanceled:
+		writeError(w, http.StatusBadRequest, "100435", "the command is not pending")
+	default:
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// cancelCommands cancels the pending commands of a device
+func (p *Platform) cancelCommands(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		DeviceID string `json:"deviceId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.DeviceID == "" {
+		writeError(w, http.StatusBadRequest, "100022", "the input is invalid")
+		return
+	}
+
+	p.lock.Lock()
+	if _, ok := p.devices[req.DeviceID]; !ok {
+		p.lock.Unlock()
+		writeError(w, http.StatusNotFound, "100403", "the device is not existed")
+		return
+	}
+	p.nextID++
+	task := oceanconnect.CancelTask{
+		TaskID:   fmt.Sprintf("task-%04d", p.nextID),
+		AppID:    AppID,
+		DeviceID: req.DeviceID,
+		Status:   "SUCCESS",
+	}
+	for _, cmd := range p.commands {
+		if cmd.DeviceID == req.DeviceID && cmd.Status == oceanconnect.CommandPending {
+			cmd.Status = oceanconnect.CommandCanceled
+			task.DeviceCommands = append(task.DeviceCommands, *cmd)
+		}
+	}
+	task.TotalCount = len(task.DeviceCommands)
+	p.lock.Unlock()
+
+	writeJSON(w, http.StatusCreated, task)
+}
+
+// pendingCommands returns the pending commands of the device and marks them
+// as sent
+func (p *Platform) pendingCommands(deviceID string) []oceanconnect.DeviceCommand {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var cmds []oceanconnect.DeviceCommand
+	for _, cmd := range p.commands {
+		if cmd.DeviceID == deviceID && cmd.Status == oceanconnect.CommandPending {
+			cmd.Status = oceanconnect.CommandSent
+			cmd.PlatformIssuedTime = oceanconnect.OcTime{Time: time.Now().UTC()}
+			cmds = append(cmds, *cmd)
+		}
+	}
+	return cmds
+}
+
+// completeCommand stores the result of the command and notifies the
+// application
+func (p *Platform) completeCommand(cmd oceanconnect.DeviceCommand, result interface{}, err error) {
+	status := oceanconnect.CommandSuccessful
+	if err != nil {
+		status = oceanconnect.CommandFailed
+		result = map[string]string{"error": err.Error()}
+	}
+	detail, merr := json.Marshal(result)
+	if merr != nil {
+		status = oceanconnect.CommandFailed
+		detail = []byte("null")
+	}
+
+	p.lock.Lock()
+	if stored, ok := p.commands[cmd.CommandID]; ok {
+		stored.Status = status
+		stored.Result = oceanconnect.CommandResult{ResultCode: string(status), ResultDetail: detail}
+		stored.ExecuteTime = oceanconnect.OcTime{Time: time.Now().UTC()}
+	}
+	p.lock.Unlock()
+
+	if cmd.CallbackURL != "" {
+		p.post(cmd.CallbackURL, CommandStatus(cmd.DeviceID, cmd.CommandID, status, json.RawMessage(detail)))
+	}
+	p.Notify(oceanconnect.NotificationCommandResponse, CommandResponse(cmd.DeviceID, cmd.Command.ServiceID, cmd.Command.Method, cmd.CommandID, json.RawMessage(detail)))
+}
+
+// Notify posts the notification payload, e.g. a fixture, to the subscribers of
+// its type
+func (p *Platform) Notify(not oceanconnect.Notification, payload []byte) {
+	p.lock.Lock()
+	var urls []string
+	for _, s := range p.subs {
+		if s.NotifyType == not {
+			urls = append(urls, s.CallbackURL)
+		}
+	}
+	p.lock.Unlock()
+
+	for _, u := range urls {
+		p.post(u, payload)
+	}
+}
+
+func (p *Platform) post(url string, body []byte) {
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		logrus.Warnf("posting notification to %s failed: %v", url, err)
+		return
+	}
+	resp.Body.Close()
+}
+
+// page returns the pageNo and pageSize of the request
+func page(r *http.Request, defaultSize int) (int, int) {
+	pageNo, _ := strconv.Atoi(r.FormValue("pageNo"))
+	pageSize, err := strconv.Atoi(r.FormValue("pageSize"))
+	if err != nil || pageSize <= 0 {
+		pageSize = defaultSize
+	}
+	if pageNo < 0 {
+		pageNo = 0
+	}
+	return pageNo, pageSize
+}
+
+// pageRange returns the bounds of the page in n items
+func pageRange(n, pageNo, pageSize int) (int, int) {
+	lo := pageNo * pageSize
+	if lo > n {
+		lo = n
+	}
+	hi := lo + pageSize
+	if hi > n {
+		hi = n
+	}
+	return lo, hi
+}
+
+func writeError(w http.ResponseWriter, status int, code, desc string) {
+	writeJSON(w, status, map[string]string{"error_code": code, "error_desc": desc})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.Errorf("writing response failed: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnecttest
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dualinventive/go-oceanconnect"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlatform(t *testing.T) {
+	p := NewPlatform()
+	defer p.Close()
+	c, err := oceanconnect.NewClient(p.Config())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	var ids []string
+	for _, imei := range []string{"123456789012345", "123456789012346"} {
+		reply, err := c.RegisterDevice(imei)
+		if !assert.Nil(t, err) {
+			return
+		}
+		ids = append(ids, reply.DeviceID)
+	}
+	devs, err := c.GetAllDevices(context.Background(), oceanconnect.GetDevicesStruct{PageSize: 1})
+	if assert.Nil(t, err) && assert.Equal(t, 2, len(devs)) {
+		assert.Equal(t, ids[0], devs[0].DeviceID)
+		assert.Equal(t, ids[1], devs[1].DeviceID)
+	}
+
+	// without an agent the commands stay pending
+	opts := oceanconnect.CommandOptions{ExpireTime: time.Hour}
+	first, err := c.SendCommandWithResponse(ids[0], "Switch", "ON", nil, opts)
+	assert.Nil(t, err)
+	_, err = c.SendCommandWithResponse(ids[0], "Switch", "OFF", nil, opts)
+	assert.Nil(t, err)
+	cmds, err := c.ListCommands(oceanconnect.CommandFilter{DeviceID: ids[0]})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(cmds))
+	assert.Nil(t, c.CancelCommand(first.CommandID))
+	assert.Equal(t, oceanconnect.CommandCanceled, p.Command(first.CommandID).Status)
+	task, err := c.CancelAllCommands(ids[0])
+	if assert.Nil(t, err) {
+		assert.Equal(t, 1, task.TotalCount)
+	}
+
+	callback := httptest.NewServer(nil)
+	defer callback.Close()
+	srv, err := c.Subscribe(callback.URL)
+	if !assert.Nil(t, err) {
+		return
+	}
+	callback.Config.Handler = srv
+	subs, err := c.ListSubscriptions("")
+	if assert.Nil(t, err) && assert.Equal(t, 1, len(subs)) {
+		assert.Equal(t, srv.Subscription.SubscriptionID, subs[0].SubscriptionID)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := c.WatchDevice(ctx, ids[1])
+	p.Notify(oceanconnect.NotificationDeviceDataChanged, DeviceDataChanged(ids[1], "Meter", map[string]int{"value": 7}))
+	ev := next(t, events)
+	if assert.Equal(t, oceanconnect.NotificationDeviceDataChanged, ev.Type) {
+		assert.JSONEq(t, `{"value":7}`, string(ev.Data.(*oceanconnect.DeviceDataChanged).Service.Data))
+	}
+
+	assert.Nil(t, c.DeleteSubscription(srv.Subscription.SubscriptionID))
+	subs, err = c.ListSubscriptions("")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(subs))
+
+	assert.Nil(t, c.DeleteDevice(ids[1]))
+	assert.Nil(t, p.Device(ids[1]))
+}
@@ -0,0 +1,99 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RegistrationEvent is passed to the registration hooks after a device is
+// registered successfully
+type RegistrationEvent struct {
+	IMEI       string `json:"imei"`
+	DeviceID   string `json:"deviceId"`
+	VerifyCode string `json:"verifyCode"`
+	PSK        string `json:"psk,omitempty"`
+}
+
+// RegistrationHook is called after a device is registered successfully
+type RegistrationHook func(RegistrationEvent) error
+
+// OnRegistration adds a hook which is called after each successful device
+// registration. Errors returned by hooks are logged and don't fail the
+// registration.
+func (c *Client) OnRegistration(h RegistrationHook) {
+	c.hooksLock.Lock()
+	c.regHooks = append(c.regHooks, h)
+	c.hooksLock.Unlock()
+}
+
+func (c *Client) runRegistrationHooks(ev RegistrationEvent) {
+	c.hooksLock.Lock()
+	hooks := c.regHooks
+	c.hooksLock.Unlock()
+
+	for _, h := range hooks {
+		if err := h(ev); err != nil {
+			logrus.Errorf("registration hook for device %s failed: %v", ev.DeviceID, err)
+		}
+	}
+}
+
+// WebhookOptions configures the hook of WebhookRegistrationHook
+type WebhookOptions struct {
+	// IncludePSK posts the PSK of the device with the event, it is left out
+	// by default so the secret doesn't leave the application
+	IncludePSK bool `yaml:"include_psk"`
+	// Timeout of the post (default 10s)
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// WebhookRegistrationHook returns a hook which posts the registration event as
+// JSON to url, so external systems learn about new devices. The post is done
+// in the background so a slow webhook doesn't delay the registration, errors
+// are logged.
+func WebhookRegistrationHook(url string, opts ...WebhookOptions) RegistrationHook {
+	var o WebhookOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 10 * time.Second
+	}
+	hc := &http.Client{Timeout: o.Timeout}
+	return func(ev RegistrationEvent) error {
+		if !o.IncludePSK {
+			ev.PSK = ""
+		}
+		body, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		go func() {
+			if err := postWebhook(hc, url, body); err != nil {
+				logrus.Errorf("registration webhook for device %s failed: %v", ev.DeviceID, err)
+			}
+		}()
+		return nil
+	}
+}
+
+func postWebhook(hc *http.Client, url string, body []byte) error {
+	resp, err := hc.Post(url, "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("webhook returned: " + resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistrationHooks(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/iocm/app/sec/v1.1.0/login":
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+		case "/iocm/app/reg/v1.2.0/devices":
+			fmt.Fprintln(w, `{"deviceId":"dev1","verifyCode":"123456789012345","timeout":180,"psk":"secret"}`)
+		default:
+			// naming the device fails
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer s.Close()
+
+	c, err := NewClient(Config{URL: s.URL, NameTemplate: "meter-{{.IMEI}}"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	var events []RegistrationEvent
+	c.OnRegistration(func(ev RegistrationEvent) error {
+		events = append(events, ev)
+		return nil
+	})
+
+	reply, err := c.RegisterDevice("123456789012345")
+	assert.NotNil(t, err, "expected the naming error")
+	if assert.NotNil(t, reply) {
+		assert.Equal(t, "dev1", reply.DeviceID)
+	}
+	assert.Equal(t, []RegistrationEvent{{IMEI: "123456789012345", DeviceID: "dev1", VerifyCode: "123456789012345", PSK: "secret"}}, events)
+}
+
+func TestWebhookRegistrationHook(t *testing.T) {
+	bodies := make(chan string, 2)
+	unblock := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies <- string(b)
+		<-unblock
+	}))
+	defer s.Close()
+	defer close(unblock)
+
+	ev := RegistrationEvent{IMEI: "123456789012345", DeviceID: "dev1", VerifyCode: "123456789012345", PSK: "secret"}
+
+	// the hook doesn't wait for the webhook, and leaves the PSK out
+	start := time.Now()
+	assert.Nil(t, WebhookRegistrationHook(s.URL)(ev))
+	assert.True(t, time.Since(start) < time.Second)
+	assert.JSONEq(t, `{"imei":"123456789012345","deviceId":"dev1","verifyCode":"123456789012345"}`, <-bodies)
+
+	assert.Nil(t, WebhookRegistrationHook(s.URL, WebhookOptions{IncludePSK: true})(ev))
+	assert.JSONEq(t, `{"imei":"123456789012345","deviceId":"dev1","verifyCode":"123456789012345","psk":"secret"}`, <-bodies)
+}
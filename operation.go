@@ -0,0 +1,169 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Operation is the kind of API call an error occurred in, e.g. to tell a
+// failing command delivery from a failing device listing when alerting
+type Operation string
+
+// Operations of the API calls, OperationUnknown is used for endpoints which
+// aren't categorized
+const (
+	OperationUnknown        Operation = ""
+	OperationLogin          Operation = "Login"
+	OperationRegisterDevice Operation = "RegisterDevice"
+	OperationGetDevice      Operation = "GetDevice"
+	OperationListDevices    Operation = "ListDevices"
+	OperationUpdateDevice   Operation = "UpdateDevice"
+	OperationDeleteDevice   Operation = "DeleteDevice"
+	OperationSendCommand    Operation = "SendCommand"
+	OperationCommands       Operation = "Commands"
+	OperationDataHistory    Operation = "DataHistory"
+	OperationSubscribe      Operation = "Subscribe"
+	OperationDeviceGroups   Operation = "DeviceGroups"
+	OperationShadow         Operation = "Shadow"
+	OperationUpgrade        Operation = "Upgrade"
+	OperationRules          Operation = "Rules"
+	OperationBatchTask      Operation = "BatchTask"
+	OperationMessages       Operation = "Messages"
+	OperationQuota          Operation = "Quota"
+	OperationProfiles       Operation = "Profiles"
+	OperationCredentials    Operation = "Credentials"
+)
+
+// operationRoutes maps the endpoints to operations, the first route whose
+// method (any when empty) and path prefix match is used. A prefix ending in
+// "$" must match the whole path.
+var operationRoutes = []struct {
+	method string
+	prefix string
+	op     Operation
+}{
+	{"", "/iocm/app/sec/", OperationLogin},
+	{http.MethodPost, "/iocm/app/reg/", OperationRegisterDevice},
+	{http.MethodPut, "/iocm/app/reg/v1.1.0/deviceCredentials/", OperationCredentials},
+	{http.MethodGet, "/iocm/app/dm/v1.1.0/devices$", OperationListDevices},
+	{http.MethodGet, "/iocm/app/dm/v1.1.0/devices/", OperationGetDevice},
+	{http.MethodPut, "/iocm/app/dm/", OperationUpdateDevice},
+	{http.MethodDelete, "/iocm/app/dm/v1.1.0/devices/", OperationDeleteDevice},
+	{"", "/iocm/app/dm/v1.2.0/devgroups/", OperationDeviceGroups},
+	{"", "/iocm/app/devgroup/", OperationDeviceGroups},
+	{http.MethodPost, "/iocm/app/cmd/v1.4.0/deviceCommands$", OperationSendCommand},
+	{"", "/iocm/app/cmd/", OperationCommands},
+	{"", "/iocm/app/data/", OperationDataHistory},
+	{"", "/iocm/app/sub/", OperationSubscribe},
+	{"", "/iocm/app/shadow/", OperationShadow},
+	{"", "/iocm/app/maintenance/", OperationUpgrade},
+	{"", "/iocm/app/fwupgrade/", OperationUpgrade},
+	{"", "/iocm/app/swupgrade/", OperationUpgrade},
+	{"", "/iocm/app/rule/", OperationRules},
+	{"", "/iocm/app/batchtask/", OperationBatchTask},
+	{"", "/iocm/app/signaltrans/", OperationMessages},
+	{"", "/iocm/app/quota/", OperationQuota},
+	{"", "/iocm/app/profile/", OperationProfiles},
+}
+
+// requestOperation returns the operation of the request
+func requestOperation(req *http.Request) Operation {
+	if req == nil || req.URL == nil {
+		return OperationUnknown
+	}
+	for _, r := range operationRoutes {
+		if r.method != "" && r.method != req.Method {
+			continue
+		}
+		if strings.HasSuffix(r.prefix, "$") {
+			if req.URL.Path == strings.TrimSuffix(r.prefix, "$") {
+				return r.op
+			}
+		} else if strings.HasPrefix(req.URL.Path, r.prefix) {
+			return r.op
+		}
+	}
+	return OperationUnknown
+}
+
+// OpError is returned for requests which failed without a response from the
+// platform, like network errors, and for responses which couldn't be
+// decoded. Errors responded by the platform are APIErrors, which carry the
+// Operation as well.
+type OpError struct {
+	Op  Operation
+	Err error
+	// RequestID is the ID the platform assigned to the request whose
+	// response couldn't be decoded, if returned
+	RequestID string
+}
+
+// Error implements the error interface
+func (e *OpError) Error() string {
+	s := e.Err.Error()
+	if e.Op != OperationUnknown {
+		s = string(e.Op) + ": " + s
+	}
+	if e.RequestID != "" {
+		s += " [request " + e.RequestID + "]"
+	}
+	return s
+}
+
+// Unwrap returns the underlying error
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorOperation returns the operation err occurred in, retrieved with
+// errors.As from an OpError or APIError. It returns OperationUnknown for
+// other errors.
+func ErrorOperation(err error) Operation {
+	var opErr *OpError
+	if errors.As(err, &opErr) {
+		return opErr.Op
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Op
+	}
+	return OperationUnknown
+}
+
+// ErrorRequestID returns the ID the platform assigned to the failed request,
+// retrieved with errors.As from an APIError or OpError. It returns an empty
+// string when the platform didn't return one or err has no response.
+func ErrorRequestID(err error) string {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RequestID
+	}
+	var opErr *OpError
+	if errors.As(err, &opErr) {
+		return opErr.RequestID
+	}
+	return ""
+}
+
+// newOpError tags err with the operation of the request, it returns nil
+// when err is nil
+func newOpError(req *http.Request, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &OpError{Op: requestOperation(req), Err: err}
+}
+
+// newResponseOpError is like newOpError for errors reading or decoding the
+// response, the error carries the request ID of the response
+func newResponseOpError(resp *http.Response, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &OpError{Op: requestOperation(resp.Request), Err: err, RequestID: responseRequestID(resp)}
+}
@@ -0,0 +1,154 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Defaults of the OutboxRelay
+const (
+	defaultOutboxInterval    = 5 * time.Second
+	defaultOutboxBatch       = 100
+	defaultOutboxMaxAttempts = 5
+)
+
+// OutboxCommand is a command written to the outbox, to be sent by the
+// OutboxRelay
+type OutboxCommand struct {
+	// ID identifies the command in the store
+	ID        string
+	DeviceID  string
+	ServiceID string
+	Method    string
+	Params    json.RawMessage
+	Options   CommandOptions
+	Created   time.Time
+	// Attempts counts the failed sends
+	Attempts int
+}
+
+// NewOutboxCommand creates the outbox command with a random ID and the params
+// encoded, to be written to the store in the transaction of the application
+func NewOutboxCommand(deviceID, serviceID, method string, params interface{}, opts CommandOptions) (OutboxCommand, error) {
+	buf, err := json.Marshal(params)
+	if err != nil {
+		return OutboxCommand{}, err
+	}
+	return OutboxCommand{
+		ID:        randomID(),
+		DeviceID:  deviceID,
+		ServiceID: serviceID,
+		Method:    method,
+		Params:    buf,
+		Options:   opts,
+		Created:   time.Now().UTC(),
+	}, nil
+}
+
+// OutboxStore is the outbox of the application, usually a table in the
+// database the commands are written to in the same transaction as the state
+// they belong to
+type OutboxStore interface {
+	// Pending returns up to limit commands which are neither sent nor
+	// dead, oldest first
+	Pending(ctx context.Context, limit int) ([]OutboxCommand, error)
+	// MarkSent records the command was accepted by the platform
+	MarkSent(ctx context.Context, id string, cmd *DeviceCommand) error
+	// MarkFailed records a failed send, the command is dead when the relay
+	// gives up on it and must no longer be returned by Pending
+	MarkFailed(ctx context.Context, id string, attempts int, err error, dead bool) error
+}
+
+// OutboxRelay sends the commands of an OutboxStore. A command is only sent
+// again when the platform refused it or the request failed, sent commands
+// whose MarkSent failed are remembered and marked again without sending. A
+// command is only duplicated when the process stops between sending and
+// MarkSent.
+type OutboxRelay struct {
+	// Interval between polls of the store (default 5s)
+	Interval time.Duration
+	// Batch is the number of commands read per poll (default 100)
+	Batch int
+	// MaxAttempts marks commands dead after the number of failed sends
+	// (default 5), commands refused with a 4xx status are dead immediately
+	MaxAttempts int
+
+	client *Client
+	store  OutboxStore
+	// unmarked holds the commands sent but not yet marked in the store
+	unmarked map[string]*DeviceCommand
+}
+
+// NewOutboxRelay creates a relay sending the commands of the store
+func (c *Client) NewOutboxRelay(store OutboxStore) *OutboxRelay {
+	return &OutboxRelay{client: c, store: store, unmarked: make(map[string]*DeviceCommand)}
+}
+
+// Run relays the commands until the context is done
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = defaultOutboxInterval
+	}
+	for {
+		if _, err := r.Relay(ctx); err != nil && ctx.Err() == nil {
+			logrus.Warnf("relaying outbox failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-r.client.clock().After(interval):
+		}
+	}
+}
+
+// Relay sends a batch of pending commands and returns the number sent, Run
+// calls it at every interval. It must not be called concurrently.
+func (r *OutboxRelay) Relay(ctx context.Context) (int, error) {
+	batch := r.Batch
+	if batch <= 0 {
+		batch = defaultOutboxBatch
+	}
+	maxAttempts := r.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultOutboxMaxAttempts
+	}
+
+	cmds, err := r.store.Pending(ctx, batch)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, oc := range cmds {
+		cmd, ok := r.unmarked[oc.ID]
+		if !ok {
+			cmd, err = r.client.SendCommandWithResponseCtx(ctx, oc.DeviceID, oc.ServiceID, oc.Method, oc.Params, oc.Options)
+			if err != nil {
+				if ctx.Err() != nil {
+					return sent, ctx.Err()
+				}
+				oc.Attempts++
+				dead := oc.Attempts >= maxAttempts || !retryable(ctx, err)
+				logrus.Warnf("sending outbox command %s to device %s failed (attempt %d): %v", oc.ID, oc.DeviceID, oc.Attempts, err)
+				if err := r.store.MarkFailed(ctx, oc.ID, oc.Attempts, err, dead); err != nil {
+					return sent, err
+				}
+				continue
+			}
+			sent++
+		}
+		if err := r.store.MarkSent(ctx, oc.ID, cmd); err != nil {
+			r.unmarked[oc.ID] = cmd
+			return sent, err
+		}
+		delete(r.unmarked, oc.ID)
+	}
+	return sent, nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type memOutbox struct {
+	cmds     []OutboxCommand
+	sent     map[string]string
+	dead     map[string]bool
+	failSent int
+}
+
+func (m *memOutbox) Pending(ctx context.Context, limit int) ([]OutboxCommand, error) {
+	var out []OutboxCommand
+	for _, c := range m.cmds {
+		if m.sent[c.ID] == "" && !m.dead[c.ID] && len(out) < limit {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (m *memOutbox) MarkSent(ctx context.Context, id string, cmd *DeviceCommand) error {
+	if m.failSent > 0 {
+		m.failSent--
+		return errors.New("database unavailable")
+	}
+	m.sent[id] = cmd.CommandID
+	return nil
+}
+
+func (m *memOutbox) MarkFailed(ctx context.Context, id string, attempts int, err error, dead bool) error {
+	for i := range m.cmds {
+		if m.cmds[i].ID == id {
+			m.cmds[i].Attempts = attempts
+		}
+	}
+	if dead {
+		m.dead[id] = true
+	}
+	return nil
+}
+
+func TestOutboxRelay(t *testing.T) {
+	posted := map[string]int{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		var body struct {
+			DeviceID string `json:"deviceId"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		posted[body.DeviceID]++
+		switch {
+		case body.DeviceID == "dev2":
+			w.WriteHeader(http.StatusBadRequest)
+		case body.DeviceID == "dev3" && posted["dev3"] == 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"commandId":"cmd-%s","deviceId":%q,"status":"PENDING"}`, body.DeviceID, body.DeviceID)
+		}
+	}))
+	defer s.Close()
+
+	store := &memOutbox{sent: map[string]string{}, dead: map[string]bool{}, failSent: 1}
+	for _, dev := range []string{"dev1", "dev2", "dev3"} {
+		oc, err := NewOutboxCommand(dev, "Valve", "CLOSE", map[string]int{"delay": 5}, CommandOptions{})
+		assert.Nil(t, err)
+		store.cmds = append(store.cmds, oc)
+	}
+
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL}}
+	r := c.NewOutboxRelay(store)
+	ctx := context.Background()
+
+	// marking dev1 as sent fails, the relay stops at the store error
+	n, err := r.Relay(ctx)
+	assert.EqualError(t, err, "database unavailable")
+	assert.Equal(t, 1, n)
+
+	n, err = r.Relay(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, 1, posted["dev1"], "expected sent command not to be sent again")
+	assert.Equal(t, "cmd-dev1", store.sent[store.cmds[0].ID])
+	assert.True(t, store.dead[store.cmds[1].ID])
+	assert.Equal(t, 1, store.cmds[2].Attempts)
+
+	n, err = r.Relay(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, "cmd-dev3", store.sent[store.cmds[2].ID])
+	assert.Equal(t, map[string]int{"dev1": 1, "dev2": 1, "dev3": 2}, posted)
+}
@@ -0,0 +1,109 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// Default limits of the auto-paginating helpers
+const (
+	defaultMaxPages     = 1000
+	defaultMaxItems     = 100000
+	defaultPageDeadline = 10 * time.Minute
+)
+
+// PaginationLimits bounds the helpers which retrieve all pages of a listing,
+// so a bug or platform inconsistency can't cause an unbounded crawl. Zero
+// values use the defaults, negative values disable the limit.
+type PaginationLimits struct {
+	MaxPages int           `yaml:"max_pages"` // MaxPages is the number of pages retrieved (default 1000)
+	MaxItems int           `yaml:"max_items"` // MaxItems is the number of items retrieved (default 100000)
+	Deadline time.Duration `yaml:"deadline"`  // Deadline is the time a listing may take (default 10m)
+}
+
+// PaginationLimitError is returned together with the items retrieved so far
+// when a listing exceeds one of the PaginationLimits
+type PaginationLimitError struct {
+	Limit string // Limit is "max_pages", "max_items" or "deadline"
+	Pages int
+	Items int
+}
+
+// Error implements the error interface
+func (e *PaginationLimitError) Error() string {
+	return "pagination limit " + e.Limit + " exceeded after " + strconv.Itoa(e.Pages) +
+		" pages and " + strconv.Itoa(e.Items) + " items"
+}
+
+// pager tracks a listing against the pagination limits
+type pager struct {
+	limits   PaginationLimits
+	deadline time.Time // deadline is zero without a Deadline limit
+	pages    int
+	items    int
+}
+
+func (c *Client) newPager() *pager {
+	l := c.cfg.Pagination
+	if l.MaxPages == 0 {
+		l.MaxPages = defaultMaxPages
+	}
+	if l.MaxItems == 0 {
+		l.MaxItems = defaultMaxItems
+	}
+	if l.Deadline == 0 {
+		l.Deadline = defaultPageDeadline
+	}
+	p := &pager{limits: l}
+	if l.Deadline > 0 {
+		p.deadline = time.Now().Add(l.Deadline)
+	}
+	return p
+}
+
+// context bounds ctx by the deadline of the listing, so a page in flight is
+// canceled when the deadline passes
+func (p *pager) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.deadline.IsZero() {
+		return ctx, func() {}
+	}
+	return context.WithDeadline(ctx, p.deadline)
+}
+
+// limitErr returns the PaginationLimitError when a page failed because the
+// deadline passed, ctx is the context of the caller
+func (p *pager) limitErr(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() == nil && p.expired() {
+		return &PaginationLimitError{Limit: "deadline", Pages: p.pages, Items: p.items}
+	}
+	return err
+}
+
+func (p *pager) expired() bool {
+	return !p.deadline.IsZero() && !time.Now().Before(p.deadline)
+}
+
+// next records a retrieved page with n items and returns an error when no
+// further page may be retrieved
+func (p *pager) next(n int) error {
+	p.pages++
+	p.items += n
+	limit := ""
+	switch {
+	case p.limits.MaxPages > 0 && p.pages >= p.limits.MaxPages:
+		limit = "max_pages"
+	case p.limits.MaxItems > 0 && p.items >= p.limits.MaxItems:
+		limit = "max_items"
+	case p.expired():
+		limit = "deadline"
+	}
+	if limit == "" {
+		return nil
+	}
+	return &PaginationLimitError{Limit: limit, Pages: p.pages, Items: p.items}
+}
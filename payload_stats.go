@@ -0,0 +1,167 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PayloadStats struct with the sizes and field frequencies of the payloads of
+// a kind
+type PayloadStats struct {
+	Count int
+	// Bytes is the total size of the payloads
+	Bytes int64
+	Max   int
+	// Fields counts the payloads each field occurs in, by path, e.g.
+	// "devices[].services[].data.volume"
+	Fields map[string]int
+}
+
+// Avg returns the average payload size
+func (s PayloadStats) Avg() int64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Bytes / int64(s.Count)
+}
+
+// PayloadRecorder records the sizes and fields of the response and
+// notification payloads, to choose projections and compression for
+// bandwidth-constrained links. It is opt-in: set it as Config.Payloads, or
+// as Server.Payloads for the notifications only.
+type PayloadRecorder struct {
+	lock  sync.Mutex
+	stats map[string]*PayloadStats
+}
+
+// Record records a payload of the kind, e.g. "response GetDevice" or
+// "notification deviceDataChanged"
+func (p *PayloadRecorder) Record(kind string, payload []byte) {
+	fields := make(map[string]bool)
+	var v interface{}
+	if json.Unmarshal(payload, &v) == nil {
+		payloadFields(v, "", fields)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.stats == nil {
+		p.stats = make(map[string]*PayloadStats)
+	}
+	s, ok := p.stats[kind]
+	if !ok {
+		s = &PayloadStats{Fields: make(map[string]int)}
+		p.stats[kind] = s
+	}
+	s.Count++
+	s.Bytes += int64(len(payload))
+	if len(payload) > s.Max {
+		s.Max = len(payload)
+	}
+	for f := range fields {
+		s.Fields[f]++
+	}
+}
+
+// payloadFields adds the paths of the fields of v to fields
+func payloadFields(v interface{}, prefix string, fields map[string]bool) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			path := k
+			if prefix != "" {
+				path = prefix + "." + k
+			}
+			fields[path] = true
+			payloadFields(e, path, fields)
+		}
+	case []interface{}:
+		for _, e := range t {
+			payloadFields(e, prefix+"[]", fields)
+		}
+	}
+}
+
+// Report returns the statistics per kind
+func (p *PayloadRecorder) Report() map[string]PayloadStats {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	ret := make(map[string]PayloadStats, len(p.stats))
+	for kind, s := range p.stats {
+		c := *s
+		c.Fields = make(map[string]int, len(s.Fields))
+		for f, n := range s.Fields {
+			c.Fields[f] = n
+		}
+		ret[kind] = c
+	}
+	return ret
+}
+
+// WriteReport writes the statistics per kind, with the fields by frequency
+// and the share of the payloads they occur in
+func (p *PayloadRecorder) WriteReport(w io.Writer) error {
+	report := p.Report()
+	kinds := make([]string, 0, len(report))
+	for kind := range report {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	var b strings.Builder
+	for _, kind := range kinds {
+		s := report[kind]
+		fmt.Fprintf(&b, "%s: %d payloads, %d bytes, avg %d, max %d\n", kind, s.Count, s.Bytes, s.Avg(), s.Max)
+		fields := make([]string, 0, len(s.Fields))
+		for f := range s.Fields {
+			fields = append(fields, f)
+		}
+		sort.Slice(fields, func(i, j int) bool {
+			if s.Fields[fields[i]] != s.Fields[fields[j]] {
+				return s.Fields[fields[i]] > s.Fields[fields[j]]
+			}
+			return fields[i] < fields[j]
+		})
+		for _, f := range fields {
+			fmt.Fprintf(&b, "  %-40s %5.1f%%\n", f, 100*float64(s.Fields[f])/float64(s.Count))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// recordResponse records the body of a successful response when payloads are
+// recorded, the body is read and replaced. The bodies of logins and
+// registrations aren't recorded, they hold tokens and PSKs.
+func (c *Client) recordResponse(req *http.Request, resp *http.Response) error {
+	if c.cfg.Payloads == nil || resp == nil || resp.StatusCode >= 300 || secretPath(req.URL.Path) {
+		return nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if len(body) == 0 {
+		return nil
+	}
+	kind := string(requestOperation(req))
+	if kind == "" {
+		kind = req.URL.Path
+	}
+	c.cfg.Payloads.Record("response "+kind, body)
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayloadRecorder(t *testing.T) {
+	const device = `{"deviceId":"dev1","services":[{"serviceId":"Meter","data":{"volume":1}},{"serviceId":"Battery"}]}`
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		if r.URL.Path == "/iocm/app/reg/v1.2.0/devices" {
+			fmt.Fprint(w, `{"deviceId":"dev3","verifyCode":"123456789012345","psk":"secret"}`)
+			return
+		}
+		fmt.Fprint(w, device)
+	}))
+	defer s.Close()
+
+	p := &PayloadRecorder{}
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL, Payloads: p}}
+	d, err := c.GetDevice("dev1")
+	assert.Nil(t, err)
+	assert.Equal(t, "dev1", d.DeviceID, "expected the recorded body to be decoded")
+	_, err = c.GetDevice("dev1")
+	assert.Nil(t, err)
+	_, err = c.RegisterDevice("123456789012345")
+	assert.Nil(t, err)
+
+	srv := c.newServer(nil)
+	srv.RegisterCallback(NotificationDeviceAdded, func(interface{}) error { return nil })
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"notifyType":"deviceAdded","deviceId":"dev2"}`))
+	srv.ServeHTTP(httptest.NewRecorder(), req)
+
+	report := p.Report()
+	dev := report["response GetDevice"]
+	assert.Equal(t, 2, dev.Count)
+	assert.Equal(t, int64(2*len(device)), dev.Bytes)
+	assert.Equal(t, len(device), dev.Max)
+	assert.Equal(t, map[string]int{
+		"deviceId":               2,
+		"services":               2,
+		"services[].serviceId":   2,
+		"services[].data":        2,
+		"services[].data.volume": 2,
+	}, dev.Fields)
+	assert.Equal(t, 1, report["notification deviceAdded"].Count)
+	_, ok := report["response Login"]
+	assert.False(t, ok, "expected the login not to be recorded")
+	_, ok = report["response RegisterDevice"]
+	assert.False(t, ok, "expected the registration not to be recorded")
+
+	var b bytes.Buffer
+	assert.Nil(t, p.WriteReport(&b))
+	assert.Contains(t, b.String(), "response GetDevice: 2 payloads, 196 bytes, avg 98, max 98\n  deviceId")
+}
+
+// failingBody fails after the first bytes of the body
+type failingBody struct{ read bool }
+
+func (b *failingBody) Read(p []byte) (int, error) {
+	if b.read {
+		return 0, errors.New("connection reset")
+	}
+	b.read = true
+	return copy(p, `{"deviceId"`), nil
+}
+
+func (b *failingBody) Close() error { return nil }
+
+func TestRecordResponseError(t *testing.T) {
+	c := &Client{cfg: Config{Payloads: &PayloadRecorder{}}}
+	req := httptest.NewRequest(http.MethodGet, "/iocm/app/dm/v1.1.0/devices/dev1", nil)
+	err := c.recordResponse(req, &http.Response{StatusCode: http.StatusOK, Body: &failingBody{}})
+	assert.EqualError(t, err, "connection reset")
+}
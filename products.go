@@ -0,0 +1,103 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"net/http"
+)
+
+// Product is a device model on the platform, created by uploading its device
+// profile
+type Product struct {
+	ProductID        string       `json:"productId"`
+	ProductName      string       `json:"productName,omitempty"`
+	DeviceType       string       `json:"deviceType"`
+	ManufacturerID   string       `json:"manufacturerId"`
+	ManufacturerName string       `json:"manufacturerName"`
+	Model            string       `json:"model"`
+	ProtocolType     ProtocolType `json:"protocolType"`
+	CreateTime       OcTime       `json:"createTime"`
+}
+
+type productsResponse struct {
+	TotalCount int       `json:"totalCount"`
+	Products   []Product `json:"products"`
+}
+
+// ListProducts returns the products of the application
+func (c *Client) ListProducts() ([]Product, error) {
+	return c.ListProductsCtx(context.Background())
+}
+
+// ListProductsCtx is like ListProducts but with a context
+func (c *Client) ListProductsCtx(ctx context.Context) ([]Product, error) {
+	const pageSize = 100
+	p := c.newPager()
+	pctx, cancel := p.context(ctx)
+	defer cancel()
+	var products []Product
+	for page := 0; ; page++ {
+		e := c.appEndpoint("/iocm/app/profile/v1.1.0/products").SetInt("pageNo", page).SetInt("pageSize", pageSize)
+		resp, err := c.requestCtx(pctx, http.MethodGet, e.String(), nil)
+		if err != nil {
+			return products, p.limitErr(ctx, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return products, c.newAPIError(resp)
+		}
+		r := productsResponse{}
+		if err := c.decode(resp, &r); err != nil {
+			return products, p.limitErr(ctx, err)
+		}
+		products = append(products, r.Products...)
+		if len(r.Products) < pageSize {
+			return products, nil
+		}
+		if err := p.next(len(r.Products)); err != nil {
+			return products, err
+		}
+	}
+}
+
+// GetProduct returns the product with the ID
+func (c *Client) GetProduct(productID string) (*Product, error) {
+	return c.GetProductCtx(context.Background(), productID)
+}
+
+// GetProductCtx is like GetProduct but with a context
+func (c *Client) GetProductCtx(ctx context.Context, productID string) (*Product, error) {
+	resp, err := c.requestCtx(ctx, http.MethodGet, c.appEndpoint("/iocm/app/profile/v1.1.0/products", productID).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.newAPIError(resp)
+	}
+	p := &Product{}
+	if err := c.decode(resp, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// DeleteProduct deletes the product and its device profile, the platform
+// refuses it while devices of the product exist
+func (c *Client) DeleteProduct(productID string) error {
+	return c.DeleteProductCtx(context.Background(), productID)
+}
+
+// DeleteProductCtx is like DeleteProduct but with a context
+func (c *Client) DeleteProductCtx(ctx context.Context, productID string) error {
+	resp, err := c.requestCtx(ctx, http.MethodDelete, c.appEndpoint("/iocm/app/profile/v1.1.0/products", productID).String(), nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return c.newAPIError(resp)
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,402 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Data types of the profile properties
+const (
+	ProfileInt        = "int"
+	ProfileDecimal    = "decimal"
+	ProfileString     = "string"
+	ProfileDateTime   = "DateTime"
+	ProfileJSONObject = "jsonObject"
+	ProfileArray      = "array"
+)
+
+// ProfileProperty is a property of a service, or a parameter of a command,
+// in a device profile
+type ProfileProperty struct {
+	Name      string   `json:"propertyName,omitempty"`
+	ParaName  string   `json:"paraName,omitempty"`
+	DataType  string   `json:"dataType"`
+	Required  bool     `json:"required"`
+	Min       string   `json:"min,omitempty"`
+	Max       string   `json:"max,omitempty"`
+	Step      float64  `json:"step,omitempty"`
+	MaxLength int      `json:"maxLength,omitempty"`
+	Method    string   `json:"method,omitempty"`
+	Unit      string   `json:"unit,omitempty"`
+	EnumList  []string `json:"enumList,omitempty"`
+}
+
+// ProfileCommand is a command of a service in a device profile
+type ProfileCommand struct {
+	Name      string            `json:"commandName"`
+	Paras     []ProfileProperty `json:"paras"`
+	Responses []ProfileResponse `json:"responses,omitempty"`
+}
+
+// ProfileResponse is the response of a command in a device profile
+type ProfileResponse struct {
+	Name  string            `json:"responseName"`
+	Paras []ProfileProperty `json:"paras"`
+}
+
+// ProfileService is a service of a device profile
+type ProfileService struct {
+	ServiceType string            `json:"serviceType"`
+	Description string            `json:"description,omitempty"`
+	Properties  []ProfileProperty `json:"properties"`
+	Commands    []ProfileCommand  `json:"commands"`
+	// Optional marks the service as optional for the devices of the type
+	Optional bool `json:"-"`
+}
+
+// ProfileBuilder builds a device profile package (devicetype-capability.json
+// and a servicetype-capability.json per service) from Go structs, so the
+// device model can be kept in versioned code. The properties are read from
+// the exported fields, named by their json tag, with options from the
+// profile tag:
+//
+//	Volume float64 `json:"volume" profile:"required,min=0,max=99999,unit=m3"`
+//
+// The options are required, min, max, step, maxlen, unit, method (e.g. "RW")
+// and enum (values separated by |). Fields tagged profile:"-" are skipped.
+type ProfileBuilder struct {
+	DeviceType       string
+	ManufacturerID   string
+	ManufacturerName string
+	Model            string
+	ProtocolType     ProtocolType
+
+	services []*ProfileService
+}
+
+// NewProfileBuilder creates a builder for the device type, manufacturer,
+// model and protocol from the config
+func (c *Client) NewProfileBuilder() *ProfileBuilder {
+	return &ProfileBuilder{
+		DeviceType:       c.cfg.DeviceType,
+		ManufacturerID:   c.cfg.ManufacturerID,
+		ManufacturerName: c.cfg.ManufacturerName,
+		Model:            c.cfg.Model,
+		ProtocolType:     c.cfg.ProtocolType,
+	}
+}
+
+// service returns the service, it is added when it doesn't exist
+func (b *ProfileBuilder) service(serviceType string) *ProfileService {
+	for _, s := range b.services {
+		if s.ServiceType == serviceType {
+			return s
+		}
+	}
+	s := &ProfileService{ServiceType: serviceType, Properties: []ProfileProperty{}, Commands: []ProfileCommand{}}
+	b.services = append(b.services, s)
+	return s
+}
+
+// AddService adds the properties of the struct v, or a pointer to it, as
+// service. The properties are readable and reportable unless the method
+// option is set.
+func (b *ProfileBuilder) AddService(serviceType string, v interface{}) error {
+	props, err := profileProperties(v, false)
+	if err != nil {
+		return err
+	}
+	for i := range props {
+		if props[i].Method == "" {
+			props[i].Method = "RE"
+		}
+	}
+	s := b.service(serviceType)
+	s.Properties = append(s.Properties, props...)
+	return nil
+}
+
+// AddCommand adds a command to the service with the parameters of the
+// struct params, and the response with the parameters of response when it
+// isn't nil
+func (b *ProfileBuilder) AddCommand(serviceType, name string, params, response interface{}) error {
+	cmd := ProfileCommand{Name: name, Paras: []ProfileProperty{}}
+	if params != nil {
+		paras, err := profileProperties(params, true)
+		if err != nil {
+			return err
+		}
+		cmd.Paras = paras
+	}
+	if response != nil {
+		paras, err := profileProperties(response, true)
+		if err != nil {
+			return err
+		}
+		cmd.Responses = []ProfileResponse{{Name: name + "_RSP", Paras: paras}}
+	}
+	s := b.service(serviceType)
+	s.Commands = append(s.Commands, cmd)
+	return nil
+}
+
+// Services returns the services added to the builder
+func (b *ProfileBuilder) Services() []ProfileService {
+	ret := make([]ProfileService, len(b.services))
+	for i, s := range b.services {
+		ret[i] = *s
+	}
+	return ret
+}
+
+// DeviceCapability returns the devicetype-capability.json of the profile
+func (b *ProfileBuilder) DeviceCapability() ([]byte, error) {
+	if b.DeviceType == "" || b.ManufacturerID == "" || b.Model == "" {
+		return nil, errors.New("profile needs a device type, manufacturer ID and model")
+	}
+	type serviceCapability struct {
+		ServiceID   string `json:"serviceId"`
+		ServiceType string `json:"serviceType"`
+		Option      string `json:"option"`
+	}
+	type device struct {
+		DeviceType       string              `json:"deviceType"`
+		ManufacturerID   string              `json:"manufacturerId"`
+		ManufacturerName string              `json:"manufacturerName"`
+		Model            string              `json:"model"`
+		ProtocolType     ProtocolType        `json:"protocolType"`
+		Services         []serviceCapability `json:"serviceTypeCapabilities"`
+	}
+	d := device{
+		DeviceType:       b.DeviceType,
+		ManufacturerID:   b.ManufacturerID,
+		ManufacturerName: b.ManufacturerName,
+		Model:            b.Model,
+		ProtocolType:     b.ProtocolType,
+		Services:         []serviceCapability{},
+	}
+	if d.ProtocolType == "" {
+		d.ProtocolType = ProtocolCoAP
+	}
+	for _, s := range b.services {
+		option := "Mandatory"
+		if s.Optional {
+			option = "Optional"
+		}
+		d.Services = append(d.Services, serviceCapability{ServiceID: s.ServiceType, ServiceType: s.ServiceType, Option: option})
+	}
+	return json.MarshalIndent(map[string][]device{"devices": {d}}, "", "  ")
+}
+
+// ServiceCapability returns the servicetype-capability.json of the service
+func (b *ProfileBuilder) ServiceCapability(serviceType string) ([]byte, error) {
+	for _, s := range b.services {
+		if s.ServiceType == serviceType {
+			return json.MarshalIndent(map[string][]*ProfileService{"services": {s}}, "", "  ")
+		}
+	}
+	return nil, ErrServiceNotFound
+}
+
+// WriteZip writes the profile package as zip, in the layout the platform
+// expects
+func (b *ProfileBuilder) WriteZip(w io.Writer) error {
+	dev, err := b.DeviceCapability()
+	if err != nil {
+		return err
+	}
+	root := b.DeviceType + "_" + b.ManufacturerID + "_" + b.Model + "/"
+	zw := zip.NewWriter(w)
+	files := [][2]string{{root + "profile/devicetype-capability.json", string(dev)}}
+	for _, s := range b.services {
+		svc, err := b.ServiceCapability(s.ServiceType)
+		if err != nil {
+			return err
+		}
+		files = append(files, [2]string{root + "service/" + s.ServiceType + "/profile/servicetype-capability.json", string(svc)})
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f[0])
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f[1]); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// UploadProfile uploads the profile package built by b to the platform
+func (c *Client) UploadProfile(b *ProfileBuilder) error {
+	return c.UploadProfileCtx(context.Background(), b)
+}
+
+// UploadProfileCtx is like UploadProfile but with a context
+func (c *Client) UploadProfileCtx(ctx context.Context, b *ProfileBuilder) error {
+	var buf bytes.Buffer
+	if err := b.WriteZip(&buf); err != nil {
+		return err
+	}
+	return c.uploadProfile(ctx, b.DeviceType+"_"+b.ManufacturerID+"_"+b.Model+".zip", &buf)
+}
+
+// UploadDeviceProfile uploads a device profile package, the product model
+// zip file as exported by the web console or written by a ProfileBuilder
+func (c *Client) UploadDeviceProfile(r io.Reader) error {
+	return c.UploadDeviceProfileCtx(context.Background(), r)
+}
+
+// UploadDeviceProfileCtx is like UploadDeviceProfile but with a context
+func (c *Client) UploadDeviceProfileCtx(ctx context.Context, r io.Reader) error {
+	return c.uploadProfile(ctx, "profile.zip", r)
+}
+
+func (c *Client) uploadProfile(ctx context.Context, filename string, r io.Reader) error {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	fw, err := w.CreateFormFile("file", filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(fw, r); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	resp, err := c.requestContent(ctx, opUpload, http.MethodPost, c.appEndpoint("/iocm/app/profile/v1.1.0/profiles").String(), w.FormDataContentType(), &body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return c.newAPIError(resp)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// profileProperties reads the properties from the fields of the struct v,
+// as command parameters when paras is set
+func profileProperties(v interface{}, paras bool) ([]ProfileProperty, error) {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("profile: %T is not a struct", v)
+	}
+
+	props := []ProfileProperty{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag := f.Tag.Get("profile")
+		if tag == "-" {
+			continue
+		}
+		name := f.Name
+		if j := strings.Split(f.Tag.Get("json"), ",")[0]; j == "-" {
+			continue
+		} else if j != "" {
+			name = j
+		}
+
+		p := ProfileProperty{DataType: profileDataType(f.Type)}
+		if p.DataType == "" {
+			return nil, fmt.Errorf("profile: unsupported type %s of field %s", f.Type, f.Name)
+		}
+		if f.Type.Kind() == reflect.Bool {
+			p.Min, p.Max = "0", "1"
+		}
+		if tag != "" {
+			for _, opt := range strings.Split(tag, ",") {
+				key, val := opt, ""
+				if k := strings.Index(opt, "="); k >= 0 {
+					key, val = opt[:k], opt[k+1:]
+				}
+				if err := p.setOption(key, val); err != nil {
+					return nil, fmt.Errorf("profile: field %s: %v", f.Name, err)
+				}
+			}
+		}
+		if paras {
+			p.ParaName = name
+		} else {
+			p.Name = name
+		}
+		props = append(props, p)
+	}
+	return props, nil
+}
+
+// setOption sets an option of the profile tag
+func (p *ProfileProperty) setOption(key, val string) error {
+	var err error
+	switch key {
+	case "":
+	case "required":
+		p.Required = true
+	case "min":
+		p.Min = val
+	case "max":
+		p.Max = val
+	case "step":
+		p.Step, err = strconv.ParseFloat(val, 64)
+	case "maxlen":
+		p.MaxLength, err = strconv.Atoi(val)
+	case "unit":
+		p.Unit = val
+	case "method":
+		p.Method = val
+	case "enum":
+		p.EnumList = strings.Split(val, "|")
+	default:
+		return errors.New("unknown option " + key)
+	}
+	return err
+}
+
+// profileDataType returns the profile data type of a Go type, empty when it
+// can't be represented
+func profileDataType(t reflect.Type) string {
+	if t == timeType || t == reflect.TypeOf(OcTime{}) {
+		return ProfileDateTime
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Bool:
+		return ProfileInt
+	case reflect.Float32, reflect.Float64:
+		return ProfileDecimal
+	case reflect.String:
+		return ProfileString
+	case reflect.Slice, reflect.Array:
+		return ProfileArray
+	case reflect.Struct, reflect.Map:
+		return ProfileJSONObject
+	case reflect.Ptr:
+		return profileDataType(t.Elem())
+	}
+	return ""
+}
@@ -0,0 +1,135 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type profileMeter struct {
+	Volume  float64   `json:"volume" profile:"required,min=0,max=99999,unit=m3"`
+	Leak    bool      `json:"leak"`
+	Read    time.Time `json:"readTime"`
+	Serial  string    `json:"serial" profile:"maxlen=20,method=R"`
+	Ignored string    `json:"-"`
+	private int
+}
+
+type profileValve struct {
+	State string `json:"state" profile:"required,enum=OPEN|CLOSED"`
+}
+
+func TestProfileBuilder(t *testing.T) {
+	c := &Client{cfg: Config{DeviceType: "WaterMeter", ManufacturerID: "acme", Model: "WM1"}}
+	b := c.NewProfileBuilder()
+	assert.Nil(t, b.AddService("Meter", &profileMeter{}))
+	assert.Nil(t, b.AddCommand("Valve", "SET", profileValve{}, profileValve{}))
+	assert.NotNil(t, b.AddService("Bad", 3))
+	assert.NotNil(t, b.AddService("Bad", struct {
+		X int `profile:"bogus"`
+	}{}))
+
+	svcs := b.Services()
+	if assert.Len(t, svcs, 2) {
+		assert.Equal(t, []ProfileProperty{
+			{Name: "volume", DataType: ProfileDecimal, Required: true, Min: "0", Max: "99999", Unit: "m3", Method: "RE"},
+			{Name: "leak", DataType: ProfileInt, Min: "0", Max: "1", Method: "RE"},
+			{Name: "readTime", DataType: ProfileDateTime, Method: "RE"},
+			{Name: "serial", DataType: ProfileString, MaxLength: 20, Method: "R"},
+		}, svcs[0].Properties)
+		assert.Equal(t, []ProfileProperty{{ParaName: "state", DataType: ProfileString, Required: true, EnumList: []string{"OPEN", "CLOSED"}}}, svcs[1].Commands[0].Paras)
+		assert.Equal(t, "SET_RSP", svcs[1].Commands[0].Responses[0].Name)
+	}
+
+	dev, err := b.DeviceCapability()
+	assert.Nil(t, err)
+	assert.Contains(t, string(dev), `"serviceTypeCapabilities": [`)
+	assert.Contains(t, string(dev), `"protocolType": "CoAP"`)
+
+	var uploaded []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			w.Write([]byte(`{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`))
+			return
+		}
+		f, _, err := r.FormFile("file")
+		if !assert.Nil(t, err) {
+			return
+		}
+		buf, _ := ioutil.ReadAll(f)
+		zr, err := zip.NewReader(bytes.NewReader(buf), int64(len(buf)))
+		if assert.Nil(t, err) {
+			for _, zf := range zr.File {
+				uploaded = append(uploaded, zf.Name)
+			}
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer s.Close()
+	c.c = &http.Client{}
+	c.cfg.URL = s.URL
+	assert.Nil(t, c.UploadProfile(b))
+	assert.Equal(t, []string{
+		"WaterMeter_acme_WM1/profile/devicetype-capability.json",
+		"WaterMeter_acme_WM1/service/Meter/profile/servicetype-capability.json",
+		"WaterMeter_acme_WM1/service/Valve/profile/servicetype-capability.json",
+	}, uploaded)
+}
+
+func TestProducts(t *testing.T) {
+	var calls []string
+	var uploaded []byte
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			w.Write([]byte(`{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`))
+			return
+		}
+		calls = append(calls, r.Method+" "+r.URL.String())
+		switch r.Method {
+		case http.MethodPost:
+			f, _, err := r.FormFile("file")
+			if assert.Nil(t, err) {
+				uploaded, _ = ioutil.ReadAll(f)
+			}
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			if r.URL.Path == "/iocm/app/profile/v1.1.0/products" {
+				w.Write([]byte(`{"totalCount":1,"products":[{"productId":"p1","deviceType":"WaterMeter","model":"wm1"}]}`))
+				return
+			}
+			w.Write([]byte(`{"productId":"p1","deviceType":"WaterMeter","model":"wm1","createTime":"20170912T100000Z"}`))
+		}
+	}))
+	defer s.Close()
+
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL, AppID: "app"}}
+	assert.Nil(t, c.UploadDeviceProfile(bytes.NewReader([]byte("PK zip"))))
+	assert.Equal(t, "PK zip", string(uploaded))
+
+	products, err := c.ListProducts()
+	assert.Nil(t, err)
+	assert.Equal(t, []Product{{ProductID: "p1", DeviceType: "WaterMeter", Model: "wm1"}}, products)
+
+	p, err := c.GetProduct("p1")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2017, 9, 12, 10, 0, 0, 0, time.UTC), p.CreateTime.Time)
+	assert.Nil(t, c.DeleteProduct("p1"))
+
+	assert.Equal(t, []string{
+		"POST /iocm/app/profile/v1.1.0/profiles?appId=app",
+		"GET /iocm/app/profile/v1.1.0/products?appId=app&pageNo=0&pageSize=100",
+		"GET /iocm/app/profile/v1.1.0/products/p1?appId=app",
+		"DELETE /iocm/app/profile/v1.1.0/products/p1?appId=app",
+	}, calls)
+}
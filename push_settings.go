@@ -0,0 +1,156 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+)
+
+// ErrNoCertificate is returned when uploading PEM data without certificates
+var ErrNoCertificate = errors.New("no certificate found in PEM data")
+
+// PushProtocol is the protocol the platform pushes notifications with
+type PushProtocol string
+
+// Push protocols
+const (
+	PushProtocolHTTP  PushProtocol = "HTTP"
+	PushProtocolHTTPS PushProtocol = "HTTPS"
+)
+
+// PushSettings struct with the global notification push settings of the
+// application
+type PushSettings struct {
+	Protocol PushProtocol `json:"protocol"`
+	// CallbackURL is the default callback of subscriptions
+	CallbackURL string `json:"callbackUrl,omitempty"`
+	// VerifyServer makes the platform verify the certificate of the callback
+	// server against the uploaded CA certificate
+	VerifyServer bool `json:"verifyServer"`
+	// CACertificateID is the ID of the uploaded CA certificate
+	CACertificateID string `json:"caCertificateId,omitempty"`
+}
+
+// GetPushSettings returns the push settings of the application
+func (c *Client) GetPushSettings() (*PushSettings, error) {
+	return c.GetPushSettingsCtx(context.Background())
+}
+
+// GetPushSettingsCtx is like GetPushSettings but with a context
+func (c *Client) GetPushSettingsCtx(ctx context.Context) (*PushSettings, error) {
+	resp, err := c.requestCtx(ctx, http.MethodGet, c.appEndpoint("/iocm/app/sub/v1.2.0/pushSettings").String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.newAPIError(resp)
+	}
+	s := &PushSettings{}
+	if err := c.decode(resp, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// UpdatePushSettings replaces the push settings of the application
+func (c *Client) UpdatePushSettings(s PushSettings) error {
+	return c.UpdatePushSettingsCtx(context.Background(), s)
+}
+
+// UpdatePushSettingsCtx is like UpdatePushSettings but with a context
+func (c *Client) UpdatePushSettingsCtx(ctx context.Context, s PushSettings) error {
+	body, err := c.codec().Marshal(s)
+	if err != nil {
+		return err
+	}
+	resp, err := c.requestCtx(ctx, http.MethodPut, c.appEndpoint("/iocm/app/sub/v1.2.0/pushSettings").String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return c.newAPIError(resp)
+	}
+	return nil
+}
+
+// UploadPushCACertificate uploads the PEM CA certificates the platform
+// verifies the callback server with and returns the certificate ID, see
+// PushSettings.VerifyServer
+func (c *Client) UploadPushCACertificate(pemData []byte) (string, error) {
+	return c.UploadPushCACertificateCtx(context.Background(), pemData)
+}
+
+// UploadPushCACertificateCtx is like UploadPushCACertificate but with a context
+func (c *Client) UploadPushCACertificateCtx(ctx context.Context, pemData []byte) (string, error) {
+	return c.uploadCertificate(ctx, "/iocm/app/sub/v1.2.0/pushSettings/caCertificates", pemData)
+}
+
+// UploadCallbackCertificate registers the PEM certificate chain of the
+// callback server, leaf certificate first, and returns the certificate ID.
+// Uploading a new chain replaces the previous one, so certificates can be
+// rotated without changing the subscriptions.
+func (c *Client) UploadCallbackCertificate(pemData []byte) (string, error) {
+	return c.UploadCallbackCertificateCtx(context.Background(), pemData)
+}
+
+// UploadCallbackCertificateCtx is like UploadCallbackCertificate but with a context
+func (c *Client) UploadCallbackCertificateCtx(ctx context.Context, pemData []byte) (string, error) {
+	return c.uploadCertificate(ctx, "/iocm/app/sub/v1.2.0/pushSettings/serverCertificates", pemData)
+}
+
+// uploadCertificate uploads the PEM certificates to the path and returns the
+// ID the platform assigned
+func (c *Client) uploadCertificate(ctx context.Context, path string, pemData []byte) (string, error) {
+	if err := checkCertificates(pemData); err != nil {
+		return "", err
+	}
+	body, err := c.codec().Marshal(map[string]string{"content": string(pemData)})
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.requestOp(ctx, opUpload, http.MethodPost, c.appEndpoint(path).String(), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return "", c.newAPIError(resp)
+	}
+	r := struct {
+		CertificateID string `json:"certificateId"`
+	}{}
+	if err := c.decode(resp, &r); err != nil {
+		return "", err
+	}
+	return r.CertificateID, nil
+}
+
+// checkCertificates checks the PEM data holds only valid certificates
+func checkCertificates(pemData []byte) error {
+	n := 0
+	for rest := pemData; ; {
+		var b *pem.Block
+		b, rest = pem.Decode(rest)
+		if b == nil {
+			break
+		}
+		if b.Type != "CERTIFICATE" {
+			return errors.New("unexpected PEM block: " + b.Type)
+		}
+		if _, err := x509.ParseCertificate(b.Bytes); err != nil {
+			return err
+		}
+		n++
+	}
+	if n == 0 {
+		return ErrNoCertificate
+	}
+	return nil
+}
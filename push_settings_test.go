@@ -0,0 +1,69 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPushSettings(t *testing.T) {
+	var updated PushSettings
+	var uploaded string
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/iocm/app/sec/v1.1.0/login":
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+		case r.URL.Path == "/iocm/app/sub/v1.2.0/pushSettings" && r.Method == http.MethodGet:
+			fmt.Fprintln(w, `{"protocol":"HTTPS","callbackUrl":"https://cb","verifyServer":true,"caCertificateId":"ca1"}`)
+		case r.URL.Path == "/iocm/app/sub/v1.2.0/pushSettings":
+			json.NewDecoder(r.Body).Decode(&updated)
+		case r.URL.Path == "/iocm/app/sub/v1.2.0/pushSettings/caCertificates",
+			r.URL.Path == "/iocm/app/sub/v1.2.0/pushSettings/serverCertificates":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			uploaded = body["content"]
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"certificateId":"%s"}`, path.Base(r.URL.Path))
+		}
+	}))
+	defer s.Close()
+
+	c := Client{c: s.Client(), cfg: Config{URL: s.URL, AppID: "app"}}
+
+	ps, err := c.GetPushSettings()
+	if assert.Nil(t, err) {
+		assert.Equal(t, PushSettings{Protocol: PushProtocolHTTPS, CallbackURL: "https://cb", VerifyServer: true, CACertificateID: "ca1"}, *ps)
+	}
+
+	_, err = c.UploadPushCACertificate([]byte("garbage"))
+	assert.Equal(t, ErrNoCertificate, err)
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw})
+	id, err := c.UploadPushCACertificate(ca)
+	assert.Nil(t, err)
+	assert.Equal(t, "caCertificates", id)
+	assert.Equal(t, string(ca), uploaded)
+
+	ps.CACertificateID = id
+	assert.Nil(t, c.UpdatePushSettings(*ps))
+	assert.Equal(t, "caCertificates", updated.CACertificateID)
+
+	chain := append(ca, ca...)
+	id, err = c.UploadCallbackCertificate(chain)
+	assert.Nil(t, err)
+	assert.Equal(t, "serverCertificates", id)
+	assert.Equal(t, string(chain), uploaded)
+
+	_, err = c.UploadCallbackCertificate(append(chain, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte{1}})...))
+	assert.NotNil(t, err, "expected error for private key")
+}
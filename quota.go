@@ -0,0 +1,62 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// ErrNotSupported is returned when the platform doesn't offer the endpoint
+var ErrNotSupported = errors.New("not supported by the platform")
+
+// Quota struct with a limit and the current usage
+type Quota struct {
+	Limit int64 `json:"limit"`
+	Used  int64 `json:"used"`
+}
+
+// Remaining returns the remaining part of the quota
+func (q Quota) Remaining() int64 {
+	return q.Limit - q.Used
+}
+
+// AppQuotas struct with the quotas of the application
+type AppQuotas struct {
+	Devices       Quota `json:"devices"`       // registered devices
+	Messages      Quota `json:"messages"`      // messages per day
+	Commands      Quota `json:"commands"`      // commands per day
+	Subscriptions Quota `json:"subscriptions"` // notification subscriptions
+	// RequestRate is the allowed number of API calls per second
+	RequestRate int64 `json:"requestRate"`
+}
+
+// GetAppQuotas returns the quotas and the current usage of the application.
+// Not all platform versions offer the quota endpoint, ErrNotSupported is
+// returned for those.
+func (c *Client) GetAppQuotas() (*AppQuotas, error) {
+	return c.GetAppQuotasCtx(context.Background())
+}
+
+// GetAppQuotasCtx is like GetAppQuotas but with a context
+func (c *Client) GetAppQuotasCtx(ctx context.Context) (*AppQuotas, error) {
+	resp, err := c.requestCtx(ctx, http.MethodGet, c.appEndpoint("/iocm/app/quota/v1.1.0/quotas").String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotSupported
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.newAPIError(resp)
+	}
+	q := &AppQuotas{}
+	if err := c.decode(resp, q); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAppQuotas(t *testing.T) {
+	status := http.StatusOK
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		assert.Equal(t, "/iocm/app/quota/v1.1.0/quotas", r.URL.Path)
+		assert.Equal(t, "app", r.URL.Query().Get("appId"))
+		w.WriteHeader(status)
+		switch status {
+		case http.StatusOK:
+			fmt.Fprintln(w, `{"devices":{"limit":10000,"used":9500},"messages":{"limit":1000000,"used":1200},"commands":{"limit":50000,"used":0},"subscriptions":{"limit":20,"used":8},"requestRate":100}`)
+		case http.StatusForbidden:
+			fmt.Fprintln(w, `{"error_code":"100001","error_desc":"no permission"}`)
+		}
+	}))
+	defer s.Close()
+
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL, AppID: "app"}}
+	q, err := c.GetAppQuotas()
+	if assert.Nil(t, err) {
+		assert.Equal(t, Quota{Limit: 10000, Used: 9500}, q.Devices)
+		assert.Equal(t, int64(500), q.Devices.Remaining())
+		assert.Equal(t, int64(12), q.Subscriptions.Remaining())
+		assert.Equal(t, int64(100), q.RequestRate)
+	}
+
+	// platform versions without the quota endpoint
+	status = http.StatusNotFound
+	_, err = c.GetAppQuotas()
+	assert.Equal(t, ErrNotSupported, err)
+
+	status = http.StatusForbidden
+	_, err = c.GetAppQuotas()
+	if assert.IsType(t, &APIError{}, err) {
+		assert.Equal(t, "100001", err.(*APIError).Code)
+	}
+}
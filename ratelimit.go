@@ -0,0 +1,102 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimit is the rate-limit budget as announced by the platform in the
+// X-RateLimit headers of the last response
+type RateLimit struct {
+	Known     bool      // Known is false until the platform sent rate-limit headers
+	Limit     int       // Limit is the number of requests allowed per window
+	Remaining int       // Remaining is the number of requests left in the window
+	Reset     time.Time // Reset is when the window resets
+}
+
+// RateLimitStatus returns the rate-limit budget of the application
+func (c *Client) RateLimitStatus() RateLimit {
+	c.rateLock.Lock()
+	defer c.rateLock.Unlock()
+	return c.rate
+}
+
+// updateRateLimit records the rate-limit headers of the response, responses
+// without them leave the status as is
+func (c *Client) updateRateLimit(resp *http.Response) {
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	r := RateLimit{Known: true, Remaining: remaining}
+	r.Limit, _ = strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		r.Reset = c.resetTime(reset)
+	}
+
+	c.rateLock.Lock()
+	c.rate = r
+	c.rateLock.Unlock()
+}
+
+// resetTime returns the time of the X-RateLimit-Reset header. The platform
+// sends either an epoch timestamp or the seconds left, the seconds left are
+// tiny as timestamp and lie decades before now.
+func (c *Client) resetTime(reset int64) time.Time {
+	now := c.clock().Now()
+	if at := time.Unix(reset, 0); at.After(now.Add(-time.Hour)) {
+		return at
+	}
+	return now.Add(time.Duration(reset) * time.Second)
+}
+
+// waitRateLimit blocks until the window resets when the budget is used up,
+// and spaces the requests when the limiter is enabled
+func (c *Client) waitRateLimit(ctx context.Context) error {
+	now := c.clock().Now()
+	var d time.Duration
+	c.rateLock.Lock()
+	r := c.rate
+	if r.Known && r.Remaining <= 0 && r.Reset.After(now) {
+		d = r.Reset.Sub(now)
+	} else if interval := c.requestInterval(r, now); interval > 0 {
+		next := c.rateNext
+		if next.Before(now) {
+			next = now
+		}
+		d = next.Sub(now)
+		c.rateNext = next.Add(interval)
+	}
+	c.rateLock.Unlock()
+	if d <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.clock().After(d):
+	}
+	return nil
+}
+
+// requestInterval returns the time between the requests of the limiter, 0
+// when it is disabled. The configured rate is lowered when the remaining
+// budget wouldn't last until the window resets.
+func (c *Client) requestInterval(r RateLimit, now time.Time) time.Duration {
+	if c.cfg.MaxRequestRate <= 0 {
+		return 0
+	}
+	interval := time.Duration(float64(time.Second) / c.cfg.MaxRequestRate)
+	if r.Known && r.Remaining > 0 && r.Reset.After(now) {
+		if spread := r.Reset.Sub(now) / time.Duration(r.Remaining); spread > interval {
+			interval = spread
+		}
+	}
+	return interval
+}
@@ -0,0 +1,356 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ChangeAction is the kind of change of a Plan
+type ChangeAction string
+
+const (
+	// ChangeCreate creates a resource
+	ChangeCreate ChangeAction = "create"
+	// ChangeUpdate changes an existing resource
+	ChangeUpdate ChangeAction = "update"
+	// ChangeDelete deletes a resource
+	ChangeDelete ChangeAction = "delete"
+)
+
+// Change is a change of a Plan. Before and After hold the state of the
+// resource on the platform and the desired state, Before is nil for creates
+// and After is nil for deletes.
+type Change struct {
+	Action ChangeAction
+	// Kind is the kind of resource, e.g. "subscription", "device" or
+	// "shadow"
+	Kind string
+	// ID identifies the resource
+	ID     string
+	Before interface{}
+	After  interface{}
+	// Result is set by Apply to what the platform returned for the change,
+	// the *RegistrationReply of a registered device
+	Result interface{}
+
+	apply func(ctx context.Context) (interface{}, error)
+}
+
+// String returns the change as one line, prefixed with +, ~ or - for
+// creates, updates and deletes
+func (ch Change) String() string {
+	sign := "~"
+	switch ch.Action {
+	case ChangeCreate:
+		sign = "+"
+	case ChangeDelete:
+		sign = "-"
+	}
+	s := sign + " " + ch.Kind + " " + ch.ID
+	if ch.Action == ChangeUpdate {
+		s += ": " + changeValue(ch.Before) + " -> " + changeValue(ch.After)
+	}
+	return s
+}
+
+func changeValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(buf)
+}
+
+// Plan is the set of changes which reconciles the platform with a desired
+// state. It is returned by the Plan methods so the changes can be reviewed
+// or printed before they are applied.
+type Plan struct {
+	Changes []Change
+}
+
+// Empty reports whether the platform is already in the desired state
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// String returns the changes one per line
+func (p *Plan) String() string {
+	if p.Empty() {
+		return "no changes\n"
+	}
+	var b strings.Builder
+	for _, ch := range p.Changes {
+		b.WriteString(ch.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// WriteTo writes the changes one per line
+func (p *Plan) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, p.String())
+	return int64(n), err
+}
+
+// ChangeError is returned by Plan.Apply for the change which failed
+type ChangeError struct {
+	Change Change
+	// Applied is the number of changes applied before the failed change
+	Applied int
+	Err     error
+}
+
+// Error implements the error interface
+func (e *ChangeError) Error() string {
+	return "applying " + e.Change.String() + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *ChangeError) Unwrap() error {
+	return e.Err
+}
+
+// Apply applies the changes in order, it stops at the first failed change
+// with a *ChangeError
+func (p *Plan) Apply(ctx context.Context) error {
+	for i, ch := range p.Changes {
+		if err := ctx.Err(); err != nil {
+			return &ChangeError{Change: ch, Applied: i, Err: err}
+		}
+		res, err := ch.apply(ctx)
+		p.Changes[i].Result = res
+		if err != nil {
+			return &ChangeError{Change: p.Changes[i], Applied: i, Err: err}
+		}
+	}
+	return nil
+}
+
+// Registrations returns the replies of the devices registered by Apply, with
+// the PSK and verify code unless a SecretSink took the secrets. A device is
+// also returned when a later step of its change, like naming it, failed.
+func (p *Plan) Registrations() []*RegistrationReply {
+	var ret []*RegistrationReply
+	for _, ch := range p.Changes {
+		if r, ok := ch.Result.(*RegistrationReply); ok && r != nil {
+			ret = append(ret, r)
+		}
+	}
+	return ret
+}
+
+// PlanSubscriptions plans the subscriptions of the application: the desired
+// subscriptions (by notification type and callback URL) which don't exist
+// are created, and with prune the other subscriptions are deleted
+func (c *Client) PlanSubscriptions(ctx context.Context, desired []Subscription, prune bool) (*Plan, error) {
+	subs, err := c.ListSubscriptionsCtx(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	key := func(s Subscription) string {
+		return string(s.NotifyType) + " " + s.CallbackURL
+	}
+	existing := make(map[string]bool, len(subs))
+	for _, s := range subs {
+		existing[key(s)] = true
+	}
+	wanted := make(map[string]bool, len(desired))
+
+	p := &Plan{}
+	for _, s := range desired {
+		k := key(s)
+		if wanted[k] {
+			continue
+		}
+		wanted[k] = true
+		if existing[k] {
+			continue
+		}
+		s := s
+		p.Changes = append(p.Changes, Change{
+			Action: ChangeCreate,
+			Kind:   "subscription",
+			ID:     k,
+			After:  s,
+			apply: func(ctx context.Context) (interface{}, error) {
+				_, err := c.SubscribeToCtx(ctx, s.NotifyType, s.CallbackURL)
+				return nil, err
+			},
+		})
+	}
+	if !prune {
+		return p, nil
+	}
+	for _, s := range subs {
+		if wanted[key(s)] {
+			continue
+		}
+		s := s
+		p.Changes = append(p.Changes, Change{
+			Action: ChangeDelete,
+			Kind:   "subscription",
+			ID:     key(s),
+			Before: s,
+			apply: func(ctx context.Context) (interface{}, error) {
+				return nil, c.DeleteSubscriptionCtx(ctx, s.SubscriptionID)
+			},
+		})
+	}
+	return p, nil
+}
+
+// DeviceSpec is the desired state of a device of the fleet, identified by
+// its IMEI (node ID)
+type DeviceSpec struct {
+	IMEI string
+	// Name is set as name of the device when not empty
+	Name string
+}
+
+// PlanFleet plans the devices of the application: devices which aren't
+// registered are registered, devices with another name are renamed, and with
+// prune the devices which aren't desired are deleted. After Apply the
+// credentials of the registered devices are in Plan.Registrations. When not
+// all devices could be decoded their DecodeErrors are returned, as any of them
+// could be a desired device.
+func (c *Client) PlanFleet(ctx context.Context, desired []DeviceSpec, prune bool) (*Plan, error) {
+	devs, skipped, err := c.allDevices(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(skipped) > 0 {
+		// planning without them would register them again
+		return nil, skipped
+	}
+	registered := make(map[string]Device, len(devs))
+	for _, d := range devs {
+		registered[d.DeviceInfo.NodeID] = d
+	}
+	wanted := make(map[string]bool, len(desired))
+
+	p := &Plan{}
+	for _, spec := range desired {
+		if wanted[spec.IMEI] {
+			continue
+		}
+		wanted[spec.IMEI] = true
+		spec := spec
+		d, ok := registered[spec.IMEI]
+		switch {
+		case !ok:
+			p.Changes = append(p.Changes, Change{
+				Action: ChangeCreate,
+				Kind:   "device",
+				ID:     spec.IMEI,
+				After:  spec,
+				apply: func(ctx context.Context) (interface{}, error) {
+					reply, err := c.RegisterDeviceCtx(ctx, spec.IMEI)
+					if reply == nil {
+						return nil, err
+					}
+					if err == nil && spec.Name != "" {
+						err = c.SetDeviceInfoCtx(ctx, reply.DeviceID, spec.Name)
+					}
+					return reply, err
+				},
+			})
+		case spec.Name != "" && spec.Name != d.DeviceInfo.Name:
+			deviceID := d.DeviceID
+			p.Changes = append(p.Changes, Change{
+				Action: ChangeUpdate,
+				Kind:   "device",
+				ID:     spec.IMEI,
+				Before: d.DeviceInfo.Name,
+				After:  spec.Name,
+				apply: func(ctx context.Context) (interface{}, error) {
+					return nil, c.SetDeviceInfoCtx(ctx, deviceID, spec.Name)
+				},
+			})
+		}
+	}
+	if !prune {
+		return p, nil
+	}
+	for _, d := range devs {
+		if wanted[d.DeviceInfo.NodeID] {
+			continue
+		}
+		deviceID := d.DeviceID
+		p.Changes = append(p.Changes, Change{
+			Action: ChangeDelete,
+			Kind:   "device",
+			ID:     d.DeviceInfo.NodeID,
+			Before: d.DeviceInfo.Name,
+			apply: func(ctx context.Context) (interface{}, error) {
+				return nil, c.DeleteDeviceCtx(ctx, deviceID)
+			},
+		})
+	}
+	return p, nil
+}
+
+// PlanDesiredState plans the shadow updates of a desired-state file (see
+// ApplyDesiredState), properties which are already desired are left out
+func (c *Client) PlanDesiredState(ctx context.Context, r io.Reader) (*Plan, error) {
+	results, err := parseDesiredState(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Plan{}
+	for _, res := range results {
+		shadow, err := c.GetDeviceShadowCtx(ctx, res.DeviceID)
+		if err != nil {
+			return nil, err
+		}
+		for _, svc := range res.Services {
+			current := make(map[string]interface{})
+			if s := shadow.Service(svc.ServiceID); s != nil {
+				if err := s.Desired.Decode(&current); err != nil {
+					return nil, err
+				}
+			}
+			props := make([]string, 0, len(svc.Desired))
+			for prop := range svc.Desired {
+				props = append(props, prop)
+			}
+			sort.Strings(props)
+			for _, prop := range props {
+				want := svc.Desired[prop]
+				have, ok := current[prop]
+				if ok && changeValue(have) == changeValue(want) {
+					continue
+				}
+				ch := Change{
+					Action: ChangeUpdate,
+					Kind:   "shadow",
+					ID:     res.DeviceID + "/" + svc.ServiceID + "/" + prop,
+					Before: have,
+					After:  want,
+				}
+				if !ok {
+					ch.Action = ChangeCreate
+					ch.Before = nil
+				}
+				update := []ServiceDesired{{ServiceID: svc.ServiceID, Desired: map[string]interface{}{prop: want}}}
+				deviceID := res.DeviceID
+				ch.apply = func(ctx context.Context) (interface{}, error) {
+					return nil, c.UpdateDeviceShadowCtx(ctx, deviceID, update)
+				}
+				p.Changes = append(p.Changes, ch)
+			}
+		}
+	}
+	return p, nil
+}
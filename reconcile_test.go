@@ -0,0 +1,121 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlan(t *testing.T) {
+	var requests []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		if r.Method != http.MethodGet {
+			requests = append(requests, r.Method+" "+r.URL.Path)
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/iocm/app/sub/v1.2.0/subscriptions":
+			fmt.Fprintln(w, `{"totalCount":2,"subscriptions":[
+				{"subscriptionId":"s1","notifyType":"deviceDataChanged","callbackUrl":"http://cb"},
+				{"subscriptionId":"s2","notifyType":"deviceAdded","callbackUrl":"http://old"}]}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/iocm/app/dm/v1.1.0/devices":
+			fmt.Fprintln(w, `{"totalCount":2,"devices":[
+				{"deviceId":"dev1","deviceInfo":{"nodeId":"111","name":"meter-1"}},
+				{"deviceId":"dev2","deviceInfo":{"nodeId":"222","name":"meter-2"}}]}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/iocm/app/shadow/v1.5.0/devices/dev1":
+			fmt.Fprintln(w, `{"deviceId":"dev1","services":[{"serviceId":"Valve","desired":{"data":{"open":true,"interval":60}}}]}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/iocm/app/sub/v1.2.0/subscribe":
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintln(w, `{"subscriptionId":"s3"}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/iocm/app/reg/v1.2.0/devices":
+			fmt.Fprintln(w, `{"deviceId":"dev3","verifyCode":"333","timeout":180,"psk":"secret"}`)
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/iocm/app/dm/v1.2.0/devices/"):
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer s.Close()
+
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL}}
+	ctx := context.Background()
+
+	p, err := c.PlanSubscriptions(ctx, []Subscription{
+		{NotifyType: NotificationDeviceDataChanged, CallbackURL: "http://cb"},
+		{NotifyType: NotificationDeviceInfoChanged, CallbackURL: "http://cb"},
+	}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "+ subscription deviceInfoChanged http://cb\n- subscription deviceAdded http://old\n", p.String())
+	assert.Nil(t, p.Apply(ctx))
+	assert.Equal(t, []string{"POST /iocm/app/sub/v1.2.0/subscribe", "DELETE /iocm/app/sub/v1.2.0/subscriptions/s2"}, requests)
+
+	requests = nil
+	p, err = c.PlanFleet(ctx, []DeviceSpec{{IMEI: "111", Name: "valve-1"}, {IMEI: "333"}}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "~ device 111: \"meter-1\" -> \"valve-1\"\n+ device 333\n", p.String())
+	err = p.Apply(ctx)
+	var chErr *ChangeError
+	if assert.True(t, errors.As(err, &chErr)) {
+		assert.Equal(t, 0, chErr.Applied)
+		assert.Equal(t, "111", chErr.Change.ID)
+	}
+	assert.Equal(t, []string{"PUT /iocm/app/dm/v1.2.0/devices/dev1"}, requests)
+
+	// the credentials of registered devices are kept, also when naming failed
+	requests = nil
+	p, err = c.PlanFleet(ctx, []DeviceSpec{{IMEI: "111"}, {IMEI: "222"}, {IMEI: "333", Name: "valve-3"}}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "+ device 333\n", p.String())
+	assert.NotNil(t, p.Apply(ctx))
+	assert.Equal(t, []string{"POST /iocm/app/reg/v1.2.0/devices", "PUT /iocm/app/dm/v1.2.0/devices/dev3"}, requests)
+	if regs := p.Registrations(); assert.Equal(t, 1, len(regs)) {
+		assert.Equal(t, "dev3", regs[0].DeviceID)
+		assert.Equal(t, "secret", regs[0].Psk)
+	}
+
+	p, err = c.PlanDesiredState(ctx, strings.NewReader("dev1,Valve,open,true\ndev1,Valve,interval,300\ndev1,Valve,mode,\"eco\"\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "~ shadow dev1/Valve/interval: 60 -> 300\n+ shadow dev1/Valve/mode\n", p.String())
+
+	p, err = c.PlanDesiredState(ctx, strings.NewReader("dev1,Valve,open,true\n"))
+	assert.Nil(t, err)
+	assert.True(t, p.Empty())
+	assert.Equal(t, "no changes\n", p.String())
+}
+
+func TestPlanFleetUndecodable(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		assert.Equal(t, http.MethodGet, r.Method, "expected nothing to be changed")
+		fmt.Fprintln(w, `{"totalCount":2,"devices":[
+			{"deviceId":"dev1","deviceInfo":{"nodeId":"111","name":"meter-1"}},
+			{"deviceId":"dev2","creationTime":"garbage","deviceInfo":{"nodeId":"222"}}]}`)
+	}))
+	defer s.Close()
+
+	// the undecodable device could be the desired one, it isn't planned as
+	// missing
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL}}
+	p, err := c.PlanFleet(context.Background(), []DeviceSpec{{IMEI: "111"}, {IMEI: "222"}}, false)
+	assert.Nil(t, p)
+	var decErrs DecodeErrors
+	if assert.True(t, errors.As(err, &decErrs)) {
+		assert.Equal(t, "dev2", decErrs[0].DeviceID)
+	}
+}
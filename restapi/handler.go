@@ -0,0 +1,261 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package restapi serves a simplified and stable REST API backed by an
+// OceanConnect client. The API uses its own schema, so its users don't depend
+// on the platform's:
+//
+//	GET    /devices                   list devices (?status=, ?gateway=, ?page=, ?size=)
+//	POST   /devices                   register a device {"imei": "...", "timeout": 180}
+//	GET    /devices/{id}              get a device
+//	DELETE /devices/{id}              delete a device
+//	POST   /devices/{id}/commands     send a command {"service": "...", "method": "...", "params": {...}}
+//	GET    /devices/{id}/events       server-sent events of the device
+package restapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dualinventive/go-oceanconnect"
+	"github.com/sirupsen/logrus"
+)
+
+// Device is a device in the REST API
+type Device struct {
+	ID           string                     `json:"id"`
+	GatewayID    string                     `json:"gateway_id,omitempty"`
+	Name         string                     `json:"name"`
+	Manufacturer string                     `json:"manufacturer,omitempty"`
+	DeviceType   string                     `json:"device_type,omitempty"`
+	Model        string                     `json:"model,omitempty"`
+	Firmware     string                     `json:"firmware,omitempty"`
+	Status       string                     `json:"status"`
+	Services     map[string]json.RawMessage `json:"services,omitempty"`
+}
+
+// Registration is the reply of a device registration
+type Registration struct {
+	DeviceID   string `json:"device_id"`
+	VerifyCode string `json:"verify_code"`
+	Timeout    uint   `json:"timeout"`
+	PSK        string `json:"psk,omitempty"`
+}
+
+// Command is a command sent to a device
+type Command struct {
+	Service       string      `json:"service"`
+	Method        string      `json:"method"`
+	Params        interface{} `json:"params"`
+	ExpireSeconds int64       `json:"expire_seconds"`
+}
+
+// Event is a notification streamed to the clients
+type Event struct {
+	Type     string      `json:"type"`
+	DeviceID string      `json:"device_id"`
+	Data     interface{} `json:"data"`
+}
+
+// Error is the body of a failed request
+type Error struct {
+	Error string `json:"error"`
+	// RequestID is the ID the platform assigned to the failed request, if
+	// returned
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// Handler serves the REST API
+type Handler struct {
+	// KeepAlive is the interval of the comments sent on idle event streams,
+	// so proxies don't close them (default 30s)
+	KeepAlive time.Duration
+
+	client *oceanconnect.Client
+}
+
+// NewHandler creates the REST API for the client. Events are streamed from the
+// client's watchers, so run the Server returned by Subscribe or a FleetPoller
+// next to it.
+func NewHandler(c *oceanconnect.Client) *Handler {
+	return &Handler{KeepAlive: 30 * time.Second, client: c}
+}
+
+// ServeHTTP implements the http.Handler interface
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if parts[0] != "devices" {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		h.listDevices(w, r)
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		h.registerDevice(w, r)
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		h.getDevice(w, r, parts[1])
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		h.deleteDevice(w, r, parts[1])
+	case len(parts) == 3 && parts[2] == "commands" && r.Method == http.MethodPost:
+		h.sendCommand(w, r, parts[1])
+	case len(parts) == 3 && parts[2] == "events" && r.Method == http.MethodGet:
+		h.events(w, r, parts[1])
+	case len(parts) <= 3:
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+func (h *Handler) listDevices(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	page, _ := strconv.Atoi(q.Get("page"))
+	size, _ := strconv.Atoi(q.Get("size"))
+	devs, err := h.client.GetDevicesCtx(r.Context(), oceanconnect.GetDevicesStruct{
+		PageNo:    page,
+		PageSize:  size,
+		Status:    oceanconnect.DeviceStatus(q.Get("status")),
+		GatewayID: q.Get("gateway"),
+	})
+	if _, ok := err.(oceanconnect.DecodeErrors); err != nil && !ok {
+		writeClientError(w, err)
+		return
+	}
+	out := make([]Device, len(devs))
+	for i := range devs {
+		out[i] = device(&devs[i])
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) registerDevice(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IMEI    string `json:"imei"`
+		Timeout uint   `json:"timeout"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.IMEI == "" {
+		writeError(w, http.StatusBadRequest, errors.New("body must contain an imei"))
+		return
+	}
+	reply, err := h.client.RegisterDeviceCtx(r.Context(), req.IMEI, req.Timeout)
+	if reply == nil {
+		writeClientError(w, err)
+		return
+	}
+	if err != nil {
+		logrus.Warnf("registration of %s incomplete: %v", reply.DeviceID, err)
+	}
+	writeJSON(w, http.StatusCreated, Registration{
+		DeviceID:   reply.DeviceID,
+		VerifyCode: reply.VerifyCode,
+		Timeout:    reply.Timeout,
+		PSK:        reply.Psk,
+	})
+}
+
+func (h *Handler) getDevice(w http.ResponseWriter, r *http.Request, id string) {
+	d, err := h.client.GetDeviceCtx(r.Context(), id)
+	if err != nil {
+		writeClientError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, device(d))
+}
+
+func (h *Handler) deleteDevice(w http.ResponseWriter, r *http.Request, id string) {
+	if err := h.client.DeleteDeviceCtx(r.Context(), id); err != nil {
+		writeClientError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) sendCommand(w http.ResponseWriter, r *http.Request, id string) {
+	var cmd Command
+	if err := json.NewDecoder(r.Body).Decode(&cmd); err != nil || cmd.Service == "" || cmd.Method == "" {
+		writeError(w, http.StatusBadRequest, errors.New("body must contain a service and method"))
+		return
+	}
+	err := h.client.SendCommandWithOptionsCtx(r.Context(), id, cmd.Service, cmd.Method, cmd.Params, oceanconnect.CommandOptions{
+		ExpireTime: time.Duration(cmd.ExpireSeconds) * time.Second,
+	})
+	if err != nil {
+		writeClientError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// events streams the events of the device as server-sent events
+func (h *Handler) events(w http.ResponseWriter, r *http.Request, id string) {
+	if _, ok := w.(http.Flusher); !ok {
+		writeError(w, http.StatusInternalServerError, oceanconnect.ErrStreamingNotSupported)
+		return
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	events := h.client.WatchDevice(ctx, id)
+	oceanconnect.ServeEvents(w, events, h.KeepAlive, func(ev oceanconnect.Event) interface{} {
+		return Event{Type: string(ev.Type), DeviceID: ev.DeviceID, Data: ev.Data}
+	})
+}
+
+func device(d *oceanconnect.Device) Device {
+	out := Device{
+		ID:           d.DeviceID,
+		GatewayID:    d.GatewayID,
+		Name:         d.DeviceInfo.Name,
+		Manufacturer: d.DeviceInfo.ManufacturerName,
+		DeviceType:   d.DeviceInfo.DeviceType,
+		Model:        d.DeviceInfo.Model,
+		Firmware:     d.DeviceInfo.FwVersion,
+		Status:       string(d.DeviceInfo.Status),
+	}
+	if len(d.Services) > 0 {
+		out.Services = make(map[string]json.RawMessage, len(d.Services))
+		for _, s := range d.Services {
+			if len(s.Data) > 0 {
+				out.Services[s.ServiceID] = json.RawMessage(s.Data)
+			}
+		}
+	}
+	return out
+}
+
+// writeClientError writes the error of a client call with a matching status
+func writeClientError(w http.ResponseWriter, err error) {
+	var apiErr *oceanconnect.APIError
+	switch {
+	case oceanconnect.IsNotFound(err):
+		writeError(w, http.StatusNotFound, err)
+	case oceanconnect.IsRateLimited(err):
+		writeError(w, http.StatusTooManyRequests, err)
+	case errors.Is(err, oceanconnect.ErrReadOnly):
+		writeError(w, http.StatusForbidden, err)
+	case errors.As(err, &apiErr) && apiErr.StatusCode >= 400 && apiErr.StatusCode < 500 && !oceanconnect.IsUnauthorized(err):
+		writeError(w, http.StatusBadRequest, err)
+	default:
+		// the platform credentials are ours, the caller can't fix those
+		writeError(w, http.StatusBadGateway, err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, Error{Error: err.Error(), RequestID: oceanconnect.ErrorRequestID(err)})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.Errorf("writing response failed: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package restapi
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dualinventive/go-oceanconnect"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	platform := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/login"):
+			fmt.Fprint(w, `{"accessToken":"token","tokenType":"bearer","expiresIn":3600}`)
+		case r.URL.Path == "/iocm/app/sub/v1.2.0/subscribe":
+			w.WriteHeader(http.StatusCreated)
+		case r.URL.Path == "/iocm/app/dm/v1.1.0/devices/dev1":
+			fmt.Fprint(w, `{"deviceId":"dev1","deviceInfo":{"name":"meter","status":"ONLINE"},
+				"services":[{"serviceId":"Meter","data":{"value":3}}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error_code":"100403","error_desc":"device not exist"}`)
+		}
+	}))
+	defer platform.Close()
+
+	c, err := oceanconnect.NewClient(oceanconnect.Config{URL: platform.URL, AppID: "app"})
+	assert.Nil(t, err)
+	notifications, err := c.Subscribe("http://callback")
+	assert.Nil(t, err)
+
+	s := httptest.NewServer(NewHandler(c))
+	defer s.Close()
+
+	resp, err := http.Get(s.URL + "/devices/dev1")
+	if assert.Nil(t, err) {
+		var d Device
+		assert.Nil(t, json.NewDecoder(resp.Body).Decode(&d))
+		resp.Body.Close()
+		assert.Equal(t, "meter", d.Name)
+		assert.Equal(t, `{"value":3}`, string(d.Services["Meter"]))
+	}
+
+	resp, err = http.Get(s.URL + "/devices/dev2")
+	if assert.Nil(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	}
+
+	resp, err = http.Post(s.URL+"/devices/dev1/commands", "application/json", strings.NewReader(`{}`))
+	if assert.Nil(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	}
+
+	resp, err = http.Get(s.URL + "/devices/dev1/events")
+	if assert.Nil(t, err) {
+		defer resp.Body.Close()
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		notifications.Dispatch(oceanconnect.NotificationDeviceDataChanged, &oceanconnect.DeviceDataChanged{DeviceID: "dev1"})
+
+		r := bufio.NewReader(resp.Body)
+		line, _ := r.ReadString('\n')
+		assert.Equal(t, "event: deviceDataChanged\n", line)
+		line, _ = r.ReadString('\n')
+		assert.True(t, strings.HasPrefix(line, `data: {"type":"deviceDataChanged","device_id":"dev1"`), line)
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Defaults of the RetryPolicy
+const (
+	defaultRetryBaseDelay = 500 * time.Millisecond
+	defaultRetryMaxDelay  = 30 * time.Second
+)
+
+// defaultRetryStatusCodes are the responses retried when the policy doesn't
+// list them
+var defaultRetryStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// RetryPolicy retries requests which failed with a transient error, with
+// exponential backoff. Requests which create something (POST) are only
+// retried on 429, which the platform returns before processing the request,
+// unless RetryPOST is set.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first, retries
+	// are disabled when it is 1 or less
+	MaxAttempts int `yaml:"max_attempts"`
+	// BaseDelay is the delay before the first retry, it doubles for every
+	// next retry (default 500ms)
+	BaseDelay time.Duration `yaml:"base_delay"`
+	// MaxDelay caps the delay (default 30s). A Retry-After of the platform
+	// which is longer fails the request.
+	MaxDelay time.Duration `yaml:"max_delay"`
+	// Jitter is the fraction of the delay which is randomized, between 0 and
+	// 1, so clients don't retry in lockstep
+	Jitter float64 `yaml:"jitter"`
+	// StatusCodes are the response codes retried (default 429, 502, 503 and
+	// 504)
+	StatusCodes []int `yaml:"status_codes"`
+	// RetryPOST retries POST requests like the others, at the risk of
+	// duplicate registrations or commands
+	RetryPOST bool `yaml:"retry_post"`
+}
+
+type retryPolicyKey struct{}
+
+// WithRetryPolicy returns a context which makes the calls with it use the
+// policy instead of Config.Retry
+func WithRetryPolicy(ctx context.Context, p RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, p)
+}
+
+// WithoutRetry returns a context which disables the retries of the calls
+// with it
+func WithoutRetry(ctx context.Context) context.Context {
+	return WithRetryPolicy(ctx, RetryPolicy{})
+}
+
+// retryPolicy returns the policy of the call
+func (c *Client) retryPolicy(ctx context.Context) RetryPolicy {
+	if p, ok := ctx.Value(retryPolicyKey{}).(RetryPolicy); ok {
+		return p
+	}
+	return c.cfg.Retry
+}
+
+// shouldRetry reports whether the result of the attempt may be retried
+func (p RetryPolicy) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		// the body can't be sent again
+		return false
+	}
+	if err != nil {
+		return req.Method != http.MethodPost || p.RetryPOST
+	}
+	codes := p.StatusCodes
+	if codes == nil {
+		codes = defaultRetryStatusCodes
+	}
+	for _, code := range codes {
+		if resp.StatusCode != code {
+			continue
+		}
+		return req.Method != http.MethodPost || p.RetryPOST || code == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// delay returns the delay before the retry after the attempt (counting from
+// 1), false when the platform asks to wait longer than MaxDelay
+func (p RetryPolicy) delay(attempt int, resp *http.Response, now time.Time) (time.Duration, bool) {
+	base, max := p.BaseDelay, p.MaxDelay
+	if base <= 0 {
+		base = defaultRetryBaseDelay
+	}
+	if max <= 0 {
+		max = defaultRetryMaxDelay
+	}
+	if resp != nil {
+		if d, ok := retryAfter(resp.Header.Get("Retry-After"), now); ok {
+			return d, d <= max
+		}
+	}
+	d := base
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	if p.Jitter > 0 {
+		j := p.Jitter
+		if j > 1 {
+			j = 1
+		}
+		d = time.Duration(float64(d) * (1 - j*rand.Float64()))
+	}
+	return d, true
+}
+
+// retryAfter parses a Retry-After header in seconds or as HTTP date
+func retryAfter(h string, now time.Time) (time.Duration, bool) {
+	if h == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(h); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+	t, err := http.ParseTime(h)
+	if err != nil {
+		return 0, false
+	}
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// doRequest sends the request, retrying transient failures according to the
+// retry policy of the call
+func (c *Client) doRequest(req *http.Request) (*http.Response, error) {
+	p := c.retryPolicy(req.Context())
+	for attempt := 1; ; attempt++ {
+		start := c.clock().Now()
+		resp, err := c.roundTrip(req)
+		c.logRequest(req, attempt, start, resp, err)
+		if attempt >= p.MaxAttempts || !p.shouldRetry(req, resp, err) {
+			if rerr := c.recordResponse(req, resp); rerr != nil {
+				return nil, rerr
+			}
+			return resp, err
+		}
+		d, ok := p.delay(attempt, resp, c.clock().Now())
+		if !ok {
+			if rerr := c.recordResponse(req, resp); rerr != nil {
+				return nil, rerr
+			}
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+			entry := logrus.NewEntry(logrus.StandardLogger())
+			if id := responseRequestID(resp); id != "" {
+				entry = entry.WithField("request_id", id)
+			}
+			entry.Debugf("retrying %s %s after %s in %v", req.Method, req.URL.Path, resp.Status, d)
+		} else {
+			logrus.Debugf("retrying %s %s after %v in %v", req.Method, req.URL.Path, err, d)
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-c.clock().After(d):
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	var statuses []int
+	var bodies []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		assert.Equal(t, 1, len(r.Header["Authorization"]))
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		status := http.StatusOK
+		if len(statuses) > 0 {
+			status, statuses = statuses[0], statuses[1:]
+		}
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "2")
+		}
+		w.WriteHeader(status)
+	}))
+	defer s.Close()
+
+	clock := &fakeClock{now: time.Date(2017, 9, 12, 10, 0, 0, 0, time.UTC)}
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL, Clock: clock, Retry: RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second}}}
+
+	// server errors are retried with backoff
+	statuses = []int{http.StatusServiceUnavailable, http.StatusBadGateway}
+	start := clock.now
+	resp, err := c.request(http.MethodGet, "/", nil)
+	if assert.Nil(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	assert.Equal(t, 3*time.Second, clock.now.Sub(start))
+
+	// the attempts are bounded
+	statuses = []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable}
+	resp, err = c.request(http.MethodGet, "/", nil)
+	if assert.Nil(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	}
+
+	// a POST is retried on 429 after the Retry-After with the same body
+	bodies = nil
+	statuses = []int{http.StatusTooManyRequests}
+	start = clock.now
+	resp, err = c.request(http.MethodPost, "/", strings.NewReader("body"))
+	if assert.Nil(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	assert.Equal(t, 2*time.Second, clock.now.Sub(start))
+	assert.Equal(t, []string{"body", "body"}, bodies)
+
+	// but not on server errors
+	statuses = []int{http.StatusServiceUnavailable}
+	resp, err = c.request(http.MethodPost, "/", strings.NewReader("body"))
+	if assert.Nil(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	}
+
+	// retries can be disabled per call
+	statuses = []int{http.StatusServiceUnavailable}
+	resp, err = c.requestCtx(WithoutRetry(context.Background()), http.MethodGet, "/", nil)
+	if assert.Nil(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	}
+	assert.Equal(t, 0, len(statuses))
+}
@@ -3,3 +3,291 @@
 // license that can be found in the LICENSE file.
 
 package oceanconnect
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ErrNoRuleID is returned when updating a rule without RuleID
+var ErrNoRuleID = errors.New("rule has no ID")
+
+// RuleStatus is the status of a rule, only active rules are evaluated
+type RuleStatus string
+
+const (
+	// RuleActive is used for rules which are evaluated
+	RuleActive RuleStatus = "active"
+	// RuleInactive is used for rules which are disabled
+	RuleInactive RuleStatus = "inactive"
+)
+
+// RuleLogic combines the conditions of a rule
+type RuleLogic string
+
+const (
+	// RuleLogicAnd triggers the rule when all conditions match
+	RuleLogicAnd RuleLogic = "and"
+	// RuleLogicOr triggers the rule when any condition matches
+	RuleLogicOr RuleLogic = "or"
+)
+
+// RuleConditionType is the type of a rule condition
+type RuleConditionType string
+
+const (
+	// RuleConditionDeviceData matches the data of one device
+	RuleConditionDeviceData RuleConditionType = "DEVICE_DATA"
+	// RuleConditionDeviceTypeData matches the data of all devices of a type
+	RuleConditionDeviceTypeData RuleConditionType = "DEVICE_TYPE_DATA"
+	// RuleConditionDailyTimer matches at a time of the day
+	RuleConditionDailyTimer RuleConditionType = "DAILY_TIMER"
+	// RuleConditionCycleTimer matches every period
+	RuleConditionCycleTimer RuleConditionType = "CYCLE_TIMER"
+)
+
+// RuleOperator compares the data of a condition with its value
+type RuleOperator string
+
+// Operators of the data conditions
+const (
+	RuleOperatorGreater      RuleOperator = ">"
+	RuleOperatorLess         RuleOperator = "<"
+	RuleOperatorEqual        RuleOperator = "="
+	RuleOperatorGreaterEqual RuleOperator = ">="
+	RuleOperatorLessEqual    RuleOperator = "<="
+	// RuleOperatorBetween matches data between the values "low,high"
+	RuleOperatorBetween RuleOperator = "between"
+)
+
+// RuleDeviceInfo selects the data a condition matches, Path is the service ID
+// and property, e.g. "Meter/value"
+type RuleDeviceInfo struct {
+	DeviceID string `json:"deviceId,omitempty"`
+	Path     string `json:"path"`
+}
+
+// RuleDeviceTypeInfo selects the devices a DEVICE_TYPE_DATA condition matches
+type RuleDeviceTypeInfo struct {
+	DeviceType       string `json:"deviceType"`
+	ManufacturerID   string `json:"manufacturerId"`
+	Model            string `json:"model"`
+	Path             string `json:"path"`
+	ManufacturerName string `json:"manufacturerName,omitempty"`
+}
+
+// RuleCondition is a condition of a rule
+type RuleCondition struct {
+	Type           RuleConditionType   `json:"type"`
+	ID             string              `json:"id,omitempty"`
+	DeviceInfo     *RuleDeviceInfo     `json:"deviceInfo,omitempty"`
+	DeviceTypeInfo *RuleDeviceTypeInfo `json:"deviceTypeInfo,omitempty"`
+	Operator       RuleOperator        `json:"operator,omitempty"`
+	Value          string              `json:"value,omitempty"`
+	// Duration is the number of minutes the condition must match before
+	// it triggers
+	Duration int `json:"duration,omitempty"`
+	// Time is the time of day of a DAILY_TIMER condition, e.g. "08:30"
+	Time string `json:"time,omitempty"`
+	// Period is the number of minutes between triggers of a CYCLE_TIMER
+	// condition
+	Period int `json:"period,omitempty"`
+}
+
+// RuleActionType is the type of a rule action
+type RuleActionType string
+
+const (
+	// RuleActionDeviceCommand sends a command to a device
+	RuleActionDeviceCommand RuleActionType = "DEVICE_CMD"
+	// RuleActionDeviceAlarm raises an alarm for the device
+	RuleActionDeviceAlarm RuleActionType = "DEVICE_ALARM"
+	// RuleActionSMS sends a text message
+	RuleActionSMS RuleActionType = "SMS"
+	// RuleActionEmail sends an email
+	RuleActionEmail RuleActionType = "EMAIL"
+)
+
+// RuleCommand is the command of a DEVICE_CMD action, like a Command the
+// method is sent as messageType
+type RuleCommand struct {
+	ServiceID   string          `json:"serviceId"`
+	MessageType string          `json:"messageType"`
+	MessageBody json.RawMessage `json:"messageBody,omitempty"`
+}
+
+// RuleAlarm is the alarm of a DEVICE_ALARM action
+type RuleAlarm struct {
+	Name        string `json:"name"`
+	Severity    string `json:"severity,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// RuleAction is an action of a rule
+type RuleAction struct {
+	Type     RuleActionType `json:"type"`
+	ID       string         `json:"id,omitempty"`
+	DeviceID string         `json:"deviceId,omitempty"`
+	Cmd      *RuleCommand   `json:"cmd,omitempty"`
+	Alarm    *RuleAlarm     `json:"alarm,omitempty"`
+	// MSISDN is the phone number of an SMS action
+	MSISDN string `json:"msisdn,omitempty"`
+	// Email is the address of an EMAIL action
+	Email   string `json:"email,omitempty"`
+	Title   string `json:"title,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// Rule is a server-side automation rule: when its conditions match, the
+// platform executes its actions
+type Rule struct {
+	RuleID      string          `json:"ruleId,omitempty"`
+	AppKey      string          `json:"appKey,omitempty"`
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Author      string          `json:"author,omitempty"`
+	Conditions  []RuleCondition `json:"conditions"`
+	Logic       RuleLogic       `json:"logic,omitempty"`
+	Actions     []RuleAction    `json:"actions"`
+	Status      RuleStatus      `json:"status,omitempty"`
+	// MatchNow evaluates the rule against the current data when it is
+	// created, instead of the next report
+	MatchNow   bool   `json:"-"`
+	TimezoneID string `json:"timezoneID,omitempty"`
+}
+
+// ruleRequest adds the fields the API encodes differently to a rule
+type ruleRequest struct {
+	Rule
+	MatchNow string `json:"matchNow,omitempty"`
+}
+
+type ruleIDResponse struct {
+	RuleID string `json:"ruleId"`
+}
+
+// CreateRule creates a rule and returns its ID, the rule is active unless
+// Status is set
+func (c *Client) CreateRule(r Rule) (string, error) {
+	return c.CreateRuleCtx(context.Background(), r)
+}
+
+// CreateRuleCtx is like CreateRule but with a context
+func (c *Client) CreateRuleCtx(ctx context.Context, r Rule) (string, error) {
+	if r.AppKey == "" {
+		r.AppKey = c.cfg.AppID
+	}
+	return c.putRule(ctx, http.MethodPost, r)
+}
+
+// UpdateRule replaces the rule with the RuleID of r
+func (c *Client) UpdateRule(r Rule) error {
+	return c.UpdateRuleCtx(context.Background(), r)
+}
+
+// UpdateRuleCtx is like UpdateRule but with a context
+func (c *Client) UpdateRuleCtx(ctx context.Context, r Rule) error {
+	if r.RuleID == "" {
+		return ErrNoRuleID
+	}
+	if r.AppKey == "" {
+		r.AppKey = c.cfg.AppID
+	}
+	_, err := c.putRule(ctx, http.MethodPut, r)
+	return err
+}
+
+func (c *Client) putRule(ctx context.Context, method string, r Rule) (string, error) {
+	req := ruleRequest{Rule: r}
+	if r.MatchNow {
+		req.MatchNow = "yes"
+	}
+	body, err := c.codec().Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.requestCtx(ctx, method, "/iocm/app/rule/v1.2.0/rules", bytes.NewBuffer(body))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return "", c.newAPIError(resp)
+	}
+	id := ruleIDResponse{}
+	if err := c.decode(resp, &id); err != nil {
+		return "", err
+	}
+	return id.RuleID, nil
+}
+
+// DeleteRule deletes a rule
+func (c *Client) DeleteRule(ruleID string) error {
+	return c.DeleteRuleCtx(context.Background(), ruleID)
+}
+
+// DeleteRuleCtx is like DeleteRule but with a context
+func (c *Client) DeleteRuleCtx(ctx context.Context, ruleID string) error {
+	resp, err := c.requestCtx(ctx, http.MethodDelete, newEndpoint("/iocm/app/rule/v1.2.0/rules", ruleID).String(), nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return c.newAPIError(resp)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// ListRules returns the rules of the application, of all authors when author
+// is empty
+func (c *Client) ListRules(author string) ([]Rule, error) {
+	return c.ListRulesCtx(context.Background(), author)
+}
+
+// ListRulesCtx is like ListRules but with a context
+func (c *Client) ListRulesCtx(ctx context.Context, author string) ([]Rule, error) {
+	e := newEndpoint("/iocm/app/rule/v1.2.0/rules").
+		Set("appKey", c.cfg.AppID).
+		Set("author", author)
+	resp, err := c.requestCtx(ctx, http.MethodGet, e.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.newAPIError(resp)
+	}
+	var rules []ruleRequest
+	if err := c.decode(resp, &rules); err != nil {
+		return nil, err
+	}
+	ret := make([]Rule, len(rules))
+	for i, r := range rules {
+		ret[i] = r.Rule
+		ret[i].MatchNow = r.MatchNow == "yes"
+	}
+	return ret, nil
+}
+
+// SetRuleStatus activates or deactivates a rule
+func (c *Client) SetRuleStatus(ruleID string, status RuleStatus) error {
+	return c.SetRuleStatusCtx(context.Background(), ruleID, status)
+}
+
+// SetRuleStatusCtx is like SetRuleStatus but with a context
+func (c *Client) SetRuleStatusCtx(ctx context.Context, ruleID string, status RuleStatus) error {
+	if status != RuleActive && status != RuleInactive {
+		return errors.New("invalid rule status: " + string(status))
+	}
+	resp, err := c.requestCtx(ctx, http.MethodPut, newEndpoint("/iocm/app/rule/v1.2.0/rules", ruleID, "status", string(status)).String(), nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return c.newAPIError(resp)
+	}
+	resp.Body.Close()
+	return nil
+}
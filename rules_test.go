@@ -0,0 +1,89 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRules(t *testing.T) {
+	var created map[string]interface{}
+	var requests []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		switch r.Method {
+		case http.MethodPost:
+			json.NewDecoder(r.Body).Decode(&created)
+			fmt.Fprintln(w, `{"ruleId":"rule1"}`)
+		case http.MethodPut:
+			fmt.Fprintln(w, `{"ruleId":"rule1"}`)
+		case http.MethodGet:
+			fmt.Fprintln(w, `[{"ruleId":"rule1","name":"overflow","matchNow":"yes","status":"active",
+				"conditions":[{"type":"DEVICE_DATA","deviceInfo":{"deviceId":"dev1","path":"Meter/volume"},"operator":">","value":"100"}],
+				"actions":[{"type":"DEVICE_ALARM","alarm":{"name":"overflow","severity":"critical"}}]}]`)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer s.Close()
+
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL, AppID: "app"}}
+	rule := Rule{
+		Name:     "close valve",
+		MatchNow: true,
+		Conditions: []RuleCondition{{
+			Type:       RuleConditionDeviceData,
+			DeviceInfo: &RuleDeviceInfo{DeviceID: "dev1", Path: "Meter/volume"},
+			Operator:   RuleOperatorGreater,
+			Value:      "100",
+		}},
+		Actions: []RuleAction{{
+			Type:     RuleActionDeviceCommand,
+			DeviceID: "dev1",
+			Cmd:      &RuleCommand{ServiceID: "Valve", MessageType: "CLOSE"},
+		}},
+	}
+	id, err := c.CreateRule(rule)
+	assert.Nil(t, err)
+	assert.Equal(t, "rule1", id)
+	assert.Equal(t, "app", created["appKey"])
+	assert.Equal(t, "yes", created["matchNow"])
+	assert.Equal(t, "Valve", created["actions"].([]interface{})[0].(map[string]interface{})["cmd"].(map[string]interface{})["serviceId"])
+
+	assert.Equal(t, ErrNoRuleID, c.UpdateRule(rule))
+	rule.RuleID = id
+	assert.Nil(t, c.UpdateRule(rule))
+
+	rules, err := c.ListRules("")
+	assert.Nil(t, err)
+	if assert.Len(t, rules, 1) {
+		assert.True(t, rules[0].MatchNow)
+		assert.Equal(t, RuleActive, rules[0].Status)
+		assert.Equal(t, "Meter/volume", rules[0].Conditions[0].DeviceInfo.Path)
+		assert.Equal(t, "critical", rules[0].Actions[0].Alarm.Severity)
+	}
+
+	assert.Nil(t, c.SetRuleStatus(id, RuleInactive))
+	assert.EqualError(t, c.SetRuleStatus(id, "paused"), "invalid rule status: paused")
+	assert.Nil(t, c.DeleteRule(id))
+
+	assert.Equal(t, []string{
+		"POST /iocm/app/rule/v1.2.0/rules",
+		"PUT /iocm/app/rule/v1.2.0/rules",
+		"GET /iocm/app/rule/v1.2.0/rules?appKey=app",
+		"PUT /iocm/app/rule/v1.2.0/rules/rule1/status/inactive",
+		"DELETE /iocm/app/rule/v1.2.0/rules/rule1",
+	}, requests)
+}
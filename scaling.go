@@ -0,0 +1,82 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// Scale describes how a raw numeric property is normalized:
+// value*Factor + Offset. A zero Factor leaves the value unscaled, so a
+// Scale can be used to only declare the unit.
+type Scale struct {
+	Factor float64
+	Offset float64
+	Unit   string
+}
+
+// ScaleRegistry holds the scaling of properties per service. It is used by
+// the Dispatcher and the Client to normalize notification and history data.
+type ScaleRegistry struct {
+	lock   sync.RWMutex
+	scales map[string]map[string]Scale
+}
+
+// NewScaleRegistry creates an empty registry
+func NewScaleRegistry() *ScaleRegistry {
+	return &ScaleRegistry{scales: make(map[string]map[string]Scale)}
+}
+
+// Register sets the scaling of a property of a service, e.g. a raw
+// temperature in 1/10 °C: Register("Sensor", "temperature", Scale{Factor: 0.1, Unit: "°C"})
+func (r *ScaleRegistry) Register(serviceID, property string, s Scale) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	svc, ok := r.scales[serviceID]
+	if !ok {
+		svc = make(map[string]Scale)
+		r.scales[serviceID] = svc
+	}
+	svc[property] = s
+}
+
+// Unit returns the unit of the property, or an empty string when unknown
+func (r *ScaleRegistry) Unit(serviceID, property string) string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.scales[serviceID][property].Unit
+}
+
+// Normalize applies the scaling to the service data, which must be a JSON
+// object. Data of services without registered scaling is returned as is.
+func (r *ScaleRegistry) Normalize(serviceID string, data []byte) ([]byte, error) {
+	if r == nil {
+		return data, nil
+	}
+	r.lock.RLock()
+	svc, ok := r.scales[serviceID]
+	r.lock.RUnlock()
+	if !ok {
+		return data, nil
+	}
+
+	var props map[string]interface{}
+	if err := json.Unmarshal(data, &props); err != nil {
+		return nil, err
+	}
+	for prop, s := range svc {
+		v, ok := props[prop].(float64)
+		if !ok {
+			continue
+		}
+		if s.Factor != 0 {
+			v *= s.Factor
+		}
+		props[prop] = v + s.Offset
+	}
+	return json.Marshal(props)
+}
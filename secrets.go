@@ -0,0 +1,192 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+)
+
+// SecretSink receives the PSK and verify code of a registered device, e.g. to
+// store them in a secret manager. The slices are zeroed when the sink
+// returns, so the sink must copy what it keeps.
+type SecretSink func(deviceID string, psk, verifyCode []byte) error
+
+// SetSecretSink sets the sink for the secrets of registered devices. With a
+// sink the PSK is no longer returned in the RegistrationReply nor passed to
+// the registration hooks.
+func (c *Client) SetSecretSink(s SecretSink) {
+	c.hooksLock.Lock()
+	c.secretSink = s
+	c.hooksLock.Unlock()
+}
+
+// secretBytes is a JSON string held in a byte slice which can be zeroed
+type secretBytes []byte
+
+// MarshalJSON copies the bytes without escaping, PSKs are hex
+func (s secretBytes) MarshalJSON() ([]byte, error) {
+	b := make([]byte, 0, len(s)+2)
+	b = append(b, '"')
+	b = append(b, s...)
+	return append(b, '"'), nil
+}
+
+// UnmarshalJSON copies the string without unescaping, PSKs and verify codes
+// are hex or alphanumeric
+func (s *secretBytes) UnmarshalJSON(b []byte) error {
+	if len(b) < 2 || b[0] != '"' || b[len(b)-1] != '"' {
+		*s = nil
+		return nil
+	}
+	*s = append((*s)[:0], b[1:len(b)-1]...)
+	return nil
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// decodeRegistration decodes the registration reply. With a secret sink the
+// PSK is handed to the sink and the copies held by the client are zeroed,
+// when the sink fails the reply is returned together with the error. Without
+// a sink the PSK is returned as a string, which can't be zeroed.
+func (c *Client) decodeRegistration(resp *http.Response) (*RegistrationReply, error) {
+	return c.decodeCredentials(resp, "", nil)
+}
+
+// decodeCredentials is like decodeRegistration for replies which may lack
+// the device ID or the PSK, deviceID and psk are used for those
+func (c *Client) decodeCredentials(resp *http.Response, deviceID string, psk []byte) (*RegistrationReply, error) {
+	c.hooksLock.Lock()
+	sink := c.secretSink
+	c.hooksLock.Unlock()
+	if sink == nil {
+		d := &RegistrationReply{}
+		if err := c.decode(resp, d); err != nil {
+			return nil, err
+		}
+		if d.DeviceID == "" {
+			d.DeviceID = deviceID
+		}
+		if d.Psk == "" {
+			d.Psk = string(psk)
+		}
+		return d, nil
+	}
+
+	defer resp.Body.Close()
+	buf, err := ioutil.ReadAll(resp.Body)
+	defer zero(buf)
+	if err != nil {
+		return nil, err
+	}
+	var r struct {
+		VerifyCode secretBytes `json:"verifyCode"`
+		DeviceID   string      `json:"deviceId"`
+		Timeout    uint        `json:"timeout"`
+		Psk        secretBytes `json:"psk"`
+	}
+	defer func() {
+		zero(r.Psk)
+		zero(r.VerifyCode)
+	}()
+	if err := withStrict(c.codec(), c.cfg.Strict).Unmarshal(buf, &r); err != nil {
+		return nil, newResponseOpError(resp, err)
+	}
+	if r.DeviceID == "" {
+		r.DeviceID = deviceID
+	}
+	if len(r.Psk) == 0 {
+		r.Psk = append(r.Psk, psk...)
+	}
+	d := &RegistrationReply{
+		VerifyCode: string(r.VerifyCode),
+		DeviceID:   r.DeviceID,
+		Timeout:    r.Timeout,
+	}
+	return d, sink(r.DeviceID, r.Psk, r.VerifyCode)
+}
+
+// String implements fmt.Stringer without the PSK, so replies can be logged
+func (r RegistrationReply) String() string {
+	psk := ""
+	if r.Psk != "" {
+		psk = "<redacted>"
+	}
+	return "{VerifyCode:" + r.VerifyCode + " DeviceID:" + r.DeviceID + " Psk:" + psk + "}"
+}
+
+// String implements fmt.Stringer without the PSK, so events can be logged
+func (ev RegistrationEvent) String() string {
+	psk := ""
+	if ev.PSK != "" {
+		psk = "<redacted>"
+	}
+	return "{IMEI:" + ev.IMEI + " DeviceID:" + ev.DeviceID + " PSK:" + psk + "}"
+}
+
+// ResetDeviceSecret replaces the PSK of the device with a new random PSK, e.g.
+// when the device is compromised. The reply holds the new PSK, or with a
+// secret sink the PSK is handed to the sink like at registration. The device
+// must be provisioned with the new PSK to connect again.
+func (c *Client) ResetDeviceSecret(deviceID string) (*RegistrationReply, error) {
+	return c.ResetDeviceSecretCtx(context.Background(), deviceID)
+}
+
+// ResetDeviceSecretCtx is like ResetDeviceSecret but with a context
+func (c *Client) ResetDeviceSecretCtx(ctx context.Context, deviceID string) (*RegistrationReply, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	psk := make([]byte, hex.EncodedLen(len(b)))
+	hex.Encode(psk, b)
+	defer zero(psk)
+	zero(b)
+	return c.refreshCredentials(ctx, deviceID, credentialsRequest{PSK: psk}, psk)
+}
+
+// RefreshDeviceKey issues a new verify code for the device, equal to the node
+// ID like at registration, so a device which lost its binding can bind again
+// within the timeout in seconds (0 is the platform default). The node ID of
+// the device is changed when it differs.
+func (c *Client) RefreshDeviceKey(deviceID, nodeID string, timeout uint) (*RegistrationReply, error) {
+	return c.RefreshDeviceKeyCtx(context.Background(), deviceID, nodeID, timeout)
+}
+
+// RefreshDeviceKeyCtx is like RefreshDeviceKey but with a context
+func (c *Client) RefreshDeviceKeyCtx(ctx context.Context, deviceID, nodeID string, timeout uint) (*RegistrationReply, error) {
+	return c.refreshCredentials(ctx, deviceID, credentialsRequest{VerifyCode: nodeID, NodeID: nodeID, Timeout: timeout}, nil)
+}
+
+type credentialsRequest struct {
+	VerifyCode string      `json:"verifyCode,omitempty"`
+	NodeID     string      `json:"nodeId,omitempty"`
+	Timeout    uint        `json:"timeout,omitempty"`
+	PSK        secretBytes `json:"psk,omitempty"`
+}
+
+func (c *Client) refreshCredentials(ctx context.Context, deviceID string, req credentialsRequest, psk []byte) (*RegistrationReply, error) {
+	body, err := c.codec().Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(body)
+	resp, err := c.requestCtx(ctx, http.MethodPut, c.appEndpoint("/iocm/app/reg/v1.1.0/deviceCredentials", deviceID).String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.newAPIError(resp)
+	}
+	return c.decodeCredentials(resp, deviceID, psk)
+}
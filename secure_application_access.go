@@ -5,10 +5,12 @@
 package oceanconnect
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -16,31 +18,100 @@ import (
 
 // loginResponse struct with response data
 type loginResponse struct {
-	AccessToken string
-	TokenType   string
-	ExpiresIn   int64
-	Scope       string
+	AccessToken  string
+	TokenType    string
+	RefreshToken string
+	ExpiresIn    int64
+	Scope        string
 }
 
 // Login with the client to oceanconnect
 func (c *Client) Login() error {
+	return c.LoginCtx(context.Background())
+}
+
+// LoginCtx is like Login but with a context
+func (c *Client) LoginCtx(ctx context.Context) error {
+	t, err := c.login(ctx)
+	if err == nil {
+		c.setToken(t)
+		logrus.Infof("Token retrieved, expires: %v", t.Expires)
+	}
+	return err
+}
+
+// setToken stores the token in the client
+func (c *Client) setToken(t Token) {
+	c.tokenLock.Lock()
+	defer c.tokenLock.Unlock()
+	c.token = t.Header()
+	c.tokenExpires = t.Expires
+	c.refreshTok = t.RefreshToken
+}
+
+// login retrieves a new token without storing it in the client
+func (c *Client) login(ctx context.Context) (Token, error) {
 	v := url.Values{}
 	v.Set("appId", c.cfg.AppID)
 	v.Set("Secret", c.cfg.Secret)
 
-	resp, err := c.c.PostForm(c.cfg.URL+"/iocm/app/sec/v1.1.0/login", v)
+	req, err := http.NewRequest(http.MethodPost, c.cfg.URL+"/iocm/app/sec/v1.1.0/login", strings.NewReader(v.Encode()))
+	if err != nil {
+		return Token{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	c.addHeaders(req)
+	start := c.clock().Now()
+	resp, err := c.httpClient().Do(req.WithContext(ctx))
+	c.logRequest(req, 1, start, resp, err)
 	if err != nil {
-		return err
+		return Token{}, newOpError(req, err)
 	}
 	if resp.StatusCode != http.StatusOK {
-		return errors.New("invalid response code: " + resp.Status)
+		return Token{}, c.newAPIError(resp)
 	}
+	return c.decodeToken(resp)
+}
+
+// refreshLogin retrieves a new token with the refresh token of the previous
+// login, which is cheaper than a login and not rate limited like it
+func (c *Client) refreshLogin(ctx context.Context, refreshToken string) (Token, error) {
+	body, err := json.Marshal(map[string]string{
+		"appId":        c.cfg.AppID,
+		"secret":       c.cfg.Secret,
+		"refreshToken": refreshToken,
+	})
+	if err != nil {
+		return Token{}, err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.cfg.URL+"/iocm/app/sec/v1.1.0/refreshToken", bytes.NewReader(body))
+	if err != nil {
+		return Token{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.addHeaders(req)
+	start := c.clock().Now()
+	resp, err := c.httpClient().Do(req.WithContext(ctx))
+	c.logRequest(req, 1, start, resp, err)
+	if err != nil {
+		return Token{}, newOpError(req, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Token{}, c.newAPIError(resp)
+	}
+	return c.decodeToken(resp)
+}
+
+// decodeToken decodes the token of a login or refresh response
+func (c *Client) decodeToken(resp *http.Response) (Token, error) {
 	l := loginResponse{}
-	err = json.NewDecoder(resp.Body).Decode(&l)
-	if err == nil {
-		c.token = l.TokenType + " " + l.AccessToken
-		c.tokenExpires = time.Now().Add(time.Second * time.Duration(l.ExpiresIn))
-		logrus.Infof("Token retrieved, expires: %v", c.tokenExpires)
+	if err := c.decode(resp, &l); err != nil {
+		return Token{}, err
 	}
-	return err
+	return Token{
+		AccessToken:  l.AccessToken,
+		TokenType:    l.TokenType,
+		RefreshToken: l.RefreshToken,
+		Expires:      c.clock().Now().Add(time.Second * time.Duration(l.ExpiresIn)),
+	}, nil
 }
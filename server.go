@@ -5,70 +5,263 @@
 package oceanconnect
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
-type NotificationFunc func(interface{}) error
-
+// Server receives the notifications the platform pushes to the callback URL
+// and dispatches them to the registered callbacks
 type Server struct {
-	cbsLock sync.RWMutex
-	cbs     map[Notification]NotificationFunc
+	Dispatcher
+
+	// Codec decodes the notifications, defaults to encoding/json
+	Codec Codec
+	// Strict logs or fails on unknown fields in notifications
+	Strict StrictMode
+	// Subscription the server is created for, if known
+	Subscription *Subscription
+	// Envelope extracts the notifications from a POST body, defaults to
+	// PlainEnvelope
+	Envelope EnvelopeParser
+	// Verify checks whether a request is sent by the platform, e.g. by a
+	// token in the callback URL. Requests failing it are refused with 401.
+	Verify func(*http.Request) error
+	// TLSConfig is used by ListenAndServeTLS, e.g. to verify the client
+	// certificate of the platform
+	TLSConfig *tls.Config
+	// Payloads records the sizes and fields of the notifications when set
+	Payloads *PayloadRecorder
+	// Metrics receives the handled notifications when set
+	Metrics Metrics
+
+	httpLock  sync.Mutex
+	httpSrv   *http.Server
+	ackClient *Client
+	ackTypes  map[Notification]bool
+	ackSlots  chan struct{}
+	acks      sync.WaitGroup
+}
+
+// maxNotificationSize is the maximum accepted size of a notification body
+const maxNotificationSize = 1 << 20
+
+// The confirmations of EnableAutoAck are sent in the background, with at most
+// maxPendingAcks at a time which each may take ackTimeout
+const (
+	maxPendingAcks = 16
+	ackTimeout     = 30 * time.Second
+)
+
+// ServeHTTP implements the http.Handler interface, so the Server can be
+// mounted on an existing mux
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.Verify != nil {
+		if err := s.Verify(r); err != nil {
+			logrus.Warnf("refused notification from %s: %v", r.RemoteAddr, err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+	switch r.Method {
+	case http.MethodPost:
+		s.handler(w, r)
+	case http.MethodGet, http.MethodHead:
+		// the platform checks the callback URL is reachable
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
 }
 
 func (s *Server) handler(w http.ResponseWriter, r *http.Request) {
-	buf, err := ioutil.ReadAll(r.Body)
+	// a malformed notification or faulty callback must never crash the server
+	defer func() {
+		if rec := recover(); rec != nil {
+			logrus.Errorf("panic handling notification: %v", rec)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}()
+
+	buf, err := ioutil.ReadAll(io.LimitReader(r.Body, maxNotificationSize+1))
 	if err != nil {
 		return
 	}
+	if len(buf) > maxNotificationSize {
+		logrus.Errorf("notification exceeds %d bytes", maxNotificationSize)
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
 
-	var n struct {
-		NotifyType string `json:"notifyType"`
+	env := s.Envelope
+	if env == nil {
+		env = PlainEnvelope
 	}
-	if err := json.Unmarshal(buf, &n); err != nil {
-		logrus.Errorf("error decoding notification type")
+	parts, err := env.Parse(buf)
+	if err != nil {
+		logrus.Errorf("error parsing notification envelope: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	if err := s.runCallback(Notification(n.NotifyType), buf); err != nil {
-		logrus.Errorf("Error running callback: %v", err)
-		return
+	nots := make([]Notification, len(parts))
+	for i, part := range parts {
+		var n struct {
+			NotifyType string `json:"notifyType"`
+			CommandID  string `json:"commandId"`
+		}
+		if err := codecOrDefault(s.Codec).Unmarshal(part, &n); err != nil {
+			logrus.Errorf("error decoding notification type")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		nots[i] = Notification(n.NotifyType)
+		if nots[i] == "" && n.CommandID != "" {
+			nots[i] = NotificationCommandStatus
+		}
+	}
+
+	for i, part := range parts {
+		if err := s.runCallback(nots[i], part); err != nil {
+			logrus.Errorf("Error running callback: %v", err)
+		}
 	}
 }
 
 func (s *Server) runCallback(not Notification, dec []byte) error {
-	s.cbsLock.RLock()
-	defer s.cbsLock.RUnlock()
+	if s.Payloads != nil {
+		s.Payloads.Record("notification "+string(not), dec)
+	}
+	if !s.wants(not) {
+		logrus.Debugf("no callback registered for %s", string(not))
+		return nil
+	}
+	v, err := notificationDeserializer(withStrict(codecOrDefault(s.Codec), s.Strict), not, dec)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	err = s.dispatch(codecOrDefault(s.Codec), not, v)
+	if s.Metrics != nil {
+		s.Metrics.ObserveNotification(not, time.Since(start), err)
+	}
+	if err != nil {
+		return err
+	}
+	if s.ackTypes[not] {
+		s.ack(v)
+	}
+	return nil
+}
 
-	if s.cbs == nil {
-		logrus.Infof("no callbacks registered, callback received")
+// EnableAutoAck confirms the upstream messages of the notification types
+// with c once the callback handled them without error. The confirmations are
+// sent in the background so a slow platform doesn't delay the response to the
+// notification, when too many are pending a message is left unconfirmed and
+// the platform sends it again.
+func (s *Server) EnableAutoAck(c *Client, nots ...Notification) {
+	s.ackClient = c
+	if s.ackSlots == nil {
+		s.ackSlots = make(chan struct{}, maxPendingAcks)
+	}
+	if s.ackTypes == nil {
+		s.ackTypes = make(map[Notification]bool)
+	}
+	for _, not := range nots {
+		s.ackTypes[not] = true
+	}
+}
+
+func (s *Server) ack(v interface{}) {
+	var deviceID, serviceID, requestID string
+	switch n := v.(type) {
+	case *DeviceDataChanged:
+		deviceID, serviceID, requestID = n.DeviceID, n.Service.ServiceID, n.RequestID
+	case *CommandResponse:
+		deviceID, serviceID, requestID = n.Header.DeviceID, n.Header.ServiceType, n.Header.RequestID
+	}
+	if requestID == "" {
+		// the message doesn't require a confirmation
+		return
+	}
+	select {
+	case s.ackSlots <- struct{}{}:
+	default:
+		logrus.Errorf("confirming message %s of device %s failed: too many pending confirmations", requestID, deviceID)
+		return
 	}
-	cb, ok := s.cbs[not]
-	if ok {
-		v, err := notificationDeserializer(not, dec)
-		if err != nil {
-			return err
+	s.acks.Add(1)
+	go func() {
+		defer s.acks.Done()
+		defer func() { <-s.ackSlots }()
+		ctx, cancel := context.WithTimeout(context.Background(), ackTimeout)
+		defer cancel()
+		if err := s.ackClient.ConfirmDeviceMessageCtx(ctx, deviceID, serviceID, requestID); err != nil {
+			logrus.Errorf("confirming message %s of device %s failed: %v", requestID, deviceID, err)
 		}
-		return cb(v)
+	}()
+}
+
+// ListenAndServe listens on the address and serves the notifications. After
+// Shutdown it returns http.ErrServerClosed.
+func (s *Server) ListenAndServe(addr string) error {
+	return s.httpServer(addr).ListenAndServe()
+}
+
+// ListenAndServeTLS is like ListenAndServe but serves HTTPS with the
+// certificate, which may be empty when TLSConfig holds the certificates
+func (s *Server) ListenAndServeTLS(addr, certFile, keyFile string) error {
+	return s.httpServer(addr).ListenAndServeTLS(certFile, keyFile)
+}
+
+// Shutdown stops the listener and waits until the notifications being
+// handled and their confirmations are done, or until the context is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.httpLock.Lock()
+	srv := s.httpSrv
+	s.httpLock.Unlock()
+	if srv == nil {
+		return errors.New("server is not listening")
 	}
-	logrus.Debugf("no callback registered for %s", string(not))
-	return nil
+	if err := srv.Shutdown(ctx); err != nil {
+		return err
+	}
+	return s.waitAcks(ctx)
 }
 
-func (s *Server) ListenAndServe(uri string) error {
-	http.HandleFunc("/", s.handler)
-	return http.ListenAndServe(uri, nil)
+// waitAcks waits until the pending confirmations are sent, or until the
+// context is done
+func (s *Server) waitAcks(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.acks.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-func (s *Server) RegisterCallback(not Notification, cb NotificationFunc) {
-	s.cbsLock.Lock()
-	if s.cbs == nil {
-		s.cbs = make(map[Notification]NotificationFunc)
+func (s *Server) httpServer(addr string) *http.Server {
+	s.httpLock.Lock()
+	defer s.httpLock.Unlock()
+
+	s.httpSrv = &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		TLSConfig:         s.TLSConfig,
+		ReadHeaderTimeout: 10 * time.Second,
 	}
-	s.cbs[not] = cb
-	s.cbsLock.Unlock()
+	return s.httpSrv
 }
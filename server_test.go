@@ -0,0 +1,185 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerEnvelope(t *testing.T) {
+	const not = `{"notifyType":"deviceDataChanged","deviceId":"%s","service":{"serviceId":"Config","data":{}}}`
+	tests := []struct {
+		env  EnvelopeParser
+		body string
+		want []string
+	}{
+		{nil, strings.Replace(not, "%s", "dev1", 1), []string{"dev1"}},
+		{ArrayEnvelope, "[" + strings.Replace(not, "%s", "dev1", 1) + "," + strings.Replace(not, "%s", "dev2", 1) + "]", []string{"dev1", "dev2"}},
+		{WrappedEnvelope("data"), `{"data":[` + strings.Replace(not, "%s", "dev1", 1) + "]}", []string{"dev1"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		s := &Server{Envelope: tt.env}
+		s.RegisterCallback(NotificationDeviceDataChanged, func(v interface{}) error {
+			got = append(got, v.(*DeviceDataChanged).DeviceID)
+			return nil
+		})
+		w := httptest.NewRecorder()
+		s.handler(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, tt.want, got)
+	}
+
+	w := httptest.NewRecorder()
+	s := &Server{Envelope: WrappedEnvelope("data")}
+	s.handler(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"other":{}}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestServerAutoAck(t *testing.T) {
+	var lock sync.Mutex
+	var confirmed []string
+	unblock := make(chan struct{})
+	p := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		<-unblock
+		lock.Lock()
+		confirmed = append(confirmed, r.URL.Path)
+		lock.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer p.Close()
+	c := &Client{c: &http.Client{}, cfg: Config{URL: p.URL}}
+
+	s := &Server{}
+	s.RegisterCallback(NotificationDeviceDataChanged, func(interface{}) error { return nil })
+	s.EnableAutoAck(c, NotificationDeviceDataChanged)
+	// a single confirmation may be pending
+	s.ackSlots = make(chan struct{}, 1)
+
+	for _, body := range []string{
+		`{"notifyType":"deviceDataChanged","deviceId":"dev1","requestId":"req1","service":{"serviceId":"Meter","data":{}}}`,
+		`{"notifyType":"deviceDataChanged","deviceId":"dev1","service":{"serviceId":"Meter","data":{}}}`,
+		`{"notifyType":"deviceDataChanged","deviceId":"dev1","requestId":"req2","service":{"serviceId":"Meter","data":{}}}`,
+	} {
+		w := httptest.NewRecorder()
+		s.handler(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	// the notification is answered before the platform confirmed the message
+	lock.Lock()
+	assert.Empty(t, confirmed)
+	lock.Unlock()
+	close(unblock)
+	assert.Nil(t, s.waitAcks(context.Background()))
+	assert.Equal(t, []string{"/iocm/app/signaltrans/v1.1.0/devices/dev1/services/Meter/messages/req1/confirm"}, confirmed)
+}
+
+func TestServerServeHTTP(t *testing.T) {
+	s := &Server{Verify: func(r *http.Request) error {
+		if r.URL.Query().Get("token") != "secret" {
+			return errors.New("invalid token")
+		}
+		return nil
+	}}
+	var added *DeviceAdded
+	s.OnDeviceAdded(func(n *DeviceAdded) error {
+		added = n
+		return nil
+	})
+
+	tests := []struct {
+		method, url, body string
+		code              int
+	}{
+		{http.MethodPost, "/?token=wrong", "{}", http.StatusUnauthorized},
+		{http.MethodGet, "/?token=secret", "", http.StatusOK},
+		{http.MethodPut, "/?token=secret", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/?token=secret", `{"notifyType":"deviceAdded","deviceId":"dev1","nodeType":"ENDPOINT","deviceInfo":{"name":"meter"}}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body)))
+		assert.Equal(t, tt.code, w.Code, tt.method+" "+tt.url)
+	}
+	if assert.NotNil(t, added) {
+		assert.Equal(t, "dev1", added.DeviceID)
+		assert.Equal(t, NodeTypeEndpoint, added.NodeType)
+		assert.Equal(t, "meter", added.DeviceInfo.Name)
+	}
+}
+
+func TestServerStream(t *testing.T) {
+	s := &Server{}
+	hs := httptest.NewServer(s.StreamHandler())
+	defer hs.Close()
+
+	resp, err := http.Get(hs.URL + "?type=deviceAdded&device=dev2")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	for _, body := range []string{
+		`{"notifyType":"deviceDataChanged","deviceId":"dev2","service":{"serviceId":"Meter","data":{}}}`,
+		`{"notifyType":"deviceAdded","deviceId":"dev1"}`,
+		`{"notifyType":"deviceAdded","deviceId":"dev2"}`,
+	} {
+		s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	}
+
+	r := bufio.NewReader(resp.Body)
+	line, _ := r.ReadString('\n')
+	assert.Equal(t, "event: deviceAdded\n", line)
+	line, _ = r.ReadString('\n')
+	assert.True(t, strings.HasPrefix(line, `data: {"type":"deviceAdded","deviceId":"dev2"`), line)
+}
+
+func TestServerCommandProgress(t *testing.T) {
+	s := &Server{}
+	ch := s.CommandProgress("cmd1")
+
+	// status updates carry no notifyType, they are recognized by the commandId
+	for _, body := range []string{
+		`{"deviceId":"dev1","commandId":"cmd2","result":{"resultCode":"SENT"}}`,
+		`{"deviceId":"dev1","commandId":"cmd1","result":{"resultCode":"DELIVERED","resultDetail":{"progress":40}}}`,
+		`{"deviceId":"dev1","commandId":"cmd1","result":{"resultCode":"SUCCESSFUL","resultDetail":{"progress":100}}}`,
+	} {
+		w := httptest.NewRecorder()
+		s.handler(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	var progress []int
+	var codes []string
+	for u := range ch {
+		p, ok := u.Progress()
+		assert.True(t, ok)
+		progress = append(progress, p)
+		codes = append(codes, u.Result.ResultCode)
+	}
+	assert.Equal(t, []int{40, 100}, progress)
+	assert.Equal(t, []string{"DELIVERED", "SUCCESSFUL"}, codes)
+
+	u := CommandStatusUpdate{Result: CommandResult{ResultCode: "SENT"}}
+	assert.False(t, u.Final())
+	_, ok := u.Progress()
+	assert.False(t, ok)
+}
@@ -0,0 +1,73 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"reflect"
+	"sync"
+)
+
+// ServiceRegistry holds the Go types the data of the services of a profile
+// is decoded into. It is used by the Dispatcher and the Client, so
+// notifications and history queries deliver the same typed values.
+type ServiceRegistry struct {
+	lock  sync.RWMutex
+	types map[string]reflect.Type
+}
+
+// NewServiceRegistry creates an empty registry
+func NewServiceRegistry() *ServiceRegistry {
+	return &ServiceRegistry{types: make(map[string]reflect.Type)}
+}
+
+// Register sets the type the data of the service is decoded into, v is a
+// value or pointer of the type, e.g. Register("Meter", MeterData{})
+func (r *ServiceRegistry) Register(serviceID string, v interface{}) {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	r.lock.Lock()
+	r.types[serviceID] = t
+	r.lock.Unlock()
+}
+
+// Decode decodes the service data into a new value of the registered type
+// and returns a pointer to it. It returns nil for services without
+// registered type. The Client and Server decode with their Codec, Decode
+// with encoding/json.
+func (r *ServiceRegistry) Decode(serviceID string, data []byte) (interface{}, error) {
+	return r.decode(StdCodec{}, serviceID, data)
+}
+
+// decode is like Decode but with the codec
+func (r *ServiceRegistry) decode(codec Codec, serviceID string, data []byte) (interface{}, error) {
+	if r == nil {
+		return nil, nil
+	}
+	r.lock.RLock()
+	t, ok := r.types[serviceID]
+	r.lock.RUnlock()
+	if !ok || len(data) == 0 {
+		return nil, nil
+	}
+	v := reflect.New(t).Interface()
+	if err := codec.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// decodeServices sets the decoded value of the services
+func (r *ServiceRegistry) decodeServices(codec Codec, svcs []Service) error {
+	for i := range svcs {
+		v, err := r.decode(codec, svcs[i].ServiceID, svcs[i].Data)
+		if err != nil {
+			return err
+		}
+		svcs[i].Value = v
+	}
+	return nil
+}
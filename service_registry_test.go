@@ -0,0 +1,128 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type meterData struct {
+	Volume float64 `json:"volume"`
+}
+
+func TestServiceRegistry(t *testing.T) {
+	services := NewServiceRegistry()
+	services.Register("Meter", &meterData{})
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/iocm/app/sec/v1.1.0/login":
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+		case "/iocm/app/sub/v1.2.0/subscribe":
+			w.WriteHeader(http.StatusCreated)
+		default:
+			fmt.Fprintln(w, `{"totalCount":2,"deviceDataHistoryDTOs":[
+				{"deviceId":"dev1","serviceId":"Meter","data":{"volume":12.5}},
+				{"deviceId":"dev1","serviceId":"Battery","data":{"level":80}}]}`)
+		}
+	}))
+	defer s.Close()
+
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL, Services: services}}
+	recs, err := c.GetDeviceDataHistory("dev1", "")
+	if assert.Nil(t, err) && assert.Equal(t, 2, len(recs)) {
+		assert.Equal(t, &meterData{Volume: 12.5}, recs[0].Value)
+		assert.Nil(t, recs[1].Value)
+	}
+
+	srv, err := c.Subscribe("http://cb")
+	assert.Nil(t, err)
+	var live interface{}
+	srv.OnDeviceDataChanged(func(n *DeviceDataChanged) error {
+		live = n.Service.Value
+		return nil
+	})
+	assert.Nil(t, srv.Dispatch(NotificationDeviceDataChanged, &DeviceDataChanged{
+		Service: Service{ServiceID: "Meter", Data: []byte(`{"volume":13}`)},
+	}))
+	assert.Equal(t, &meterData{Volume: 13}, live)
+
+	assert.NotNil(t, srv.Dispatch(NotificationDeviceDataChanged, &DeviceDataChanged{
+		Service: Service{ServiceID: "Meter", Data: []byte(`{"volume":"full"}`)},
+	}))
+}
+
+// typesCodec records the types it decodes into
+type typesCodec struct {
+	StdCodec
+	types []string
+}
+
+func (c *typesCodec) Unmarshal(data []byte, v interface{}) error {
+	c.types = append(c.types, fmt.Sprintf("%T", v))
+	return c.StdCodec.Unmarshal(data, v)
+}
+
+func TestServiceRegistryCodec(t *testing.T) {
+	services := NewServiceRegistry()
+	services.Register("Meter", &meterData{})
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		fmt.Fprintln(w, `{"deviceId":"dev1","services":[{"serviceId":"Meter","data":{"volume":42}}]}`)
+	}))
+	defer s.Close()
+
+	codec := &typesCodec{}
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL, Services: services, Codec: codec}}
+	d, err := c.GetDevice("dev1")
+	if assert.Nil(t, err) {
+		assert.Equal(t, &meterData{Volume: 42}, d.Services[0].Value)
+	}
+	assert.Contains(t, codec.types, "*oceanconnect.meterData")
+
+	codec.types = nil
+	srv := c.newServer(nil)
+	srv.OnDeviceDataChanged(func(*DeviceDataChanged) error { return nil })
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"notifyType":"deviceDataChanged","deviceId":"dev1","service":{"serviceId":"Meter","data":{"volume":43}}}`)))
+	assert.Contains(t, codec.types, "*oceanconnect.meterData")
+}
+
+func TestGetDeviceServices(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		fmt.Fprintln(w, `{"deviceId":"dev1","services":[
+			{"serviceId":"Meter","data":{"volume":42},"eventTime":"20170912T101530Z"},
+			{"serviceId":"Battery","data":null}]}`)
+	}))
+	defer s.Close()
+
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL}}
+	svcs, err := c.GetDeviceServices("dev1")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2017, 9, 12, 10, 15, 30, 0, time.UTC), svcs.Service("Meter").EventTime.Time)
+
+	var m meterData
+	assert.Nil(t, svcs.DecodeInto("Meter", &m))
+	assert.Equal(t, 42.0, m.Volume)
+	m = meterData{Volume: 1}
+	assert.Nil(t, svcs.DecodeInto("Battery", &m))
+	assert.Equal(t, 1.0, m.Volume)
+	assert.Equal(t, ErrServiceNotFound, svcs.DecodeInto("Valve", &m))
+}
@@ -0,0 +1,112 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SnapshotSink receives the devices with their latest data from a
+// SnapshotJob. It is called concurrently.
+type SnapshotSink interface {
+	WriteSnapshot(Device) error
+}
+
+// SnapshotSinkFunc is a function implementing SnapshotSink
+type SnapshotSinkFunc func(Device) error
+
+// WriteSnapshot implements the SnapshotSink interface
+func (f SnapshotSinkFunc) WriteSnapshot(d Device) error {
+	return f(d)
+}
+
+// jsonSnapshotSink writes the devices as JSON lines
+type jsonSnapshotSink struct {
+	lock sync.Mutex
+	enc  *json.Encoder
+}
+
+// NewJSONSnapshotSink returns a sink which writes every device as a line of
+// JSON to w
+func NewJSONSnapshotSink(w io.Writer) SnapshotSink {
+	return &jsonSnapshotSink{enc: json.NewEncoder(w)}
+}
+
+func (s *jsonSnapshotSink) WriteSnapshot(d Device) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.enc.Encode(d)
+}
+
+// SnapshotJob writes all devices with their latest data to a sink. With a
+// Checkpoint in the BulkOptions an interrupted job resumes where it stopped,
+// devices already written are skipped.
+type SnapshotJob struct {
+	// PageSize is the number of devices retrieved per request (default 100)
+	PageSize int
+
+	client *Client
+	sink   SnapshotSink
+	opts   []BulkOptions
+}
+
+// NewSnapshotJob creates a job writing to sink, the options control the
+// concurrency, retries and checkpointing of the writes
+func (c *Client) NewSnapshotJob(sink SnapshotSink, opts ...BulkOptions) *SnapshotJob {
+	return &SnapshotJob{PageSize: 100, client: c, sink: sink, opts: opts}
+}
+
+// Run walks all devices page by page and writes them to the sink. The
+// report holds the result per device, devices which couldn't be decoded are
+// logged and skipped.
+func (j *SnapshotJob) Run(ctx context.Context) (*BulkReport, error) {
+	pageSize := j.PageSize
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+	e := j.client.NewBulkExecutor(j.opts...)
+	report := &BulkReport{}
+	p := j.client.newPager()
+	pctx, cancel := p.context(ctx)
+	defer cancel()
+	for page := 0; ; page++ {
+		devs, err := j.client.GetDevicesCtx(pctx, GetDevicesStruct{PageNo: page, PageSize: pageSize})
+		decErrs, ok := err.(DecodeErrors)
+		if !ok && err != nil {
+			return report, p.limitErr(ctx, err)
+		}
+		if ok {
+			logrus.Warnf("snapshot skipping devices: %v", decErrs)
+		}
+
+		byID := make(map[string]Device, len(devs))
+		keys := make([]string, 0, len(devs))
+		for _, d := range devs {
+			byID[d.DeviceID] = d
+			keys = append(keys, d.DeviceID)
+		}
+		r, err := e.Run(ctx, keys, func(ctx context.Context, id string) error {
+			return j.sink.WriteSnapshot(byID[id])
+		})
+		if r != nil {
+			report.Results = append(report.Results, r.Results...)
+		}
+		if err != nil {
+			return report, err
+		}
+
+		if len(devs)+len(decErrs) < pageSize {
+			return report, nil
+		}
+		if err := p.next(len(devs) + len(decErrs)); err != nil {
+			return report, err
+		}
+	}
+}
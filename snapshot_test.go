@@ -0,0 +1,63 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotJob(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("pageNo"))
+		if page > 1 {
+			fmt.Fprintln(w, `{"totalCount":4,"devices":[]}`)
+			return
+		}
+		fmt.Fprintf(w, `{"totalCount":4,"devices":[{"deviceId":"dev%d"},{"deviceId":"dev%d"}]}`, 2*page, 2*page+1)
+	}))
+	defer s.Close()
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL}}
+	cp := &FileCheckpoint{Path: filepath.Join(t.TempDir(), "snapshot")}
+
+	var lock sync.Mutex
+	written := map[string]int{}
+	fail := true
+	sink := SnapshotSinkFunc(func(d Device) error {
+		lock.Lock()
+		defer lock.Unlock()
+		if d.DeviceID == "dev3" && fail {
+			return errors.New("sink unavailable")
+		}
+		written[d.DeviceID]++
+		return nil
+	})
+
+	job := c.NewSnapshotJob(sink, BulkOptions{Checkpoint: cp, Retries: -1, Concurrency: 1})
+	job.PageSize = 2
+	report, err := job.Run(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(report.Results))
+	assert.Equal(t, 1, len(report.Failed()))
+
+	fail = false
+	report, err = job.Run(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(report.Failed()))
+	assert.Equal(t, map[string]int{"dev0": 1, "dev1": 1, "dev2": 1, "dev3": 1}, written)
+}
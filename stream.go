@@ -0,0 +1,122 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// streamKeepAlive is the interval of the comments sent on idle streams, so
+// proxies don't close them
+const streamKeepAlive = 30 * time.Second
+
+// EventFilter selects events, empty fields match everything
+type EventFilter struct {
+	DeviceIDs []string
+	Types     []Notification
+}
+
+// Match reports whether the event passes the filter
+func (f EventFilter) Match(ev Event) bool {
+	return matchAny(len(f.DeviceIDs), func(i int) bool { return f.DeviceIDs[i] == ev.DeviceID }) &&
+		matchAny(len(f.Types), func(i int) bool { return f.Types[i] == ev.Type })
+}
+
+func matchAny(n int, eq func(int) bool) bool {
+	if n == 0 {
+		return true
+	}
+	for i := 0; i < n; i++ {
+		if eq(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stream returns a channel which receives the events dispatched by d which
+// pass the filter, until the context is done. The channel is closed then.
+func (d *Dispatcher) Stream(ctx context.Context, f EventFilter) <-chan Event {
+	return d.events().watchFunc(ctx, f.Match)
+}
+
+// ErrStreamingNotSupported is returned by ServeEvents when the
+// ResponseWriter can't flush
+var ErrStreamingNotSupported = errors.New("streaming not supported")
+
+// ServeEvents writes the events as server-sent events until the channel is
+// closed or a write fails. The data of an event is the JSON of what encode
+// returns for it. Comments are sent every keepAlive (default 30s) on idle
+// streams, so proxies don't close them. Nothing is written when the
+// ResponseWriter can't flush, ErrStreamingNotSupported is returned then.
+func ServeEvents(w http.ResponseWriter, events <-chan Event, keepAlive time.Duration, encode func(Event) interface{}) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return ErrStreamingNotSupported
+	}
+	if keepAlive <= 0 {
+		keepAlive = streamKeepAlive
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return nil
+			}
+			data, err := json.Marshal(encode(ev))
+			if err != nil {
+				logrus.Errorf("encoding %s event failed: %v", string(ev.Type), err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return err
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// StreamHandler returns a handler which streams the dispatched events as
+// server-sent events, so tools can tail the live device traffic. The device
+// and type query parameters, which can be repeated, set the filter of the
+// connection.
+func (d *Dispatcher) StreamHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			http.Error(w, ErrStreamingNotSupported.Error(), http.StatusInternalServerError)
+			return
+		}
+		f := EventFilter{DeviceIDs: r.URL.Query()["device"]}
+		for _, t := range r.URL.Query()["type"] {
+			f.Types = append(f.Types, Notification(t))
+		}
+		ServeEvents(w, d.Stream(r.Context(), f), streamKeepAlive, func(ev Event) interface{} {
+			return struct {
+				Type     Notification `json:"type"`
+				DeviceID string       `json:"deviceId,omitempty"`
+				Data     interface{}  `json:"data"`
+			}{ev.Type, ev.DeviceID, ev.Data}
+		})
+	})
+}
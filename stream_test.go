@@ -0,0 +1,26 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeEvents(t *testing.T) {
+	events := make(chan Event, 2)
+	events <- Event{Type: NotificationDeviceDeleted, DeviceID: "dev1"}
+	close(events)
+
+	w := httptest.NewRecorder()
+	err := ServeEvents(w, events, 0, func(ev Event) interface{} {
+		return map[string]string{"id": ev.DeviceID}
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, "event: deviceDeleted\ndata: {\"id\":\"dev1\"}\n\n", w.Body.String())
+}
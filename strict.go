@@ -0,0 +1,74 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+
+	"github.com/sirupsen/logrus"
+)
+
+// StrictMode controls how unknown fields in responses and notifications are
+// handled, to detect platform API changes early
+type StrictMode string
+
+const (
+	// StrictOff ignores unknown fields (default)
+	StrictOff StrictMode = ""
+	// StrictLog logs unknown fields as warning
+	StrictLog StrictMode = "log"
+	// StrictError fails decoding on unknown fields, meant for tests
+	StrictError StrictMode = "error"
+)
+
+// UnknownFieldError is returned in StrictError mode when a response or
+// notification holds a field the type doesn't know
+type UnknownFieldError struct {
+	Type string
+	Err  error
+}
+
+// Error implements the error interface
+func (e *UnknownFieldError) Error() string {
+	return "strict decoding " + e.Type + ": " + e.Err.Error()
+}
+
+// strictCodec checks for unknown fields after decoding with the wrapped codec
+type strictCodec struct {
+	Codec
+	mode StrictMode
+}
+
+// withStrict wraps the codec when strict mode is enabled
+func withStrict(c Codec, mode StrictMode) Codec {
+	if mode == StrictOff {
+		return c
+	}
+	return strictCodec{Codec: c, mode: mode}
+}
+
+// Unmarshal implements Codec
+func (s strictCodec) Unmarshal(data []byte, v interface{}) error {
+	if err := s.Codec.Unmarshal(data, v); err != nil {
+		return err
+	}
+	t := reflect.TypeOf(v)
+	if t.Kind() != reflect.Ptr {
+		return nil
+	}
+	// decode again into a fresh value, the first decode determines the result
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(reflect.New(t.Elem()).Interface()); err != nil {
+		ferr := &UnknownFieldError{Type: t.Elem().String(), Err: err}
+		if s.mode == StrictError {
+			return ferr
+		}
+		logrus.Warn(ferr.Error())
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrictCodec(t *testing.T) {
+	in := []byte(`{"subscriptionId":"sub1","notifyType":"deviceDataChanged","newField":true}`)
+
+	var sub Subscription
+	assert.Nil(t, withStrict(StdCodec{}, StrictLog).Unmarshal(in, &sub))
+	assert.Equal(t, "sub1", sub.SubscriptionID)
+
+	err := withStrict(StdCodec{}, StrictError).Unmarshal(in, &sub)
+	ferr, ok := err.(*UnknownFieldError)
+	assert.True(t, ok, "expected UnknownFieldError")
+	assert.Equal(t, "oceanconnect.Subscription", ferr.Type)
+
+	assert.Nil(t, withStrict(StdCodec{}, StrictError).Unmarshal([]byte(`{"subscriptionId":"sub1"}`), &sub))
+}
@@ -0,0 +1,245 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// Subscription struct with a notification subscription of the application
+type Subscription struct {
+	SubscriptionID string       `json:"subscriptionId"`
+	NotifyType     Notification `json:"notifyType"`
+	CallbackURL    string       `json:"callbackUrl"`
+	// Raw is the body the platform replied to the subscribe request with,
+	// empty for subscriptions which weren't created by SubscribeTo
+	Raw json.RawMessage `json:"-"`
+}
+
+// subscribeResponse is the reply to a subscribe request, some platform
+// versions wrap the subscription in an object
+type subscribeResponse struct {
+	Subscription
+	Wrapped *Subscription `json:"subscription"`
+	// OwnerFlag is sent by some platform versions, it isn't used
+	OwnerFlag bool `json:"ownerFlag"`
+}
+
+type subscriptionsResponse struct {
+	TotalCount    int            `json:"totalCount"`
+	PageNo        int            `json:"pageNo"`
+	PageSize      int            `json:"pageSize"`
+	Subscriptions []Subscription `json:"subscriptions"`
+}
+
+// isAlreadyExists reports whether the platform refused to create a resource
+// because it already exists. The error description is localized, so the
+// error code is checked.
+func isAlreadyExists(err error) bool {
+	var e *APIError
+	if !errors.As(err, &e) {
+		return false
+	}
+	return e.StatusCode == http.StatusConflict || alreadyExistsCodes[e.Code]
+}
+
+// SubscribeTo subscribes the callback URL to notifications of the type. When
+// the subscription already exists the existing subscription is returned.
+func (c *Client) SubscribeTo(not Notification, callbackURL string) (*Subscription, error) {
+	return c.SubscribeToCtx(context.Background(), not, callbackURL)
+}
+
+// SubscribeToCtx is like SubscribeTo but with a context
+func (c *Client) SubscribeToCtx(ctx context.Context, not Notification, callbackURL string) (*Subscription, error) {
+	if !not.Subscribable() {
+		return nil, fmt.Errorf("can't subscribe to %s notifications", not)
+	}
+	b := struct {
+		NotifyType  Notification `json:"notifyType"`
+		CallbackURL string       `json:"callbackurl"`
+	}{
+		NotifyType:  not,
+		CallbackURL: callbackURL,
+	}
+	body, err := c.codec().Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.requestCtx(ctx, http.MethodPost, "/iocm/app/sub/v1.2.0/subscribe", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		err := c.newAPIError(resp)
+		if !isAlreadyExists(err) {
+			return nil, err
+		}
+		sub, ferr := c.findSubscription(ctx, not, callbackURL)
+		if ferr != nil || sub == nil {
+			return nil, err
+		}
+		return sub, nil
+	}
+
+	// platform versions reply with 200 or 201, with the subscription, the
+	// wrapped subscription or without body
+	defer resp.Body.Close()
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	sub := &Subscription{}
+	if buf = bytes.TrimSpace(buf); len(buf) > 0 {
+		r := subscribeResponse{}
+		if err := withStrict(c.codec(), c.cfg.Strict).Unmarshal(buf, &r); err != nil {
+			return nil, err
+		}
+		*sub = r.Subscription
+		if r.Wrapped != nil {
+			*sub = *r.Wrapped
+		}
+		sub.Raw = json.RawMessage(buf)
+	}
+	if sub.NotifyType == "" {
+		sub.NotifyType = not
+	}
+	if sub.CallbackURL == "" {
+		sub.CallbackURL = callbackURL
+	}
+	return sub, nil
+}
+
+// ListSubscriptions returns the subscriptions of the application, of all
+// notification types when not is empty
+func (c *Client) ListSubscriptions(not Notification) ([]Subscription, error) {
+	return c.ListSubscriptionsCtx(context.Background(), not)
+}
+
+// ListSubscriptionsCtx is like ListSubscriptions but with a context
+func (c *Client) ListSubscriptionsCtx(ctx context.Context, not Notification) ([]Subscription, error) {
+	var subs []Subscription
+	err := c.eachSubscription(ctx, not, func(s Subscription) bool {
+		subs = append(subs, s)
+		return true
+	})
+	return subs, err
+}
+
+// findSubscription returns the subscription for the notification type and
+// callback URL, or nil when there is none
+func (c *Client) findSubscription(ctx context.Context, not Notification, callbackURL string) (*Subscription, error) {
+	var found *Subscription
+	err := c.eachSubscription(ctx, not, func(s Subscription) bool {
+		if s.NotifyType == not && s.CallbackURL == callbackURL {
+			found = &s
+			return false
+		}
+		return true
+	})
+	return found, err
+}
+
+// eachSubscription calls fn for the subscriptions page by page, until fn
+// returns false
+func (c *Client) eachSubscription(ctx context.Context, not Notification, fn func(Subscription) bool) error {
+	const pageSize = 100
+	p := c.newPager()
+	pctx, cancel := p.context(ctx)
+	defer cancel()
+	for page := 0; ; page++ {
+		e := c.appEndpoint("/iocm/app/sub/v1.2.0/subscriptions").
+			Set("notifyType", string(not)).
+			SetInt("pageNo", page).
+			SetInt("pageSize", pageSize)
+		resp, err := c.requestCtx(pctx, http.MethodGet, e.String(), nil)
+		if err != nil {
+			return p.limitErr(ctx, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return c.newAPIError(resp)
+		}
+		r := subscriptionsResponse{}
+		if err := c.decode(resp, &r); err != nil {
+			return p.limitErr(ctx, err)
+		}
+		for _, s := range r.Subscriptions {
+			if !fn(s) {
+				return nil
+			}
+		}
+		if len(r.Subscriptions) < pageSize {
+			return nil
+		}
+		if err := p.next(len(r.Subscriptions)); err != nil {
+			return err
+		}
+	}
+}
+
+// GetSubscription returns the subscription with the ID
+func (c *Client) GetSubscription(subscriptionID string) (*Subscription, error) {
+	return c.GetSubscriptionCtx(context.Background(), subscriptionID)
+}
+
+// GetSubscriptionCtx is like GetSubscription but with a context
+func (c *Client) GetSubscriptionCtx(ctx context.Context, subscriptionID string) (*Subscription, error) {
+	resp, err := c.requestCtx(ctx, http.MethodGet, c.appEndpoint("/iocm/app/sub/v1.2.0/subscriptions", subscriptionID).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.newAPIError(resp)
+	}
+	sub := &Subscription{}
+	if err := c.decode(resp, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// DeleteSubscription deletes the subscription with the ID
+func (c *Client) DeleteSubscription(subscriptionID string) error {
+	return c.DeleteSubscriptionCtx(context.Background(), subscriptionID)
+}
+
+// DeleteSubscriptionCtx is like DeleteSubscription but with a context
+func (c *Client) DeleteSubscriptionCtx(ctx context.Context, subscriptionID string) error {
+	resp, err := c.requestCtx(ctx, http.MethodDelete, c.appEndpoint("/iocm/app/sub/v1.2.0/subscriptions", subscriptionID).String(), nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusNoContent {
+		return c.newAPIError(resp)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// DeleteAllSubscriptions deletes the subscriptions of the application, of all
+// notification types when not is empty
+func (c *Client) DeleteAllSubscriptions(not Notification) error {
+	return c.DeleteAllSubscriptionsCtx(context.Background(), not)
+}
+
+// DeleteAllSubscriptionsCtx is like DeleteAllSubscriptions but with a context
+func (c *Client) DeleteAllSubscriptionsCtx(ctx context.Context, not Notification) error {
+	e := c.appEndpoint("/iocm/app/sub/v1.2.0/subscriptions").
+		Set("notifyType", string(not))
+	resp, err := c.requestCtx(ctx, http.MethodDelete, e.String(), nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusNoContent {
+		return c.newAPIError(resp)
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -17,14 +17,50 @@ type OcTime struct {
 	time.Time
 }
 
-// UnmarshalJSON reads the times to time.Time
+// UnmarshalJSON reads the times to time.Time, the result is always in UTC
 func (ct *OcTime) UnmarshalJSON(b []byte) error {
-	var err error
 	s := strings.Trim(string(b), "\"")
-	if s == "null" {
+	if s == "null" || s == "" {
 		ct.Time = time.Time{}
 		return nil
 	}
-	ct.Time, err = time.Parse(ocTimeLayout, s)
-	return err
+	t, err := ParseOcTime(s)
+	if err != nil {
+		return err
+	}
+	ct.Time = t
+	return nil
+}
+
+// MarshalJSON writes the time in the format used by the API
+func (ct OcTime) MarshalJSON() ([]byte, error) {
+	if ct.IsZero() {
+		return []byte("null"), nil
+	}
+	return []byte("\"" + FormatOcTime(ct.Time) + "\""), nil
+}
+
+// FormatOcTime formats t in UTC in the format the API expects for query
+// parameters like startTime and endTime
+func FormatOcTime(t time.Time) string {
+	return t.UTC().Format(ocTimeLayout)
+}
+
+// ParseOcTime parses a time as returned by the API and converts it to UTC
+func ParseOcTime(s string) (time.Time, error) {
+	t, err := time.Parse(ocTimeLayout, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
+
+// DayRange returns the start of the day of t and the start of the next day in
+// loc. Days with a DST transition are 23 or 25 hours long, so the range must
+// not be computed by adding 24 hours when querying history per local day.
+func DayRange(t time.Time, loc *time.Location) (start, end time.Time) {
+	t = t.In(loc)
+	start = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	end = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+	return start, end
 }
@@ -0,0 +1,39 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOcTime(t *testing.T) {
+	var v struct {
+		T OcTime `json:"t"`
+	}
+	assert.Nil(t, json.Unmarshal([]byte(`{"t":"20170912T101530+02:00"}`), &v), "expected no error for unmarshal")
+	assert.Equal(t, time.UTC, v.T.Location(), "expected time in UTC")
+	assert.Equal(t, "20170912T081530Z", FormatOcTime(v.T.Time))
+
+	out, err := json.Marshal(v)
+	assert.Nil(t, err, "expected no error for marshal")
+	assert.Equal(t, `{"t":"20170912T081530Z"}`, string(out))
+}
+
+func TestDayRange(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Amsterdam")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+
+	// the day DST ends is 25 hours long
+	start, end := DayRange(time.Date(2017, 10, 29, 12, 0, 0, 0, time.UTC), loc)
+	assert.Equal(t, 25*time.Hour, end.Sub(start))
+	assert.Equal(t, "20171028T220000Z", FormatOcTime(start))
+	assert.Equal(t, "20171029T230000Z", FormatOcTime(end))
+}
@@ -0,0 +1,78 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Default timeouts of the operation types, see Config.ReadTimeout
+const (
+	defaultReadTimeout   = 30 * time.Second
+	defaultWriteTimeout  = time.Minute
+	defaultUploadTimeout = 10 * time.Minute
+)
+
+// operation is the type of an API call, it determines the default timeout
+type operation int
+
+const (
+	opRead operation = iota
+	opWrite
+	opUpload
+)
+
+// methodOperation returns the operation type of a request method
+func methodOperation(method string) operation {
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		return opRead
+	}
+	return opWrite
+}
+
+// timeout returns the configured or default timeout of the operation type
+func (c *Client) timeout(op operation) time.Duration {
+	t, def := c.cfg.ReadTimeout, defaultReadTimeout
+	switch op {
+	case opWrite:
+		t, def = c.cfg.WriteTimeout, defaultWriteTimeout
+	case opUpload:
+		t, def = c.cfg.UploadTimeout, defaultUploadTimeout
+	}
+	if t == 0 {
+		return def
+	}
+	return t
+}
+
+// withTimeout applies the timeout of the operation type when the context has
+// no deadline. A negative timeout disables it.
+func (c *Client) withTimeout(ctx context.Context, op operation) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	t := c.timeout(op)
+	if t < 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, t)
+}
+
+// cancelBody cancels the request context when the response body is closed,
+// the body is read after the request returns
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,266 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// tokenRefreshMargin is the time before expiry a token is refreshed
+const tokenRefreshMargin = 5 * time.Minute
+
+// Token is an access token for the API
+type Token struct {
+	AccessToken string    `json:"accessToken"`
+	TokenType   string    `json:"tokenType"`
+	Expires     time.Time `json:"expires"`
+	// RefreshToken retrieves a new access token without login, empty when
+	// the platform didn't provide one
+	RefreshToken string `json:"refreshToken,omitempty"`
+}
+
+// Header returns the value for the Authorization header
+func (t Token) Header() string {
+	return t.TokenType + " " + t.AccessToken
+}
+
+// TokenSource provides the token for the API calls, it replaces the login
+// the client does by itself
+type TokenSource interface {
+	Token() (Token, error)
+}
+
+// TokenSourceCtx is a TokenSource which takes the context of the request
+// needing the token, the client uses TokenCtx when the source implements it
+type TokenSourceCtx interface {
+	TokenSource
+	TokenCtx(ctx context.Context) (Token, error)
+}
+
+// SetTokenSource makes the client use ts for its tokens
+func (c *Client) SetTokenSource(ts TokenSource) {
+	c.tokenLock.Lock()
+	c.tokenSource = ts
+	c.tokenLock.Unlock()
+}
+
+// tokenCall is a token refresh shared by the concurrent requests
+type tokenCall struct {
+	done chan struct{}
+	err  error
+}
+
+// accessToken returns the authorization header of the requests. An expired
+// token is refreshed once for all requests waiting for it.
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	for {
+		c.tokenLock.Lock()
+		if !c.tokenExpires.Before(c.clock().Now().Add(tokenRefreshMargin)) {
+			token := c.token
+			c.tokenLock.Unlock()
+			return token, nil
+		}
+		call := c.tokenCall
+		leader := call == nil
+		if leader {
+			call = &tokenCall{done: make(chan struct{})}
+			c.tokenCall = call
+		}
+		c.tokenLock.Unlock()
+
+		if leader {
+			call.err = c.refreshToken(ctx)
+			if c.cfg.Metrics != nil {
+				c.cfg.Metrics.ObserveTokenRefresh(call.err)
+			}
+			c.tokenLock.Lock()
+			c.tokenCall = nil
+			c.tokenLock.Unlock()
+			close(call.done)
+		} else {
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-call.done:
+			}
+		}
+		if call.err != nil {
+			// the refresh of another request was canceled, try again
+			if !leader && (errors.Is(call.err, context.Canceled) || errors.Is(call.err, context.DeadlineExceeded)) && ctx.Err() == nil {
+				continue
+			}
+			return "", call.err
+		}
+		// use the new token, even if it expires within the refresh margin
+		c.tokenLock.Lock()
+		token := c.token
+		c.tokenLock.Unlock()
+		return token, nil
+	}
+}
+
+// invalidateToken makes the next request get a new token, unless the token
+// was replaced already
+func (c *Client) invalidateToken(token string) {
+	c.tokenLock.Lock()
+	if c.token == token {
+		c.tokenExpires = time.Time{}
+	}
+	c.tokenLock.Unlock()
+}
+
+// httpClient returns the http client the requests are sent with
+func (c *Client) httpClient() *http.Client {
+	c.tokenLock.Lock()
+	defer c.tokenLock.Unlock()
+	return c.c
+}
+
+// refreshToken retrieves a new token from the token source, with the refresh
+// token of the previous login or by logging in again
+func (c *Client) refreshToken(ctx context.Context) error {
+	c.tokenLock.Lock()
+	ts, refreshTok := c.tokenSource, c.refreshTok
+	c.tokenLock.Unlock()
+
+	if ts != nil {
+		var t Token
+		var err error
+		if tsc, ok := ts.(TokenSourceCtx); ok {
+			t, err = tsc.TokenCtx(ctx)
+		} else {
+			t, err = ts.Token()
+		}
+		if err != nil {
+			return err
+		}
+		c.setToken(t)
+		return nil
+	}
+	if refreshTok != "" {
+		t, err := c.refreshLogin(ctx, refreshTok)
+		if err == nil {
+			c.setToken(t)
+			logrus.Debugf("Token refreshed, expires: %v", t.Expires)
+			return nil
+		}
+		logrus.Warnf("token refresh failed, logging in: %v", err)
+	}
+	return c.LoginCtx(ctx)
+}
+
+// TokenStore is storage shared by the replicas using the same application ID,
+// e.g. backed by a database or redis. The lease is a lock with a time to live,
+// so a crashed replica can't block the others.
+type TokenStore interface {
+	// Load returns the stored token, or nil when there is none
+	Load() (*Token, error)
+	// Save stores the token
+	Save(Token) error
+	// AcquireLease takes the refresh lease, it returns false when another
+	// replica holds it
+	AcquireLease(ttl time.Duration) (bool, error)
+	// ReleaseLease releases the lease taken with AcquireLease
+	ReleaseLease() error
+}
+
+// SharedTokenSource shares the token between replicas through a TokenStore.
+// Only the replica holding the lease logs in, the others wait for the new
+// token, so concurrent logins can't invalidate each other's tokens.
+type SharedTokenSource struct {
+	// LeaseTTL is the time to live of the lease (default 30s), replicas wait
+	// at most this long for another replica to store a new token
+	LeaseTTL time.Duration
+	// PollInterval is the interval at which waiting replicas check the store
+	// (default 1s)
+	PollInterval time.Duration
+
+	client *Client
+	store  TokenStore
+}
+
+// NewSharedTokenSource creates a token source which logs in with the client
+// and shares the token through store, use SetTokenSource to activate it
+func NewSharedTokenSource(c *Client, store TokenStore) *SharedTokenSource {
+	return &SharedTokenSource{
+		LeaseTTL:     30 * time.Second,
+		PollInterval: time.Second,
+		client:       c,
+		store:        store,
+	}
+}
+
+// Token implements TokenSource
+func (s *SharedTokenSource) Token() (Token, error) {
+	return s.TokenCtx(context.Background())
+}
+
+// TokenCtx implements TokenSourceCtx
+func (s *SharedTokenSource) TokenCtx(ctx context.Context) (Token, error) {
+	clock := s.client.clock()
+	poll := s.PollInterval
+	if poll <= 0 {
+		poll = time.Second
+	}
+	deadline := clock.Now().Add(s.LeaseTTL)
+	for {
+		if t, ok, err := s.load(); ok || err != nil {
+			return t, err
+		}
+
+		ok, err := s.store.AcquireLease(s.LeaseTTL)
+		if err != nil {
+			return Token{}, err
+		}
+		if ok {
+			return s.refresh(ctx)
+		}
+
+		if clock.Now().After(deadline) {
+			return Token{}, errors.New("timeout waiting for token refresh by another replica")
+		}
+		select {
+		case <-ctx.Done():
+			return Token{}, ctx.Err()
+		case <-clock.After(poll):
+		}
+	}
+}
+
+// load returns the stored token, ok is false when there is none or it is
+// about to expire
+func (s *SharedTokenSource) load() (Token, bool, error) {
+	t, err := s.store.Load()
+	if err != nil || t == nil {
+		return Token{}, false, err
+	}
+	return *t, t.Expires.After(s.client.clock().Now().Add(tokenRefreshMargin)), nil
+}
+
+// refresh logs in while holding the lease. The store is checked again first:
+// the previous holder may have saved a new token after this replica loaded
+// the store, logging in again would invalidate it.
+func (s *SharedTokenSource) refresh(ctx context.Context) (Token, error) {
+	defer func() {
+		if err := s.store.ReleaseLease(); err != nil {
+			logrus.Errorf("releasing token lease failed: %v", err)
+		}
+	}()
+
+	if t, ok, err := s.load(); ok || err != nil {
+		return t, err
+	}
+	t, err := s.client.login(ctx)
+	if err != nil {
+		return Token{}, err
+	}
+	logrus.Infof("Token retrieved, expires: %v", t.Expires)
+	return t, s.store.Save(t)
+}
@@ -0,0 +1,118 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memTokenStore is a TokenStore in memory, acquire decides whether the lease
+// is taken
+type memTokenStore struct {
+	token    *Token
+	acquire  func() bool
+	acquired int
+	released int
+}
+
+func (m *memTokenStore) Load() (*Token, error) { return m.token, nil }
+func (m *memTokenStore) Save(t Token) error    { m.token = &t; return nil }
+func (m *memTokenStore) ReleaseLease() error   { m.released++; return nil }
+
+func (m *memTokenStore) AcquireLease(ttl time.Duration) (bool, error) {
+	m.acquired++
+	return m.acquire(), nil
+}
+
+func TestSharedTokenSource(t *testing.T) {
+	logins := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logins++
+		fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+	}))
+	defer s.Close()
+	clock := &fakeClock{now: time.Date(2017, 9, 12, 10, 0, 0, 0, time.UTC)}
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL, Clock: clock}}
+
+	// the lease holder logs in and saves the token
+	store := &memTokenStore{acquire: func() bool { return true }}
+	tok, err := NewSharedTokenSource(c, store).Token()
+	assert.Nil(t, err)
+	assert.Equal(t, "85fe3222f362e3b6e943e483bd9c6f9b", tok.AccessToken)
+	assert.Equal(t, &tok, store.token)
+	assert.Equal(t, 1, logins)
+	assert.Equal(t, 1, store.released)
+
+	// another replica saved a token between the load and the lease
+	fresh := Token{AccessToken: "fresh", Expires: clock.now.Add(time.Hour)}
+	store = &memTokenStore{
+		token: &Token{AccessToken: "expired", Expires: clock.now},
+		acquire: func() bool {
+			store.token = &fresh
+			return true
+		},
+	}
+	tok, err = NewSharedTokenSource(c, store).Token()
+	assert.Nil(t, err)
+	assert.Equal(t, fresh, tok)
+	assert.Equal(t, 1, logins)
+	assert.Equal(t, 1, store.released)
+}
+
+func TestSharedTokenSourceWait(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2017, 9, 12, 10, 0, 0, 0, time.UTC)}
+	c := &Client{c: &http.Client{}, cfg: Config{Clock: clock}}
+
+	// a zero poll interval waits the default interval
+	store := &memTokenStore{acquire: func() bool { return false }}
+	ts := NewSharedTokenSource(c, store)
+	ts.PollInterval = 0
+	_, err := ts.Token()
+	assert.NotNil(t, err)
+	assert.Equal(t, 32, store.acquired)
+	assert.Equal(t, 0, store.released)
+
+	// waiting ends with the context
+	c = &Client{c: &http.Client{}}
+	ts = NewSharedTokenSource(c, store)
+	ts.PollInterval = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err = ts.TokenCtx(ctx)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestInvalidateTokenOnUnauthorized(t *testing.T) {
+	logins, requests := 0, 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			logins++
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		requests++
+		if requests == 1 {
+			// the platform invalidated the token
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"deviceId":"dev1"}`)
+	}))
+	defer s.Close()
+
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL}}
+	_, err := c.GetDevice("dev1")
+	assert.True(t, IsUnauthorized(err))
+	_, err = c.GetDevice("dev1")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, logins, "expected a new login after the 401")
+}
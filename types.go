@@ -0,0 +1,119 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import "errors"
+
+// NodeType is the type of node a device is
+type NodeType string
+
+const (
+	// NodeTypeEndpoint is a device which is directly connected to the platform
+	NodeTypeEndpoint NodeType = "ENDPOINT"
+	// NodeTypeGateway is a gateway with devices connected behind it
+	NodeTypeGateway NodeType = "GATEWAY"
+	// NodeTypeUnknown is used by the platform when the type is not known
+	NodeTypeUnknown NodeType = "UNKNOW"
+)
+
+// Valid reports whether n is a known node type
+func (n NodeType) Valid() bool {
+	switch n {
+	case NodeTypeEndpoint, NodeTypeGateway, NodeTypeUnknown:
+		return true
+	}
+	return false
+}
+
+// ProtocolType is the protocol a device uses to communicate with the platform
+type ProtocolType string
+
+const (
+	// ProtocolCoAP is used for devices using CoAP with a codec plugin
+	ProtocolCoAP ProtocolType = "CoAP"
+	// ProtocolLWM2M is used for devices using LWM2M
+	ProtocolLWM2M ProtocolType = "LWM2M"
+	// ProtocolMQTT is used for devices using MQTT
+	ProtocolMQTT ProtocolType = "MQTT"
+)
+
+// Valid reports whether p is a known protocol type
+func (p ProtocolType) Valid() bool {
+	switch p {
+	case ProtocolCoAP, ProtocolLWM2M, ProtocolMQTT:
+		return true
+	}
+	return false
+}
+
+// DeviceStatus is the status of a device as reported by the platform
+type DeviceStatus string
+
+const (
+	// DeviceStatusOnline is used for devices which are online
+	DeviceStatusOnline DeviceStatus = "ONLINE"
+	// DeviceStatusOffline is used for devices which are offline
+	DeviceStatusOffline DeviceStatus = "OFFLINE"
+	// DeviceStatusInbox is used for devices which are registered but never came online
+	DeviceStatusInbox DeviceStatus = "INBOX"
+	// DeviceStatusAbnormal is used for devices in an abnormal state
+	DeviceStatusAbnormal DeviceStatus = "ABNORMAL"
+)
+
+// Valid reports whether s is a known device status
+func (s DeviceStatus) Valid() bool {
+	switch s {
+	case DeviceStatusOnline, DeviceStatusOffline, DeviceStatusInbox, DeviceStatusAbnormal:
+		return true
+	}
+	return false
+}
+
+// SortOrder is the order of a sorted listing
+type SortOrder string
+
+const (
+	// SortAscending sorts the oldest first
+	SortAscending SortOrder = "ASC"
+	// SortDescending sorts the newest first
+	SortDescending SortOrder = "DESC"
+)
+
+// Valid reports whether o is a known sort order
+func (o SortOrder) Valid() bool {
+	switch o {
+	case SortAscending, SortDescending:
+		return true
+	}
+	return false
+}
+
+func validateNodeType(n NodeType) error {
+	if n != "" && !n.Valid() {
+		return errors.New("invalid node type: " + string(n))
+	}
+	return nil
+}
+
+func validateProtocolType(p ProtocolType) error {
+	if p != "" && !p.Valid() {
+		return errors.New("invalid protocol type: " + string(p))
+	}
+	return nil
+}
+
+func validateSort(o SortOrder) error {
+	if o != "" && !o.Valid() {
+		return errors.New("invalid sort order: " + string(o))
+	}
+	return nil
+}
+
+func validateDeviceStatus(s DeviceStatus) error {
+	if s != "" && !s.Valid() {
+		return errors.New("invalid device status: " + string(s))
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypeValidation(t *testing.T) {
+	var requests int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/iocm/app/sec/v1.1.0/login" {
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+			return
+		}
+		requests++
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprintln(w, `{"totalCount":0,"pageNo":0,"pageSize":100,"devices":[]}`)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer s.Close()
+
+	c := &Client{c: &http.Client{}, cfg: Config{URL: s.URL}}
+	_, err := c.GetDevices(GetDevicesStruct{NodeType: NodeTypeGateway, Status: DeviceStatusOnline, Sort: SortAscending})
+	assert.Nil(t, err)
+	assert.Nil(t, c.SetDeviceInfo("dev1", "meter"))
+	assert.Equal(t, 2, requests)
+
+	// invalid values are rejected before anything is sent
+	_, err = c.GetDevices(GetDevicesStruct{NodeType: "endpoint"})
+	assert.EqualError(t, err, "invalid node type: endpoint")
+	_, err = c.GetDevices(GetDevicesStruct{Status: "online"})
+	assert.EqualError(t, err, "invalid device status: online")
+	assert.True(t, DeviceStatusInbox.Valid())
+	assert.False(t, DeviceStatus("online").Valid())
+	assert.False(t, ProtocolType("HTTP").Valid())
+	c.cfg.ProtocolType = "HTTP"
+	assert.EqualError(t, c.SetDeviceInfo("dev1", "meter"), "invalid protocol type: HTTP")
+	assert.Equal(t, 2, requests)
+}
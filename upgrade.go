@@ -0,0 +1,410 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+)
+
+// PackageType is the type of an upgrade package
+type PackageType string
+
+const (
+	// PackageFirmware is a firmware package, upgraded with LWM2M
+	PackageFirmware PackageType = "firmwarePackage"
+	// PackageSoftware is a software package, upgraded with PCP
+	PackageSoftware PackageType = "softwarePackage"
+)
+
+// UpgradePackage struct with an uploaded upgrade package
+type UpgradePackage struct {
+	FileID           string       `json:"fileId"`
+	Name             string       `json:"name"`
+	Version          string       `json:"version"`
+	FileType         PackageType  `json:"fileType"`
+	DeviceType       string       `json:"deviceType"`
+	Model            string       `json:"model"`
+	ManufacturerName string       `json:"manufacturerName"`
+	ProtocolType     ProtocolType `json:"protocolType"`
+	Description      string       `json:"description"`
+	Date             OcTime       `json:"date"`
+	UploadTime       OcTime       `json:"uploadTime"`
+}
+
+// PackageInfo describes the devices a package is uploaded for
+type PackageInfo struct {
+	Name             string
+	Version          string
+	DeviceType       string
+	Model            string
+	ManufacturerName string
+	ProtocolType     ProtocolType
+	Description      string
+}
+
+// PackageFilter selects the packages returned by a listing, empty fields are
+// not filtered on. All pages are returned when PageSize is 0.
+type PackageFilter struct {
+	DeviceType       string
+	Model            string
+	ManufacturerName string
+	Version          string
+	PageNo           int
+	PageSize         int
+}
+
+type packagesResponse struct {
+	TotalCount int              `json:"totalCount"`
+	PageNo     int              `json:"pageNo"`
+	PageSize   int              `json:"pageSize"`
+	Data       []UpgradePackage `json:"data"`
+}
+
+// UpgradeStatus is the status of an upgrade task or sub-task
+type UpgradeStatus string
+
+const (
+	// UpgradeWait is used for tasks which are not started yet
+	UpgradeWait UpgradeStatus = "wait"
+	// UpgradeProcessing is used for tasks which are being executed
+	UpgradeProcessing UpgradeStatus = "processing"
+	// UpgradeSuccess is used for tasks which finished successfully
+	UpgradeSuccess UpgradeStatus = "success"
+	// UpgradeFailed is used for tasks which failed
+	UpgradeFailed UpgradeStatus = "failed"
+	// UpgradeStopped is used for tasks which were stopped
+	UpgradeStopped UpgradeStatus = "stop"
+	// UpgradeTimeout is used for sub-tasks of devices which didn't respond
+	UpgradeTimeout UpgradeStatus = "timeout"
+)
+
+// Final reports whether the status can't change anymore
+func (s UpgradeStatus) Final() bool {
+	switch s {
+	case UpgradeSuccess, UpgradeFailed, UpgradeStopped, UpgradeTimeout:
+		return true
+	}
+	return false
+}
+
+// UpgradeStats struct with the number of sub-tasks per status
+type UpgradeStats struct {
+	Total      int `json:"total"`
+	Wait       int `json:"wait"`
+	Processing int `json:"processing"`
+	Success    int `json:"success"`
+	Fail       int `json:"fail"`
+	Stop       int `json:"stop"`
+	Timeout    int `json:"timeout"`
+}
+
+// UpgradeTask struct with an upgrade task of a list of devices
+type UpgradeTask struct {
+	OperationID string          `json:"operationId"`
+	OperateType string          `json:"operateType"`
+	Status      UpgradeStatus   `json:"status"`
+	CreateTime  OcTime          `json:"createTime"`
+	StartTime   OcTime          `json:"startTime"`
+	StopTime    OcTime          `json:"stopTime"`
+	Stats       UpgradeStats    `json:"staResult"`
+	ExtendPara  json.RawMessage `json:"extendPara"`
+}
+
+// Progress returns the part of the sub-tasks which finished, between 0 and 1
+func (t *UpgradeTask) Progress() float64 {
+	if t.Stats.Total == 0 {
+		return 0
+	}
+	done := t.Stats.Success + t.Stats.Fail + t.Stats.Stop + t.Stats.Timeout
+	return float64(done) / float64(t.Stats.Total)
+}
+
+// UpgradeSubTask struct with the upgrade of one device of a task
+type UpgradeSubTask struct {
+	SubOperationID string          `json:"subOperationId"`
+	OperateType    string          `json:"operateType"`
+	DeviceID       string          `json:"deviceId"`
+	Status         UpgradeStatus   `json:"status"`
+	CreateTime     OcTime          `json:"createTime"`
+	StartTime      OcTime          `json:"startTime"`
+	StopTime       OcTime          `json:"stopTime"`
+	DetailInfo     string          `json:"detailInfo"`
+	ExtendInfo     json.RawMessage `json:"extendInfo"`
+}
+
+type subTasksResponse struct {
+	Pagination struct {
+		PageNo    int   `json:"pageNo"`
+		PageSize  int   `json:"pageSize"`
+		TotalSize int64 `json:"totalSize"`
+	} `json:"pagination"`
+	SubOperations []UpgradeSubTask `json:"subOperations"`
+}
+
+// uploadPackage uploads the package read from r
+func (c *Client) uploadPackage(ctx context.Context, typ PackageType, info PackageInfo, r io.Reader) (*UpgradePackage, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	fields := [][2]string{
+		{"fileType", string(typ)},
+		{"name", info.Name},
+		{"version", info.Version},
+		{"deviceType", info.DeviceType},
+		{"model", info.Model},
+		{"manufacturerName", info.ManufacturerName},
+		{"protocolType", string(info.ProtocolType)},
+		{"description", info.Description},
+	}
+	for _, f := range fields {
+		if f[1] == "" {
+			continue
+		}
+		if err := w.WriteField(f[0], f[1]); err != nil {
+			return nil, err
+		}
+	}
+	fw, err := w.CreateFormFile("file", info.Name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(fw, r); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.requestContent(ctx, opUpload, http.MethodPost, c.appEndpoint("/iocm/app/maintenance/v1.2.0/packages").String(), w.FormDataContentType(), &body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, c.newAPIError(resp)
+	}
+	p := &UpgradePackage{}
+	if err := c.decode(resp, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// listPackages returns the packages of the type selected by the filter
+func (c *Client) listPackages(ctx context.Context, typ PackageType, f PackageFilter) ([]UpgradePackage, error) {
+	if f.PageSize > 0 {
+		return c.packagesPage(ctx, typ, f)
+	}
+	const pageSize = 100
+	var pkgs []UpgradePackage
+	p := c.newPager()
+	pctx, cancel := p.context(ctx)
+	defer cancel()
+	for f.PageSize = pageSize; ; f.PageNo++ {
+		page, err := c.packagesPage(pctx, typ, f)
+		if err != nil {
+			return pkgs, p.limitErr(ctx, err)
+		}
+		pkgs = append(pkgs, page...)
+		if len(page) < pageSize {
+			return pkgs, nil
+		}
+		if err := p.next(len(page)); err != nil {
+			return pkgs, err
+		}
+	}
+}
+
+func (c *Client) packagesPage(ctx context.Context, typ PackageType, f PackageFilter) ([]UpgradePackage, error) {
+	e := c.appEndpoint("/iocm/app/maintenance/v1.2.0/devices/packages").
+		Set("fileType", string(typ)).
+		Set("deviceType", f.DeviceType).
+		Set("model", f.Model).
+		Set("manufacturerName", f.ManufacturerName).
+		Set("version", f.Version).
+		SetInt("pageNo", f.PageNo).
+		SetInt("pageSize", f.PageSize)
+	resp, err := c.requestCtx(ctx, http.MethodGet, e.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.newAPIError(resp)
+	}
+	r := packagesResponse{}
+	if err := c.decode(resp, &r); err != nil {
+		return nil, err
+	}
+	return r.Data, nil
+}
+
+// GetUpgradePackage returns the firmware or software package with the file ID
+func (c *Client) GetUpgradePackage(fileID string) (*UpgradePackage, error) {
+	return c.GetUpgradePackageCtx(context.Background(), fileID)
+}
+
+// GetUpgradePackageCtx is like GetUpgradePackage but with a context
+func (c *Client) GetUpgradePackageCtx(ctx context.Context, fileID string) (*UpgradePackage, error) {
+	resp, err := c.requestCtx(ctx, http.MethodGet, c.appEndpoint("/iocm/app/maintenance/v1.2.0/packages", fileID).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.newAPIError(resp)
+	}
+	p := &UpgradePackage{}
+	if err := c.decode(resp, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// DeleteUpgradePackage deletes the firmware or software package with the
+// file ID
+func (c *Client) DeleteUpgradePackage(fileID string) error {
+	return c.DeleteUpgradePackageCtx(context.Background(), fileID)
+}
+
+// DeleteUpgradePackageCtx is like DeleteUpgradePackage but with a context
+func (c *Client) DeleteUpgradePackageCtx(ctx context.Context, fileID string) error {
+	resp, err := c.requestCtx(ctx, http.MethodDelete, c.appEndpoint("/iocm/app/maintenance/v1.2.0/packages", fileID).String(), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return c.newAPIError(resp)
+	}
+	return nil
+}
+
+// createUpgradeTask creates a task upgrading the devices to the package with
+// the upgrade API at the path, it returns the operation ID
+func (c *Client) createUpgradeTask(ctx context.Context, path, fileID string, deviceIDs []string) (string, error) {
+	b := struct {
+		FileID  string `json:"fileId"`
+		Targets struct {
+			DeviceIDs []string `json:"deviceIds"`
+		} `json:"targets"`
+		Policy struct {
+			ExecuteType string `json:"executeType"`
+		} `json:"policy"`
+	}{FileID: fileID}
+	b.Targets.DeviceIDs = deviceIDs
+	b.Policy.ExecuteType = "now"
+	body, err := c.codec().Marshal(b)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.requestCtx(ctx, http.MethodPost, c.appEndpoint(path).String(), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return "", c.newAPIError(resp)
+	}
+	r := struct {
+		OperationID string `json:"operationId"`
+	}{}
+	if err := c.decode(resp, &r); err != nil {
+		return "", err
+	}
+	return r.OperationID, nil
+}
+
+// GetUpgradeTask returns the firmware or software upgrade task with the
+// operation ID
+func (c *Client) GetUpgradeTask(operationID string) (*UpgradeTask, error) {
+	return c.GetUpgradeTaskCtx(context.Background(), operationID)
+}
+
+// GetUpgradeTaskCtx is like GetUpgradeTask but with a context
+func (c *Client) GetUpgradeTaskCtx(ctx context.Context, operationID string) (*UpgradeTask, error) {
+	resp, err := c.requestCtx(ctx, http.MethodGet, c.appEndpoint("/iocm/app/maintenance/v1.1.0/operations", operationID).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.newAPIError(resp)
+	}
+	t := &UpgradeTask{}
+	if err := c.decode(resp, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ListUpgradeSubTasks returns the upgrades of the devices of the task, only
+// those with the status when it isn't empty
+func (c *Client) ListUpgradeSubTasks(operationID string, status UpgradeStatus) ([]UpgradeSubTask, error) {
+	return c.ListUpgradeSubTasksCtx(context.Background(), operationID, status)
+}
+
+// ListUpgradeSubTasksCtx is like ListUpgradeSubTasks but with a context
+func (c *Client) ListUpgradeSubTasksCtx(ctx context.Context, operationID string, status UpgradeStatus) ([]UpgradeSubTask, error) {
+	const pageSize = 100
+	var subs []UpgradeSubTask
+	p := c.newPager()
+	pctx, cancel := p.context(ctx)
+	defer cancel()
+	for pageNo := 0; ; pageNo++ {
+		e := c.appEndpoint("/iocm/app/maintenance/v1.1.0/operations", operationID, "subOperations").
+			Set("subOperationStatus", string(status)).
+			SetInt("pageNo", pageNo).
+			SetInt("pageSize", pageSize)
+		resp, err := c.requestCtx(pctx, http.MethodGet, e.String(), nil)
+		if err != nil {
+			return subs, p.limitErr(ctx, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return subs, c.newAPIError(resp)
+		}
+		r := subTasksResponse{}
+		if err := c.decode(resp, &r); err != nil {
+			return subs, p.limitErr(ctx, err)
+		}
+		subs = append(subs, r.SubOperations...)
+		if len(r.SubOperations) < pageSize {
+			return subs, nil
+		}
+		if err := p.next(len(r.SubOperations)); err != nil {
+			return subs, err
+		}
+	}
+}
+
+// defaultUpgradePollInterval is the interval of WaitUpgradeTask without
+// interval
+const defaultUpgradePollInterval = 10 * time.Second
+
+// WaitUpgradeTask polls the upgrade task at the interval (default 10s) until
+// it reached a final status or the context is done, progress is called with
+// every polled task when not nil
+func (c *Client) WaitUpgradeTask(ctx context.Context, operationID string, interval time.Duration, progress func(*UpgradeTask)) (*UpgradeTask, error) {
+	if interval <= 0 {
+		interval = defaultUpgradePollInterval
+	}
+	for {
+		t, err := c.GetUpgradeTaskCtx(ctx, operationID)
+		if err != nil {
+			return nil, err
+		}
+		if progress != nil {
+			progress(t)
+		}
+		if t.Status.Final() {
+			return t, nil
+		}
+		select {
+		case <-ctx.Done():
+			return t, ctx.Err()
+		case <-c.clock().After(interval):
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"io"
+)
+
+// UpgradeService manages the firmware or software packages and the over the
+// air upgrades of devices to them. Packages and tasks are looked up by their
+// ID with the Client, see GetUpgradePackage, DeleteUpgradePackage,
+// GetUpgradeTask, ListUpgradeSubTasks and WaitUpgradeTask.
+type UpgradeService struct {
+	c     *Client
+	typ   PackageType
+	tasks string
+	not   Notification
+}
+
+// Firmware returns the firmware upgrade API of the client, firmware is
+// upgraded with LWM2M
+func (c *Client) Firmware() *UpgradeService {
+	return &UpgradeService{
+		c:     c,
+		typ:   PackageFirmware,
+		tasks: "/iocm/app/fwupgrade/v1.1.0/operations",
+		not:   NotificationFwUpgradeState,
+	}
+}
+
+// Software returns the software upgrade API of the client, software is
+// upgraded with PCP
+func (c *Client) Software() *UpgradeService {
+	return &UpgradeService{
+		c:     c,
+		typ:   PackageSoftware,
+		tasks: "/iocm/app/swupgrade/v1.1.0/operations",
+		not:   NotificationSwUpgradeState,
+	}
+}
+
+// UploadPackage uploads a package read from r
+func (s *UpgradeService) UploadPackage(info PackageInfo, r io.Reader) (*UpgradePackage, error) {
+	return s.UploadPackageCtx(context.Background(), info, r)
+}
+
+// UploadPackageCtx is like UploadPackage but with a context
+func (s *UpgradeService) UploadPackageCtx(ctx context.Context, info PackageInfo, r io.Reader) (*UpgradePackage, error) {
+	return s.c.uploadPackage(ctx, s.typ, info, r)
+}
+
+// ListPackages returns the packages selected by the filter
+func (s *UpgradeService) ListPackages(f PackageFilter) ([]UpgradePackage, error) {
+	return s.ListPackagesCtx(context.Background(), f)
+}
+
+// ListPackagesCtx is like ListPackages but with a context
+func (s *UpgradeService) ListPackagesCtx(ctx context.Context, f PackageFilter) ([]UpgradePackage, error) {
+	return s.c.listPackages(ctx, s.typ, f)
+}
+
+// CreateTask upgrades the devices to the package and returns the operation
+// ID of the task
+func (s *UpgradeService) CreateTask(fileID string, deviceIDs []string) (string, error) {
+	return s.CreateTaskCtx(context.Background(), fileID, deviceIDs)
+}
+
+// CreateTaskCtx is like CreateTask but with a context
+func (s *UpgradeService) CreateTaskCtx(ctx context.Context, fileID string, deviceIDs []string) (string, error) {
+	return s.c.createUpgradeTask(ctx, s.tasks, fileID, deviceIDs)
+}
+
+// Subscribe subscribes the callback URL to the upgrade state notifications,
+// register the callback with OnFwUpgradeState or OnSwUpgradeState on the
+// returned Server
+func (s *UpgradeService) Subscribe(callbackURL string) (*Server, error) {
+	return s.SubscribeCtx(context.Background(), callbackURL)
+}
+
+// SubscribeCtx is like Subscribe but with a context
+func (s *UpgradeService) SubscribeCtx(ctx context.Context, callbackURL string) (*Server, error) {
+	sub, err := s.c.SubscribeToCtx(ctx, s.not, callbackURL)
+	if err != nil {
+		return nil, err
+	}
+	return s.c.newServer(sub), nil
+}
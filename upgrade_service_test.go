@@ -0,0 +1,137 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpgradeServiceFirmware(t *testing.T) {
+	polls := 0
+	var deleted string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/iocm/app/sec/v1.1.0/login":
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+		case r.URL.Path == "/iocm/app/maintenance/v1.2.0/packages" && r.Method == http.MethodPost:
+			assert.Equal(t, "firmwarePackage", r.FormValue("fileType"))
+			assert.Equal(t, "1.2.0", r.FormValue("version"))
+			f, _, err := r.FormFile("file")
+			if assert.Nil(t, err) {
+				b, _ := ioutil.ReadAll(f)
+				assert.Equal(t, "image", string(b))
+			}
+			fmt.Fprintln(w, `{"fileId":"f1","name":"fw.bin","version":"1.2.0","fileType":"firmwarePackage"}`)
+		case r.URL.Path == "/iocm/app/maintenance/v1.2.0/devices/packages":
+			assert.Equal(t, "firmwarePackage", r.URL.Query().Get("fileType"))
+			assert.Equal(t, "WaterMeter", r.URL.Query().Get("deviceType"))
+			fmt.Fprintln(w, `{"totalCount":1,"data":[{"fileId":"f1","version":"1.2.0"}]}`)
+		case r.Method == http.MethodDelete:
+			deleted = r.URL.Path
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/iocm/app/fwupgrade/v1.1.0/operations":
+			b, _ := ioutil.ReadAll(r.Body)
+			assert.Equal(t, `{"fileId":"f1","targets":{"deviceIds":["dev1","dev2"]},"policy":{"executeType":"now"}}`, string(b))
+			fmt.Fprintln(w, `{"operationId":"op1"}`)
+		case r.URL.Path == "/iocm/app/maintenance/v1.1.0/operations/op1":
+			polls++
+			status := "processing"
+			if polls == 2 {
+				status = "success"
+			}
+			fmt.Fprintf(w, `{"operationId":"op1","status":"%s","staResult":{"total":2,"processing":%d,"success":%d}}`, status, 2-polls, polls)
+		case r.URL.Path == "/iocm/app/maintenance/v1.1.0/operations/op1/subOperations":
+			assert.Equal(t, "failed", r.URL.Query().Get("subOperationStatus"))
+			fmt.Fprintln(w, `{"pagination":{"pageNo":0,"pageSize":100,"totalSize":1},"subOperations":[{"subOperationId":"sub1","deviceId":"dev2","status":"failed","detailInfo":"battery low"}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	c := Client{c: &http.Client{}, cfg: Config{URL: s.URL, AppID: "app", Clock: &fakeClock{}}}
+	fw := c.Firmware()
+
+	pkg, err := fw.UploadPackage(PackageInfo{Name: "fw.bin", Version: "1.2.0"}, strings.NewReader("image"))
+	if assert.Nil(t, err) {
+		assert.Equal(t, "f1", pkg.FileID)
+	}
+	pkgs, err := fw.ListPackages(PackageFilter{DeviceType: "WaterMeter"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(pkgs))
+
+	op, err := fw.CreateTask("f1", []string{"dev1", "dev2"})
+	assert.Nil(t, err)
+	assert.Equal(t, "op1", op)
+
+	var progress []float64
+	task, err := c.WaitUpgradeTask(context.Background(), op, time.Second, func(t *UpgradeTask) {
+		progress = append(progress, t.Progress())
+	})
+	if assert.Nil(t, err) {
+		assert.Equal(t, UpgradeSuccess, task.Status)
+	}
+	assert.Equal(t, []float64{0.5, 1}, progress)
+
+	subs, err := c.ListUpgradeSubTasks(op, UpgradeFailed)
+	if assert.Nil(t, err) && assert.Equal(t, 1, len(subs)) {
+		assert.Equal(t, "battery low", subs[0].DetailInfo)
+	}
+
+	assert.Nil(t, c.DeleteUpgradePackage("f1"))
+	assert.Equal(t, "/iocm/app/maintenance/v1.2.0/packages/f1", deleted)
+}
+
+func TestUpgradeServiceSoftware(t *testing.T) {
+	polls := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/iocm/app/sec/v1.1.0/login":
+			fmt.Fprintln(w, `{"accessToken":"85fe3222f362e3b6e943e483bd9c6f9b","tokenType":"bearer","expiresIn":3600}`)
+		case "/iocm/app/maintenance/v1.2.0/devices/packages":
+			assert.Equal(t, "softwarePackage", r.URL.Query().Get("fileType"))
+			fmt.Fprintln(w, `{"totalCount":1,"data":[{"fileId":"sw1","fileType":"softwarePackage"}]}`)
+		case "/iocm/app/swupgrade/v1.1.0/operations":
+			fmt.Fprintln(w, `{"operationId":"op2"}`)
+		case "/iocm/app/maintenance/v1.1.0/operations/op2":
+			polls++
+			if polls == 1 {
+				fmt.Fprintln(w, `{"operationId":"op2","status":"processing","staResult":{"total":1,"processing":1}}`)
+				return
+			}
+			fmt.Fprintln(w, `{"operationId":"op2","status":"failed","staResult":{"total":1,"fail":1}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	clock := &fakeClock{}
+	c := Client{c: &http.Client{}, cfg: Config{URL: s.URL, AppID: "app", Clock: clock}}
+	sw := c.Software()
+	pkgs, err := sw.ListPackages(PackageFilter{})
+	if assert.Nil(t, err) && assert.Equal(t, 1, len(pkgs)) {
+		assert.Equal(t, PackageSoftware, pkgs[0].FileType)
+	}
+	op, err := sw.CreateTask("sw1", []string{"dev1"})
+	assert.Nil(t, err)
+	// without an interval the task is polled at the default interval
+	task, err := c.WaitUpgradeTask(context.Background(), op, 0, nil)
+	if assert.Nil(t, err) {
+		assert.Equal(t, UpgradeFailed, task.Status)
+		assert.Equal(t, 1.0, task.Progress())
+	}
+	assert.Equal(t, 2, polls)
+	assert.Equal(t, defaultUpgradePollInterval, clock.now.Sub(time.Time{}))
+}
@@ -0,0 +1,66 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is the semantic version of the package. Replaced identifiers are
+// kept as Deprecated wrappers, TestAPICompatibility checks the API against the
+// first release in testdata/apibase.
+const Version = "1.0.0"
+
+// ErrInvalidVersion is returned by CheckVersion for versions which aren't of
+// the form major.minor.patch
+var ErrInvalidVersion = errors.New("invalid version")
+
+// CheckVersion returns an error when the package isn't compatible with an
+// integration written against the version: the major versions differ, or the
+// package is older. Integrations can call it at start up to fail early when
+// built with an unexpected version of the package.
+func CheckVersion(version string) error {
+	want, err := parseVersion(version)
+	if err != nil {
+		return err
+	}
+	have, err := parseVersion(Version)
+	if err != nil {
+		return err
+	}
+	if want[0] != have[0] {
+		return fmt.Errorf("oceanconnect %s is incompatible with %s: major versions differ", Version, version)
+	}
+	for i := 1; i < len(want); i++ {
+		if have[i] != want[i] {
+			if have[i] < want[i] {
+				return fmt.Errorf("oceanconnect %s is older than %s", Version, version)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// parseVersion parses a major.minor.patch version, with an optional "v"
+// prefix
+func parseVersion(version string) ([3]int, error) {
+	var v [3]int
+	parts := strings.Split(strings.TrimPrefix(version, "v"), ".")
+	if len(parts) != len(v) {
+		return v, ErrInvalidVersion
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, ErrInvalidVersion
+		}
+		v[i] = n
+	}
+	return v, nil
+}
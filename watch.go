@@ -0,0 +1,142 @@
+// Copyright 2017 The go-oceanconnect authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oceanconnect
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// watchBuffer is the number of events buffered per watcher, events are
+// dropped when a watcher doesn't keep up
+const watchBuffer = 64
+
+// Event is a notification delivered to a watcher. Data holds the typed
+// notification, e.g. *DeviceDataChanged, *CommandStatusUpdate or
+// *CommandResponse.
+type Event struct {
+	Type     Notification
+	DeviceID string
+	Data     interface{}
+}
+
+type watcher struct {
+	match func(Event) bool
+	ch    chan Event
+}
+
+// watchHub fans dispatched notifications out to the watchers
+type watchHub struct {
+	lock     sync.Mutex
+	next     int
+	watchers map[int]*watcher
+}
+
+func (h *watchHub) watch(ctx context.Context, deviceID string) <-chan Event {
+	return h.watchFunc(ctx, func(ev Event) bool { return ev.DeviceID != "" && ev.DeviceID == deviceID })
+}
+
+// watchFunc returns a channel which receives the events match returns true for
+func (h *watchHub) watchFunc(ctx context.Context, match func(Event) bool) <-chan Event {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.watchers == nil {
+		h.watchers = make(map[int]*watcher)
+	}
+	id := h.next
+	h.next++
+	w := &watcher{match: match, ch: make(chan Event, watchBuffer)}
+	h.watchers[id] = w
+
+	go func() {
+		<-ctx.Done()
+		h.lock.Lock()
+		delete(h.watchers, id)
+		close(w.ch)
+		h.lock.Unlock()
+	}()
+	return w.ch
+}
+
+func (h *watchHub) active() bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return len(h.watchers) > 0
+}
+
+func (h *watchHub) publish(ev Event) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for _, w := range h.watchers {
+		if !w.match(ev) {
+			continue
+		}
+		select {
+		case w.ch <- ev:
+		default:
+			logrus.Warnf("watch channel is full, dropping %s event of device %s", string(ev.Type), ev.DeviceID)
+		}
+	}
+}
+
+// notificationDeviceID returns the device a typed notification is about
+func notificationDeviceID(v interface{}) string {
+	switch n := v.(type) {
+	case *DeviceDataChanged:
+		return n.DeviceID
+	case *CommandStatusUpdate:
+		return n.DeviceID
+	case *CommandResponse:
+		return n.Header.DeviceID
+	case *MessageConfirm:
+		return n.Header.DeviceID
+	case *DeviceAdded:
+		return n.DeviceID
+	case *DeviceInfoChanged:
+		return n.DeviceID
+	case *DeviceDatasChanged:
+		return n.DeviceID
+	case *DeviceDeleted:
+		return n.DeviceID
+	case *DeviceEvent:
+		return n.DeviceID
+	case *ServiceInfoChanged:
+		return n.DeviceID
+	case *SwUpgradeStateChanged:
+		return n.DeviceID
+	case *FwUpgradeStateChanged:
+		return n.DeviceID
+	}
+	return ""
+}
+
+// WatchDevice returns a channel which receives the events of the device
+// dispatched by d, until the context is done. The channel is closed then.
+func (d *Dispatcher) WatchDevice(ctx context.Context, deviceID string) <-chan Event {
+	return d.events().watch(ctx, deviceID)
+}
+
+func (d *Dispatcher) events() *watchHub {
+	d.cbsLock.Lock()
+	defer d.cbsLock.Unlock()
+
+	if d.hub == nil {
+		d.hub = &watchHub{}
+	}
+	return d.hub
+}
+
+// WatchDevice returns a channel which receives the data, command status and
+// command response events of the device, until the context is done. Events
+// are multiplexed from the Server returned by Subscribe and from the
+// FleetPoller created with NewFleetPoller, so a watcher doesn't need to know
+// whether notifications are pushed or polled.
+func (c *Client) WatchDevice(ctx context.Context, deviceID string) <-chan Event {
+	return c.events.watch(ctx, deviceID)
+}